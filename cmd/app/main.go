@@ -78,18 +78,34 @@ func main() {
 	defer redisCli.Close()
 	logging.L(ctx).Info("redis connected successfully")
 
-	queue := repository.NewQueue(redisCli.Client())
+	queue := repository.NewQueue(redisCli.Client(), time.Duration(cfg.Webhook.VisibilityTimeoutSecs)*time.Second)
 
 	incedentService := repository.NewIncidentRepository(db.Client())
 	coordinatesService := repository.NewCoordinatesRepository(db.Client())
 	cache := repository.NewCacheRepository(redisCli.Client())
-
-	svc := service.NewService(incedentService, coordinatesService, queue, cache, logger)
+	membership := repository.NewMembershipRepository(redisCli.Client())
+	webhooks := repository.NewWebhookSubscriptionRepository(db.Client())
+
+	svc := service.NewService(incedentService, coordinatesService, queue, cache, membership, webhooks, logger, service.Options{
+		DwellThreshold:     time.Duration(cfg.App.ZoneDwellMins) * time.Minute,
+		WarningBufferM:     cfg.App.WarningBufferMeters,
+		BatchMaxPoints:     cfg.App.BatchCheckMaxPoints,
+		MaxSegmentGap:      time.Duration(cfg.App.SegmentMaxGapMins) * time.Minute,
+		Categories:         cfg.App.IncidentCategories,
+		PurgeRetentionDays: cfg.App.PurgeRetentionDays,
+		ImportMaxIncidents: cfg.App.ImportMaxIncidents,
+		ExportMaxIncidents: cfg.App.ExportMaxIncidents,
+		OccupantWindowMins: cfg.App.OccupantWindowMins,
+	})
 
 	// Запуск вебхук воркера
-	webhookWorker := worker.NewWebhookWorker(queue, cfg.Webhook.URL, logger)
+	webhookWorker := worker.NewWebhookWorker(queue, webhooks, cfg.Webhook.URL, cfg.Webhook.Secret, logger)
 	go webhookWorker.Start(ctx)
 
+	// Запуск воркера расписания инцидентов
+	scheduleWorker := worker.NewScheduleWorker(svc, time.Duration(cfg.App.ScheduleIntervalSecs)*time.Second, logger)
+	go scheduleWorker.Start(ctx)
+
 	httpMux := handler.NewRouter(svc, logger, cfg)
 	httpAddr := ":" + cfg.App.Port
 	httpServer := handler.NewServer(httpAddr, httpMux)
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"red_collar/internal/config"
 	"red_collar/internal/domain"
+	"red_collar/internal/signature"
+	"time"
 
 	"github.com/theartofdevel/logging"
 )
@@ -28,28 +31,89 @@ func main() {
 
 	ctx = logging.ContextWithLogger(ctx, logger)
 
+	// Подпись проверяется секретом WEBHOOK_SECRET: тем же, которым сервис подписывает запросы на WEBHOOK_URL.
+	// Для подписки из /api/v1/webhooks нужно задать ей этот же секрет
+	tolerance := time.Duration(cfg.Webhook.SignatureToleranceSecs) * time.Second
+	if cfg.Webhook.Secret == "" {
+		logging.L(ctx).Warn("WEBHOOK_SECRET is empty, signature verification is disabled")
+	}
+
+	// X-Timestamp может отставать или опережать часы получателя на tolerance, поэтому
+	// запрос проходит проверку подписи в течение 2*tolerance - столько и помним id событий
+	seen := newSeenEvents(2 * tolerance)
+
 	http.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 
-		var check domain.LocationCheck
-		if err := json.NewDecoder(r.Body).Decode(&check); err != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logging.L(ctx).Error("Error reading request", logging.ErrAttr(err))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if cfg.Webhook.Secret != "" {
+			err := signature.Verify(
+				cfg.Webhook.Secret,
+				r.Header.Get(signature.TimestampHeader),
+				r.Header.Get(signature.SignatureHeader),
+				body,
+				tolerance,
+				time.Now(),
+			)
+			if err != nil {
+				logging.L(ctx).Warn("Rejected webhook",
+					logging.StringAttr("Timestamp", r.Header.Get(signature.TimestampHeader)),
+					logging.ErrAttr(err),
+				)
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprintf(w, "Error: %v", err)
+				return
+			}
+		}
+
+		var event domain.EventEnvelope
+		if err := json.Unmarshal(body, &event); err != nil {
 			logging.L(ctx).Error("Error decoding request", logging.ErrAttr(err))
 			w.WriteHeader(http.StatusBadRequest)
 			fmt.Fprintf(w, "Error: %v", err)
 			return
 		}
 
-		logging.L(ctx).Info("Received webhook",
-			logging.IntAttr("CheckID", check.ID),
-			logging.StringAttr("UserID", check.UserID),
-			logging.BoolAttr("InDangerZone", check.InDangerZone),
-			logging.IntAttr("NearestID", *check.NearestID),
-			logging.Float64Attr("Lat", check.Lat),
-			logging.Float64Attr("Long", check.Long),
-		)
+		// Повторная доставка того же события (в том числе штатный повтор после потерянного ответа)
+		// подтверждается 2xx, чтобы сервис не повторял ее дальше, но не обрабатывается
+		if event.ID != "" && !seen.add(event.ID, time.Now()) {
+			logging.L(ctx).Warn("Duplicate webhook ignored",
+				logging.StringAttr("ID", event.ID),
+				logging.StringAttr("Event", string(event.Type)),
+			)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(map[string]string{
+				"message": "duplicate webhook ignored",
+			})
+			return
+		}
+
+		attrs := []any{
+			logging.StringAttr("ID", event.ID),
+			logging.StringAttr("Event", string(event.Type)),
+			logging.StringAttr("Version", r.Header.Get(domain.EventVersionHeader)),
+			logging.IntAttr("IncidentID", event.Data.IncidentID),
+		}
+		if check := event.Data.LocationCheck; check != nil {
+			attrs = append(attrs,
+				logging.IntAttr("CheckID", check.ID),
+				logging.StringAttr("UserID", check.UserID),
+				logging.BoolAttr("InDangerZone", check.InDangerZone),
+				logging.Float64Attr("Lat", check.Lat),
+				logging.Float64Attr("Long", check.Long),
+			)
+		}
+		logging.L(ctx).Info("Received webhook", attrs...)
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
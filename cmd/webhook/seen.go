@@ -0,0 +1,43 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// seenEvents помнит id полученных событий в течение ttl. Перехваченный запрос, повторенный
+// в пределах окна допуска X-Timestamp, проходит проверку подписи, поэтому отсекается по id
+type seenEvents struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	seen      map[string]time.Time
+	nextPrune time.Time
+}
+
+func newSeenEvents(ttl time.Duration) *seenEvents {
+	return &seenEvents{
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// add запоминает id и возвращает false, если событие с таким id уже было получено за последние ttl
+func (s *seenEvents) add(id string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextPrune) {
+		for seenID, at := range s.seen {
+			if now.Sub(at) >= s.ttl {
+				delete(s.seen, seenID)
+			}
+		}
+		s.nextPrune = now.Add(s.ttl)
+	}
+
+	if at, ok := s.seen[id]; ok && now.Sub(at) < s.ttl {
+		return false
+	}
+	s.seen[id] = now
+	return true
+}
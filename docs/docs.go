@@ -16,14 +16,48 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/incidents": {
+        "/admin/incidents/purge": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Окончательно удаляет инциденты, находящиеся в архиве дольше INCIDENT_PURGE_RETENTION_DAYS дней. Доступно только ключам из ADMIN_API_KEYS",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Очистка архива инцидентов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.purgeIncidentsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/dlq": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Получает список инцидентов с пагинацией",
+                "description": "Возвращает задачи доставки, которые исчерпали попытки или получили неповторяемую ошибку, с последней ошибкой и числом попыток. Сначала последние попавшие в DLQ. Доступно только ключам из ADMIN_API_KEYS",
                 "consumes": [
                     "application/json"
                 ],
@@ -31,36 +65,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "incidents"
+                    "webhooks"
                 ],
-                "summary": "Получение списка инцидентов",
+                "summary": "Список DLQ вебхуков",
                 "parameters": [
                     {
                         "type": "integer",
-                        "default": 1,
-                        "description": "Номер страницы",
-                        "name": "page",
+                        "description": "ID подписки, 0 - WEBHOOK_URL",
+                        "name": "subscription_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тип события",
+                        "name": "event_type",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "default": 5,
-                        "description": "Количество на странице",
+                        "description": "ID инцидента",
+                        "name": "incident_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество записей, до 500",
                         "name": "limit",
                         "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Сколько записей пропустить",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/service.PaginateIncidentsOutput"
+                            "$ref": "#/definitions/service.DeadLettersOutput"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.badRequestErrorResponsePaginate"
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
                         }
                     },
                     "401": {
@@ -68,16 +120,22 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.unauthorizedErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
+                        }
                     }
                 }
             },
-            "post": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Создает новую опасную зону с географическими координатами",
+                "description": "Удаляет записи, попавшие в DLQ раньше before. Доступно только ключам из ADMIN_API_KEYS",
                 "consumes": [
                     "application/json"
                 ],
@@ -85,25 +143,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "incidents"
+                    "webhooks"
                 ],
-                "summary": "Создание инцидента",
+                "summary": "Очистка DLQ",
                 "parameters": [
                     {
-                        "description": "Данные инцидента",
-                        "name": "incident",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.IncidentJSON"
-                        }
+                        "type": "string",
+                        "description": "Граница времени попадания в DLQ (RFC3339)",
+                        "name": "before",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.incedentRequestResponse"
+                            "$ref": "#/definitions/handler.deleteDeadLettersResponse"
                         }
                     },
                     "400": {
@@ -127,9 +183,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/incidents/stats": {
-            "get": {
-                "description": "Получает статистику по количеству пользователей в каждой зоне за указанный временной период",
+        "/admin/webhooks/dlq/replay": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает в очередь доставки записи DLQ, подходящие под фильтры, или всю DLQ с all=true. Счетчик попыток сбрасывается. Доступно только ключам из ADMIN_API_KEYS",
                 "consumes": [
                     "application/json"
                 ],
@@ -137,14 +198,37 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "incidents"
+                    "webhooks"
+                ],
+                "summary": "Повтор записей DLQ",
+                "parameters": [
+                    {
+                        "description": "Отбор записей",
+                        "name": "filter",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ReplayDeadLettersJSON"
+                        }
+                    }
                 ],
-                "summary": "Статистика по зонам",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.statsRequestResponse"
+                            "$ref": "#/definitions/handler.replayDeadLettersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
                         }
                     },
                     "500": {
@@ -156,14 +240,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/incidents/{id}": {
-            "get": {
+        "/admin/webhooks/dlq/{id}/replay": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Получает информацию об инциденте по его идентификатору",
+                "description": "Возвращает одну запись DLQ в очередь доставки со сброшенным счетчиком попыток. Доступно только ключам из ADMIN_API_KEYS",
                 "consumes": [
                     "application/json"
                 ],
@@ -171,13 +255,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "incidents"
+                    "webhooks"
                 ],
-                "summary": "Получение инцидента по ID",
+                "summary": "Повтор записи DLQ",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID инцидента",
+                        "type": "string",
+                        "description": "ID записи DLQ",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -187,13 +271,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.incedentRequestResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/handler.badRequestErrorResponseGetByID"
+                            "$ref": "#/definitions/handler.replayDeadLettersResponse"
                         }
                     },
                     "401": {
@@ -207,55 +285,195 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.notFoundErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
+                        }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/incidents": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Полностью обновляет данные инцидента",
+                "description": "Получает список инцидентов с пагинацией (page/limit или курсор), фильтрами, полнотекстовым поиском и сортировкой",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/geo+json",
+                    "text/csv",
+                    "application/vnd.google-earth.kml+xml"
                 ],
                 "tags": [
                     "incidents"
                 ],
-                "summary": "Обновление инцидента",
+                "summary": "Получение списка инцидентов",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID инцидента",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
                     },
                     {
-                        "description": "Обновленные данные инцидента",
-                        "name": "incident",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.IncidentJSON"
-                        }
+                        "type": "integer",
+                        "default": 5,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Уровни опасности через запятую (info, warning, danger, critical)",
+                        "name": "severity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Фильтр по флагу active",
+                        "name": "active",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создан не раньше (RFC3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создан не позже (RFC3339)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Обновлен не раньше (RFC3339)",
+                        "name": "updated_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Обновлен не позже (RFC3339)",
+                        "name": "updated_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Полнотекстовый поиск по названию и описанию",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Прямоугольник minLon,minLat,maxLon,maxLat",
+                        "name": "bbox",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Широта точки для поиска зон рядом",
+                        "name": "lat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Долгота точки для поиска зон рядом",
+                        "name": "long",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальное расстояние от точки до границы зоны в метрах",
+                        "name": "radius_m",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "updated_at",
+                            "title",
+                            "active",
+                            "severity",
+                            "category",
+                            "distance",
+                            "relevance"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Поле сортировки",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Направление сортировки",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exclude",
+                            "include",
+                            "only"
+                        ],
+                        "type": "string",
+                        "default": "exclude",
+                        "description": "Учет архивных инцидентов",
+                        "name": "deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor). Пустое значение - первая страница в курсорном режиме, page игнорируется. Курсор действителен только с тем же order",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "geojson",
+                            "csv",
+                            "kml"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Формат выгрузки. geojson, csv и kml возвращают все подходящие инциденты одним файлом без пагинации",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.incedentRequestResponse"
+                            "$ref": "#/definitions/service.PaginateIncidentsOutput"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                            "$ref": "#/definitions/handler.badRequestErrorResponsePaginate"
                         }
                     },
                     "401": {
@@ -263,22 +481,16 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.unauthorizedErrorResponse"
                         }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/handler.notFoundErrorResponse"
-                        }
                     }
                 }
             },
-            "delete": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Удаляет инцидент по его идентификатору",
+                "description": "Создает новую опасную зону: круг (lat/long и radius_m) или полигон (boundary в формате GeoJSON Polygon/MultiPolygon)",
                 "consumes": [
                     "application/json"
                 ],
@@ -288,24 +500,29 @@ const docTemplate = `{
                 "tags": [
                     "incidents"
                 ],
-                "summary": "Удаление инцидента",
+                "summary": "Создание инцидента",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID инцидента",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Данные инцидента",
+                        "name": "incident",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.IncidentJSON"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handler.incedentRequestResponse"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.badRequestErrorResponseGetByID"
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
                         }
                     },
                     "401": {
@@ -314,36 +531,61 @@ const docTemplate = `{
                             "$ref": "#/definitions/handler.unauthorizedErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/location/check": {
+        "/incidents/import": {
             "post": {
-                "description": "Проверяет, находится ли пользователь в опасной зоне. Если да, отправляет webhook-уведомление.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Создает или обновляет инциденты из GeoJSON FeatureCollection, CSV или KML одной транзакцией, сопоставляя их по названию. Формат задается параметром format или заголовком Content-Type. Каждый объект проверяется по правилам создания инцидента; если хотя бы один не прошел проверку, ничего не записывается и возвращается 422 с ошибками по объектам. С dry_run=true данные только проверяются",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.google-earth.kml+xml"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "location"
+                    "incidents"
                 ],
-                "summary": "Проверка координат",
+                "summary": "Импорт инцидентов",
                 "parameters": [
                     {
-                        "description": "Координаты пользователя",
-                        "name": "coordinates",
+                        "enum": [
+                            "geojson",
+                            "csv",
+                            "kml"
+                        ],
+                        "type": "string",
+                        "description": "Формат данных, по умолчанию определяется по Content-Type",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Только проверить данные",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "description": "GeoJSON FeatureCollection, CSV с колонками IncidentJSON или KML",
+                        "name": "data",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.CheckJSON"
+                            "type": "object"
                         }
                     }
                 ],
@@ -351,7 +593,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.LocationCheck"
+                            "$ref": "#/definitions/service.ImportIncidentsOutput"
                         }
                     },
                     "400": {
@@ -360,6 +602,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/handler.badRequestErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/handler.apiErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/service.ImportIncidentsOutput"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -369,9 +629,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/system/health": {
+        "/incidents/stats": {
             "get": {
-                "description": "Проверка работоспособности сервиса",
+                "description": "Без параметров возвращает количество пользователей в каждой зоне за STATS_TIME_WINDOW_MINUTES (statsRequestResponse). С любым из параметров from, to, bucket, incident_id возвращает временные ряды по зонам: уникальные пользователи, число проверок, первая и последняя проверка в каждом интервале. Без from период равен STATS_TIME_WINDOW_MINUTES до to, начало выравнивается по границе интервала, ряд не длиннее 1440 интервалов",
                 "consumes": [
                     "application/json"
                 ],
@@ -379,36 +639,1509 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "incidents"
+                ],
+                "summary": "Статистика по зонам",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Начало периода (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода, не включается (RFC3339), по умолчанию сейчас",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "1m",
+                            "5m",
+                            "1h",
+                            "1d"
+                        ],
+                        "type": "string",
+                        "description": "Интервал ряда, по умолчанию самый мелкий допустимый для периода",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID зоны",
+                        "name": "incident_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Health Check",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/service.StatsSeriesOutput"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/incidents/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Получает информацию об инциденте по его идентификатору. С параметром as_of возвращает состояние инцидента на указанный момент",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incidents"
+                ],
+                "summary": "Получение инцидента по ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID инцидента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Момент времени (RFC3339)",
+                        "name": "as_of",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.incedentRequestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponseGetByID"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Полностью обновляет данные инцидента",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incidents"
+                ],
+                "summary": "Обновление инцидента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID инцидента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag, полученный при чтении инцидента, или список ETag через запятую",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Обновленные данные инцидента",
+                        "name": "incident",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.IncidentJSON"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.incedentRequestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.preconditionFailedErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Переносит инцидент в архив: он перестает участвовать в проверках и пропадает из списка, но остается в истории и статистике",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incidents"
+                ],
+                "summary": "Удаление инцидента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID инцидента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponseGetByID"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
                         }
                     }
                 }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Применяет JSON Merge Patch (RFC 7396): переданные поля заменяются, null сбрасывает поле, остальные не меняются.\nПри заголовке If-Match обновление выполняется только если ETag совпадает с текущей версией инцидента",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incidents"
+                ],
+                "summary": "Частичное обновление инцидента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID инцидента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag, полученный при чтении инцидента, или список ETag через запятую",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "JSON Merge Patch",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.incedentRequestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.preconditionFailedErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/incidents/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает все версии инцидента (создание, обновления, удаление) с автором изменения, начиная с последней",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incidents"
+                ],
+                "summary": "История изменений инцидента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID инцидента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.incidentHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponseGetByID"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/incidents/{id}/occupants": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает пользователей, которые сейчас находятся в зоне инцидента: их последняя проверка попадает в зону и сделана не раньше, чем minutes минут назад. Зона проверяется по текущей геометрии инцидента, для выключенного или неактивного по расписанию инцидента список пуст",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incidents"
+                ],
+                "summary": "Пользователи в зоне",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID инцидента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Окно актуальности последней проверки в минутах, до 1440 (по умолчанию OCCUPANT_WINDOW_MINUTES)",
+                        "name": "minutes",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.IncidentOccupantsOutput"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/incidents/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает инцидент из архива",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "incidents"
+                ],
+                "summary": "Восстановление инцидента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID инцидента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.incedentRequestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponseGetByID"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.alreadyExistsErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/location/check": {
+            "post": {
+                "description": "Проверяет, находится ли пользователь в опасной зоне. При входе в зону или выходе из нее отправляет webhook-уведомление.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "location"
+                ],
+                "summary": "Проверка координат",
+                "parameters": [
+                    {
+                        "description": "Координаты пользователя",
+                        "name": "coordinates",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CheckJSON"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.LocationCheck"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/location/check/batch": {
+            "post": {
+                "description": "Проверяет набор точек (в том числе разных пользователей) одним запросом к БД. Результаты возвращаются в порядке входных точек, переходы между зонами считаются в порядке checked_at.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "location"
+                ],
+                "summary": "Пакетная проверка координат",
+                "parameters": [
+                    {
+                        "description": "Координаты пользователей",
+                        "name": "points",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CheckBatchJSON"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.checkBatchRequestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/location/heatmap": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Считает проверки координат в ячейках сетки над областью bbox за период. Сетка - geohash заданной точности или квадраты/шестиугольники заданного размера в метрах Web Mercator. Ячейки без проверок не возвращаются. С format=geojson ячейки возвращаются как FeatureCollection полигонов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "location"
+                ],
+                "summary": "Тепловая карта проверок",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Область minLon,minLat,maxLon,maxLat",
+                        "name": "bbox",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало периода (RFC3339), по умолчанию сутки до to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода, не включается (RFC3339), по умолчанию сейчас",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "geohash",
+                            "square",
+                            "hex"
+                        ],
+                        "type": "string",
+                        "default": "geohash",
+                        "description": "Сетка",
+                        "name": "grid",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 6,
+                        "description": "Длина geohash, от 1 до 9",
+                        "name": "precision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 500,
+                        "description": "Размер квадрата или сторона шестиугольника в метрах, не меньше 10",
+                        "name": "size_m",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только проверки в опасных зонах",
+                        "name": "in_danger",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "geojson"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Формат ответа",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.HeatmapOutput"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/health": {
+            "get": {
+                "description": "Проверка работоспособности сервиса",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Health Check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/checks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает проверки координат пользователя с зонами, в которые попала каждая точка, с курсорной пагинацией. С format=geojson та же страница возвращается треком: линия LineString через все точки и точка Point на каждую проверку с признаком in_danger_zone и incident_ids",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "location"
+                ],
+                "summary": "История проверок пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Проверки не раньше (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Проверки раньше (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Количество на странице, до 1000",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor), действителен только с тем же order",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Направление по времени",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "geojson"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Формат ответа",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.UserChecksOutput"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает все подписки без секретов. Доступно только ключам из ADMIN_API_KEYS",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Список подписок на вебхуки",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.webhookSubscriptionsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Добавляет получателя вебхуков с фильтрами по типам событий, зонам, уровню опасности и области. Секрет возвращается только в этом ответе. Доступно только ключам из ADMIN_API_KEYS",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Создание подписки на вебхуки",
+                "parameters": [
+                    {
+                        "description": "Данные подписки",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.WebhookSubscriptionJSON"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handler.webhookSubscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.internalServerErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/events": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Каждый запрос вебхука содержит конверт: id (UUID, ключ идемпотентности - одинаков во всех повторах и для всех подписок), type, version, occurred_at и data с данными события. Версия схемы дублируется в заголовке X-Webhook-Version и меняется только при несовместимых изменениях. Для incident.deleted в data заполнен только incident_id. Доступно только ключам из ADMIN_API_KEYS",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Формат событий вебхуков",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.webhookEventsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает подписку без секрета. Доступно только ключам из ADMIN_API_KEYS",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Получение подписки на вебхуки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.webhookSubscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponseGetByID"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Полностью заменяет адрес, флаг enabled и фильтры подписки. Без secret секрет остается прежним. Доступно только ключам из ADMIN_API_KEYS",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Обновление подписки на вебхуки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Данные подписки",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.WebhookSubscriptionJSON"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.webhookSubscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Удаляет подписку. Ее задачи, которые еще ждут повторной отправки, отбрасываются. Доступно только ключам из ADMIN_API_KEYS",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Удаление подписки на вебхуки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponseGetByID"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/rotate-secret": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Заменяет секрет подписки. Прежний секрет действует еще grace_period_seconds секунд (по умолчанию сутки): все это время X-Signature содержит подписи обоими секретами, и получатель может сменить секрет в любой момент. Новый секрет возвращается только в этом ответе. Доступно только ключам из ADMIN_API_KEYS",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Ротация секрета подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры ротации",
+                        "name": "rotate",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RotateWebhookSecretJSON"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.webhookSubscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.badRequestErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.unauthorizedErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.notFoundErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "domain.BBox": {
+            "type": "object",
+            "properties": {
+                "max_lat": {
+                    "type": "number"
+                },
+                "max_long": {
+                    "type": "number"
+                },
+                "min_lat": {
+                    "type": "number"
+                },
+                "min_long": {
+                    "type": "number"
+                }
+            }
+        },
+        "domain.DeadLetter": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "event": {
+                    "$ref": "#/definitions/domain.Event"
+                },
+                "failed_at": {
+                    "type": "string"
+                },
+                "first_attempt": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID - ID события и подписки через двоеточие, см. DeadLetterID",
+                    "type": "string",
+                    "example": "3f0c8c1e-7a4b-4d2e-9c1a-5b6d7e8f9a0b:1"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "raw": {
+                    "description": "Raw - исходная запись, если ее не удалось разобрать. Event у такой записи нет, а ID выводится из содержимого",
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "description": "SubscriptionID отсутствует, если событие не удалось разложить по подпискам",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Event": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID и OccurredAt задаются при постановке в очередь и не меняются при повторах\nи раскладке по подпискам, поэтому получатель может по ID отбрасывать дубли",
+                    "type": "string"
+                },
+                "incident": {
+                    "description": "Incident - зона, к которой относится событие incident.*",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Incident"
+                        }
+                    ]
+                },
+                "incident_id": {
+                    "type": "integer"
+                },
+                "location_check": {
+                    "$ref": "#/definitions/domain.LocationCheck"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "retroactive": {
+                    "description": "Retroactive - zone.entered отправлен не по новой проверке, а потому что зона появилась\nили расширилась там, где уже находился пользователь. LocationCheck - его последняя точка",
+                    "type": "boolean"
+                },
+                "severity": {
+                    "$ref": "#/definitions/domain.Severity"
+                },
+                "type": {
+                    "$ref": "#/definitions/domain.EventType"
+                }
+            }
+        },
+        "domain.EventData": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "incident": {
+                    "$ref": "#/definitions/domain.Incident"
+                },
+                "incident_id": {
+                    "type": "integer"
+                },
+                "location_check": {
+                    "$ref": "#/definitions/domain.LocationCheck"
+                },
+                "retroactive": {
+                    "type": "boolean"
+                },
+                "severity": {
+                    "$ref": "#/definitions/domain.Severity"
+                }
+            }
+        },
+        "domain.EventEnvelope": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/domain.EventData"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f0c8c1e-7a4b-4d2e-9c1a-5b6d7e8f9a0b"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.EventType"
+                        }
+                    ],
+                    "example": "zone.entered"
+                },
+                "version": {
+                    "type": "string",
+                    "example": "1"
+                }
+            }
+        },
+        "domain.EventType": {
+            "type": "string",
+            "enum": [
+                "zone.entered",
+                "zone.exited",
+                "zone.dwell",
+                "zone.approaching",
+                "zone.crossed",
+                "incident.activated",
+                "incident.expired",
+                "incident.created",
+                "incident.updated",
+                "incident.deleted"
+            ],
+            "x-enum-varnames": [
+                "EventZoneEntered",
+                "EventZoneExited",
+                "EventZoneDwell",
+                "EventZoneApproaching",
+                "EventZoneCrossed",
+                "EventIncidentActivated",
+                "EventIncidentExpired",
+                "EventIncidentCreated",
+                "EventIncidentUpdated",
+                "EventIncidentDeleted"
+            ]
+        },
+        "domain.HeatmapCell": {
+            "type": "object",
+            "properties": {
+                "cell_id": {
+                    "description": "ID - geohash или индексы ячейки сетки \"i:j\"",
+                    "type": "string"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "geometry": {
+                    "type": "object"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "long": {
+                    "type": "number"
+                },
+                "unique_users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.HeatmapGrid": {
+            "type": "string",
+            "enum": [
+                "geohash",
+                "square",
+                "hex"
+            ],
+            "x-enum-varnames": [
+                "HeatmapGridGeohash",
+                "HeatmapGridSquare",
+                "HeatmapGridHex"
+            ]
+        },
+        "domain.Incident": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "boundary": {
+                    "description": "Boundary - граница зоны в формате GeoJSON (Polygon/MultiPolygon).\nЕсли задана, зона считается полигональной, а lat/long указывают на ее центроид",
+                    "type": "object"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "DeletedAt - момент архивации. Архивные инциденты не участвуют в проверках и по умолчанию не попадают в список",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "long": {
+                    "type": "number"
+                },
+                "radius_m": {
+                    "type": "integer"
+                },
+                "severity": {
+                    "$ref": "#/definitions/domain.Severity"
+                },
+                "starts_at": {
+                    "description": "StartsAt/EndsAt - необязательное расписание зоны. Вне его зона не учитывается при проверках,\nдаже если Active = true",
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version увеличивается при каждом изменении и служит основой ETag.\nПри обновлении ненулевое значение означает ожидаемую текущую версию",
+                    "type": "integer"
+                },
+                "warning_buffer_m": {
+                    "description": "WarningBuffer - ширина зоны предупреждения вокруг границы в метрах.\nЕсли не задана, используется глобальное значение WARNING_BUFFER_METERS",
+                    "type": "integer"
+                }
             }
-        }
-    },
-    "definitions": {
-        "domain.Incident": {
+        },
+        "domain.IncidentMatch": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "distance_m": {
+                    "type": "number"
+                },
+                "incident_id": {
+                    "type": "integer"
+                },
+                "severity": {
+                    "$ref": "#/definitions/domain.Severity"
+                }
+            }
+        },
+        "domain.IncidentVersion": {
             "type": "object",
             "properties": {
                 "active": {
                     "type": "boolean"
                 },
+                "boundary": {
+                    "description": "Boundary - граница зоны в формате GeoJSON (Polygon/MultiPolygon).\nЕсли задана, зона считается полигональной, а lat/long указывают на ее центроид",
+                    "type": "object"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "changed_at": {
+                    "type": "string"
+                },
+                "changed_by": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "description": "DeletedAt - момент архивации. Архивные инциденты не участвуют в проверках и по умолчанию не попадают в список",
+                    "type": "string"
+                },
                 "description": {
                     "type": "string"
                 },
+                "ends_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -418,23 +2151,55 @@ const docTemplate = `{
                 "long": {
                     "type": "number"
                 },
+                "operation": {
+                    "type": "string"
+                },
                 "radius_m": {
                     "type": "integer"
                 },
+                "severity": {
+                    "$ref": "#/definitions/domain.Severity"
+                },
+                "starts_at": {
+                    "description": "StartsAt/EndsAt - необязательное расписание зоны. Вне его зона не учитывается при проверках,\nдаже если Active = true",
+                    "type": "string"
+                },
                 "title": {
                     "type": "string"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "version": {
+                    "description": "Version увеличивается при каждом изменении и служит основой ETag.\nПри обновлении ненулевое значение означает ожидаемую текущую версию",
+                    "type": "integer"
+                },
+                "warning_buffer_m": {
+                    "description": "WarningBuffer - ширина зоны предупреждения вокруг границы в метрах.\nЕсли не задана, используется глобальное значение WARNING_BUFFER_METERS",
+                    "type": "integer"
                 }
             }
         },
         "domain.LocationCheck": {
             "type": "object",
             "properties": {
+                "approaching": {
+                    "description": "Approaching - зоны, к границе которых пользователь приблизился, но еще не вошел",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ProximityWarning"
+                    }
+                },
                 "checked_at": {
                     "type": "string"
                 },
+                "crossed": {
+                    "description": "Crossed - зоны, которые пользователь пересек между предыдущей и текущей проверкой",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ZoneCrossing"
+                    }
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -447,61 +2212,383 @@ const docTemplate = `{
                 "long": {
                     "type": "number"
                 },
+                "matches": {
+                    "description": "Matches - все зоны, в которые попала точка, от ближайшей к дальней",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.IncidentMatch"
+                    }
+                },
                 "nearest_id": {
                     "type": "integer"
                 },
+                "primary_id": {
+                    "description": "PrimaryID - самая опасная из зон, в которые попала точка (при равенстве - ближайшая)",
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ProximityWarning": {
+            "type": "object",
+            "properties": {
+                "bearing_deg": {
+                    "description": "BearingDeg - направление от пользователя на ближайшую точку границы, градусы от севера по часовой стрелке",
+                    "type": "number"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "distance_to_edge_m": {
+                    "type": "number"
+                },
+                "incident_id": {
+                    "type": "integer"
+                },
+                "severity": {
+                    "$ref": "#/definitions/domain.Severity"
+                }
+            }
+        },
+        "domain.Severity": {
+            "type": "string",
+            "enum": [
+                "info",
+                "warning",
+                "danger",
+                "critical"
+            ],
+            "x-enum-varnames": [
+                "SeverityInfo",
+                "SeverityWarning",
+                "SeverityDanger",
+                "SeverityCritical"
+            ]
+        },
+        "domain.WebhookSubscription": {
+            "type": "object",
+            "properties": {
+                "bbox": {
+                    "description": "BBox - область, в которую должна попасть точка события, nil - без ограничения",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.BBox"
+                        }
+                    ]
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "event_types": {
+                    "description": "EventTypes - типы событий, пустой список - все события",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.EventType"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "incident_ids": {
+                    "description": "IncidentIDs - зоны, события которых доставляются, пустой список - все зоны",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "previous_secret_expires_at": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Secret возвращается только при создании подписки и ротации секрета",
+                    "type": "string"
+                },
+                "severities": {
+                    "description": "Severities - уровни опасности зоны, пустой список - любой уровень",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Severity"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ZoneCrossing": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "estimated_entry_at": {
+                    "type": "string"
+                },
+                "incident_id": {
+                    "type": "integer"
+                },
+                "severity": {
+                    "$ref": "#/definitions/domain.Severity"
+                }
+            }
+        },
+        "domain.ZoneOccupant": {
+            "type": "object",
+            "properties": {
+                "check_id": {
+                    "type": "integer"
+                },
+                "distance_m": {
+                    "type": "number"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "long": {
+                    "type": "number"
+                },
                 "user_id": {
                     "type": "string"
                 }
             }
         },
-        "domain.ZoneStat": {
+        "domain.ZoneStatBucket": {
+            "type": "object",
+            "properties": {
+                "first_seen": {
+                    "type": "string"
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "total_checks": {
+                    "type": "integer"
+                },
+                "unique_users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.CheckBatchJSON": {
+            "description": "Набор координат пользователей для проверки",
+            "type": "object",
+            "properties": {
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.CheckPointJSON"
+                    }
+                }
+            }
+        },
+        "handler.CheckJSON": {
+            "description": "Координаты пользователя для проверки",
+            "type": "object",
+            "properties": {
+                "lat": {
+                    "type": "number"
+                },
+                "long": {
+                    "type": "number"
+                },
+                "used_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.CheckPointJSON": {
+            "description": "Координаты пользователя и время их снятия",
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "description": "CheckedAt - время снятия координат (RFC3339), не позже текущего. По умолчанию - время запроса",
+                    "type": "string",
+                    "example": "2026-10-16T10:00:00Z"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "long": {
+                    "type": "number"
+                },
+                "used_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.IncidentJSON": {
+            "description": "Данные инцидента (опасной зоны)",
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "boundary": {
+                    "description": "Boundary - GeoJSON Polygon/MultiPolygon. Если задан, lat/long/radius_m игнорируются",
+                    "type": "object"
+                },
+                "category": {
+                    "description": "Category - категория из списка INCIDENT_CATEGORIES, по умолчанию other",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "long": {
+                    "type": "number"
+                },
+                "radius_m": {
+                    "type": "integer"
+                },
+                "severity": {
+                    "description": "Severity - info, warning (по умолчанию), danger или critical",
+                    "type": "string"
+                },
+                "starts_at": {
+                    "description": "StartsAt/EndsAt - необязательное расписание зоны в формате RFC 3339",
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "warning_buffer_m": {
+                    "description": "WarningBuffer - ширина полосы предупреждения вокруг зоны в метрах. Если не задан, используется глобальное значение",
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.ReplayDeadLettersJSON": {
+            "description": "Фильтры записей DLQ. Без фильтров нужно передать all=true",
+            "type": "object",
+            "properties": {
+                "all": {
+                    "description": "All - повторить всю DLQ, не сочетается с фильтрами",
+                    "type": "boolean"
+                },
+                "event_type": {
+                    "type": "string",
+                    "example": "zone.entered"
+                },
+                "incident_id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "subscription_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handler.RotateWebhookSecretJSON": {
+            "description": "Новый секрет и срок действия прежнего",
             "type": "object",
             "properties": {
-                "user_count": {
+                "grace_period_seconds": {
+                    "description": "GracePeriodSecs - сколько секунд прежний секрет еще действует, по умолчанию 86400, не больше 604800",
                     "type": "integer"
                 },
-                "zone_id": {
-                    "type": "integer"
+                "secret": {
+                    "description": "Secret - новый секрет не короче 16 символов, без него секрет генерируется",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.WebhookSubscriptionJSON": {
+            "description": "Получатель вебхуков и фильтры событий. Пустой фильтр не ограничивает события",
+            "type": "object",
+            "properties": {
+                "bbox": {
+                    "description": "BBox - [minLon, minLat, maxLon, maxLat], в который должна попасть точка события",
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "event_types": {
+                    "description": "EventTypes - типы событий, например zone.entered или incident.expired",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "incident_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "secret": {
+                    "description": "Secret - не короче 16 символов. При создании без секрета он генерируется, при обновлении без секрета остается прежним",
+                    "type": "string"
+                },
+                "severities": {
+                    "description": "Severities - уровни опасности зоны: info, warning, danger, critical",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/webhook"
                 }
             }
         },
-        "handler.CheckJSON": {
-            "description": "Координаты пользователя для проверки",
+        "handler.alreadyExistsErrorResponse": {
             "type": "object",
             "properties": {
-                "lat": {
-                    "type": "number"
-                },
-                "long": {
-                    "type": "number"
-                },
-                "used_id": {
-                    "type": "string"
+                "error": {
+                    "type": "object",
+                    "properties": {
+                        "code": {
+                            "type": "string",
+                            "example": "ALREADY_EXISTS"
+                        },
+                        "message": {
+                            "type": "string",
+                            "example": "incident already exists"
+                        }
+                    }
                 }
             }
         },
-        "handler.IncidentJSON": {
-            "description": "Данные инцидента (опасной зоны)",
+        "handler.apiErrorResponse": {
+            "description": "Структура ошибки API",
             "type": "object",
             "properties": {
-                "active": {
-                    "type": "boolean"
-                },
-                "description": {
-                    "type": "string"
-                },
-                "lat": {
-                    "type": "number"
-                },
-                "long": {
-                    "type": "number"
-                },
-                "radius_m": {
-                    "type": "integer"
-                },
-                "title": {
-                    "type": "string"
+                "error": {
+                    "type": "object",
+                    "properties": {
+                        "code": {
+                            "type": "string"
+                        },
+                        "message": {
+                            "type": "string"
+                        }
+                    }
                 }
             }
         },
@@ -562,6 +2649,25 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.checkBatchRequestResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.LocationCheck"
+                    }
+                }
+            }
+        },
+        "handler.deleteDeadLettersResponse": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                }
+            }
+        },
         "handler.incedentRequestResponse": {
             "type": "object",
             "properties": {
@@ -570,6 +2676,17 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.incidentHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "versions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.IncidentVersion"
+                    }
+                }
+            }
+        },
         "handler.internalServerErrorResponse": {
             "description": "Внутренняя ошибка сервера",
             "type": "object",
@@ -608,17 +2725,40 @@ const docTemplate = `{
                 }
             }
         },
-        "handler.statsRequestResponse": {
+        "handler.preconditionFailedErrorResponse": {
             "type": "object",
             "properties": {
-                "Stats": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/domain.ZoneStat"
+                "error": {
+                    "type": "object",
+                    "properties": {
+                        "code": {
+                            "type": "string",
+                            "example": "PRECONDITION_FAILED"
+                        },
+                        "message": {
+                            "type": "string",
+                            "example": "incident was modified by another request"
+                        }
                     }
                 }
             }
         },
+        "handler.purgeIncidentsResponse": {
+            "type": "object",
+            "properties": {
+                "purged": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.replayDeadLettersResponse": {
+            "type": "object",
+            "properties": {
+                "replayed": {
+                    "type": "integer"
+                }
+            }
+        },
         "handler.unauthorizedErrorResponse": {
             "description": "Ошибка аутентификации",
             "type": "object",
@@ -638,6 +2778,184 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.webhookEventsResponse": {
+            "type": "object",
+            "properties": {
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.EventType"
+                    }
+                },
+                "example": {
+                    "description": "Example - пример тела запроса",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.EventEnvelope"
+                        }
+                    ]
+                },
+                "version": {
+                    "type": "string",
+                    "example": "1"
+                },
+                "version_header": {
+                    "type": "string",
+                    "example": "X-Webhook-Version"
+                }
+            }
+        },
+        "handler.webhookSubscriptionResponse": {
+            "type": "object",
+            "properties": {
+                "subscription": {
+                    "$ref": "#/definitions/domain.WebhookSubscription"
+                }
+            }
+        },
+        "handler.webhookSubscriptionsResponse": {
+            "type": "object",
+            "properties": {
+                "subscriptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.WebhookSubscription"
+                    }
+                }
+            }
+        },
+        "service.DeadLettersOutput": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DeadLetter"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "service.HeatmapOutput": {
+            "type": "object",
+            "properties": {
+                "cells": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.HeatmapCell"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "grid": {
+                    "$ref": "#/definitions/domain.HeatmapGrid"
+                },
+                "in_danger_only": {
+                    "type": "boolean"
+                },
+                "precision": {
+                    "type": "integer"
+                },
+                "size_m": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "service.ImportIncidentsOutput": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.ImportItemFailure"
+                    }
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.ImportItemResult"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "service.ImportItemFailure": {
+            "type": "object",
+            "properties": {
+                "index": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.ImportItemResult": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.IncidentOccupantsOutput": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ZoneOccupant"
+                    }
+                },
+                "incident_id": {
+                    "type": "integer"
+                },
+                "window_minutes": {
+                    "type": "integer"
+                }
+            }
+        },
         "service.PaginateIncidentsOutput": {
             "type": "object",
             "properties": {
@@ -647,6 +2965,10 @@ const docTemplate = `{
                         "$ref": "#/definitions/domain.Incident"
                     }
                 },
+                "next_cursor": {
+                    "description": "NextCursor - курсор следующей страницы в курсорном режиме, отсутствует на последней странице",
+                    "type": "string"
+                },
                 "pagination": {
                     "$ref": "#/definitions/service.Pagination"
                 }
@@ -668,6 +2990,55 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "service.StatsSeriesOutput": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "series": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.ZoneStatSeries"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.UserChecksOutput": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.LocationCheck"
+                    }
+                },
+                "next_cursor": {
+                    "description": "NextCursor - курсор следующей страницы, отсутствует на последней странице",
+                    "type": "string"
+                }
+            }
+        },
+        "service.ZoneStatSeries": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ZoneStatBucket"
+                    }
+                },
+                "incident_id": {
+                    "type": "integer"
+                }
+            }
         }
     },
     "securityDefinitions": {
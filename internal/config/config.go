@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/ilyakaznacheev/cleanenv"
@@ -16,10 +17,62 @@ type Config struct {
 }
 
 type App struct {
-	Mode                string `env:"MODE" env-required:"true"` // debug, release
-	Port                string `env:"PORT" env-required:"true"`
-	APIKey              string `env:"API_KEY" env-required:"true"`
-	StatsTimeWindowMins int    `env:"STATS_TIME_WINDOW_MINUTES" env-required:"true"`
+	Mode                 string   `env:"MODE" env-required:"true"` // debug, release
+	Port                 string   `env:"PORT" env-required:"true"`
+	APIKey               string   `env:"API_KEY" env-required:"true"`
+	APIKeys              []string `env:"API_KEYS" env-default:""`       // дополнительные ключи name:key, имя попадает в историю изменений
+	AdminAPIKeys         []string `env:"ADMIN_API_KEYS" env-default:""` // ключи name:key с доступом к /api/v1/admin
+	PurgeRetentionDays   int      `env:"INCIDENT_PURGE_RETENTION_DAYS" env-default:"30"`
+	ImportMaxIncidents   int      `env:"INCIDENT_IMPORT_MAX" env-default:"1000"`
+	ExportMaxIncidents   int      `env:"INCIDENT_EXPORT_MAX" env-default:"10000"`
+	StatsTimeWindowMins  int      `env:"STATS_TIME_WINDOW_MINUTES" env-required:"true"`
+	OccupantWindowMins   int      `env:"OCCUPANT_WINDOW_MINUTES" env-default:"15"`
+	ZoneDwellMins        int      `env:"ZONE_DWELL_MINUTES" env-default:"0"` // 0 - событие zone.dwell отключено
+	WarningBufferMeters  int      `env:"WARNING_BUFFER_METERS" env-default:"200"`
+	BatchCheckMaxPoints  int      `env:"BATCH_CHECK_MAX_POINTS" env-default:"500"`
+	SegmentMaxGapMins    int      `env:"SEGMENT_MAX_GAP_MINUTES" env-default:"30"` // 0 - проверка пути между точками отключена
+	ScheduleIntervalSecs int      `env:"INCIDENT_SCHEDULE_INTERVAL_SECONDS" env-default:"30"`
+	IncidentCategories   []string `env:"INCIDENT_CATEGORIES" env-default:"road_works,demonstration,flood,fire,crime,other"`
+}
+
+// DefaultAPIKeyName - имя основного ключа API_KEY в истории изменений
+const DefaultAPIKeyName = "default"
+
+// APIKeyIdentities сопоставляет API ключи с их именами. Администраторские ключи тоже дают доступ к API
+func (a App) APIKeyIdentities() map[string]string {
+	identities := map[string]string{a.APIKey: DefaultAPIKeyName}
+	parseAPIKeys(a.APIKeys, identities)
+	parseAPIKeys(a.AdminAPIKeys, identities)
+	return identities
+}
+
+// AdminAPIKeyIdentities - ключи с доступом к администраторским операциям
+func (a App) AdminAPIKeyIdentities() map[string]string {
+	identities := make(map[string]string)
+	parseAPIKeys(a.AdminAPIKeys, identities)
+	return identities
+}
+
+func parseAPIKeys(entries []string, identities map[string]string) {
+	for _, entry := range entries {
+		name, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if ok && name != "" && key != "" {
+			identities[key] = name
+		}
+	}
+}
+
+func (a App) validateAPIKeys() error {
+	for _, entry := range append(a.APIKeys, a.AdminAPIKeys...) {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" || key == "" {
+			return fmt.Errorf("API key entry %q must be in name:key format", entry)
+		}
+	}
+	return nil
 }
 
 type Database struct {
@@ -43,7 +96,15 @@ func (r Redis) Addr() string {
 }
 
 type Webhook struct {
-	URL string `env:"WEBHOOK_URL" env-required:"true"`
+	// URL - необязательный получатель всех событий без фильтров, в дополнение к подпискам /api/v1/webhooks
+	URL string `env:"WEBHOOK_URL" env-default:""`
+	// Secret - секрет подписи запросов на URL. cmd/webhook проверяет им подпись входящих запросов
+	Secret string `env:"WEBHOOK_SECRET" env-default:""`
+	// SignatureToleranceSecs - насколько X-Timestamp может отличаться от часов получателя
+	SignatureToleranceSecs int `env:"WEBHOOK_SIGNATURE_TOLERANCE_SECONDS" env-default:"300"`
+	// VisibilityTimeoutSecs - через сколько неподтвержденная задача (воркер упал во время отправки)
+	// возвращается в очередь. Должен быть больше таймаута запроса к получателю
+	VisibilityTimeoutSecs int `env:"WEBHOOK_VISIBILITY_TIMEOUT_SECONDS" env-default:"60"`
 }
 
 func (d Database) DSN() string {
@@ -86,5 +147,9 @@ func readConfig(cfg *Config) error {
 	if err := cleanenv.ReadEnv(cfg); err != nil {
 		return fmt.Errorf("invalid or missing environment variables: %w", err)
 	}
+
+	if err := cfg.App.validateAPIKeys(); err != nil {
+		return fmt.Errorf("invalid environment variables: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,21 @@
+package domain
+
+import "context"
+
+// SystemActor - автор изменений, сделанных не через API (воркеры, миграции)
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// ContextWithActor сохраняет в контексте имя API ключа, от лица которого выполняется запрос
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext возвращает автора изменений из контекста или SystemActor
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
@@ -1,13 +1,16 @@
 package domain
 
+import "fmt"
+
 type ErrorCode string
 
 const (
-	CodeAlreadyExists     ErrorCode = "ALREADY_EXISTS"
-	CodeInvalidRequest    ErrorCode = "INVALID_REQUEST"
-	CodeInvalidValidation ErrorCode = "INVALID_VALIDATION"
-	CodeNotFound          ErrorCode = "NOT_FOUND"
-	CodeUnauthorized      ErrorCode = "UNAUTHORIZED"
+	CodeAlreadyExists      ErrorCode = "ALREADY_EXISTS"
+	CodeInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	CodeInvalidValidation  ErrorCode = "INVALID_VALIDATION"
+	CodeNotFound           ErrorCode = "NOT_FOUND"
+	CodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	CodePreconditionFailed ErrorCode = "PRECONDITION_FAILED"
 )
 
 type AppError struct {
@@ -41,3 +44,22 @@ func ErrNotFound(msg string) error {
 func ErrUnauthorized(msg string) error {
 	return &AppError{Code: CodeUnauthorized, Message: msg}
 }
+
+func ErrPreconditionFailed(msg string) error {
+	return &AppError{Code: CodePreconditionFailed, Message: msg}
+}
+
+// ImportItemError - ошибка записи одного инцидента из импортируемого набора.
+// Index - позиция инцидента в наборе, начиная с 0
+type ImportItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ImportItemError) Error() string {
+	return fmt.Sprintf("incident #%d: %v", e.Index, e.Err)
+}
+
+func (e *ImportItemError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"crypto/rand"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventZoneEntered     EventType = "zone.entered"
+	EventZoneExited      EventType = "zone.exited"
+	EventZoneDwell       EventType = "zone.dwell"
+	EventZoneApproaching EventType = "zone.approaching"
+	EventZoneCrossed     EventType = "zone.crossed"
+
+	EventIncidentActivated EventType = "incident.activated"
+	EventIncidentExpired   EventType = "incident.expired"
+
+	EventIncidentCreated EventType = "incident.created"
+	EventIncidentUpdated EventType = "incident.updated"
+	EventIncidentDeleted EventType = "incident.deleted"
+)
+
+const (
+	// EventVersion - версия схемы конверта и поля data. Меняется только при несовместимых изменениях
+	EventVersion = "1"
+	// EventVersionHeader - заголовок запроса вебхука с версией схемы
+	EventVersionHeader = "X-Webhook-Version"
+)
+
+var eventTypes = map[EventType]struct{}{
+	EventZoneEntered:       {},
+	EventZoneExited:        {},
+	EventZoneDwell:         {},
+	EventZoneApproaching:   {},
+	EventZoneCrossed:       {},
+	EventIncidentActivated: {},
+	EventIncidentExpired:   {},
+	EventIncidentCreated:   {},
+	EventIncidentUpdated:   {},
+	EventIncidentDeleted:   {},
+}
+
+func (t EventType) Valid() bool {
+	_, ok := eventTypes[t]
+	return ok
+}
+
+// EventTypes возвращает все типы событий по алфавиту
+func EventTypes() []EventType {
+	types := make([]EventType, 0, len(eventTypes))
+	for t := range eventTypes {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Event - событие, которое доставляется во внешние системы через вебхук
+type Event struct {
+	// ID и OccurredAt задаются при постановке в очередь и не меняются при повторах
+	// и раскладке по подпискам, поэтому получатель может по ID отбрасывать дубли
+	ID            string         `json:"id"`
+	OccurredAt    time.Time      `json:"occurred_at"`
+	Type          EventType      `json:"type"`
+	IncidentID    int            `json:"incident_id"`
+	Severity      Severity       `json:"severity,omitempty"`
+	Category      string         `json:"category,omitempty"`
+	LocationCheck *LocationCheck `json:"location_check,omitempty"`
+	// Incident - зона, к которой относится событие incident.*
+	Incident *Incident `json:"incident,omitempty"`
+	// Retroactive - zone.entered отправлен не по новой проверке, а потому что зона появилась
+	// или расширилась там, где уже находился пользователь. LocationCheck - его последняя точка
+	Retroactive bool `json:"retroactive,omitempty"`
+}
+
+// Point возвращает координаты события: точку проверки для событий пользователя
+// и центр зоны для событий incident.*
+func (e *Event) Point() (lat, long float64, ok bool) {
+	switch {
+	case e.LocationCheck != nil:
+		return e.LocationCheck.Lat, e.LocationCheck.Long, true
+	case e.Incident != nil:
+		return e.Incident.Lat, e.Incident.Long, true
+	}
+	return 0, 0, false
+}
+
+// EventEnvelope - тело запроса вебхука. Поля конверта стабильны между версиями,
+// состав Data определяется Type и Version
+type EventEnvelope struct {
+	ID         string    `json:"id" example:"3f0c8c1e-7a4b-4d2e-9c1a-5b6d7e8f9a0b"`
+	Type       EventType `json:"type" example:"zone.entered"`
+	Version    string    `json:"version" example:"1"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       EventData `json:"data"`
+}
+
+// EventData - данные события. Для incident.deleted заполнен только IncidentID
+type EventData struct {
+	IncidentID    int            `json:"incident_id"`
+	Severity      Severity       `json:"severity,omitempty"`
+	Category      string         `json:"category,omitempty"`
+	LocationCheck *LocationCheck `json:"location_check,omitempty"`
+	Incident      *Incident      `json:"incident,omitempty"`
+	Retroactive   bool           `json:"retroactive,omitempty"`
+}
+
+// Envelope упаковывает событие в конверт текущей версии
+func (e *Event) Envelope() *EventEnvelope {
+	return &EventEnvelope{
+		ID:         e.ID,
+		Type:       e.Type,
+		Version:    EventVersion,
+		OccurredAt: e.OccurredAt,
+		Data: EventData{
+			IncidentID:    e.IncidentID,
+			Severity:      e.Severity,
+			Category:      e.Category,
+			LocationCheck: e.LocationCheck,
+			Incident:      e.Incident,
+			Retroactive:   e.Retroactive,
+		},
+	}
+}
+
+// NewEventID возвращает случайный UUID версии 4
+func NewEventID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate event id: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// HeatmapGrid - способ разбиения карты на ячейки тепловой карты
+type HeatmapGrid string
+
+const (
+	// HeatmapGridGeohash - ячейки geohash заданной точности
+	HeatmapGridGeohash HeatmapGrid = "geohash"
+	// HeatmapGridSquare/HeatmapGridHex - квадраты и шестиугольники заданного размера в метрах Web Mercator
+	HeatmapGridSquare HeatmapGrid = "square"
+	HeatmapGridHex    HeatmapGrid = "hex"
+)
+
+// HeatmapFilter - область, период и сетка тепловой карты
+type HeatmapFilter struct {
+	BBox BBox
+	From time.Time
+	To   time.Time
+	Grid HeatmapGrid
+	// Precision - длина geohash, только для HeatmapGridGeohash
+	Precision int
+	// SizeM - размер ячейки в метрах, только для HeatmapGridSquare и HeatmapGridHex
+	SizeM int
+	// InDangerOnly - учитывать только проверки, попавшие в опасную зону
+	InDangerOnly bool
+}
+
+// HeatmapCell - ячейка тепловой карты с проверками. Lat/Long - центр ячейки
+type HeatmapCell struct {
+	// ID - geohash или индексы ячейки сетки "i:j"
+	ID          string          `db:"cell_id" json:"cell_id"`
+	Lat         float64         `db:"lat" json:"lat"`
+	Long        float64         `db:"long" json:"long"`
+	Count       int             `db:"check_count" json:"count"`
+	UniqueUsers int             `db:"unique_users" json:"unique_users"`
+	Geometry    json.RawMessage `db:"geometry" json:"geometry" swaggertype:"object"`
+}
@@ -1,19 +1,50 @@
 package domain
 
 import (
+	"encoding/json"
 	"time"
 )
 
 type Incident struct {
-	ID          int       `db:"id" json:"id"`
-	Title       string    `db:"title" json:"title"`
-	Description string    `db:"description" json:"description"`
-	Lat         float64   `db:"lat" json:"lat"`
-	Long        float64   `db:"long" json:"long"`
-	Radius      int       `db:"radius_m" json:"radius_m"`
-	Active      bool      `db:"active" json:"active"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+	ID          int      `db:"id" json:"id"`
+	Title       string   `db:"title" json:"title"`
+	Description string   `db:"description" json:"description"`
+	Lat         float64  `db:"lat" json:"lat"`
+	Long        float64  `db:"long" json:"long"`
+	Radius      int      `db:"radius_m" json:"radius_m"`
+	Active      bool     `db:"active" json:"active"`
+	Severity    Severity `db:"severity" json:"severity"`
+	Category    string   `db:"category" json:"category"`
+	// Boundary - граница зоны в формате GeoJSON (Polygon/MultiPolygon).
+	// Если задана, зона считается полигональной, а lat/long указывают на ее центроид
+	Boundary json.RawMessage `db:"boundary" json:"boundary,omitempty" swaggertype:"object"`
+	// WarningBuffer - ширина зоны предупреждения вокруг границы в метрах.
+	// Если не задана, используется глобальное значение WARNING_BUFFER_METERS
+	WarningBuffer *int `db:"warning_buffer_m" json:"warning_buffer_m,omitempty"`
+	// StartsAt/EndsAt - необязательное расписание зоны. Вне его зона не учитывается при проверках,
+	// даже если Active = true
+	StartsAt *time.Time `db:"starts_at" json:"starts_at,omitempty"`
+	EndsAt   *time.Time `db:"ends_at" json:"ends_at,omitempty"`
+	// Version увеличивается при каждом изменении и служит основой ETag.
+	// При обновлении ненулевое значение означает ожидаемую текущую версию
+	Version   int       `db:"version" json:"version"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	// DeletedAt - момент архивации. Архивные инциденты не участвуют в проверках и по умолчанию не попадают в список
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+}
+
+// DefaultDescription - описание инцидента, если оно не указано
+const DefaultDescription = "without description"
+
+// SetDefaults заполняет незаданные уровень опасности и категорию значениями по умолчанию
+func (i *Incident) SetDefaults() {
+	if i.Severity == "" {
+		i.Severity = SeverityWarning
+	}
+	if i.Category == "" {
+		i.Category = DefaultCategory
+	}
 }
 
 type LocationCheck struct {
@@ -24,9 +55,258 @@ type LocationCheck struct {
 	Long         float64   `db:"long" json:"long"`
 	InDangerZone bool      `db:"in_danger_zone" json:"in_danger_zone"`
 	NearestID    *int      `db:"nearest_id" json:"nearest_id,omitempty"`
+	// PrimaryID - самая опасная из зон, в которые попала точка (при равенстве - ближайшая)
+	PrimaryID *int `db:"-" json:"primary_id,omitempty"`
+	// Matches - все зоны, в которые попала точка, от ближайшей к дальней
+	Matches []IncidentMatch `db:"-" json:"matches"`
+	// Approaching - зоны, к границе которых пользователь приблизился, но еще не вошел
+	Approaching []ProximityWarning `db:"-" json:"approaching,omitempty"`
+	// Crossed - зоны, которые пользователь пересек между предыдущей и текущей проверкой
+	Crossed []ZoneCrossing `db:"-" json:"crossed,omitempty"`
+}
+
+// CheckFilter - условия отбора проверок координат одного пользователя. Пустые поля не ограничивают выборку
+type CheckFilter struct {
+	UserID string
+	From   *time.Time
+	To     *time.Time
+	// SortAsc - от старых проверок к новым, по умолчанию от новых к старым
+	SortAsc bool
+	// After - ключ последней проверки предыдущей страницы
+	After *CheckCursor
+}
+
+// CheckCursor - позиция в списке проверок, упорядоченном по (checked_at, id)
+type CheckCursor struct {
+	CheckedAt time.Time
+	ID        int
+}
+
+// CheckParams - параметры определения зон для проверки координат
+type CheckParams struct {
+	// WarningBufferM - буфер предупреждения для инцидентов без собственного значения
+	WarningBufferM int
+	// MaxSegmentGap - максимальный интервал между проверками, при котором путь между ними
+	// проверяется на пересечение зон. Нулевое значение отключает проверку пути
+	MaxSegmentGap time.Duration
+}
+
+// IncidentMatch - зона, в которую попала проверка, и расстояние до ее центра
+type IncidentMatch struct {
+	IncidentID int      `db:"incident_id" json:"incident_id"`
+	DistanceM  float64  `db:"distance_m" json:"distance_m"`
+	Severity   Severity `db:"severity" json:"severity"`
+	Category   string   `db:"category" json:"category"`
+}
+
+// ZoneOccupant - пользователь, последняя проверка которого находится внутри зоны
+type ZoneOccupant struct {
+	UserID     string    `db:"user_id" json:"user_id"`
+	CheckID    int       `db:"check_id" json:"check_id"`
+	Lat        float64   `db:"lat" json:"lat"`
+	Long       float64   `db:"long" json:"long"`
+	LastSeenAt time.Time `db:"checked_at" json:"last_seen_at"`
+	DistanceM  float64   `db:"distance_m" json:"distance_m"`
 }
 
+// ZoneMembership - состояние пребывания пользователя внутри зоны
+type ZoneMembership struct {
+	EnteredAt time.Time `json:"entered_at"`
+	DwellSent bool      `json:"dwell_sent,omitempty"`
+	// Approaching - пользователь находится в полосе предупреждения, а не внутри зоны
+	Approaching bool `json:"approaching,omitempty"`
+	// Severity/Category запоминаются, чтобы событие выхода из зоны содержало ее описание
+	Severity Severity `json:"severity,omitempty"`
+	Category string   `json:"category,omitempty"`
+}
+
+// ProximityWarning - зона, до границы которой осталось не больше буфера предупреждения
+type ProximityWarning struct {
+	IncidentID      int     `db:"incident_id" json:"incident_id"`
+	DistanceToEdgeM float64 `db:"distance_to_edge_m" json:"distance_to_edge_m"`
+	// BearingDeg - направление от пользователя на ближайшую точку границы, градусы от севера по часовой стрелке
+	BearingDeg float64  `db:"bearing_deg" json:"bearing_deg"`
+	Severity   Severity `db:"severity" json:"severity"`
+	Category   string   `db:"category" json:"category"`
+}
+
+// ZoneCrossing - зона, через которую прошел путь пользователя между двумя проверками,
+// хотя ни одна из точек в нее не попала. Время входа оценивается линейной интерполяцией
+type ZoneCrossing struct {
+	IncidentID       int       `json:"incident_id"`
+	EstimatedEntryAt time.Time `json:"estimated_entry_at"`
+	Severity         Severity  `json:"severity"`
+	Category         string    `json:"category"`
+}
+
+// Операции истории изменений инцидента
+const (
+	IncidentOperationCreate  = "create"
+	IncidentOperationUpdate  = "update"
+	IncidentOperationDelete  = "delete"
+	IncidentOperationRestore = "restore"
+)
+
+// IncidentVersion - состояние инцидента после изменения из истории incident_versions
+type IncidentVersion struct {
+	Incident
+	Operation string    `db:"operation" json:"operation"`
+	ChangedBy string    `db:"changed_by" json:"changed_by"`
+	ChangedAt time.Time `db:"changed_at" json:"changed_at"`
+}
+
+// Результат импорта инцидента: новый или обновленный по совпадению названия
+const (
+	ImportActionCreated = "created"
+	ImportActionUpdated = "updated"
+)
+
+// ImportIncident - инцидент из файла импорта. Поля со значением nil в файле не заданы: новый инцидент
+// получает для них значения по умолчанию, а обновляемый сохраняет текущие
+type ImportIncident struct {
+	Title       string
+	Description *string
+	Lat         float64
+	Long        float64
+	Radius      int
+	Active      *bool
+	Severity    *Severity
+	Category    *string
+	// Boundary - граница полигональной зоны. Пустое значение означает круговую зону с центром Lat/Long,
+	// а nil - что граница в файле не задана: полигональная зона сохраняет свою границу и центр
+	Boundary      *json.RawMessage
+	WarningBuffer *int
+	StartsAt      *time.Time
+	EndsAt        *time.Time
+}
+
+// NewIncident возвращает инцидент, который создается по элементу импорта
+func (i *ImportIncident) NewIncident() *Incident {
+	incident := &Incident{
+		Title:         i.Title,
+		Description:   DefaultDescription,
+		Lat:           i.Lat,
+		Long:          i.Long,
+		Radius:        i.Radius,
+		Active:        true,
+		WarningBuffer: i.WarningBuffer,
+		StartsAt:      i.StartsAt,
+		EndsAt:        i.EndsAt,
+	}
+	if i.Description != nil {
+		incident.Description = *i.Description
+	}
+	if i.Active != nil {
+		incident.Active = *i.Active
+	}
+	if i.Severity != nil {
+		incident.Severity = *i.Severity
+	}
+	if i.Category != nil {
+		incident.Category = *i.Category
+	}
+	if i.Boundary != nil {
+		incident.Boundary = *i.Boundary
+	}
+	incident.SetDefaults()
+	return incident
+}
+
+// ImportResult - итог записи элемента импорта
+type ImportResult struct {
+	// Incident - инцидент после импорта
+	Incident *Incident
+	// Previous - инцидент до обновления, nil - инцидент создан
+	Previous *Incident
+}
+
+// IncidentFilter - условия отбора и порядок инцидентов для списка. Пустые поля не ограничивают выборку
+type IncidentFilter struct {
+	Severities  []Severity
+	Category    string
+	Active      *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	// Query - полнотекстовый поиск по названию и описанию
+	Query string
+	BBox  *BBox
+	Near  *NearPoint
+	Sort  IncidentSort
+	// SortAsc - сортировка по возрастанию, по умолчанию по убыванию
+	SortAsc bool
+	Deleted DeletedMode
+	// After - ключ последнего инцидента предыдущей страницы при курсорной пагинации.
+	// Используется только с сортировкой по created_at
+	After *IncidentCursor
+}
+
+// IncidentCursor - позиция в списке инцидентов, упорядоченном по (created_at, id)
+type IncidentCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// DeletedMode - учет архивных инцидентов в списке
+type DeletedMode string
+
+const (
+	// DeletedExclude - только неархивные (по умолчанию)
+	DeletedExclude DeletedMode = ""
+	DeletedInclude DeletedMode = "include"
+	DeletedOnly    DeletedMode = "only"
+)
+
+// BBox - прямоугольная область в градусах (долгота, широта)
+type BBox struct {
+	MinLong float64 `json:"min_long"`
+	MinLat  float64 `json:"min_lat"`
+	MaxLong float64 `json:"max_long"`
+	MaxLat  float64 `json:"max_lat"`
+}
+
+// NearPoint - точка и расстояние до границы зоны в метрах
+type NearPoint struct {
+	Lat     float64
+	Long    float64
+	RadiusM int
+}
+
+type IncidentSort string
+
+const (
+	IncidentSortCreatedAt IncidentSort = "created_at"
+	IncidentSortUpdatedAt IncidentSort = "updated_at"
+	IncidentSortTitle     IncidentSort = "title"
+	IncidentSortActive    IncidentSort = "active"
+	IncidentSortSeverity  IncidentSort = "severity"
+	IncidentSortCategory  IncidentSort = "category"
+	// IncidentSortDistance - по расстоянию до точки Near
+	IncidentSortDistance IncidentSort = "distance"
+	// IncidentSortRelevance - по релевантности поиска Query
+	IncidentSortRelevance IncidentSort = "relevance"
+)
+
 type ZoneStat struct {
 	ZoneID    int `db:"zone_id" json:"zone_id"`
 	UserCount int `db:"user_count" json:"user_count"`
 }
+
+// StatsFilter - параметры временного ряда статистики по зонам. From выровнен по началу интервала
+type StatsFilter struct {
+	From       time.Time
+	To         time.Time
+	Bucket     time.Duration
+	IncidentID *int
+}
+
+// ZoneStatBucket - статистика попаданий в зону за один интервал временного ряда
+type ZoneStatBucket struct {
+	IncidentID  int       `db:"incident_id" json:"-"`
+	Start       time.Time `db:"bucket_start" json:"start"`
+	UniqueUsers int       `db:"unique_users" json:"unique_users"`
+	TotalChecks int       `db:"total_checks" json:"total_checks"`
+	FirstSeen   time.Time `db:"first_seen" json:"first_seen"`
+	LastSeen    time.Time `db:"last_seen" json:"last_seen"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+// Severity - уровень опасности инцидента
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityDanger   Severity = "danger"
+	SeverityCritical Severity = "critical"
+)
+
+// DefaultCategory - категория инцидента, если она не указана. Допустима всегда
+const DefaultCategory = "other"
+
+var severityRanks = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityWarning:  2,
+	SeverityDanger:   3,
+	SeverityCritical: 4,
+}
+
+// Rank возвращает порядковый номер уровня: чем выше, тем опаснее. Для неизвестного уровня - 0
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+func (s Severity) Valid() bool {
+	_, ok := severityRanks[s]
+	return ok
+}
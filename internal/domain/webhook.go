@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+// WebhookSubscription - получатель вебхуков. Пустой фильтр не ограничивает события,
+// заданные фильтры должны совпасть все одновременно
+type WebhookSubscription struct {
+	ID  int    `db:"id" json:"id"`
+	URL string `db:"url" json:"url"`
+	// Secret возвращается только при создании подписки и ротации секрета
+	Secret string `db:"secret" json:"secret,omitempty"`
+	// PreviousSecret - секрет до ротации. Пока он не истек, запросы подписываются обоими секретами
+	PreviousSecret          string     `db:"previous_secret" json:"-"`
+	PreviousSecretExpiresAt *time.Time `db:"previous_secret_expires_at" json:"previous_secret_expires_at,omitempty"`
+	Enabled                 bool       `db:"enabled" json:"enabled"`
+	// EventTypes - типы событий, пустой список - все события
+	EventTypes []EventType `db:"-" json:"event_types"`
+	// IncidentIDs - зоны, события которых доставляются, пустой список - все зоны
+	IncidentIDs []int `db:"-" json:"incident_ids"`
+	// Severities - уровни опасности зоны, пустой список - любой уровень
+	Severities []Severity `db:"-" json:"severities"`
+	// BBox - область, в которую должна попасть точка события, nil - без ограничения
+	BBox      *BBox     `db:"-" json:"bbox,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// SigningSecrets - секреты, которыми подписывается запрос: текущий и прежний во время ротации
+func (s *WebhookSubscription) SigningSecrets() []string {
+	var secrets []string
+	if s.Secret != "" {
+		secrets = append(secrets, s.Secret)
+	}
+	if s.PreviousSecret != "" {
+		secrets = append(secrets, s.PreviousSecret)
+	}
+	return secrets
+}
+
+// Matches проверяет, нужно ли доставлять событие подписке. Событие без координат
+// не проходит фильтр по области
+func (s *WebhookSubscription) Matches(event *Event) bool {
+	if len(s.EventTypes) > 0 && !slices.Contains(s.EventTypes, event.Type) {
+		return false
+	}
+	if len(s.IncidentIDs) > 0 && !slices.Contains(s.IncidentIDs, event.IncidentID) {
+		return false
+	}
+	if len(s.Severities) > 0 && !slices.Contains(s.Severities, event.Severity) {
+		return false
+	}
+	if s.BBox != nil {
+		lat, long, ok := event.Point()
+		if !ok || !s.BBox.Contains(lat, long) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains проверяет, что точка лежит внутри области, включая границу
+func (b *BBox) Contains(lat, long float64) bool {
+	return long >= b.MinLong && long <= b.MaxLong && lat >= b.MinLat && lat <= b.MaxLat
+}
+
+// DeadLetter - задача доставки из DLQ: попытки исчерпаны или получатель ответил неповторяемой ошибкой
+type DeadLetter struct {
+	// ID - ID события и подписки через двоеточие, см. DeadLetterID
+	ID    string `json:"id" example:"3f0c8c1e-7a4b-4d2e-9c1a-5b6d7e8f9a0b:1"`
+	Event *Event `json:"event"`
+	// Raw - исходная запись, если ее не удалось разобрать. Event у такой записи нет, а ID выводится из содержимого
+	Raw string `json:"raw,omitempty"`
+	// SubscriptionID отсутствует, если событие не удалось разложить по подпискам
+	SubscriptionID *int      `json:"subscription_id,omitempty"`
+	Attempts       int       `json:"attempts"`
+	LastError      string    `json:"last_error"`
+	FirstAttempt   time.Time `json:"first_attempt"`
+	FailedAt       time.Time `json:"failed_at"`
+}
+
+// DeadLetterID - идентификатор записи DLQ. У задачи, не разложенной по подпискам, это ID события
+func DeadLetterID(eventID string, subscriptionID *int) string {
+	if subscriptionID == nil {
+		return eventID
+	}
+	return eventID + ":" + strconv.Itoa(*subscriptionID)
+}
+
+// DeadLetterFilter - условия отбора записей DLQ. Пустые поля не ограничивают выборку
+type DeadLetterFilter struct {
+	ID             string
+	SubscriptionID *int
+	EventType      EventType
+	IncidentID     *int
+	// FailedBefore - только записи, попавшие в DLQ раньше этого момента
+	FailedBefore *time.Time
+}
+
+func (f *DeadLetterFilter) Matches(d *DeadLetter) bool {
+	if f.ID != "" && d.ID != f.ID {
+		return false
+	}
+	if f.SubscriptionID != nil && (d.SubscriptionID == nil || *d.SubscriptionID != *f.SubscriptionID) {
+		return false
+	}
+	if f.EventType != "" && (d.Event == nil || d.Event.Type != f.EventType) {
+		return false
+	}
+	if f.IncidentID != nil && (d.Event == nil || d.Event.IncidentID != *f.IncidentID) {
+		return false
+	}
+	if f.FailedBefore != nil && !d.FailedAt.Before(*f.FailedBefore) {
+		return false
+	}
+	return true
+}
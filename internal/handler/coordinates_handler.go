@@ -10,7 +10,7 @@ import (
 )
 
 // @Summary      Проверка координат
-// @Description  Проверяет, находится ли пользователь в опасной зоне. Если да, отправляет webhook-уведомление.
+// @Description  Проверяет, находится ли пользователь в опасной зоне. При входе в зону или выходе из нее отправляет webhook-уведомление.
 // @Tags         location
 // @Accept       json
 // @Produce      json
@@ -41,15 +41,64 @@ func (h *Handler) handleCheckCoordinates(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, 200, out)
 }
 
+// @Summary      Пакетная проверка координат
+// @Description  Проверяет набор точек (в том числе разных пользователей) одним запросом к БД. Результаты возвращаются в порядке входных точек, переходы между зонами считаются в порядке checked_at.
+// @Tags         location
+// @Accept       json
+// @Produce      json
+// @Param        points  body      CheckBatchJSON  true  "Координаты пользователей"
+// @Success      200     {object}  checkBatchRequestResponse
+// @Failure      400     {object}  badRequestErrorResponse
+// @Failure      500     {object}  internalServerErrorResponse
+// @Router       /location/check/batch [post]
+func (h *Handler) handleCheckCoordinatesBatch(w http.ResponseWriter, r *http.Request) {
+	var req CheckBatchJSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body", logging.ErrAttr(err))
+		h.WriteError(w, domain.ErrInvalidRequest("invalid json payload"))
+		return
+	}
+
+	in := &service.CheckCoordinatesBatchRequestInput{
+		Points: make([]service.CheckCoordinatesRequestInput, len(req.Points)),
+	}
+	for i, point := range req.Points {
+		in.Points[i] = service.CheckCoordinatesRequestInput{
+			UserID:    point.UserID,
+			Lat:       point.Lat,
+			Long:      point.Long,
+			CheckedAt: point.CheckedAt,
+		}
+	}
+
+	out, err := h.svc.CheckCoordinatesBatch(r.Context(), in)
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, 200, checkBatchRequestResponse{Results: out})
+}
+
 // @Summary      Статистика по зонам
-// @Description  Получает статистику по количеству пользователей в каждой зоне за указанный временной период
+// @Description  Без параметров возвращает количество пользователей в каждой зоне за STATS_TIME_WINDOW_MINUTES (statsRequestResponse). С любым из параметров from, to, bucket, incident_id возвращает временные ряды по зонам: уникальные пользователи, число проверок, первая и последняя проверка в каждом интервале. Без from период равен STATS_TIME_WINDOW_MINUTES до to, начало выравнивается по границе интервала, ряд не длиннее 1440 интервалов
 // @Tags         incidents
 // @Accept       json
 // @Produce      json
-// @Success      200  {object}  statsRequestResponse
-// @Failure      500  {object}  internalServerErrorResponse
+// @Param        from         query     string  false  "Начало периода (RFC3339)"
+// @Param        to           query     string  false  "Конец периода, не включается (RFC3339), по умолчанию сейчас"
+// @Param        bucket       query     string  false  "Интервал ряда, по умолчанию самый мелкий допустимый для периода"  Enums(1m, 5m, 1h, 1d)
+// @Param        incident_id  query     int     false  "ID зоны"
+// @Success      200          {object}  service.StatsSeriesOutput
+// @Failure      400          {object}  badRequestErrorResponse
+// @Failure      500          {object}  internalServerErrorResponse
 // @Router       /incidents/stats [get]
 func (h *Handler) handleStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Has("from") || query.Has("to") || query.Has("bucket") || query.Has("incident_id") {
+		h.handleStatsSeries(w, r)
+		return
+	}
+
 	out, err := h.svc.GetStats(r.Context(), h.statsTimeWindowMins)
 	if err != nil {
 		h.WriteError(w, err)
@@ -61,3 +110,152 @@ func (h *Handler) handleStats(w http.ResponseWriter, r *http.Request) {
 	}
 	writeJSON(w, 200, zones)
 }
+
+func (h *Handler) handleStatsSeries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	in := &service.StatsSeriesRequestInput{
+		From:          query.Get("from"),
+		To:            query.Get("to"),
+		Bucket:        query.Get("bucket"),
+		IncidentID:    query.Get("incident_id"),
+		WindowMinutes: h.statsTimeWindowMins,
+	}
+
+	out, err := h.svc.GetStatsSeries(r.Context(), in)
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// @Summary      Пользователи в зоне
+// @Description  Возвращает пользователей, которые сейчас находятся в зоне инцидента: их последняя проверка попадает в зону и сделана не раньше, чем minutes минут назад. Зона проверяется по текущей геометрии инцидента, для выключенного или неактивного по расписанию инцидента список пуст
+// @Tags         incidents
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int  true   "ID инцидента"
+// @Param        minutes  query     int  false  "Окно актуальности последней проверки в минутах, до 1440 (по умолчанию OCCUPANT_WINDOW_MINUTES)"
+// @Success      200      {object}  service.IncidentOccupantsOutput
+// @Failure      400      {object}  badRequestErrorResponse
+// @Failure      401      {object}  unauthorizedErrorResponse
+// @Failure      404      {object}  notFoundErrorResponse
+// @Failure      500      {object}  internalServerErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /incidents/{id}/occupants [get]
+func (h *Handler) handleIncidentOccupants(w http.ResponseWriter, r *http.Request) {
+	in := &service.IncidentOccupantsRequestInput{
+		ID:      r.PathValue("id"),
+		Minutes: r.URL.Query().Get("minutes"),
+	}
+
+	out, err := h.svc.GetIncidentOccupants(r.Context(), in)
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// @Summary      История проверок пользователя
+// @Description  Возвращает проверки координат пользователя с зонами, в которые попала каждая точка, с курсорной пагинацией. С format=geojson та же страница возвращается треком: линия LineString через все точки и точка Point на каждую проверку с признаком in_danger_zone и incident_ids
+// @Tags         location
+// @Accept       json
+// @Produce      json
+// @Param        user_id  path      string  true   "ID пользователя"
+// @Param        from     query     string  false  "Проверки не раньше (RFC3339)"
+// @Param        to       query     string  false  "Проверки раньше (RFC3339)"
+// @Param        limit    query     int     false  "Количество на странице, до 1000"  default(100)
+// @Param        cursor   query     string  false  "Курсор следующей страницы (next_cursor), действителен только с тем же order"
+// @Param        order    query     string  false  "Направление по времени"  Enums(asc, desc)  default(desc)
+// @Param        format   query     string  false  "Формат ответа"  Enums(json, geojson)  default(json)
+// @Success      200      {object}  service.UserChecksOutput
+// @Failure      400      {object}  badRequestErrorResponse
+// @Failure      401      {object}  unauthorizedErrorResponse
+// @Failure      500      {object}  internalServerErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /users/{user_id}/checks [get]
+func (h *Handler) handleUserChecks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	in := &service.UserChecksRequestInput{
+		UserID: r.PathValue("user_id"),
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+		Limit:  query.Get("limit"),
+		Cursor: query.Get("cursor"),
+		Order:  query.Get("order"),
+	}
+
+	switch query.Get("format") {
+	case "", "json":
+		out, err := h.svc.GetUserChecks(r.Context(), in)
+		if err != nil {
+			h.WriteError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	case service.FormatGeoJSON:
+		out, err := h.svc.GetUserTrack(r.Context(), in)
+		if err != nil {
+			h.WriteError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	default:
+		h.WriteError(w, domain.ErrInvalidValidation("format must be json or geojson"))
+	}
+}
+
+// @Summary      Тепловая карта проверок
+// @Description  Считает проверки координат в ячейках сетки над областью bbox за период. Сетка - geohash заданной точности или квадраты/шестиугольники заданного размера в метрах Web Mercator. Ячейки без проверок не возвращаются. С format=geojson ячейки возвращаются как FeatureCollection полигонов
+// @Tags         location
+// @Accept       json
+// @Produce      json
+// @Param        bbox       query     string  true   "Область minLon,minLat,maxLon,maxLat"
+// @Param        from       query     string  false  "Начало периода (RFC3339), по умолчанию сутки до to"
+// @Param        to         query     string  false  "Конец периода, не включается (RFC3339), по умолчанию сейчас"
+// @Param        grid       query     string  false  "Сетка"  Enums(geohash, square, hex)  default(geohash)
+// @Param        precision  query     int     false  "Длина geohash, от 1 до 9"  default(6)
+// @Param        size_m     query     int     false  "Размер квадрата или сторона шестиугольника в метрах, не меньше 10"  default(500)
+// @Param        in_danger  query     bool    false  "Только проверки в опасных зонах"
+// @Param        format     query     string  false  "Формат ответа"  Enums(json, geojson)  default(json)
+// @Success      200        {object}  service.HeatmapOutput
+// @Failure      400        {object}  badRequestErrorResponse
+// @Failure      401        {object}  unauthorizedErrorResponse
+// @Failure      500        {object}  internalServerErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /location/heatmap [get]
+func (h *Handler) handleHeatmap(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	in := &service.HeatmapRequestInput{
+		BBox:      query.Get("bbox"),
+		From:      query.Get("from"),
+		To:        query.Get("to"),
+		Grid:      query.Get("grid"),
+		Precision: query.Get("precision"),
+		SizeM:     query.Get("size_m"),
+		InDanger:  query.Get("in_danger"),
+	}
+
+	switch query.Get("format") {
+	case "", "json":
+		out, err := h.svc.GetHeatmap(r.Context(), in)
+		if err != nil {
+			h.WriteError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	case service.FormatGeoJSON:
+		out, err := h.svc.GetHeatmapGeoJSON(r.Context(), in)
+		if err != nil {
+			h.WriteError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	default:
+		h.WriteError(w, domain.ErrInvalidValidation("format must be json or geojson"))
+	}
+}
@@ -1,6 +1,10 @@
 package handler
 
-import "red_collar/internal/domain"
+import (
+	"encoding/json"
+	"red_collar/internal/domain"
+	"time"
+)
 
 // IncidentJSON представляет данные для создания/обновления инцидента
 // @Description Данные инцидента (опасной зоны)
@@ -11,6 +15,17 @@ type IncidentJSON struct {
 	Long        float64 `json:"long"`
 	Radius      int     `json:"radius_m"`
 	Active      *bool   `json:"active,omitempty"`
+	// Severity - info, warning (по умолчанию), danger или critical
+	Severity string `json:"severity,omitempty"`
+	// Category - категория из списка INCIDENT_CATEGORIES, по умолчанию other
+	Category string `json:"category,omitempty"`
+	// Boundary - GeoJSON Polygon/MultiPolygon. Если задан, lat/long/radius_m игнорируются
+	Boundary json.RawMessage `json:"boundary,omitempty" swaggertype:"object"`
+	// WarningBuffer - ширина полосы предупреждения вокруг зоны в метрах. Если не задан, используется глобальное значение
+	WarningBuffer *int `json:"warning_buffer_m,omitempty"`
+	// StartsAt/EndsAt - необязательное расписание зоны в формате RFC 3339
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
 }
 
 // CheckJSON представляет данные для проверки координат
@@ -21,11 +36,97 @@ type CheckJSON struct {
 	Long   float64 `json:"long"`
 }
 
+// CheckPointJSON представляет точку пакетной проверки координат
+// @Description Координаты пользователя и время их снятия
+type CheckPointJSON struct {
+	CheckJSON
+	// CheckedAt - время снятия координат (RFC3339), не позже текущего. По умолчанию - время запроса
+	CheckedAt string `json:"checked_at,omitempty" example:"2026-10-16T10:00:00Z"`
+}
+
+// CheckBatchJSON представляет данные для пакетной проверки координат
+// @Description Набор координат пользователей для проверки
+type CheckBatchJSON struct {
+	Points []CheckPointJSON `json:"points"`
+}
+
+// WebhookSubscriptionJSON представляет данные для создания/обновления подписки на вебхуки
+// @Description Получатель вебхуков и фильтры событий. Пустой фильтр не ограничивает события
+type WebhookSubscriptionJSON struct {
+	URL string `json:"url" example:"https://example.com/webhook"`
+	// Secret - не короче 16 символов. При создании без секрета он генерируется, при обновлении без секрета остается прежним
+	Secret  string `json:"secret,omitempty"`
+	Enabled *bool  `json:"enabled,omitempty"`
+	// EventTypes - типы событий, например zone.entered или incident.expired
+	EventTypes  []string `json:"event_types,omitempty"`
+	IncidentIDs []int    `json:"incident_ids,omitempty"`
+	// Severities - уровни опасности зоны: info, warning, danger, critical
+	Severities []string `json:"severities,omitempty"`
+	// BBox - [minLon, minLat, maxLon, maxLat], в который должна попасть точка события
+	BBox []float64 `json:"bbox,omitempty"`
+}
+
+// RotateWebhookSecretJSON представляет параметры ротации секрета подписки
+// @Description Новый секрет и срок действия прежнего
+type RotateWebhookSecretJSON struct {
+	// Secret - новый секрет не короче 16 символов, без него секрет генерируется
+	Secret string `json:"secret,omitempty"`
+	// GracePeriodSecs - сколько секунд прежний секрет еще действует, по умолчанию 86400, не больше 604800
+	GracePeriodSecs *int `json:"grace_period_seconds,omitempty"`
+}
+
+// ReplayDeadLettersJSON представляет отбор записей DLQ для повторной отправки
+// @Description Фильтры записей DLQ. Без фильтров нужно передать all=true
+type ReplayDeadLettersJSON struct {
+	SubscriptionID *int   `json:"subscription_id,omitempty" example:"1"`
+	EventType      string `json:"event_type,omitempty" example:"zone.entered"`
+	IncidentID     *int   `json:"incident_id,omitempty" example:"7"`
+	// All - повторить всю DLQ, не сочетается с фильтрами
+	All bool `json:"all,omitempty"`
+}
+
 // Responses
 type incedentRequestResponse struct {
 	Incendent *domain.Incident `json:"Incedent"`
 }
 
+type incidentHistoryResponse struct {
+	Versions []domain.IncidentVersion `json:"versions"`
+}
+
+type purgeIncidentsResponse struct {
+	Purged int `json:"purged"`
+}
+
+type replayDeadLettersResponse struct {
+	Replayed int `json:"replayed"`
+}
+
+type deleteDeadLettersResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+type checkBatchRequestResponse struct {
+	Results []*domain.LocationCheck `json:"results"`
+}
+
 type statsRequestResponse struct {
 	Stats []domain.ZoneStat `json:"Stats"`
 }
+
+type webhookSubscriptionResponse struct {
+	Subscription *domain.WebhookSubscription `json:"subscription"`
+}
+
+type webhookSubscriptionsResponse struct {
+	Subscriptions []domain.WebhookSubscription `json:"subscriptions"`
+}
+
+// webhookEventsResponse - описание тела запросов вебхуков
+type webhookEventsResponse struct {
+	Version       string             `json:"version" example:"1"`
+	VersionHeader string             `json:"version_header" example:"X-Webhook-Version"`
+	EventTypes    []domain.EventType `json:"event_types"`
+	// Example - пример тела запроса
+	Example *domain.EventEnvelope `json:"example"`
+}
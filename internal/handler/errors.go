@@ -59,6 +59,8 @@ func statusFromCode(code domain.ErrorCode) int {
 		return 409
 	case domain.CodeNotFound:
 		return 404
+	case domain.CodePreconditionFailed:
+		return 412
 	default:
 		return 503
 	}
@@ -117,3 +119,17 @@ type notFoundErrorResponse struct {
 		Message string `json:"message" example:"incident is not exists"`
 	} `json:"error"`
 }
+
+type preconditionFailedErrorResponse struct {
+	Error struct {
+		Code    string `json:"code" example:"PRECONDITION_FAILED"`
+		Message string `json:"message" example:"incident was modified by another request"`
+	} `json:"error"`
+}
+
+type alreadyExistsErrorResponse struct {
+	Error struct {
+		Code    string `json:"code" example:"ALREADY_EXISTS"`
+		Message string `json:"message" example:"incident already exists"`
+	} `json:"error"`
+}
@@ -2,15 +2,24 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"red_collar/internal/domain"
 	"red_collar/internal/service"
+	"strconv"
+	"strings"
 
 	"github.com/theartofdevel/logging"
 )
 
+// maxImportBodyBytes - максимальный размер импортируемого файла
+const maxImportBodyBytes = 10 << 20
+
 // @Summary      Создание инцидента
-// @Description  Создает новую опасную зону с географическими координатами
+// @Description  Создает новую опасную зону: круг (lat/long и radius_m) или полигон (boundary в формате GeoJSON Polygon/MultiPolygon)
 // @Tags         incidents
 // @Accept       json
 // @Produce      json
@@ -30,12 +39,18 @@ func (h *Handler) handleCreateIncident(w http.ResponseWriter, r *http.Request) {
 	}
 
 	in := service.CreateIncidentRequestInput{
-		Title:       req.Title,
-		Description: req.Description,
-		Lat:         req.Lat,
-		Long:        req.Long,
-		Radius:      req.Radius,
-		Active:      req.Active,
+		Title:         req.Title,
+		Description:   req.Description,
+		Lat:           req.Lat,
+		Long:          req.Long,
+		Radius:        req.Radius,
+		Active:        req.Active,
+		Severity:      req.Severity,
+		Category:      req.Category,
+		Boundary:      req.Boundary,
+		WarningBuffer: req.WarningBuffer,
+		StartsAt:      req.StartsAt,
+		EndsAt:        req.EndsAt,
 	}
 
 	out, err := h.svc.CreateIncident(r.Context(), &in)
@@ -43,6 +58,7 @@ func (h *Handler) handleCreateIncident(w http.ResponseWriter, r *http.Request) {
 		h.WriteError(w, err)
 		return
 	}
+	setIncidentETag(w, out)
 
 	resp := incedentRequestResponse{
 		Incendent: out,
@@ -51,22 +67,66 @@ func (h *Handler) handleCreateIncident(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary      Получение списка инцидентов
-// @Description  Получает список инцидентов с пагинацией
+// @Description  Получает список инцидентов с пагинацией (page/limit или курсор), фильтрами, полнотекстовым поиском и сортировкой
 // @Tags         incidents
 // @Accept       json
-// @Produce      json
+// @Produce      json,application/geo+json,text/csv,application/vnd.google-earth.kml+xml
 // @Param        page   query     int  false  "Номер страницы"  default(1)
 // @Param        limit  query     int  false  "Количество на странице"  default(5)
+// @Param        severity  query  string  false  "Уровни опасности через запятую (info, warning, danger, critical)"
+// @Param        category  query  string  false  "Категория"
+// @Param        active        query  bool    false  "Фильтр по флагу active"
+// @Param        created_from  query  string  false  "Создан не раньше (RFC3339)"
+// @Param        created_to    query  string  false  "Создан не позже (RFC3339)"
+// @Param        updated_from  query  string  false  "Обновлен не раньше (RFC3339)"
+// @Param        updated_to    query  string  false  "Обновлен не позже (RFC3339)"
+// @Param        q             query  string  false  "Полнотекстовый поиск по названию и описанию"
+// @Param        bbox          query  string  false  "Прямоугольник minLon,minLat,maxLon,maxLat"
+// @Param        lat           query  number  false  "Широта точки для поиска зон рядом"
+// @Param        long          query  number  false  "Долгота точки для поиска зон рядом"
+// @Param        radius_m      query  int     false  "Максимальное расстояние от точки до границы зоны в метрах"
+// @Param        sort          query  string  false  "Поле сортировки"  Enums(created_at, updated_at, title, active, severity, category, distance, relevance)  default(created_at)
+// @Param        order         query  string  false  "Направление сортировки"  Enums(asc, desc)  default(desc)
+// @Param        deleted       query  string  false  "Учет архивных инцидентов"  Enums(exclude, include, only)  default(exclude)
+// @Param        cursor        query  string  false  "Курсор следующей страницы (next_cursor). Пустое значение - первая страница в курсорном режиме, page игнорируется. Курсор действителен только с тем же order"
+// @Param        format        query  string  false  "Формат выгрузки. geojson, csv и kml возвращают все подходящие инциденты одним файлом без пагинации"  Enums(json, geojson, csv, kml)  default(json)
 // @Success      200    {object}  service.PaginateIncidentsOutput
 // @Failure      400    {object}  badRequestErrorResponsePaginate
 // @Failure      401    {object}  unauthorizedErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /incidents [get]
 func (h *Handler) handlePaginate(w http.ResponseWriter, r *http.Request) {
-	rawPage := r.URL.Query().Get("page")
-	rawlimit := r.URL.Query().Get("limit")
+	query := r.URL.Query()
+
+	in := &service.PaginateIncidentsRequestInput{
+		Limit:       query.Get("limit"),
+		Page:        query.Get("page"),
+		Severity:    query.Get("severity"),
+		Category:    query.Get("category"),
+		Active:      query.Get("active"),
+		CreatedFrom: query.Get("created_from"),
+		CreatedTo:   query.Get("created_to"),
+		UpdatedFrom: query.Get("updated_from"),
+		UpdatedTo:   query.Get("updated_to"),
+		Query:       query.Get("q"),
+		BBox:        query.Get("bbox"),
+		Lat:         query.Get("lat"),
+		Long:        query.Get("long"),
+		Radius:      query.Get("radius_m"),
+		Sort:        query.Get("sort"),
+		Order:       query.Get("order"),
+		Deleted:     query.Get("deleted"),
+	}
+	if format := query.Get("format"); format != "" && format != "json" {
+		h.exportIncidents(w, r, format, in)
+		return
+	}
+	if query.Has("cursor") {
+		cursor := query.Get("cursor")
+		in.Cursor = &cursor
+	}
 
-	out, err := h.svc.PaginateIncident(r.Context(), rawlimit, rawPage)
+	out, err := h.svc.PaginateIncident(r.Context(), in)
 	if err != nil {
 		h.WriteError(w, err)
 		return
@@ -75,26 +135,120 @@ func (h *Handler) handlePaginate(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, out)
 }
 
+func (h *Handler) exportIncidents(w http.ResponseWriter, r *http.Request, format string, filter *service.PaginateIncidentsRequestInput) {
+	out, err := h.svc.ExportIncidents(r.Context(), &service.ExportIncidentsRequestInput{
+		Format: format,
+		Filter: *filter,
+	})
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", out.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"incidents.%s\"", format))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(out.Data)
+}
+
+// @Summary      Импорт инцидентов
+// @Description  Создает или обновляет инциденты из GeoJSON FeatureCollection, CSV или KML одной транзакцией, сопоставляя их по названию. Формат задается параметром format или заголовком Content-Type. Каждый объект проверяется по правилам создания инцидента; если хотя бы один не прошел проверку, ничего не записывается и возвращается 422 с ошибками по объектам. С dry_run=true данные только проверяются
+// @Tags         incidents
+// @Accept       json
+// @Accept       text/csv
+// @Accept       application/vnd.google-earth.kml+xml
+// @Produce      json
+// @Param        format   query     string  false  "Формат данных, по умолчанию определяется по Content-Type"  Enums(geojson, csv, kml)
+// @Param        dry_run  query     bool    false  "Только проверить данные"  default(false)
+// @Param        data     body      object  true   "GeoJSON FeatureCollection, CSV с колонками IncidentJSON или KML"
+// @Success      200      {object}  service.ImportIncidentsOutput
+// @Failure      400      {object}  badRequestErrorResponse
+// @Failure      401      {object}  unauthorizedErrorResponse
+// @Failure      413      {object}  apiErrorResponse
+// @Failure      422      {object}  service.ImportIncidentsOutput
+// @Failure      500      {object}  internalServerErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /incidents/import [post]
+func (h *Handler) handleImportIncidents(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBodyBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeAPIResponse(w, http.StatusRequestEntityTooLarge, string(domain.CodeInvalidRequest), "import file is too large")
+			return
+		}
+		h.logger.Error("invalid request body", logging.ErrAttr(err))
+		h.WriteError(w, domain.ErrInvalidRequest("invalid payload"))
+		return
+	}
+
+	out, err := h.svc.ImportIncidents(r.Context(), &service.ImportIncidentsRequestInput{
+		Format: importFormat(r),
+		Data:   data,
+		DryRun: r.URL.Query().Get("dry_run"),
+	})
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if len(out.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, out)
+}
+
+// importFormat берет формат импорта из параметра format, а если он не задан - из Content-Type
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return service.FormatCSV
+	case "application/vnd.google-earth.kml+xml":
+		return service.FormatKML
+	default:
+		return service.FormatGeoJSON
+	}
+}
+
 // @Summary      Получение инцидента по ID
-// @Description  Получает информацию об инциденте по его идентификатору
+// @Description  Получает информацию об инциденте по его идентификатору. С параметром as_of возвращает состояние инцидента на указанный момент
 // @Tags         incidents
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "ID инцидента"
-// @Success      200  {object}  incedentRequestResponse
-// @Failure      400  {object}  badRequestErrorResponseGetByID
-// @Failure      401  {object}  unauthorizedErrorResponse
-// @Failure      404  {object}  notFoundErrorResponse
+// @Param        id     path      int     true   "ID инцидента"
+// @Param        as_of  query     string  false  "Момент времени (RFC3339)"
+// @Success      200    {object}  incedentRequestResponse
+// @Failure      400    {object}  badRequestErrorResponseGetByID
+// @Failure      401    {object}  unauthorizedErrorResponse
+// @Failure      404    {object}  notFoundErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /incidents/{id} [get]
 func (h *Handler) handleGetIncidentByID(w http.ResponseWriter, r *http.Request) {
 	rawID := r.PathValue("id")
 
+	if r.URL.Query().Has("as_of") {
+		out, err := h.svc.GetIncidentAsOf(r.Context(), rawID, r.URL.Query().Get("as_of"))
+		if err != nil {
+			h.WriteError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, incedentRequestResponse{Incendent: out})
+		return
+	}
+
 	out, err := h.svc.GetIncidentByID(r.Context(), rawID)
 	if err != nil {
 		h.WriteError(w, err)
 		return
 	}
+	setIncidentETag(w, out)
 
 	resp := incedentRequestResponse{
 		Incendent: out,
@@ -102,22 +256,57 @@ func (h *Handler) handleGetIncidentByID(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// @Summary      История изменений инцидента
+// @Description  Возвращает все версии инцидента (создание, обновления, удаление) с автором изменения, начиная с последней
+// @Tags         incidents
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "ID инцидента"
+// @Success      200  {object}  incidentHistoryResponse
+// @Failure      400  {object}  badRequestErrorResponseGetByID
+// @Failure      401  {object}  unauthorizedErrorResponse
+// @Failure      404  {object}  notFoundErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /incidents/{id}/history [get]
+func (h *Handler) handleIncidentHistory(w http.ResponseWriter, r *http.Request) {
+	rawID := r.PathValue("id")
+
+	out, err := h.svc.GetIncidentHistory(r.Context(), rawID)
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+
+	resp := incidentHistoryResponse{
+		Versions: out,
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
 // @Summary      Обновление инцидента
 // @Description  Полностью обновляет данные инцидента
 // @Tags         incidents
 // @Accept       json
 // @Produce      json
 // @Param        id        path      int           true  "ID инцидента"
+// @Param        If-Match  header    string        false  "ETag, полученный при чтении инцидента, или список ETag через запятую"
 // @Param        incident  body      IncidentJSON  true  "Обновленные данные инцидента"
 // @Success      200       {object}  incedentRequestResponse
 // @Failure      400       {object}  badRequestErrorResponse
 // @Failure      401       {object}  unauthorizedErrorResponse
 // @Failure      404       {object}  notFoundErrorResponse
+// @Failure      412       {object}  preconditionFailedErrorResponse
 // @Security     ApiKeyAuth
 // @Router       /incidents/{id} [put]
 func (h *Handler) handlePutIncident(w http.ResponseWriter, r *http.Request) {
 	rawID := r.PathValue("id")
 
+	versions, err := parseIfMatch(r)
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+
 	var req IncidentJSON
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("invalid request body", logging.ErrAttr(err))
@@ -126,13 +315,20 @@ func (h *Handler) handlePutIncident(w http.ResponseWriter, r *http.Request) {
 	}
 
 	in := &service.FullUpdateIncidentRequestInput{
-		ID:          rawID,
-		Title:       req.Title,
-		Description: req.Description,
-		Lat:         req.Lat,
-		Long:        req.Long,
-		Radius:      req.Radius,
-		Active:      req.Active,
+		ID:               rawID,
+		Title:            req.Title,
+		Description:      req.Description,
+		Lat:              req.Lat,
+		Long:             req.Long,
+		Radius:           req.Radius,
+		Active:           req.Active,
+		Severity:         req.Severity,
+		Category:         req.Category,
+		Boundary:         req.Boundary,
+		WarningBuffer:    req.WarningBuffer,
+		StartsAt:         req.StartsAt,
+		EndsAt:           req.EndsAt,
+		ExpectedVersions: versions,
 	}
 
 	out, err := h.svc.FullUpdateIncident(r.Context(), in)
@@ -140,6 +336,58 @@ func (h *Handler) handlePutIncident(w http.ResponseWriter, r *http.Request) {
 		h.WriteError(w, err)
 		return
 	}
+	setIncidentETag(w, out)
+
+	resp := incedentRequestResponse{
+		Incendent: out,
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// @Summary      Частичное обновление инцидента
+// @Description  Применяет JSON Merge Patch (RFC 7396): переданные поля заменяются, null сбрасывает поле, остальные не меняются.
+// @Description  При заголовке If-Match обновление выполняется только если ETag совпадает с текущей версией инцидента
+// @Tags         incidents
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int     true   "ID инцидента"
+// @Param        If-Match  header    string  false  "ETag, полученный при чтении инцидента, или список ETag через запятую"
+// @Param        patch     body      object  true   "JSON Merge Patch"
+// @Success      200       {object}  incedentRequestResponse
+// @Failure      400       {object}  badRequestErrorResponse
+// @Failure      401       {object}  unauthorizedErrorResponse
+// @Failure      404       {object}  notFoundErrorResponse
+// @Failure      412       {object}  preconditionFailedErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /incidents/{id} [patch]
+func (h *Handler) handlePatchIncident(w http.ResponseWriter, r *http.Request) {
+	rawID := r.PathValue("id")
+
+	versions, err := parseIfMatch(r)
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+
+	var patch json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		h.logger.Error("invalid request body", logging.ErrAttr(err))
+		h.WriteError(w, domain.ErrInvalidRequest("invalid json payload"))
+		return
+	}
+
+	in := &service.PatchIncidentRequestInput{
+		ID:               rawID,
+		Patch:            patch,
+		ExpectedVersions: versions,
+	}
+
+	out, err := h.svc.PatchIncident(r.Context(), in)
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	setIncidentETag(w, out)
 
 	resp := incedentRequestResponse{
 		Incendent: out,
@@ -148,7 +396,7 @@ func (h *Handler) handlePutIncident(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary      Удаление инцидента
-// @Description  Удаляет инцидент по его идентификатору
+// @Description  Переносит инцидент в архив: он перестает участвовать в проверках и пропадает из списка, но остается в истории и статистике
 // @Tags         incidents
 // @Accept       json
 // @Produce      json
@@ -168,3 +416,97 @@ func (h *Handler) handleDeleteIncident(w http.ResponseWriter, r *http.Request) {
 	}
 	writeJSON(w, http.StatusOK, nil)
 }
+
+// setIncidentETag выставляет ETag по версии инцидента
+func setIncidentETag(w http.ResponseWriter, incident *domain.Incident) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, incident.Version))
+}
+
+// parseIfMatch возвращает версии инцидента из заголовка If-Match: один ETag или список через запятую
+// (RFC 9110), обновление выполняется при совпадении с любой из версий.
+// Без заголовка или со значением * версия не проверяется
+func parseIfMatch(r *http.Request) ([]int, error) {
+	header := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	var versions []int
+	for entry := range strings.SplitSeq(header, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// слабые ETag (W/"...") при If-Match никогда не совпадают
+		weak := strings.HasPrefix(entry, "W/")
+		tag, ok := strings.CutPrefix(strings.TrimPrefix(entry, "W/"), `"`)
+		if ok {
+			tag, ok = strings.CutSuffix(tag, `"`)
+		}
+		if !ok || strings.Contains(tag, `"`) {
+			return nil, domain.ErrInvalidRequest("invalid If-Match header")
+		}
+
+		// ETag, который не может быть версией инцидента, ни с чем не совпадает
+		version, err := strconv.Atoi(tag)
+		if weak || err != nil || version <= 0 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return nil, domain.ErrPreconditionFailed("If-Match does not match current incident version")
+	}
+	return versions, nil
+}
+
+// @Summary      Восстановление инцидента
+// @Description  Возвращает инцидент из архива
+// @Tags         incidents
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "ID инцидента"
+// @Success      200  {object}  incedentRequestResponse
+// @Failure      400  {object}  badRequestErrorResponseGetByID
+// @Failure      401  {object}  unauthorizedErrorResponse
+// @Failure      404  {object}  notFoundErrorResponse
+// @Failure      409  {object}  alreadyExistsErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /incidents/{id}/restore [post]
+func (h *Handler) handleRestoreIncident(w http.ResponseWriter, r *http.Request) {
+	rawID := r.PathValue("id")
+
+	out, err := h.svc.RestoreIncident(r.Context(), rawID)
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	setIncidentETag(w, out)
+
+	resp := incedentRequestResponse{
+		Incendent: out,
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// @Summary      Очистка архива инцидентов
+// @Description  Окончательно удаляет инциденты, находящиеся в архиве дольше INCIDENT_PURGE_RETENTION_DAYS дней. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  purgeIncidentsResponse
+// @Failure      401  {object}  unauthorizedErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /admin/incidents/purge [post]
+func (h *Handler) handlePurgeIncidents(w http.ResponseWriter, r *http.Request) {
+	purged, err := h.svc.PurgeIncidents(r.Context())
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+
+	resp := purgeIncidentsResponse{
+		Purged: purged,
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -2,20 +2,24 @@ package handler
 
 import (
 	"net/http"
+	"red_collar/internal/domain"
 	"red_collar/internal/service"
 )
 
-func apiKeyMiddleware(validAPIKey string, logger service.LoggerInterfaces) func(http.Handler) http.Handler {
+// apiKeyMiddleware пропускает запросы с известным API ключом и сохраняет
+// в контексте его имя - оно записывается автором изменений в историю инцидентов
+func apiKeyMiddleware(identities map[string]string, logger service.LoggerInterfaces) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			apiKey := r.Header.Get("X-API-Key")
 
-			if apiKey != validAPIKey {
+			name, ok := identities[apiKey]
+			if apiKey == "" || !ok {
 				logger.Error("invalid api key")
 				writeAPIResponse(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid api key")
 				return
 			}
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(domain.ContextWithActor(r.Context(), name)))
 		})
 	}
 }
@@ -27,16 +27,39 @@ func NewRouter(svc *service.Service, logger service.LoggerInterfaces, cfg *confi
 	h := NewHandler(svc, logger, cfg.App.StatsTimeWindowMins)
 	mux := http.NewServeMux()
 
-	apiKeyAuth := apiKeyMiddleware(cfg.App.APIKey, logger)
+	apiKeyAuth := apiKeyMiddleware(cfg.App.APIKeyIdentities(), logger)
+	adminAuth := apiKeyMiddleware(cfg.App.AdminAPIKeyIdentities(), logger)
 
 	mux.Handle("POST /api/v1/incidents", apiKeyAuth(http.HandlerFunc(h.handleCreateIncident)))
+	mux.Handle("POST /api/v1/incidents/import", apiKeyAuth(http.HandlerFunc(h.handleImportIncidents)))
 	mux.Handle("GET /api/v1/incidents/{id}", apiKeyAuth(http.HandlerFunc(h.handleGetIncidentByID)))
+	mux.Handle("GET /api/v1/incidents/{id}/history", apiKeyAuth(http.HandlerFunc(h.handleIncidentHistory)))
+	mux.Handle("GET /api/v1/incidents/{id}/occupants", apiKeyAuth(http.HandlerFunc(h.handleIncidentOccupants)))
 	mux.Handle("GET /api/v1/incidents", apiKeyAuth(http.HandlerFunc(h.handlePaginate)))
 	mux.Handle("DELETE /api/v1/incidents/{id}", apiKeyAuth(http.HandlerFunc(h.handleDeleteIncident)))
 	mux.Handle("PUT /api/v1/incidents/{id}", apiKeyAuth(http.HandlerFunc(h.handlePutIncident)))
+	mux.Handle("PATCH /api/v1/incidents/{id}", apiKeyAuth(http.HandlerFunc(h.handlePatchIncident)))
+	mux.Handle("POST /api/v1/incidents/{id}/restore", apiKeyAuth(http.HandlerFunc(h.handleRestoreIncident)))
+
+	mux.Handle("POST /api/v1/admin/incidents/purge", adminAuth(http.HandlerFunc(h.handlePurgeIncidents)))
+	mux.Handle("GET /api/v1/admin/webhooks/dlq", adminAuth(http.HandlerFunc(h.handleListDeadLetters)))
+	mux.Handle("DELETE /api/v1/admin/webhooks/dlq", adminAuth(http.HandlerFunc(h.handleDeleteDeadLetters)))
+	mux.Handle("POST /api/v1/admin/webhooks/dlq/replay", adminAuth(http.HandlerFunc(h.handleReplayDeadLetters)))
+	mux.Handle("POST /api/v1/admin/webhooks/dlq/{id}/replay", adminAuth(http.HandlerFunc(h.handleReplayDeadLetter)))
+
+	mux.Handle("POST /api/v1/webhooks", adminAuth(http.HandlerFunc(h.handleCreateWebhook)))
+	mux.Handle("GET /api/v1/webhooks", adminAuth(http.HandlerFunc(h.handleListWebhooks)))
+	mux.Handle("GET /api/v1/webhooks/events", adminAuth(http.HandlerFunc(h.handleWebhookEvents)))
+	mux.Handle("GET /api/v1/webhooks/{id}", adminAuth(http.HandlerFunc(h.handleGetWebhook)))
+	mux.Handle("PUT /api/v1/webhooks/{id}", adminAuth(http.HandlerFunc(h.handlePutWebhook)))
+	mux.Handle("DELETE /api/v1/webhooks/{id}", adminAuth(http.HandlerFunc(h.handleDeleteWebhook)))
+	mux.Handle("POST /api/v1/webhooks/{id}/rotate-secret", adminAuth(http.HandlerFunc(h.handleRotateWebhookSecret)))
 
 	mux.HandleFunc("POST /api/v1/location/check", h.handleCheckCoordinates)
+	mux.HandleFunc("POST /api/v1/location/check/batch", h.handleCheckCoordinatesBatch)
+	mux.Handle("GET /api/v1/location/heatmap", apiKeyAuth(http.HandlerFunc(h.handleHeatmap)))
 	mux.HandleFunc("GET /api/v1/incidents/stats", h.handleStats)
+	mux.Handle("GET /api/v1/users/{user_id}/checks", apiKeyAuth(http.HandlerFunc(h.handleUserChecks)))
 
 	mux.HandleFunc("GET /api/v1/system/health", h.handleHealth)
 
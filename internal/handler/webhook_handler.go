@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"red_collar/internal/domain"
+	"red_collar/internal/service"
+	"time"
+
+	"github.com/theartofdevel/logging"
+)
+
+// @Summary      Создание подписки на вебхуки
+// @Description  Добавляет получателя вебхуков с фильтрами по типам событий, зонам, уровню опасности и области. Секрет возвращается только в этом ответе. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        subscription  body      WebhookSubscriptionJSON  true  "Данные подписки"
+// @Success      201           {object}  webhookSubscriptionResponse
+// @Failure      400           {object}  badRequestErrorResponse
+// @Failure      401           {object}  unauthorizedErrorResponse
+// @Failure      500           {object}  internalServerErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /webhooks [post]
+func (h *Handler) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req WebhookSubscriptionJSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body", logging.ErrAttr(err))
+		h.WriteError(w, domain.ErrInvalidRequest("invalid json payload"))
+		return
+	}
+
+	out, err := h.svc.CreateWebhookSubscription(r.Context(), webhookSubscriptionInput("", &req))
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, webhookSubscriptionResponse{Subscription: out})
+}
+
+// @Summary      Список подписок на вебхуки
+// @Description  Возвращает все подписки без секретов. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  webhookSubscriptionsResponse
+// @Failure      401  {object}  unauthorizedErrorResponse
+// @Failure      500  {object}  internalServerErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /webhooks [get]
+func (h *Handler) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	out, err := h.svc.ListWebhookSubscriptions(r.Context())
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhookSubscriptionsResponse{Subscriptions: out})
+}
+
+// @Summary      Получение подписки на вебхуки
+// @Description  Возвращает подписку без секрета. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "ID подписки"
+// @Success      200  {object}  webhookSubscriptionResponse
+// @Failure      400  {object}  badRequestErrorResponseGetByID
+// @Failure      401  {object}  unauthorizedErrorResponse
+// @Failure      404  {object}  notFoundErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /webhooks/{id} [get]
+func (h *Handler) handleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	out, err := h.svc.GetWebhookSubscription(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhookSubscriptionResponse{Subscription: out})
+}
+
+// @Summary      Обновление подписки на вебхуки
+// @Description  Полностью заменяет адрес, флаг enabled и фильтры подписки. Без secret секрет остается прежним. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id            path      int                      true  "ID подписки"
+// @Param        subscription  body      WebhookSubscriptionJSON  true  "Данные подписки"
+// @Success      200           {object}  webhookSubscriptionResponse
+// @Failure      400           {object}  badRequestErrorResponse
+// @Failure      401           {object}  unauthorizedErrorResponse
+// @Failure      404           {object}  notFoundErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /webhooks/{id} [put]
+func (h *Handler) handlePutWebhook(w http.ResponseWriter, r *http.Request) {
+	var req WebhookSubscriptionJSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body", logging.ErrAttr(err))
+		h.WriteError(w, domain.ErrInvalidRequest("invalid json payload"))
+		return
+	}
+
+	out, err := h.svc.UpdateWebhookSubscription(r.Context(), webhookSubscriptionInput(r.PathValue("id"), &req))
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhookSubscriptionResponse{Subscription: out})
+}
+
+// @Summary      Удаление подписки на вебхуки
+// @Description  Удаляет подписку. Ее задачи, которые еще ждут повторной отправки, отбрасываются. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "ID подписки"
+// @Success      200
+// @Failure      400  {object}  badRequestErrorResponseGetByID
+// @Failure      401  {object}  unauthorizedErrorResponse
+// @Failure      404  {object}  notFoundErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /webhooks/{id} [delete]
+func (h *Handler) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.DeleteWebhookSubscription(r.Context(), r.PathValue("id")); err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nil)
+}
+
+// @Summary      Ротация секрета подписки
+// @Description  Заменяет секрет подписки. Прежний секрет действует еще grace_period_seconds секунд (по умолчанию сутки): все это время X-Signature содержит подписи обоими секретами, и получатель может сменить секрет в любой момент. Новый секрет возвращается только в этом ответе. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                      true   "ID подписки"
+// @Param        rotate  body      RotateWebhookSecretJSON  false  "Параметры ротации"
+// @Success      200     {object}  webhookSubscriptionResponse
+// @Failure      400     {object}  badRequestErrorResponse
+// @Failure      401     {object}  unauthorizedErrorResponse
+// @Failure      404     {object}  notFoundErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /webhooks/{id}/rotate-secret [post]
+func (h *Handler) handleRotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	var req RotateWebhookSecretJSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Error("invalid request body", logging.ErrAttr(err))
+		h.WriteError(w, domain.ErrInvalidRequest("invalid json payload"))
+		return
+	}
+
+	out, err := h.svc.RotateWebhookSecret(r.Context(), &service.RotateWebhookSecretRequestInput{
+		ID:              r.PathValue("id"),
+		Secret:          req.Secret,
+		GracePeriodSecs: req.GracePeriodSecs,
+	})
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhookSubscriptionResponse{Subscription: out})
+}
+
+// @Summary      Формат событий вебхуков
+// @Description  Каждый запрос вебхука содержит конверт: id (UUID, ключ идемпотентности - одинаков во всех повторах и для всех подписок), type, version, occurred_at и data с данными события. Версия схемы дублируется в заголовке X-Webhook-Version и меняется только при несовместимых изменениях. Для incident.deleted в data заполнен только incident_id. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  webhookEventsResponse
+// @Failure      401  {object}  unauthorizedErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /webhooks/events [get]
+func (h *Handler) handleWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	incident := &domain.Incident{
+		ID:       1,
+		Title:    "Road works",
+		Lat:      55.7558,
+		Long:     37.6173,
+		Radius:   100,
+		Active:   true,
+		Severity: domain.SeverityWarning,
+	}
+
+	writeJSON(w, http.StatusOK, webhookEventsResponse{
+		Version:       domain.EventVersion,
+		VersionHeader: domain.EventVersionHeader,
+		EventTypes:    domain.EventTypes(),
+		Example: (&domain.Event{
+			ID:         "3f0c8c1e-7a4b-4d2e-9c1a-5b6d7e8f9a0b",
+			OccurredAt: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+			Type:       domain.EventIncidentCreated,
+			IncidentID: incident.ID,
+			Severity:   incident.Severity,
+			Incident:   incident,
+		}).Envelope(),
+	})
+}
+
+// @Summary      Список DLQ вебхуков
+// @Description  Возвращает задачи доставки, которые исчерпали попытки или получили неповторяемую ошибку, с последней ошибкой и числом попыток. Сначала последние попавшие в DLQ. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        subscription_id  query     int     false  "ID подписки, 0 - WEBHOOK_URL"
+// @Param        event_type       query     string  false  "Тип события"
+// @Param        incident_id      query     int     false  "ID инцидента"
+// @Param        limit            query     int     false  "Количество записей, до 500"  default(50)
+// @Param        offset           query     int     false  "Сколько записей пропустить"  default(0)
+// @Success      200              {object}  service.DeadLettersOutput
+// @Failure      400              {object}  badRequestErrorResponse
+// @Failure      401              {object}  unauthorizedErrorResponse
+// @Failure      500              {object}  internalServerErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks/dlq [get]
+func (h *Handler) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	out, err := h.svc.ListDeadLetters(r.Context(), &service.DeadLettersRequestInput{
+		SubscriptionID: query.Get("subscription_id"),
+		EventType:      query.Get("event_type"),
+		IncidentID:     query.Get("incident_id"),
+		Limit:          query.Get("limit"),
+		Offset:         query.Get("offset"),
+	})
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// @Summary      Повтор записи DLQ
+// @Description  Возвращает одну запись DLQ в очередь доставки со сброшенным счетчиком попыток. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID записи DLQ"
+// @Success      200  {object}  replayDeadLettersResponse
+// @Failure      401  {object}  unauthorizedErrorResponse
+// @Failure      404  {object}  notFoundErrorResponse
+// @Failure      500  {object}  internalServerErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks/dlq/{id}/replay [post]
+func (h *Handler) handleReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.ReplayDeadLetter(r.Context(), r.PathValue("id")); err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, replayDeadLettersResponse{Replayed: 1})
+}
+
+// @Summary      Повтор записей DLQ
+// @Description  Возвращает в очередь доставки записи DLQ, подходящие под фильтры, или всю DLQ с all=true. Счетчик попыток сбрасывается. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        filter  body      ReplayDeadLettersJSON  true  "Отбор записей"
+// @Success      200     {object}  replayDeadLettersResponse
+// @Failure      400     {object}  badRequestErrorResponse
+// @Failure      401     {object}  unauthorizedErrorResponse
+// @Failure      500     {object}  internalServerErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks/dlq/replay [post]
+func (h *Handler) handleReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var req ReplayDeadLettersJSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("invalid request body", logging.ErrAttr(err))
+		h.WriteError(w, domain.ErrInvalidRequest("invalid json payload"))
+		return
+	}
+
+	replayed, err := h.svc.ReplayDeadLetters(r.Context(), &service.ReplayDeadLettersRequestInput{
+		SubscriptionID: req.SubscriptionID,
+		EventType:      req.EventType,
+		IncidentID:     req.IncidentID,
+		All:            req.All,
+	})
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, replayDeadLettersResponse{Replayed: replayed})
+}
+
+// @Summary      Очистка DLQ
+// @Description  Удаляет записи, попавшие в DLQ раньше before. Доступно только ключам из ADMIN_API_KEYS
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        before  query     string  true  "Граница времени попадания в DLQ (RFC3339)"
+// @Success      200     {object}  deleteDeadLettersResponse
+// @Failure      400     {object}  badRequestErrorResponse
+// @Failure      401     {object}  unauthorizedErrorResponse
+// @Failure      500     {object}  internalServerErrorResponse
+// @Security     ApiKeyAuth
+// @Router       /admin/webhooks/dlq [delete]
+func (h *Handler) handleDeleteDeadLetters(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.svc.DeleteDeadLetters(r.Context(), &service.DeleteDeadLettersRequestInput{
+		Before: r.URL.Query().Get("before"),
+	})
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deleteDeadLettersResponse{Deleted: deleted})
+}
+
+func webhookSubscriptionInput(rawID string, req *WebhookSubscriptionJSON) *service.WebhookSubscriptionRequestInput {
+	return &service.WebhookSubscriptionRequestInput{
+		ID:          rawID,
+		URL:         req.URL,
+		Secret:      req.Secret,
+		Enabled:     req.Enabled,
+		EventTypes:  req.EventTypes,
+		IncidentIDs: req.IncidentIDs,
+		Severities:  req.Severities,
+		BBox:        req.BBox,
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"sync"
 	"testing"
+	"time"
 
 	"red_collar/internal/domain"
 	redisRepo "red_collar/internal/repository/redis"
@@ -73,7 +74,7 @@ func setupTestRD() error {
 
 func setupQueue() {
 	setupTestRD()
-	testQueueRepo = NewQueue(testRD)
+	testQueueRepo = NewQueue(testRD, time.Minute)
 }
 
 func cleanupTestRD(t *testing.T) {
@@ -3,9 +3,15 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"red_collar/internal/domain"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type CoordinatesRepository struct {
@@ -18,80 +24,368 @@ func NewCoordinatesRepository(db *sqlx.DB) *CoordinatesRepository {
 	}
 }
 
-func (c *CoordinatesRepository) Check(ctx context.Context, locCheck *domain.LocationCheck) error {
+// Check определяет зоны, в которые попала точка, зоны, к границе которых она ближе,
+// чем буфер предупреждения (собственный буфер инцидента или params.WarningBufferM по умолчанию),
+// и зоны, пересеченные по пути от предыдущей проверки пользователя
+func (c *CoordinatesRepository) Check(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
+	return c.CheckBatch(ctx, []*domain.LocationCheck{locCheck}, params)
+}
+
+// CheckBatch выполняет Check для набора точек: зоны для всех точек определяются одним запросом,
+// проверки и попадания сохраняются пакетно в одной транзакции. Порядок проверок сохраняется.
+// Время проверки берется из CheckedAt, незаполненное - текущее время
+func (c *CoordinatesRepository) CheckBatch(ctx context.Context, checks []*domain.LocationCheck, params domain.CheckParams) error {
+	if len(checks) == 0 {
+		return nil
+	}
+
 	tx, err := c.db.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		} else {
-			tx.Commit()
+	defer tx.Rollback()
+
+	userIDs := make([]string, len(checks))
+	lats := make([]float64, len(checks))
+	longs := make([]float64, len(checks))
+	checkedAts := make([]time.Time, len(checks))
+	now := time.Now().UTC()
+	for i, check := range checks {
+		userIDs[i] = check.UserID
+		lats[i] = check.Lat
+		longs[i] = check.Long
+		checkedAts[i] = now
+		if !check.CheckedAt.IsZero() {
+			checkedAts[i] = check.CheckedAt.UTC()
 		}
-	}()
+	}
 
+	// Круговая зона задается центром и радиусом, полигональная - границей.
+	// Для круга расстояние до края внутри зоны отрицательное, для полигона - нулевое.
+	// Расписание зоны сверяется со временем проверки, а не с моментом запроса
 	checkQuery := `
-		SELECT id 
-		FROM incidents
-		WHERE active = true 
-			AND ST_DWithin(
-					geom,
-					ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography,
-					radius_m
-				)
-		ORDER BY ST_Distance(
-			geom,
-			ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography
+		WITH pts AS (
+			SELECT
+				p.ord,
+				p.checked_at,
+				ST_SetSRID(ST_MakePoint(p.long, p.lat), 4326)::geography AS g
+			FROM unnest($1::float8[], $2::float8[], $4::timestamp[]) WITH ORDINALITY AS p(long, lat, checked_at, ord)
 		)
-		LIMIT 1;
+		SELECT
+			pts.ord,
+			i.id AS incident_id,
+			i.severity,
+			i.category,
+			ST_Distance(i.geom, pts.g) AS distance_m,
+			CASE
+				WHEN i.boundary IS NULL THEN ST_Distance(i.geom, pts.g) - i.radius_m
+				ELSE ST_Distance(i.boundary, pts.g)
+			END AS distance_to_edge_m,
+			CASE
+				WHEN i.boundary IS NULL THEN ST_DWithin(i.geom, pts.g, i.radius_m)
+				ELSE ST_Intersects(i.boundary, pts.g)
+			END AS inside,
+			COALESCE(degrees(ST_Azimuth(
+				pts.g,
+				CASE
+					WHEN i.boundary IS NULL THEN i.geom
+					ELSE ST_ClosestPoint(i.boundary::geometry, pts.g::geometry)::geography
+				END
+			)), 0) AS bearing_deg
+		FROM pts
+		JOIN incidents i ON ` + activeIncidentCond("pts.checked_at", "pts.checked_at") + `
+			AND (
+				(i.boundary IS NULL AND ST_DWithin(i.geom, pts.g, i.radius_m + COALESCE(i.warning_buffer_m, $3)))
+				OR ST_DWithin(i.boundary, pts.g, COALESCE(i.warning_buffer_m, $3))
+			)
+		ORDER BY pts.ord, distance_m, i.id
 	`
 
-	var incidentID int
-	if err := tx.GetContext(ctx, &incidentID, checkQuery, locCheck.Long, locCheck.Lat); err != nil {
-		if err != sql.ErrNoRows {
-			return err
-		}
+	var hits []zoneHit
+	if err = tx.SelectContext(ctx, &hits, checkQuery, pq.Array(longs), pq.Array(lats), params.WarningBufferM, pq.Array(checkedAts)); err != nil {
+		return err
 	}
 
-	if incidentID == 0 {
-		locCheck.NearestID = nil
-		locCheck.InDangerZone = false
-	} else {
-		locCheck.NearestID = &incidentID
-		locCheck.InDangerZone = true
+	hitsByCheck := make([][]zoneHit, len(checks))
+	for _, hit := range hits {
+		hitsByCheck[hit.Ord-1] = append(hitsByCheck[hit.Ord-1], hit)
 	}
 
-	insertCheckQuery := `
+	inDangerZone := make([]bool, len(checks))
+	nearestIDs := make([]sql.NullInt64, len(checks))
+	for i, check := range checks {
+		applyZoneHits(check, hitsByCheck[i])
+		inDangerZone[i] = check.InDangerZone
+		if check.NearestID != nil {
+			nearestIDs[i] = sql.NullInt64{Int64: int64(*check.NearestID), Valid: true}
+		}
+	}
+
+	// id выдаются в порядке вставки, поэтому после сортировки по id строки соответствуют входному порядку
+	insertChecksQuery := `
 		INSERT INTO location_checks (
-			user_id, lat, long, in_danger_zone, nearest_id
+			user_id, lat, long, in_danger_zone, nearest_id, checked_at
 		)
-		VALUES($1, $2, $3, $4, $5)
+		SELECT c.user_id, c.lat, c.long, c.in_danger_zone, c.nearest_id, c.checked_at
+		FROM unnest($1::text[], $2::float8[], $3::float8[], $4::boolean[], $5::int[], $6::timestamp[])
+			WITH ORDINALITY AS c(user_id, lat, long, in_danger_zone, nearest_id, checked_at, ord)
+		ORDER BY c.ord
 		RETURNING id, checked_at
 	`
 
-	err = tx.QueryRowContext(ctx, insertCheckQuery,
-		locCheck.UserID,
-		locCheck.Lat,
-		locCheck.Long,
-		locCheck.InDangerZone,
-		locCheck.NearestID,
-	).Scan(&locCheck.ID, &locCheck.CheckedAt)
+	var inserted []struct {
+		ID        int       `db:"id"`
+		CheckedAt time.Time `db:"checked_at"`
+	}
+	err = tx.SelectContext(ctx, &inserted, insertChecksQuery,
+		pq.Array(userIDs),
+		pq.Array(lats),
+		pq.Array(longs),
+		pq.Array(inDangerZone),
+		pq.Array(nearestIDs),
+		pq.Array(checkedAts),
+	)
 	if err != nil {
 		return err
 	}
+	sort.Slice(inserted, func(i, j int) bool { return inserted[i].ID < inserted[j].ID })
+
+	var checkIDs, incidentIDs []int64
+	var distances []float64
+	for i, check := range checks {
+		check.ID = inserted[i].ID
+		check.CheckedAt = inserted[i].CheckedAt
+		for _, m := range check.Matches {
+			checkIDs = append(checkIDs, int64(check.ID))
+			incidentIDs = append(incidentIDs, int64(m.IncidentID))
+			distances = append(distances, m.DistanceM)
+		}
+	}
+
+	if params.MaxSegmentGap > 0 {
+		if err = c.findCrossings(ctx, tx, checks, params.MaxSegmentGap); err != nil {
+			return err
+		}
+	}
+
+	if len(checkIDs) > 0 {
+		insertMatchesQuery := `
+			INSERT INTO location_check_matches (check_id, incident_id, distance_m)
+			SELECT unnest($1::int[]), unnest($2::int[]), unnest($3::float8[])
+		`
+		_, err = tx.ExecContext(ctx, insertMatchesQuery, pq.Array(checkIDs), pq.Array(incidentIDs), pq.Array(distances))
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = saveLastChecks(ctx, tx, checks); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
+// lastCheck - последняя известная точка пользователя
+type lastCheck struct {
+	UserID    string    `db:"user_id"`
+	Lat       float64   `db:"lat"`
+	Long      float64   `db:"long"`
+	CheckedAt time.Time `db:"checked_at"`
+}
+
+// findCrossings ищет зоны, которые пересекает отрезок от предыдущей точки пользователя до текущей,
+// при условии, что ни одна из точек в зону не попала. Для нескольких точек одного пользователя
+// в пачке предыдущей считается предшествующая ей точка из той же пачки. Точка старше
+// предыдущей пути не образует и предыдущую не заменяет
+func (c *CoordinatesRepository) findCrossings(ctx context.Context, tx *sqlx.Tx, checks []*domain.LocationCheck, maxGap time.Duration) error {
+	userIDs := make([]string, 0, len(checks))
+	for _, check := range checks {
+		userIDs = append(userIDs, check.UserID)
+	}
+
+	var stored []lastCheck
+	err := tx.SelectContext(ctx, &stored, `
+		SELECT user_id, lat, long, checked_at
+		FROM user_last_checks
+		WHERE user_id = ANY($1)
+	`, pq.Array(userIDs))
+	if err != nil {
+		return err
+	}
+
+	last := make(map[string]lastCheck, len(stored))
+	for _, l := range stored {
+		last[l.UserID] = l
+	}
+
+	var ords []int64
+	var fromLongs, fromLats, toLongs, toLats []float64
+	var fromTimes, toTimes []time.Time
+	for i, check := range checks {
+		prev, ok := last[check.UserID]
+		if ok && check.CheckedAt.Before(prev.CheckedAt) {
+			continue
+		}
+		last[check.UserID] = lastCheck{UserID: check.UserID, Lat: check.Lat, Long: check.Long, CheckedAt: check.CheckedAt}
+		if !ok || check.CheckedAt.Sub(prev.CheckedAt) > maxGap {
+			continue
+		}
+		if prev.Lat == check.Lat && prev.Long == check.Long {
+			continue
+		}
+		ords = append(ords, int64(i+1))
+		fromLongs = append(fromLongs, prev.Long)
+		fromLats = append(fromLats, prev.Lat)
+		toLongs = append(toLongs, check.Long)
+		toLats = append(toLats, check.Lat)
+		fromTimes = append(fromTimes, prev.CheckedAt)
+		toTimes = append(toTimes, check.CheckedAt)
+	}
+
+	if len(ords) == 0 {
+		return nil
+	}
+
+	// fraction - доля пути от предыдущей точки до первого пересечения с границей зоны.
+	// Зона учитывается, если действовала хотя бы часть времени между точками
+	crossingsQuery := `
+		WITH seg AS (
+			SELECT
+				s.ord,
+				s.from_at,
+				s.to_at,
+				ST_SetSRID(ST_MakePoint(s.from_long, s.from_lat), 4326)::geography AS from_pt,
+				ST_SetSRID(ST_MakePoint(s.to_long, s.to_lat), 4326)::geography AS to_pt,
+				ST_MakeLine(
+					ST_SetSRID(ST_MakePoint(s.from_long, s.from_lat), 4326),
+					ST_SetSRID(ST_MakePoint(s.to_long, s.to_lat), 4326)
+				) AS line
+			FROM unnest($1::int[], $2::float8[], $3::float8[], $4::float8[], $5::float8[], $6::timestamp[], $7::timestamp[])
+				AS s(ord, from_long, from_lat, to_long, to_lat, from_at, to_at)
+		),
+		crossed AS (
+			SELECT
+				seg.ord,
+				seg.line,
+				i.id,
+				i.severity,
+				i.category,
+				COALESCE(i.boundary, ST_Buffer(i.geom, i.radius_m))::geometry AS shape
+			FROM seg
+			JOIN incidents i ON ` + activeIncidentCond("seg.from_at", "seg.to_at") + `
+				AND (
+					(i.boundary IS NULL
+						AND ST_DWithin(i.geom, seg.line::geography, i.radius_m)
+						AND NOT ST_DWithin(i.geom, seg.from_pt, i.radius_m)
+						AND NOT ST_DWithin(i.geom, seg.to_pt, i.radius_m))
+					OR (ST_Intersects(i.boundary, seg.line::geography)
+						AND NOT ST_Intersects(i.boundary, seg.from_pt)
+						AND NOT ST_Intersects(i.boundary, seg.to_pt))
+				)
+		)
+		SELECT
+			ord,
+			id AS incident_id,
+			severity,
+			category,
+			COALESCE(
+				ST_LineLocatePoint(line, ST_ClosestPoint(ST_Intersection(shape, line), ST_StartPoint(line))),
+				ST_LineLocatePoint(line, ST_ClosestPoint(shape, ST_StartPoint(line)))
+			) AS fraction
+		FROM crossed
+		ORDER BY ord, fraction, id
+	`
+
+	var rows []struct {
+		Ord        int             `db:"ord"`
+		IncidentID int             `db:"incident_id"`
+		Severity   domain.Severity `db:"severity"`
+		Category   string          `db:"category"`
+		Fraction   float64         `db:"fraction"`
+	}
+	err = tx.SelectContext(ctx, &rows, crossingsQuery,
+		pq.Array(ords),
+		pq.Array(fromLongs),
+		pq.Array(fromLats),
+		pq.Array(toLongs),
+		pq.Array(toLats),
+		pq.Array(fromTimes),
+		pq.Array(toTimes),
+	)
+	if err != nil {
+		return err
+	}
+
+	fromTimeByOrd := make(map[int]time.Time, len(ords))
+	for i, ord := range ords {
+		fromTimeByOrd[int(ord)] = fromTimes[i]
+	}
+
+	for _, row := range rows {
+		check := checks[row.Ord-1]
+		from := fromTimeByOrd[row.Ord]
+		elapsed := check.CheckedAt.Sub(from)
+		check.Crossed = append(check.Crossed, domain.ZoneCrossing{
+			IncidentID:       row.IncidentID,
+			EstimatedEntryAt: from.Add(time.Duration(row.Fraction * float64(elapsed))),
+			Severity:         row.Severity,
+			Category:         row.Category,
+		})
+	}
+	return nil
+}
+
+// saveLastChecks запоминает последнюю по времени точку каждого пользователя из пачки,
+// если она не старше уже сохраненной
+func saveLastChecks(ctx context.Context, tx *sqlx.Tx, checks []*domain.LocationCheck) error {
+	latest := make(map[string]*domain.LocationCheck, len(checks))
+	order := make([]string, 0, len(checks))
+	for _, check := range checks {
+		prev, ok := latest[check.UserID]
+		if !ok {
+			order = append(order, check.UserID)
+		}
+		if !ok || !check.CheckedAt.Before(prev.CheckedAt) {
+			latest[check.UserID] = check
+		}
+	}
+
+	userIDs := make([]string, len(order))
+	checkIDs := make([]int64, len(order))
+	lats := make([]float64, len(order))
+	longs := make([]float64, len(order))
+	checkedAts := make([]time.Time, len(order))
+	for i, userID := range order {
+		check := latest[userID]
+		userIDs[i] = userID
+		checkIDs[i] = int64(check.ID)
+		lats[i] = check.Lat
+		longs[i] = check.Long
+		checkedAts[i] = check.CheckedAt
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO user_last_checks (user_id, check_id, lat, long, checked_at)
+		SELECT unnest($1::text[]), unnest($2::int[]), unnest($3::float8[]), unnest($4::float8[]), unnest($5::timestamp[])
+		ON CONFLICT (user_id) DO UPDATE SET
+			check_id = EXCLUDED.check_id,
+			lat = EXCLUDED.lat,
+			long = EXCLUDED.long,
+			checked_at = EXCLUDED.checked_at
+		WHERE user_last_checks.checked_at <= EXCLUDED.checked_at
+	`, pq.Array(userIDs), pq.Array(checkIDs), pq.Array(lats), pq.Array(longs), pq.Array(checkedAts))
+	return err
+}
+
 func (c *CoordinatesRepository) GetStats(ctx context.Context, timeWindowMinutes int) ([]domain.ZoneStat, error) {
 	getQuery := `
 		SELECT 
-			nearest_id as zone_id,
-			COUNT(DISTINCT user_id) as user_count
-		FROM location_checks
-		WHERE in_danger_zone = true
-			AND checked_at >= NOW() - INTERVAL '1 minute' * $1
-		GROUP BY nearest_id
+			m.incident_id as zone_id,
+			COUNT(DISTINCT c.user_id) as user_count
+		FROM location_check_matches m
+		JOIN location_checks c ON c.id = m.check_id
+		WHERE c.checked_at >= NOW() - INTERVAL '1 minute' * $1
+		GROUP BY m.incident_id
 		ORDER BY zone_id
 	`
 
@@ -102,3 +396,306 @@ func (c *CoordinatesRepository) GetStats(ctx context.Context, timeWindowMinutes
 	}
 	return stats, nil
 }
+
+// StatsSeries считает попадания в зоны по интервалам: уникальных пользователей, число проверок,
+// первую и последнюю проверку. Интервалы без попаданий не возвращаются
+func (c *CoordinatesRepository) StatsSeries(ctx context.Context, filter domain.StatsFilter) ([]domain.ZoneStatBucket, error) {
+	args := []any{filter.From, filter.To, filter.Bucket.Seconds()}
+	incidentCond := ""
+	if filter.IncidentID != nil {
+		args = append(args, *filter.IncidentID)
+		incidentCond = "AND m.incident_id = $4"
+	}
+
+	seriesQuery := `
+		SELECT
+			m.incident_id,
+			date_bin($3 * INTERVAL '1 second', c.checked_at, $1::timestamp) AS bucket_start,
+			COUNT(DISTINCT c.user_id) AS unique_users,
+			COUNT(*) AS total_checks,
+			MIN(c.checked_at) AS first_seen,
+			MAX(c.checked_at) AS last_seen
+		FROM location_checks c
+		JOIN location_check_matches m ON m.check_id = c.id
+		WHERE c.checked_at >= $1::timestamp AND c.checked_at < $2::timestamp ` + incidentCond + `
+		GROUP BY m.incident_id, bucket_start
+		ORDER BY m.incident_id, bucket_start
+	`
+
+	var buckets []domain.ZoneStatBucket
+	if err := c.db.SelectContext(ctx, &buckets, seriesQuery, args...); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// checkGeom - точка проверки. Выражение совпадает с индексом location_checks_geom_gist_idx
+const checkGeom = `ST_SetSRID(ST_MakePoint(c.long, c.lat), 4326)`
+
+// heatmapGridFuncs - функции PostGIS, строящие сетку над областью и ячейку сетки по индексам
+var heatmapGridFuncs = map[domain.HeatmapGrid]struct{ grid, cell string }{
+	domain.HeatmapGridSquare: {grid: "ST_SquareGrid", cell: "ST_Square"},
+	domain.HeatmapGridHex:    {grid: "ST_HexagonGrid", cell: "ST_Hexagon"},
+}
+
+// Heatmap считает проверки в ячейках сетки над областью за период. Проверки отбираются по индексу
+// location_checks_geom_gist_idx; ячейки без проверок не возвращаются. Квадраты и шестиугольники
+// строятся в Web Mercator (3857) от начала координат, поэтому ячейки совпадают между запросами
+func (c *CoordinatesRepository) Heatmap(ctx context.Context, filter domain.HeatmapFilter) ([]domain.HeatmapCell, error) {
+	conds := []string{
+		checkGeom + ` && ST_MakeEnvelope($1, $2, $3, $4, 4326)`,
+		`c.checked_at >= $5::timestamp`,
+		`c.checked_at < $6::timestamp`,
+	}
+	if filter.InDangerOnly {
+		conds = append(conds, `c.in_danger_zone`)
+	}
+	args := []any{filter.BBox.MinLong, filter.BBox.MinLat, filter.BBox.MaxLong, filter.BBox.MaxLat, filter.From, filter.To}
+
+	var heatmapQuery string
+	if filter.Grid == domain.HeatmapGridGeohash {
+		args = append(args, filter.Precision)
+		heatmapQuery = `
+			WITH counts AS (
+				SELECT
+					ST_GeoHash(` + checkGeom + `, $7) AS cell_id,
+					COUNT(*) AS check_count,
+					COUNT(DISTINCT c.user_id) AS unique_users
+				FROM location_checks c
+				WHERE ` + strings.Join(conds, " AND ") + `
+				GROUP BY cell_id
+			)
+			SELECT
+				cell_id,
+				check_count,
+				unique_users,
+				ST_Y(ST_PointFromGeoHash(cell_id)) AS lat,
+				ST_X(ST_PointFromGeoHash(cell_id)) AS long,
+				ST_AsGeoJSON(ST_GeomFromGeoHash(cell_id))::json AS geometry
+			FROM counts
+			ORDER BY check_count DESC, cell_id
+		`
+	} else {
+		funcs, ok := heatmapGridFuncs[filter.Grid]
+		if !ok {
+			return nil, fmt.Errorf("unknown heatmap grid %q", filter.Grid)
+		}
+		args = append(args, filter.SizeM)
+		heatmapQuery = `
+			WITH cells AS (
+				SELECT g.i, g.j, ST_Transform(g.geom, 4326) AS geom
+				FROM ` + funcs.grid + `($7, ST_Transform(ST_MakeEnvelope($1, $2, $3, $4, 4326), 3857)) AS g
+			),
+			counts AS (
+				SELECT
+					cells.i,
+					cells.j,
+					COUNT(*) AS check_count,
+					COUNT(DISTINCT c.user_id) AS unique_users
+				FROM cells
+				JOIN location_checks c ON ST_Intersects(cells.geom, ` + checkGeom + `)
+				WHERE ` + strings.Join(conds, " AND ") + `
+				GROUP BY cells.i, cells.j
+			),
+			shaped AS (
+				SELECT *, ST_Transform(ST_SetSRID(` + funcs.cell + `($7, i, j), 3857), 4326) AS geom
+				FROM counts
+			)
+			SELECT
+				i::text || ':' || j::text AS cell_id,
+				check_count,
+				unique_users,
+				ST_Y(ST_Centroid(geom)) AS lat,
+				ST_X(ST_Centroid(geom)) AS long,
+				ST_AsGeoJSON(geom)::json AS geometry
+			FROM shaped
+			ORDER BY check_count DESC, cell_id
+		`
+	}
+
+	var cells []domain.HeatmapCell
+	if err := c.db.SelectContext(ctx, &cells, heatmapQuery, args...); err != nil {
+		return nil, err
+	}
+	return cells, nil
+}
+
+// lastCheckGeog - точка последней проверки пользователя. Выражение совпадает с индексом
+// user_last_checks_geog_gist_idx, иначе индекс не используется
+const lastCheckGeog = `ST_SetSRID(ST_MakePoint(l.long, l.lat), 4326)::geography`
+
+// ZoneOccupants возвращает пользователей, последняя проверка которых не старше windowMinutes
+// и находится внутри действующей зоны. Зона проверяется по текущей геометрии инцидента,
+// поэтому после ее изменения список сразу ее учитывает. Для архивного инцидента возвращает ErrNotFound
+func (c *CoordinatesRepository) ZoneOccupants(ctx context.Context, incidentID int, windowMinutes int) ([]domain.ZoneOccupant, error) {
+	occupantsQuery := `
+		SELECT
+			l.user_id,
+			l.check_id,
+			l.lat,
+			l.long,
+			l.checked_at,
+			ST_Distance(i.geom, ` + lastCheckGeog + `) AS distance_m
+		FROM incidents i
+		JOIN user_last_checks l ON
+			(i.boundary IS NULL AND ST_DWithin(i.geom, ` + lastCheckGeog + `, i.radius_m))
+			OR ST_Intersects(i.boundary, ` + lastCheckGeog + `)
+		WHERE i.id = $1
+			AND ` + activeIncidentCond("NOW()", "NOW()") + `
+			AND l.checked_at >= NOW() - INTERVAL '1 minute' * $2
+		ORDER BY l.checked_at DESC, l.user_id
+	`
+
+	var occupants []domain.ZoneOccupant
+	if err := c.db.SelectContext(ctx, &occupants, occupantsQuery, incidentID, windowMinutes); err != nil {
+		return nil, err
+	}
+	if len(occupants) > 0 {
+		return occupants, nil
+	}
+
+	var exists bool
+	if err := c.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM incidents WHERE id = $1 AND deleted_at IS NULL)`, incidentID); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrNotFound("incident not found")
+	}
+	return occupants, nil
+}
+
+// UserChecks возвращает страницу проверок пользователя вместе с зонами, в которые они попали.
+// Попадания берутся из location_check_matches, поэтому учитываются и зоны, которые позже были архивированы
+func (c *CoordinatesRepository) UserChecks(ctx context.Context, filter domain.CheckFilter, limit int) ([]domain.LocationCheck, error) {
+	conds := []string{"user_id = $1"}
+	args := []any{filter.UserID}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conds = append(conds, fmt.Sprintf("checked_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conds = append(conds, fmt.Sprintf("checked_at < $%d", len(args)))
+	}
+
+	dir, cmp := "DESC", "<"
+	if filter.SortAsc {
+		dir, cmp = "ASC", ">"
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.CheckedAt, filter.After.ID)
+		conds = append(conds, fmt.Sprintf("(checked_at, id) %s ($%d, $%d)", cmp, len(args)-1, len(args)))
+	}
+	args = append(args, limit)
+
+	checksQuery := `
+		SELECT id, user_id, checked_at, lat, long, in_danger_zone, nearest_id
+		FROM location_checks
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY checked_at ` + dir + `, id ` + dir + `
+		LIMIT $` + strconv.Itoa(len(args))
+
+	var checks []domain.LocationCheck
+	if err := c.db.SelectContext(ctx, &checks, checksQuery, args...); err != nil {
+		return nil, err
+	}
+	if len(checks) == 0 {
+		return checks, nil
+	}
+
+	checkIDs := make([]int64, len(checks))
+	for i, check := range checks {
+		checkIDs[i] = int64(check.ID)
+	}
+
+	matchesQuery := `
+		SELECT m.check_id, m.incident_id, m.distance_m, i.severity, i.category
+		FROM location_check_matches m
+		JOIN incidents i ON i.id = m.incident_id
+		WHERE m.check_id = ANY($1)
+		ORDER BY m.check_id, m.distance_m, m.incident_id
+	`
+
+	var matches []struct {
+		CheckID int `db:"check_id"`
+		domain.IncidentMatch
+	}
+	if err := c.db.SelectContext(ctx, &matches, matchesQuery, pq.Array(checkIDs)); err != nil {
+		return nil, err
+	}
+
+	byCheck := make(map[int][]domain.IncidentMatch, len(checks))
+	for _, m := range matches {
+		byCheck[m.CheckID] = append(byCheck[m.CheckID], m.IncidentMatch)
+	}
+	for i := range checks {
+		checks[i].Matches = byCheck[checks[i].ID]
+		if len(checks[i].Matches) > 0 {
+			checks[i].PrimaryID = primaryZoneID(checks[i].Matches)
+		}
+	}
+	return checks, nil
+}
+
+// zoneHit - зона рядом с точкой проверки: либо точка внутри нее, либо в полосе предупреждения
+type zoneHit struct {
+	Ord             int             `db:"ord"`
+	IncidentID      int             `db:"incident_id"`
+	Severity        domain.Severity `db:"severity"`
+	Category        string          `db:"category"`
+	DistanceM       float64         `db:"distance_m"`
+	DistanceToEdgeM float64         `db:"distance_to_edge_m"`
+	Inside          bool            `db:"inside"`
+	BearingDeg      float64         `db:"bearing_deg"`
+}
+
+// applyZoneHits раскладывает найденные зоны на попадания и предупреждения
+// и заполняет производные поля проверки
+func applyZoneHits(locCheck *domain.LocationCheck, hits []zoneHit) {
+	var matches []domain.IncidentMatch
+	var approaching []domain.ProximityWarning
+
+	for _, hit := range hits {
+		if hit.Inside {
+			matches = append(matches, domain.IncidentMatch{
+				IncidentID: hit.IncidentID,
+				DistanceM:  hit.DistanceM,
+				Severity:   hit.Severity,
+				Category:   hit.Category,
+			})
+			continue
+		}
+		approaching = append(approaching, domain.ProximityWarning{
+			IncidentID:      hit.IncidentID,
+			DistanceToEdgeM: hit.DistanceToEdgeM,
+			BearingDeg:      hit.BearingDeg,
+			Severity:        hit.Severity,
+			Category:        hit.Category,
+		})
+	}
+
+	locCheck.Matches = matches
+	locCheck.Approaching = approaching
+	if len(matches) == 0 {
+		locCheck.NearestID = nil
+		locCheck.PrimaryID = nil
+		locCheck.InDangerZone = false
+	} else {
+		nearestID := matches[0].IncidentID
+		locCheck.NearestID = &nearestID
+		locCheck.PrimaryID = primaryZoneID(matches)
+		locCheck.InDangerZone = true
+	}
+}
+
+// primaryZoneID выбирает самую опасную зону. Попадания отсортированы по расстоянию,
+// поэтому при равной опасности выигрывает ближайшая
+func primaryZoneID(matches []domain.IncidentMatch) *int {
+	primary := matches[0]
+	for _, m := range matches[1:] {
+		if m.Severity.Rank() > primary.Severity.Rank() {
+			primary = m
+		}
+	}
+	return &primary.IncidentID
+}
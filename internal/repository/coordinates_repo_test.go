@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"red_collar/internal/domain"
 	"testing"
@@ -22,11 +23,12 @@ func TestCoordinatesRepository_Check(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
-		name     string
-		locCheck *domain.LocationCheck
-		setup    func(t *testing.T)
-		wantErr  bool
-		validate func(t *testing.T, res *domain.LocationCheck)
+		name          string
+		locCheck      *domain.LocationCheck
+		warningBuffer int
+		setup         func(t *testing.T)
+		wantErr       bool
+		validate      func(t *testing.T, res *domain.LocationCheck)
 	}{
 		{
 			name: "success",
@@ -86,6 +88,221 @@ func TestCoordinatesRepository_Check(t *testing.T) {
 				require.WithinDuration(t, time.Now(), res.CheckedAt, 10*time.Second)
 			},
 		},
+		{
+			name: "success - overlapping zones",
+			locCheck: &domain.LocationCheck{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   50,
+			},
+			setup: func(t *testing.T) {
+				for i, radius := range []int{1000, 2000, 3000} {
+					incident := &domain.Incident{
+						Title:       fmt.Sprintf("Incident-%d", i),
+						Description: "Description",
+						Lat:         50.0,
+						Long:        50.0 + 0.005*float64(i),
+						Radius:      radius,
+						Active:      true,
+					}
+					err := testRepo.Create(ctx, incident)
+					require.NoError(t, err)
+				}
+			},
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.True(t, res.InDangerZone)
+				require.Equal(t, 1, *res.NearestID)
+				require.Len(t, res.Matches, 3)
+				require.Equal(t, 1, res.Matches[0].IncidentID)
+				require.Less(t, res.Matches[0].DistanceM, res.Matches[1].DistanceM)
+
+				var stored int
+				err := testDB.Get(&stored, "SELECT COUNT(*) FROM location_check_matches WHERE check_id = $1", res.ID)
+				require.NoError(t, err)
+				require.Equal(t, 3, stored)
+			},
+		},
+		{
+			name: "success - primary zone is the most severe",
+			locCheck: &domain.LocationCheck{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   50,
+			},
+			setup: func(t *testing.T) {
+				for i, severity := range []domain.Severity{domain.SeverityInfo, domain.SeverityCritical, domain.SeverityDanger} {
+					incident := &domain.Incident{
+						Title:       fmt.Sprintf("Incident-%d", i),
+						Description: "Description",
+						Lat:         50.0,
+						Long:        50.0 + 0.001*float64(i),
+						Radius:      1000,
+						Active:      true,
+						Severity:    severity,
+						Category:    domain.DefaultCategory,
+					}
+					err := testRepo.Create(ctx, incident)
+					require.NoError(t, err)
+				}
+			},
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.Equal(t, 1, *res.NearestID)
+				require.Equal(t, 2, *res.PrimaryID)
+				require.Equal(t, domain.SeverityCritical, res.Matches[1].Severity)
+			},
+		},
+		{
+			name: "success - inside polygon zone",
+			locCheck: &domain.LocationCheck{
+				UserID: "colorvax",
+				Lat:    50.001,
+				Long:   50.019,
+			},
+			setup: func(t *testing.T) {
+				incident := &domain.Incident{
+					Title:       "Polygon",
+					Description: "Description",
+					Active:      true,
+					Boundary:    json.RawMessage(`{"type":"Polygon","coordinates":[[[50,50],[50.02,50],[50.02,50.002],[50,50.002],[50,50]]]}`),
+				}
+				err := testRepo.Create(ctx, incident)
+				require.NoError(t, err)
+			},
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.True(t, res.InDangerZone)
+				require.Equal(t, 1, *res.NearestID)
+			},
+		},
+		{
+			name: "success - outside polygon zone but near its centroid",
+			locCheck: &domain.LocationCheck{
+				UserID: "colorvax",
+				Lat:    50.003,
+				Long:   50.01,
+			},
+			setup: func(t *testing.T) {
+				incident := &domain.Incident{
+					Title:       "Polygon",
+					Description: "Description",
+					Active:      true,
+					Boundary:    json.RawMessage(`{"type":"Polygon","coordinates":[[[50,50],[50.02,50],[50.02,50.002],[50,50.002],[50,50]]]}`),
+				}
+				err := testRepo.Create(ctx, incident)
+				require.NoError(t, err)
+			},
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.False(t, res.InDangerZone)
+				require.Nil(t, res.NearestID)
+				require.Empty(t, res.Approaching)
+			},
+		},
+		{
+			name: "success - zone outside its schedule is ignored",
+			locCheck: &domain.LocationCheck{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   50,
+			},
+			setup: func(t *testing.T) {
+				startsAt := time.Now().Add(time.Hour)
+				incident := &domain.Incident{
+					Title:       "Planned",
+					Description: "Description",
+					Lat:         50.0,
+					Long:        50.0,
+					Radius:      1000,
+					Active:      true,
+					StartsAt:    &startsAt,
+				}
+				err := testRepo.Create(ctx, incident)
+				require.NoError(t, err)
+			},
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.False(t, res.InDangerZone)
+				require.Empty(t, res.Matches)
+			},
+		},
+		{
+			name: "success - approaching circle zone",
+			locCheck: &domain.LocationCheck{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   50,
+			},
+			warningBuffer: 300,
+			setup: func(t *testing.T) {
+				incident := &domain.Incident{
+					Title:       "Incident",
+					Description: "Description",
+					Lat:         50.0,
+					Long:        50.01,
+					Radius:      500,
+					Active:      true,
+				}
+				err := testRepo.Create(ctx, incident)
+				require.NoError(t, err)
+			},
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.False(t, res.InDangerZone)
+				require.Empty(t, res.Matches)
+				require.Len(t, res.Approaching, 1)
+				require.Equal(t, 1, res.Approaching[0].IncidentID)
+				require.InDelta(t, 215, res.Approaching[0].DistanceToEdgeM, 5)
+				require.InDelta(t, 90, res.Approaching[0].BearingDeg, 1)
+			},
+		},
+		{
+			name: "success - approaching polygon zone",
+			locCheck: &domain.LocationCheck{
+				UserID: "colorvax",
+				Lat:    50.003,
+				Long:   50.01,
+			},
+			warningBuffer: 200,
+			setup: func(t *testing.T) {
+				incident := &domain.Incident{
+					Title:       "Polygon",
+					Description: "Description",
+					Active:      true,
+					Boundary:    json.RawMessage(`{"type":"Polygon","coordinates":[[[50,50],[50.02,50],[50.02,50.002],[50,50.002],[50,50]]]}`),
+				}
+				err := testRepo.Create(ctx, incident)
+				require.NoError(t, err)
+			},
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.False(t, res.InDangerZone)
+				require.Len(t, res.Approaching, 1)
+				require.InDelta(t, 111, res.Approaching[0].DistanceToEdgeM, 5)
+				require.InDelta(t, 180, res.Approaching[0].BearingDeg, 1)
+			},
+		},
+		{
+			name: "success - incident warning buffer overrides default",
+			locCheck: &domain.LocationCheck{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   50,
+			},
+			warningBuffer: 300,
+			setup: func(t *testing.T) {
+				buffer := 100
+				incident := &domain.Incident{
+					Title:         "Incident",
+					Description:   "Description",
+					Lat:           50.0,
+					Long:          50.01,
+					Radius:        500,
+					Active:        true,
+					WarningBuffer: &buffer,
+				}
+				err := testRepo.Create(ctx, incident)
+				require.NoError(t, err)
+			},
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.False(t, res.InDangerZone)
+				require.Empty(t, res.Approaching)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -96,7 +313,7 @@ func TestCoordinatesRepository_Check(t *testing.T) {
 				tt.setup(t)
 			}
 
-			err := testRepoCoor.Check(ctx, tt.locCheck)
+			err := testRepoCoor.Check(ctx, tt.locCheck, domain.CheckParams{WarningBufferM: tt.warningBuffer})
 
 			if tt.wantErr {
 				require.Error(t, err, "expected error but got nil")
@@ -111,6 +328,304 @@ func TestCoordinatesRepository_Check(t *testing.T) {
 	}
 }
 
+func TestCoordinatesRepository_CheckBatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping intergration test")
+	}
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	ctx := context.Background()
+	cleanupTestDB(t)
+
+	for i, long := range []float64{50.0, 60.0} {
+		incident := &domain.Incident{
+			Title:       fmt.Sprintf("Incident-%d", i),
+			Description: "Description",
+			Lat:         50.0,
+			Long:        long,
+			Radius:      1000,
+			Active:      true,
+		}
+		err := testRepo.Create(ctx, incident)
+		require.NoError(t, err)
+	}
+
+	checks := []*domain.LocationCheck{
+		{UserID: "mike", Lat: 50, Long: 60.001},
+		{UserID: "colorvax", Lat: 10, Long: 10},
+		{UserID: "colorvax", Lat: 50, Long: 50.001},
+	}
+
+	err := testRepoCoor.CheckBatch(ctx, checks, domain.CheckParams{})
+	require.NoError(t, err)
+
+	require.True(t, checks[0].InDangerZone)
+	require.Equal(t, 2, *checks[0].NearestID)
+	require.False(t, checks[1].InDangerZone)
+	require.Nil(t, checks[1].NearestID)
+	require.True(t, checks[2].InDangerZone)
+	require.Equal(t, 1, *checks[2].NearestID)
+
+	for i, check := range checks {
+		var stored domain.LocationCheck
+		err := testDB.Get(&stored, "SELECT id, user_id, lat, long, in_danger_zone, nearest_id, checked_at FROM location_checks WHERE id = $1", check.ID)
+		require.NoError(t, err)
+		require.Equal(t, checks[i].UserID, stored.UserID)
+		require.Equal(t, checks[i].Long, stored.Long)
+		require.Equal(t, checks[i].InDangerZone, stored.InDangerZone)
+	}
+
+	var matches int
+	err = testDB.Get(&matches, "SELECT COUNT(*) FROM location_check_matches")
+	require.NoError(t, err)
+	require.Equal(t, 2, matches)
+
+	newer := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	older := newer.Add(-time.Minute)
+	timed := []*domain.LocationCheck{
+		{UserID: "bob", Lat: 10, Long: 10, CheckedAt: newer},
+		{UserID: "bob", Lat: 11, Long: 11, CheckedAt: older},
+	}
+	err = testRepoCoor.CheckBatch(ctx, timed, domain.CheckParams{MaxSegmentGap: time.Hour})
+	require.NoError(t, err)
+	require.True(t, newer.Equal(timed[0].CheckedAt))
+	require.True(t, older.Equal(timed[1].CheckedAt))
+
+	var last lastCheck
+	err = testDB.Get(&last, "SELECT user_id, lat, long, checked_at FROM user_last_checks WHERE user_id = 'bob'")
+	require.NoError(t, err)
+	require.Equal(t, 10.0, last.Lat)
+	require.True(t, newer.Equal(last.CheckedAt))
+
+	// расписание зоны сверяется со временем точки: зона уже закончилась, но действовала, когда точку сняли
+	endsAt := time.Now().UTC().Add(-time.Hour)
+	expired := &domain.Incident{
+		Title:       "Expired",
+		Description: "Description",
+		Lat:         40.0,
+		Long:        40.0,
+		Radius:      1000,
+		Active:      true,
+		EndsAt:      &endsAt,
+	}
+	require.NoError(t, testRepo.Create(ctx, expired))
+
+	late := []*domain.LocationCheck{
+		{UserID: "alice", Lat: 40, Long: 40.001, CheckedAt: endsAt.Add(-time.Minute)},
+		{UserID: "alice", Lat: 40, Long: 40.001, CheckedAt: endsAt.Add(time.Minute)},
+	}
+	err = testRepoCoor.CheckBatch(ctx, late, domain.CheckParams{})
+	require.NoError(t, err)
+	require.True(t, late[0].InDangerZone)
+	require.Equal(t, expired.ID, *late[0].PrimaryID)
+	require.False(t, late[1].InDangerZone)
+}
+
+func TestCoordinatesRepository_UserChecks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping intergration test")
+	}
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	ctx := context.Background()
+	cleanupTestDB(t)
+
+	incident := &domain.Incident{
+		Title:       "Incident",
+		Description: "Description",
+		Lat:         50.0,
+		Long:        50.0,
+		Radius:      1000,
+		Active:      true,
+	}
+	err := testRepo.Create(ctx, incident)
+	require.NoError(t, err)
+
+	checks := []*domain.LocationCheck{
+		{UserID: "mike", Lat: 10, Long: 10},
+		{UserID: "mike", Lat: 50, Long: 50.001},
+		{UserID: "mike", Lat: 20, Long: 20},
+		{UserID: "colorvax", Lat: 50, Long: 50.001},
+	}
+	err = testRepoCoor.CheckBatch(ctx, checks, domain.CheckParams{})
+	require.NoError(t, err)
+
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	for i, check := range checks {
+		_, err := testDB.Exec("UPDATE location_checks SET checked_at = $1 WHERE id = $2", base.Add(time.Duration(i)*time.Minute), check.ID)
+		require.NoError(t, err)
+	}
+
+	result, err := testRepoCoor.UserChecks(ctx, domain.CheckFilter{UserID: "mike"}, 10)
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+	require.Equal(t, checks[2].ID, result[0].ID)
+	require.Equal(t, checks[0].ID, result[2].ID)
+	require.Empty(t, result[0].Matches)
+	require.True(t, result[1].InDangerZone)
+	require.Len(t, result[1].Matches, 1)
+	require.Equal(t, incident.ID, result[1].Matches[0].IncidentID)
+	require.Equal(t, incident.ID, *result[1].PrimaryID)
+
+	from, to := base.Add(time.Minute), base.Add(2*time.Minute)
+	result, err = testRepoCoor.UserChecks(ctx, domain.CheckFilter{UserID: "mike", From: &from, To: &to}, 10)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	require.Equal(t, checks[1].ID, result[0].ID)
+
+	result, err = testRepoCoor.UserChecks(ctx, domain.CheckFilter{UserID: "mike", SortAsc: true}, 2)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	require.Equal(t, checks[0].ID, result[0].ID)
+
+	after := &domain.CheckCursor{CheckedAt: result[1].CheckedAt, ID: result[1].ID}
+	result, err = testRepoCoor.UserChecks(ctx, domain.CheckFilter{UserID: "mike", SortAsc: true, After: after}, 2)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	require.Equal(t, checks[2].ID, result[0].ID)
+}
+
+func TestCoordinatesRepository_ZoneOccupants(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping intergration test")
+	}
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	ctx := context.Background()
+	cleanupTestDB(t)
+
+	incident := &domain.Incident{
+		Title:       "Incident",
+		Description: "Description",
+		Lat:         50.0,
+		Long:        50.0,
+		Radius:      1000,
+		Active:      true,
+	}
+	err := testRepo.Create(ctx, incident)
+	require.NoError(t, err)
+
+	checks := []*domain.LocationCheck{
+		// mike был в зоне, но последняя точка вне ее
+		{UserID: "mike", Lat: 50, Long: 50.001},
+		{UserID: "mike", Lat: 10, Long: 10},
+		{UserID: "colorvax", Lat: 50, Long: 50.002},
+		{UserID: "stale", Lat: 50, Long: 50.003},
+	}
+	err = testRepoCoor.CheckBatch(ctx, checks, domain.CheckParams{})
+	require.NoError(t, err)
+
+	_, err = testDB.Exec("UPDATE user_last_checks SET checked_at = NOW() - INTERVAL '2 hours' WHERE user_id = 'stale'")
+	require.NoError(t, err)
+
+	occupants, err := testRepoCoor.ZoneOccupants(ctx, incident.ID, 60)
+	require.NoError(t, err)
+	require.Len(t, occupants, 1)
+	require.Equal(t, "colorvax", occupants[0].UserID)
+	require.Equal(t, checks[2].ID, occupants[0].CheckID)
+	require.Equal(t, 50.002, occupants[0].Long)
+	require.Greater(t, occupants[0].DistanceM, 0.0)
+
+	occupants, err = testRepoCoor.ZoneOccupants(ctx, incident.ID, 180)
+	require.NoError(t, err)
+	require.Len(t, occupants, 2)
+
+	_, err = testRepoCoor.ZoneOccupants(ctx, incident.ID+100, 60)
+	require.Error(t, err)
+}
+
+func TestCoordinatesRepository_CheckCrossings(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping intergration test")
+	}
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	ctx := context.Background()
+	params := domain.CheckParams{MaxSegmentGap: time.Hour}
+
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T)
+		params   domain.CheckParams
+		validate func(t *testing.T, res *domain.LocationCheck)
+	}{
+		{
+			name: "success - path crosses zone",
+			setup: func(t *testing.T) {
+				err := testRepoCoor.Check(ctx, &domain.LocationCheck{UserID: "colorvax", Lat: 50, Long: 49.98}, params)
+				require.NoError(t, err)
+			},
+			params: params,
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.False(t, res.InDangerZone)
+				require.Len(t, res.Crossed, 1)
+				require.Equal(t, 1, res.Crossed[0].IncidentID)
+				require.False(t, res.Crossed[0].EstimatedEntryAt.After(res.CheckedAt))
+			},
+		},
+		{
+			name: "success - previous check of another user",
+			setup: func(t *testing.T) {
+				err := testRepoCoor.Check(ctx, &domain.LocationCheck{UserID: "mike", Lat: 50, Long: 49.98}, params)
+				require.NoError(t, err)
+			},
+			params: params,
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.Empty(t, res.Crossed)
+			},
+		},
+		{
+			name: "success - segment check disabled",
+			setup: func(t *testing.T) {
+				err := testRepoCoor.Check(ctx, &domain.LocationCheck{UserID: "colorvax", Lat: 50, Long: 49.98}, params)
+				require.NoError(t, err)
+			},
+			validate: func(t *testing.T, res *domain.LocationCheck) {
+				require.Empty(t, res.Crossed)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanupTestDB(t)
+
+			incident := &domain.Incident{
+				Title:       "Incident",
+				Description: "Description",
+				Lat:         50.0,
+				Long:        50.0,
+				Radius:      500,
+				Active:      true,
+			}
+			err := testRepo.Create(ctx, incident)
+			require.NoError(t, err)
+
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+
+			locCheck := &domain.LocationCheck{UserID: "colorvax", Lat: 50, Long: 50.02}
+			err = testRepoCoor.Check(ctx, locCheck, tt.params)
+			require.NoError(t, err)
+
+			tt.validate(t, locCheck)
+		})
+	}
+}
+
 func TestCoordinatesRepository_GetStats(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping intergration test")
@@ -150,7 +665,7 @@ func TestCoordinatesRepository_GetStats(t *testing.T) {
 						Lat:    50,
 						Long:   50.01,
 					}
-					err = testRepoCoor.Check(ctx, locCheck)
+					err = testRepoCoor.Check(ctx, locCheck, domain.CheckParams{})
 					require.NoError(t, err)
 				}
 			},
@@ -181,7 +696,7 @@ func TestCoordinatesRepository_GetStats(t *testing.T) {
 						Lat:    50,
 						Long:   50.01,
 					}
-					err = testRepoCoor.Check(ctx, locCheck)
+					err = testRepoCoor.Check(ctx, locCheck, domain.CheckParams{})
 					require.NoError(t, err)
 				}
 			},
@@ -215,3 +730,160 @@ func TestCoordinatesRepository_GetStats(t *testing.T) {
 		}
 	}
 }
+
+func TestCoordinatesRepository_StatsSeries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping intergration test")
+	}
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	ctx := context.Background()
+	cleanupTestDB(t)
+
+	for i, long := range []float64{50.0, 60.0} {
+		incident := &domain.Incident{
+			Title:       fmt.Sprintf("Incident-%d", i),
+			Description: "Description",
+			Lat:         50.0,
+			Long:        long,
+			Radius:      1000,
+			Active:      true,
+		}
+		err := testRepo.Create(ctx, incident)
+		require.NoError(t, err)
+	}
+
+	checks := []*domain.LocationCheck{
+		{UserID: "mike", Lat: 50, Long: 50.001},
+		{UserID: "mike", Lat: 50, Long: 50.002},
+		{UserID: "colorvax", Lat: 50, Long: 50.001},
+		{UserID: "mike", Lat: 50, Long: 50.001},
+		{UserID: "colorvax", Lat: 50, Long: 60.001},
+		{UserID: "colorvax", Lat: 10, Long: 10},
+	}
+	err := testRepoCoor.CheckBatch(ctx, checks, domain.CheckParams{})
+	require.NoError(t, err)
+
+	from := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	offsets := []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, time.Hour + time.Minute, time.Minute, time.Minute}
+	for i, check := range checks {
+		_, err := testDB.Exec("UPDATE location_checks SET checked_at = $1 WHERE id = $2", from.Add(offsets[i]), check.ID)
+		require.NoError(t, err)
+	}
+
+	filter := domain.StatsFilter{From: from, To: from.Add(2 * time.Hour), Bucket: time.Hour}
+	buckets, err := testRepoCoor.StatsSeries(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, buckets, 3)
+
+	require.Equal(t, 1, buckets[0].IncidentID)
+	require.True(t, from.Equal(buckets[0].Start))
+	require.Equal(t, 2, buckets[0].UniqueUsers)
+	require.Equal(t, 3, buckets[0].TotalChecks)
+	require.True(t, from.Add(time.Minute).Equal(buckets[0].FirstSeen))
+	require.True(t, from.Add(3*time.Minute).Equal(buckets[0].LastSeen))
+
+	require.Equal(t, 1, buckets[1].IncidentID)
+	require.True(t, from.Add(time.Hour).Equal(buckets[1].Start))
+	require.Equal(t, 1, buckets[1].TotalChecks)
+
+	require.Equal(t, 2, buckets[2].IncidentID)
+
+	incidentID := 2
+	filter.IncidentID = &incidentID
+	buckets, err = testRepoCoor.StatsSeries(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, buckets, 1)
+	require.Equal(t, 1, buckets[0].UniqueUsers)
+}
+
+func TestCoordinatesRepository_Heatmap(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping intergration test")
+	}
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	ctx := context.Background()
+	cleanupTestDB(t)
+
+	incident := &domain.Incident{
+		Title:       "Incident",
+		Description: "Description",
+		Lat:         50.0,
+		Long:        30.0,
+		Radius:      1000,
+		Active:      true,
+	}
+	err := testRepo.Create(ctx, incident)
+	require.NoError(t, err)
+
+	checks := []*domain.LocationCheck{
+		{UserID: "mike", Lat: 50.0001, Long: 30.0001},
+		{UserID: "mike", Lat: 50.0002, Long: 30.0002},
+		{UserID: "colorvax", Lat: 50.0001, Long: 30.0002},
+		{UserID: "colorvax", Lat: 50.2, Long: 30.2},
+		// вне области
+		{UserID: "colorvax", Lat: 10, Long: 10},
+	}
+	err = testRepoCoor.CheckBatch(ctx, checks, domain.CheckParams{})
+	require.NoError(t, err)
+
+	filter := domain.HeatmapFilter{
+		BBox:      domain.BBox{MinLong: 29.9, MinLat: 49.9, MaxLong: 30.3, MaxLat: 50.3},
+		From:      time.Now().UTC().Add(-time.Hour),
+		To:        time.Now().UTC().Add(time.Hour),
+		Grid:      domain.HeatmapGridGeohash,
+		Precision: 6,
+	}
+	cells, err := testRepoCoor.Heatmap(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, cells, 2)
+	require.Equal(t, 3, cells[0].Count)
+	require.Equal(t, 2, cells[0].UniqueUsers)
+	require.Len(t, cells[0].ID, 6)
+	require.InDelta(t, 50.0, cells[0].Lat, 0.01)
+
+	var cellGeometry struct {
+		Type string `json:"type"`
+	}
+	require.NoError(t, json.Unmarshal(cells[0].Geometry, &cellGeometry))
+	require.Equal(t, "Polygon", cellGeometry.Type)
+
+	filter.InDangerOnly = true
+	cells, err = testRepoCoor.Heatmap(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, cells, 1)
+	require.Equal(t, 3, cells[0].Count)
+
+	for _, grid := range []domain.HeatmapGrid{domain.HeatmapGridSquare, domain.HeatmapGridHex} {
+		filter := domain.HeatmapFilter{
+			BBox:  filter.BBox,
+			From:  filter.From,
+			To:    filter.To,
+			Grid:  grid,
+			SizeM: 1000,
+		}
+		cells, err = testRepoCoor.Heatmap(ctx, filter)
+		require.NoError(t, err)
+		require.Len(t, cells, 2, "grid %s", grid)
+		require.Equal(t, 3, cells[0].Count)
+
+		var geometry struct {
+			Type string `json:"type"`
+		}
+		require.NoError(t, json.Unmarshal(cells[0].Geometry, &geometry))
+		require.Equal(t, "Polygon", geometry.Type)
+	}
+
+	filter.From = time.Now().UTC().Add(time.Hour)
+	filter.To = time.Now().UTC().Add(2 * time.Hour)
+	cells, err = testRepoCoor.Heatmap(ctx, filter)
+	require.NoError(t, err)
+	require.Empty(t, cells)
+}
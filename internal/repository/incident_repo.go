@@ -3,12 +3,64 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"red_collar/internal/domain"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 )
 
+const (
+	// incidentBoundary - граница зоны в GeoJSON. convert_to отдает ее как bytea, который сканируется
+	// в json.RawMessage, а у круговой зоны (boundary IS NULL) COALESCE дает пустую строку,
+	// поэтому Boundary такой зоны остается пустым
+	incidentBoundary = `convert_to(COALESCE(ST_AsGeoJSON(boundary), ''), 'UTF8') AS boundary`
+
+	incidentColumns = `
+		id, title, description, lat, long, radius_m, active, severity, category,
+		` + incidentBoundary + `, warning_buffer_m,
+		starts_at, ends_at, version, created_at, updated_at, deleted_at
+	`
+
+	// incidentVersionColumns - колонки incident_versions в форме domain.IncidentVersion
+	incidentVersionColumns = `
+		incident_id AS id, title, description, lat, long, radius_m, active, severity, category,
+		` + incidentBoundary + `, warning_buffer_m,
+		starts_at, ends_at, version, created_at, updated_at,
+		operation, changed_by, changed_at
+	`
+
+	// incidentSearchVector - выражение полнотекстового индекса incidents_search_idx
+	incidentSearchVector = `to_tsvector('simple', title || ' ' || COALESCE(description, ''))`
+)
+
+// activeIncidentCond - инцидент с псевдонимом i не в архиве и действует хотя бы в один момент
+// промежутка [from, to]. from и to - SQL-выражения, для одного момента они совпадают
+func activeIncidentCond(from, to string) string {
+	return `
+		i.active = true
+		AND i.deleted_at IS NULL
+		AND (i.starts_at IS NULL OR i.starts_at <= ` + to + `)
+		AND (i.ends_at IS NULL OR i.ends_at > ` + from + `)
+	`
+}
+
+// incidentSortColumns - допустимые поля сортировки списка инцидентов.
+// distance и relevance зависят от аргументов фильтра и собираются в buildIncidentFilter
+var incidentSortColumns = map[domain.IncidentSort]string{
+	domain.IncidentSortCreatedAt: "created_at",
+	domain.IncidentSortUpdatedAt: "updated_at",
+	domain.IncidentSortTitle:     "title",
+	domain.IncidentSortActive:    "active",
+	domain.IncidentSortCategory:  "category",
+	domain.IncidentSortSeverity: `CASE severity
+		WHEN 'info' THEN 1 WHEN 'warning' THEN 2 WHEN 'danger' THEN 3 WHEN 'critical' THEN 4
+	END`,
+}
+
 type IncidentRepository struct {
 	db *sqlx.DB
 }
@@ -20,37 +72,78 @@ func NewIncidentRepository(db *sqlx.DB) *IncidentRepository {
 }
 
 func (ip *IncidentRepository) Create(ctx context.Context, incident *domain.Incident) error {
+	tx, err := ip.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = insertIncident(ctx, tx, incident); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertIncident создает инцидент и его первую версию в истории в рамках транзакции tx
+func insertIncident(ctx context.Context, tx *sqlx.Tx, incident *domain.Incident) error {
+	// Для полигональной зоны центр (lat/long, geom) вычисляется как центроид границы.
+	// О границах расписания, которые уже прошли, уведомления не отправляются
 	createIncidentQuery := `
+		WITH shape AS (
+			SELECT ST_Multi(ST_SetSRID(ST_GeomFromGeoJSON($7::text), 4326)) AS g
+		)
 		INSERT INTO incidents (
-			title, description, lat, long, radius_m, active, geom
+			title, description, lat, long, radius_m, active, geom, boundary, warning_buffer_m,
+			starts_at, ends_at, activation_notified, expiry_notified, severity, category
 		)
-		VALUES($1, $2, $3, $4, $5, $6, ST_SetSRID(ST_MakePoint($4, $3), 4326)::geography)		
-		RETURNING id, created_at, updated_at
+		SELECT
+			$1::text, $2::text,
+			COALESCE(ST_Y(ST_Centroid(g)), $3),
+			COALESCE(ST_X(ST_Centroid(g)), $4),
+			$5::int, $6::boolean,
+			COALESCE(ST_Centroid(g), ST_SetSRID(ST_MakePoint($4, $3), 4326))::geography,
+			g::geography,
+			$8::int,
+			$9::timestamp, $10::timestamp,
+			COALESCE($9::timestamp <= NOW(), true),
+			COALESCE($10::timestamp <= NOW(), true),
+			$11::text, $12::text
+		FROM shape
+		RETURNING id, lat, long, version, created_at, updated_at
 	`
 
-	err := ip.db.QueryRowContext(ctx, createIncidentQuery,
+	incident.SetDefaults()
+	err := tx.QueryRowContext(ctx, createIncidentQuery,
 		incident.Title,
 		incident.Description,
 		incident.Lat,
 		incident.Long,
 		incident.Radius,
 		incident.Active,
-	).Scan(&incident.ID, &incident.CreatedAt, &incident.UpdatedAt)
+		nullableGeoJSON(incident.Boundary),
+		incident.WarningBuffer,
+		incident.StartsAt,
+		incident.EndsAt,
+		incident.Severity,
+		incident.Category,
+	).Scan(&incident.ID, &incident.Lat, &incident.Long, &incident.Version, &incident.CreatedAt, &incident.UpdatedAt)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return domain.ErrAlreadyExists("incident already exists")
-		}
-		return err
+		return mapIncidentWriteError(err)
 	}
-	return nil
+
+	_, err = recordIncidentVersion(ctx, tx, incident.ID, domain.IncidentOperationCreate)
+	return err
 }
 
 func (ip *IncidentRepository) GetByID(ctx context.Context, id int) (*domain.Incident, error) {
 	getIncidentQuery := `
-		SELECT
-			id, title, description, lat, long, radius_m, active, created_at, updated_at  
+		SELECT ` + incidentColumns + `
 		FROM incidents 
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	var incident domain.Incident
@@ -64,34 +157,90 @@ func (ip *IncidentRepository) GetByID(ctx context.Context, id int) (*domain.Inci
 	return &incident, nil
 }
 
-func (ip *IncidentRepository) Paginate(ctx context.Context, limit, offset int) ([]domain.Incident, int, error) {
+func (ip *IncidentRepository) Paginate(ctx context.Context, filter domain.IncidentFilter, limit, offset int) ([]domain.Incident, int, error) {
 	var incidents []domain.Incident
 	var total int
 
-	totalQuery := `SELECT COUNT(*) FROM incidents`
-	if err := ip.db.GetContext(ctx, &total, totalQuery); err != nil {
+	where, orderBy, args := buildIncidentFilter(filter)
+
+	totalQuery := `SELECT COUNT(*) FROM incidents ` + where
+	if err := ip.db.GetContext(ctx, &total, totalQuery, args...); err != nil {
 		return nil, 0, err
 	}
 
-	getQuery := `
-		SELECT id, title, description, lat, long, radius_m, active, created_at, updated_at
+	getQuery := fmt.Sprintf(`
+		SELECT `+incidentColumns+`
 		FROM incidents
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
-	err := ip.db.SelectContext(ctx, &incidents, getQuery, limit, offset)
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, where, orderBy, len(args)+1, len(args)+2)
+	err := ip.db.SelectContext(ctx, &incidents, getQuery, append(args, limit, offset)...)
 	if err != nil {
 		return nil, 0, err
 	}
 	return incidents, total, nil
 }
 
+// PaginateCursor возвращает страницу инцидентов после filter.After без подсчета общего количества
+func (ip *IncidentRepository) PaginateCursor(ctx context.Context, filter domain.IncidentFilter, limit int) ([]domain.Incident, error) {
+	var incidents []domain.Incident
+
+	where, orderBy, args := buildIncidentFilter(filter)
+
+	getQuery := fmt.Sprintf(`
+		SELECT `+incidentColumns+`
+		FROM incidents
+		%s
+		ORDER BY %s
+		LIMIT $%d
+	`, where, orderBy, len(args)+1)
+	if err := ip.db.SelectContext(ctx, &incidents, getQuery, append(args, limit)...); err != nil {
+		return nil, err
+	}
+	return incidents, nil
+}
+
+// Delete архивирует инцидент: строка остается, чтобы не терять связи с историческими проверками
 func (ip *IncidentRepository) Delete(ctx context.Context, id int) error {
-	deleteQuery := `DELETE FROM incidents WHERE id = $1`
-	res, err := ip.db.ExecContext(ctx, deleteQuery, id)
+	deleteQuery := `
+		UPDATE incidents
+		SET deleted_at = NOW(), version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	return ip.changeArchiveState(ctx, id, deleteQuery, domain.IncidentOperationDelete, "incident not found")
+}
+
+// Restore возвращает инцидент из архива
+func (ip *IncidentRepository) Restore(ctx context.Context, id int) (*domain.Incident, error) {
+	restoreQuery := `
+		UPDATE incidents
+		SET deleted_at = NULL, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+	if err := ip.changeArchiveState(ctx, id, restoreQuery, domain.IncidentOperationRestore, "deleted incident not found"); err != nil {
+		return nil, err
+	}
+	return ip.GetByID(ctx, id)
+}
+
+// changeArchiveState выполняет архивацию или восстановление и пишет версию в историю
+func (ip *IncidentRepository) changeArchiveState(ctx context.Context, id int, query, operation, notFoundMsg string) error {
+	tx, err := ip.db.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		err = mapIncidentWriteError(err)
+		return err
+	}
 
 	r, err := res.RowsAffected()
 	if err != nil {
@@ -99,11 +248,82 @@ func (ip *IncidentRepository) Delete(ctx context.Context, id int) error {
 	}
 
 	if r == 0 {
-		return domain.ErrNotFound("incident not found")
+		err = domain.ErrNotFound(notFoundMsg)
+		return err
+	}
+
+	if _, err = recordIncidentVersion(ctx, tx, id, operation); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Purge окончательно удаляет инциденты, находящиеся в архиве дольше retentionDays дней.
+// История изменений в incident_versions сохраняется
+func (ip *IncidentRepository) Purge(ctx context.Context, retentionDays int) (int, error) {
+	purgeQuery := `
+		DELETE FROM incidents
+		WHERE deleted_at IS NOT NULL AND deleted_at < NOW() - INTERVAL '1 day' * $1
+	`
+
+	res, err := ip.db.ExecContext(ctx, purgeQuery, retentionDays)
+	if err != nil {
+		return 0, err
+	}
+
+	r, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(r), nil
+}
+
+// History возвращает все версии инцидента, начиная с последней
+func (ip *IncidentRepository) History(ctx context.Context, id int) ([]domain.IncidentVersion, error) {
+	historyQuery := `
+		SELECT ` + incidentVersionColumns + `
+		FROM incident_versions
+		WHERE incident_id = $1
+		ORDER BY version DESC
+	`
+
+	var versions []domain.IncidentVersion
+	if err := ip.db.SelectContext(ctx, &versions, historyQuery, id); err != nil {
+		return nil, err
 	}
-	return nil
+
+	if len(versions) == 0 {
+		return nil, domain.ErrNotFound("incident is not exists")
+	}
+	return versions, nil
 }
 
+// GetAsOf возвращает инцидент в том состоянии, в котором он был в момент at
+func (ip *IncidentRepository) GetAsOf(ctx context.Context, id int, at time.Time) (*domain.Incident, error) {
+	asOfQuery := `
+		SELECT ` + incidentVersionColumns + `
+		FROM incident_versions
+		WHERE incident_id = $1 AND changed_at <= $2
+		ORDER BY changed_at DESC, version DESC
+		LIMIT 1
+	`
+
+	var version domain.IncidentVersion
+	if err := ip.db.GetContext(ctx, &version, asOfQuery, id, at); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrNotFound("incident did not exist at this time")
+		}
+		return nil, err
+	}
+
+	if version.Operation == domain.IncidentOperationDelete {
+		return nil, domain.ErrNotFound("incident did not exist at this time")
+	}
+	return &version.Incident, nil
+}
+
+// FullUpdate перезаписывает инцидент и увеличивает его версию.
+// Если incident.Version задан, обновление выполняется только при совпадении с текущей версией
 func (ip *IncidentRepository) FullUpdate(ctx context.Context, incident *domain.Incident) error {
 	tx, err := ip.db.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
@@ -112,26 +332,53 @@ func (ip *IncidentRepository) FullUpdate(ctx context.Context, incident *domain.I
 	defer func() {
 		if err != nil {
 			tx.Rollback()
-		} else {
-			tx.Commit()
 		}
 	}()
 
+	if err = updateIncident(ctx, tx, incident); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateIncident перезаписывает инцидент и сохраняет новую версию в истории в рамках транзакции tx
+func updateIncident(ctx context.Context, tx *sqlx.Tx, incident *domain.Incident) error {
 	updateIncidentQuery := `
+		WITH shape AS (
+			SELECT ST_Multi(ST_SetSRID(ST_GeomFromGeoJSON($8::text), 4326)) AS g
+		)
 		UPDATE incidents 
 		SET 
 			title = $1,
 			description = $2,
-			lat = $3,
-			long = $4,
+			lat = COALESCE(ST_Y(ST_Centroid(shape.g)), $3),
+			long = COALESCE(ST_X(ST_Centroid(shape.g)), $4),
 			radius_m = $5,
 			active = $6,
+			geom = COALESCE(ST_Centroid(shape.g), ST_SetSRID(ST_MakePoint($4, $3), 4326))::geography,
+			boundary = shape.g::geography,
+			warning_buffer_m = $9,
+			starts_at = $10::timestamp,
+			ends_at = $11::timestamp,
+			activation_notified = CASE
+				WHEN starts_at IS DISTINCT FROM $10::timestamp THEN COALESCE($10::timestamp <= NOW(), true)
+				ELSE activation_notified
+			END,
+			expiry_notified = CASE
+				WHEN ends_at IS DISTINCT FROM $11::timestamp THEN COALESCE($11::timestamp <= NOW(), true)
+				ELSE expiry_notified
+			END,
+			severity = $12,
+			category = $13,
+			version = version + 1,
 			updated_at = NOW()
-		WHERE id = $7
-		RETURNING created_at, updated_at
+		FROM shape
+		WHERE id = $7 AND deleted_at IS NULL AND ($14 = 0 OR version = $14)
+		RETURNING lat, long, version, created_at, updated_at
 	`
 
-	err = tx.QueryRowContext(ctx, updateIncidentQuery,
+	incident.SetDefaults()
+	err := tx.QueryRowContext(ctx, updateIncidentQuery,
 		incident.Title,
 		incident.Description,
 		incident.Lat,
@@ -139,25 +386,384 @@ func (ip *IncidentRepository) FullUpdate(ctx context.Context, incident *domain.I
 		incident.Radius,
 		incident.Active,
 		incident.ID,
+		nullableGeoJSON(incident.Boundary),
+		incident.WarningBuffer,
+		incident.StartsAt,
+		incident.EndsAt,
+		incident.Severity,
+		incident.Category,
+		incident.Version,
 	).Scan(
+		&incident.Lat,
+		&incident.Long,
+		&incident.Version,
 		&incident.CreatedAt,
 		&incident.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return domain.ErrNotFound("incident not found")
+			return missingIncidentError(ctx, tx, incident.ID)
 		}
-		return err
+		return mapIncidentWriteError(err)
 	}
 
-	updateGeomQuery := `
-		UPDATE incidents 
-		SET geom = ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography
-		WHERE id = $3
+	_, err = recordIncidentVersion(ctx, tx, incident.ID, domain.IncidentOperationUpdate)
+	return err
+}
+
+// UpsertByTitle создает или обновляет инциденты одной транзакцией, сопоставляя их
+// с неархивными инцидентами по названию. Обновляемый инцидент сохраняет поля, которых нет в файле, см. mergeImportedIncident.
+// При ошибке любого из них транзакция откатывается, а ошибка оборачивается в domain.ImportItemError
+func (ip *IncidentRepository) UpsertByTitle(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error) {
+	findByTitleQuery := `
+		SELECT ` + incidentColumns + ` FROM incidents
+		WHERE title = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+
+	tx, err := ip.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	results := make([]domain.ImportResult, len(items))
+	for i, item := range items {
+		var current domain.Incident
+		err = tx.GetContext(ctx, &current, findByTitleQuery, item.Title)
+		switch {
+		case err == sql.ErrNoRows:
+			incident := item.NewIncident()
+			err = insertIncident(ctx, tx, incident)
+			results[i] = domain.ImportResult{Incident: incident}
+		case err == nil:
+			var incident *domain.Incident
+			incident, err = mergeImportedIncident(ctx, tx, current.ID, item)
+			results[i] = domain.ImportResult{Incident: incident, Previous: &current}
+		}
+		if err != nil {
+			err = &domain.ImportItemError{Index: i, Err: err}
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// mergeImportedIncident обновляет инцидент элементом импорта в рамках транзакции tx. Поля, не заданные
+// в файле (nil), сохраняют текущие значения. Без границы в файле полигональная зона сохраняет
+// границу, центр и радиус, а круговая берет центр и радиус из файла
+func mergeImportedIncident(ctx context.Context, tx *sqlx.Tx, id int, item *domain.ImportIncident) (*domain.Incident, error) {
+	mergeIncidentQuery := `
+		WITH shape AS (
+			SELECT ST_Multi(ST_SetSRID(ST_GeomFromGeoJSON(NULLIF($7::text, '')), 4326)) AS g
+		)
+		UPDATE incidents
+		SET
+			description = COALESCE($2::text, description),
+			lat = CASE
+				WHEN $7::text IS NULL AND boundary IS NOT NULL THEN lat
+				ELSE COALESCE(ST_Y(ST_Centroid(shape.g)), $3::float8)
+			END,
+			long = CASE
+				WHEN $7::text IS NULL AND boundary IS NOT NULL THEN long
+				ELSE COALESCE(ST_X(ST_Centroid(shape.g)), $4::float8)
+			END,
+			radius_m = CASE
+				WHEN $7::text IS NULL AND boundary IS NOT NULL THEN radius_m
+				ELSE $5::int
+			END,
+			active = COALESCE($6::boolean, active),
+			geom = CASE
+				WHEN $7::text IS NULL AND boundary IS NOT NULL THEN geom
+				ELSE COALESCE(ST_Centroid(shape.g), ST_SetSRID(ST_MakePoint($4::float8, $3::float8), 4326))::geography
+			END,
+			boundary = CASE WHEN $7::text IS NULL THEN boundary ELSE shape.g::geography END,
+			warning_buffer_m = COALESCE($8::int, warning_buffer_m),
+			starts_at = COALESCE($9::timestamp, starts_at),
+			ends_at = COALESCE($10::timestamp, ends_at),
+			activation_notified = CASE
+				WHEN $9::timestamp IS NOT NULL AND starts_at IS DISTINCT FROM $9::timestamp THEN $9::timestamp <= NOW()
+				ELSE activation_notified
+			END,
+			expiry_notified = CASE
+				WHEN $10::timestamp IS NOT NULL AND ends_at IS DISTINCT FROM $10::timestamp THEN $10::timestamp <= NOW()
+				ELSE expiry_notified
+			END,
+			severity = COALESCE($11::text, severity),
+			category = COALESCE($12::text, category),
+			version = version + 1,
+			updated_at = NOW()
+		FROM shape
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + incidentColumns + `
 	`
-	_, err = tx.ExecContext(ctx, updateGeomQuery, incident.Long, incident.Lat, incident.ID)
+
+	var boundary *string
+	if item.Boundary != nil {
+		text := string(*item.Boundary)
+		boundary = &text
+	}
+
+	var incident domain.Incident
+	err := tx.GetContext(ctx, &incident, mergeIncidentQuery,
+		id,
+		item.Description,
+		item.Lat,
+		item.Long,
+		item.Radius,
+		item.Active,
+		boundary,
+		item.WarningBuffer,
+		item.StartsAt,
+		item.EndsAt,
+		item.Severity,
+		item.Category,
+	)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrNotFound("incident not found")
+		}
+		return nil, mapIncidentWriteError(err)
+	}
+
+	if _, err := recordIncidentVersion(ctx, tx, id, domain.IncidentOperationUpdate); err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// recordIncidentVersion сохраняет текущее состояние инцидента в incident_versions
+// в той же транзакции, что и изменение. Автор берется из контекста запроса
+func recordIncidentVersion(ctx context.Context, tx *sqlx.Tx, id int, operation string) (bool, error) {
+	recordQuery := `
+		INSERT INTO incident_versions (
+			incident_id, version, operation, changed_by,
+			title, description, lat, long, radius_m, active, severity, category,
+			geom, boundary, warning_buffer_m, starts_at, ends_at, created_at, updated_at
+		)
+		SELECT
+			id, version, $2, $3,
+			title, description, lat, long, radius_m, active, severity, category,
+			geom, boundary, warning_buffer_m, starts_at, ends_at, created_at, updated_at
+		FROM incidents
+		WHERE id = $1
+	`
+
+	res, err := tx.ExecContext(ctx, recordQuery, id, operation, domain.ActorFromContext(ctx))
+	if err != nil {
+		return false, err
+	}
+
+	r, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return r > 0, nil
+}
+
+// missingIncidentError различает отсутствие инцидента и несовпадение версии
+func missingIncidentError(ctx context.Context, tx *sqlx.Tx, id int) error {
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM incidents WHERE id = $1 AND deleted_at IS NULL)`, id); err != nil {
 		return err
 	}
-	return tx.Commit()
+
+	if !exists {
+		return domain.ErrNotFound("incident not found")
+	}
+	return domain.ErrPreconditionFailed("incident was modified by another request")
+}
+
+// ClaimActivated отмечает инциденты, время начала которых наступило, как обработанные
+// и возвращает те из них, что включены. Повторно один и тот же инцидент не возвращается
+func (ip *IncidentRepository) ClaimActivated(ctx context.Context) ([]domain.Incident, error) {
+	claimQuery := `
+		WITH claimed AS (
+			UPDATE incidents
+			SET activation_notified = true
+			WHERE NOT activation_notified AND starts_at <= NOW()
+			RETURNING *
+		)
+		SELECT ` + incidentColumns + `
+		FROM claimed
+		WHERE active = true AND deleted_at IS NULL
+		ORDER BY starts_at, id
+	`
+
+	var incidents []domain.Incident
+	if err := ip.db.SelectContext(ctx, &incidents, claimQuery); err != nil {
+		return nil, err
+	}
+	return incidents, nil
+}
+
+// ClaimExpired - то же, что ClaimActivated, для инцидентов, время окончания которых наступило
+func (ip *IncidentRepository) ClaimExpired(ctx context.Context) ([]domain.Incident, error) {
+	claimQuery := `
+		WITH claimed AS (
+			UPDATE incidents
+			SET expiry_notified = true
+			WHERE NOT expiry_notified AND ends_at <= NOW()
+			RETURNING *
+		)
+		SELECT ` + incidentColumns + `
+		FROM claimed
+		WHERE active = true AND deleted_at IS NULL
+		ORDER BY ends_at, id
+	`
+
+	var incidents []domain.Incident
+	if err := ip.db.SelectContext(ctx, &incidents, claimQuery); err != nil {
+		return nil, err
+	}
+	return incidents, nil
+}
+
+// buildIncidentFilter собирает WHERE и ORDER BY для списка инцидентов и аргументы к ним.
+// ORDER BY ссылается только на аргументы WHERE, поэтому те же args подходят и для COUNT
+func buildIncidentFilter(filter domain.IncidentFilter) (string, string, []any) {
+	var conds []string
+	var args []any
+
+	switch filter.Deleted {
+	case domain.DeletedExclude:
+		conds = append(conds, "deleted_at IS NULL")
+	case domain.DeletedOnly:
+		conds = append(conds, "deleted_at IS NOT NULL")
+	}
+
+	if len(filter.Severities) > 0 {
+		severities := make([]string, len(filter.Severities))
+		for i, severity := range filter.Severities {
+			severities[i] = string(severity)
+		}
+		args = append(args, pq.Array(severities))
+		conds = append(conds, fmt.Sprintf("severity = ANY($%d)", len(args)))
+	}
+
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		conds = append(conds, fmt.Sprintf("category = $%d", len(args)))
+	}
+
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		conds = append(conds, fmt.Sprintf("active = $%d", len(args)))
+	}
+
+	ranges := []struct {
+		column string
+		op     string
+		value  *time.Time
+	}{
+		{"created_at", ">=", filter.CreatedFrom},
+		{"created_at", "<=", filter.CreatedTo},
+		{"updated_at", ">=", filter.UpdatedFrom},
+		{"updated_at", "<=", filter.UpdatedTo},
+	}
+	for _, r := range ranges {
+		if r.value != nil {
+			args = append(args, *r.value)
+			conds = append(conds, fmt.Sprintf("%s %s $%d", r.column, r.op, len(args)))
+		}
+	}
+
+	var rankExpr string
+	if filter.Query != "" {
+		args = append(args, filter.Query)
+		query := fmt.Sprintf("websearch_to_tsquery('simple', $%d)", len(args))
+		conds = append(conds, incidentSearchVector+" @@ "+query)
+		rankExpr = fmt.Sprintf("ts_rank(%s, %s)", incidentSearchVector, query)
+	}
+
+	if filter.BBox != nil {
+		// Границы прямоугольника на geography - дуги большого круга,
+		// для областей в пределах города отличие от параллелей несущественно
+		args = append(args, filter.BBox.MinLong, filter.BBox.MinLat, filter.BBox.MaxLong, filter.BBox.MaxLat)
+		envelope := fmt.Sprintf("ST_MakeEnvelope($%d, $%d, $%d, $%d, 4326)::geography",
+			len(args)-3, len(args)-2, len(args)-1, len(args))
+		conds = append(conds, fmt.Sprintf(
+			"((boundary IS NULL AND ST_Intersects(geom, %[1]s)) OR ST_Intersects(boundary, %[1]s))", envelope,
+		))
+	}
+
+	var distanceExpr string
+	if filter.Near != nil {
+		args = append(args, filter.Near.Long, filter.Near.Lat, filter.Near.RadiusM)
+		point := fmt.Sprintf("ST_SetSRID(ST_MakePoint($%d, $%d), 4326)::geography", len(args)-2, len(args)-1)
+		conds = append(conds, fmt.Sprintf(
+			"((boundary IS NULL AND ST_DWithin(geom, %[1]s, radius_m + $%[2]d)) OR ST_DWithin(boundary, %[1]s, $%[2]d))",
+			point, len(args),
+		))
+		distanceExpr = fmt.Sprintf(
+			"CASE WHEN boundary IS NULL THEN GREATEST(ST_Distance(geom, %[1]s) - radius_m, 0) ELSE ST_Distance(boundary, %[1]s) END",
+			point,
+		)
+	}
+
+	direction := "DESC"
+	if filter.SortAsc {
+		direction = "ASC"
+	}
+
+	if filter.After != nil {
+		cmp := "<"
+		if filter.SortAsc {
+			cmp = ">"
+		}
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		conds = append(conds, fmt.Sprintf("(created_at, id) %s ($%d, $%d)", cmp, len(args)-1, len(args)))
+	}
+
+	sortExpr, ok := incidentSortColumns[filter.Sort]
+	switch {
+	case filter.Sort == domain.IncidentSortDistance && distanceExpr != "":
+		sortExpr = distanceExpr
+	case filter.Sort == domain.IncidentSortRelevance && rankExpr != "":
+		sortExpr = rankExpr
+	case !ok:
+		sortExpr = incidentSortColumns[domain.IncidentSortCreatedAt]
+	}
+
+	orderBy := fmt.Sprintf("%s %s, id %s", sortExpr, direction, direction)
+
+	if len(conds) == 0 {
+		return "", orderBy, nil
+	}
+	return "WHERE " + strings.Join(conds, " AND "), orderBy, args
+}
+
+// nullableGeoJSON подготавливает GeoJSON границы для передачи в запрос:
+// пустая граница передается как NULL (круговая зона)
+func nullableGeoJSON(boundary json.RawMessage) any {
+	if len(boundary) == 0 {
+		return nil
+	}
+	return string(boundary)
+}
+
+func mapIncidentWriteError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code {
+		case "23505":
+			return domain.ErrAlreadyExists("incident already exists")
+		case "23514":
+			switch pqErr.Constraint {
+			case "incidents_schedule_chk":
+				return domain.ErrInvalidValidation("starts_at must be before ends_at")
+			case "incidents_severity_chk":
+				return domain.ErrInvalidValidation("severity is invalid")
+			}
+			return domain.ErrInvalidValidation("boundary geometry is invalid")
+		}
+	}
+	return err
 }
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -109,7 +111,7 @@ func setupTestDB(t *testing.T) {
 }
 
 func cleanupTestDB(t *testing.T) {
-	_, err := testDB.Exec("TRUNCATE TABLE location_checks, incidents RESTART IDENTITY CASCADE")
+	_, err := testDB.Exec("TRUNCATE TABLE location_checks, incidents, incident_versions, webhook_subscriptions RESTART IDENTITY CASCADE")
 	require.NoError(t, err)
 }
 
@@ -168,6 +170,35 @@ func TestIncidentRepository_Create(t *testing.T) {
 				require.WithinDuration(t, time.Now(), incident.UpdatedAt, 10*time.Second)
 			},
 		},
+		{
+			name: "success - polygon boundary",
+			incident: &domain.Incident{
+				Title:       "Polygon",
+				Description: "Description",
+				Active:      true,
+				Boundary:    json.RawMessage(`{"type":"Polygon","coordinates":[[[30,50],[30.02,50],[30.02,50.02],[30,50.02],[30,50]]]}`),
+			},
+			validate: func(t *testing.T, incident *domain.Incident) {
+				require.NotZero(t, incident.ID)
+				require.InDelta(t, 50.01, incident.Lat, 1e-9)
+				require.InDelta(t, 30.01, incident.Long, 1e-9)
+				require.Zero(t, incident.Radius)
+			},
+		},
+		{
+			name: "error - self-intersecting boundary",
+			incident: &domain.Incident{
+				Title:       "Bowtie",
+				Description: "Description",
+				Active:      true,
+				Boundary:    json.RawMessage(`{"type":"Polygon","coordinates":[[[30,50],[30.02,50.02],[30.02,50],[30,50.02],[30,50]]]}`),
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
 		{
 			name: "error - duplicate title",
 			incident: &domain.Incident{
@@ -221,6 +252,7 @@ func TestIncidentRepository_Create(t *testing.T) {
 				Long:        tt.incident.Long,
 				Radius:      tt.incident.Radius,
 				Active:      tt.incident.Active,
+				Boundary:    tt.incident.Boundary,
 			}
 
 			err := testRepo.Create(ctx, incident)
@@ -261,7 +293,7 @@ func TestIncidentRepository_GetByID(t *testing.T) {
 		validate func(t *testing.T, incident *domain.Incident)
 	}{
 		{
-			name: "success",
+			name: "success - circle zone",
 			id:   1,
 			setup: func(t *testing.T) {
 				incident := &domain.Incident{
@@ -283,10 +315,28 @@ func TestIncidentRepository_GetByID(t *testing.T) {
 				require.Equal(t, 30.0, incident.Long)
 				require.Equal(t, 100, incident.Radius)
 				require.True(t, incident.Active)
+				require.Empty(t, incident.Boundary)
 				require.WithinDuration(t, time.Now(), incident.CreatedAt, 10*time.Second)
 				require.WithinDuration(t, time.Now(), incident.UpdatedAt, 10*time.Second)
 			},
 		},
+		{
+			name: "success - polygon zone",
+			id:   1,
+			setup: func(t *testing.T) {
+				incident := &domain.Incident{
+					Title:    "Polygon",
+					Boundary: json.RawMessage(`{"type":"Polygon","coordinates":[[[30,50],[30.02,50],[30.02,50.02],[30,50.02],[30,50]]]}`),
+					Active:   true,
+				}
+				err := testRepo.Create(ctx, incident)
+				require.NoError(t, err)
+			},
+			validate: func(t *testing.T, incident *domain.Incident) {
+				require.Equal(t, "Polygon", incident.Title)
+				require.JSONEq(t, `{"type":"Polygon","coordinates":[[[30,50],[30.02,50],[30.02,50.02],[30,50.02],[30,50]]]}`, string(incident.Boundary))
+			},
+		},
 		{
 			name:    "error - incident not found",
 			id:      1,
@@ -341,6 +391,7 @@ func TestIncidentRepository_Paginate(t *testing.T) {
 	tests := []struct {
 		name          string
 		limit, offset int
+		filter        domain.IncidentFilter
 		setup         func(t *testing.T)
 		wantErr       bool
 		errType       func(err error) bool
@@ -424,6 +475,124 @@ func TestIncidentRepository_Paginate(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "success - filter by severity and category",
+			limit:  5,
+			offset: 0,
+			filter: domain.IncidentFilter{
+				Severities: []domain.Severity{domain.SeverityDanger, domain.SeverityCritical},
+				Category:   "fire",
+			},
+			setup: func(t *testing.T) {
+				incidents := []struct {
+					severity domain.Severity
+					category string
+				}{
+					{domain.SeverityDanger, "fire"},
+					{domain.SeverityCritical, "fire"},
+					{domain.SeverityInfo, "fire"},
+					{domain.SeverityCritical, "flood"},
+				}
+				for i, in := range incidents {
+					incident := &domain.Incident{
+						Title:       fmt.Sprintf("Incident-#%d", i),
+						Description: "Description",
+						Lat:         50.0,
+						Long:        30.0,
+						Radius:      100,
+						Active:      true,
+						Severity:    in.severity,
+						Category:    in.category,
+					}
+					err := testRepo.Create(ctx, incident)
+					require.NoError(t, err)
+				}
+			},
+			validate: func(t *testing.T, incidents []domain.Incident, total int) {
+				require.Equal(t, 2, total)
+				require.Len(t, incidents, 2)
+				for _, incident := range incidents {
+					require.Equal(t, "fire", incident.Category)
+					require.GreaterOrEqual(t, incident.Severity.Rank(), domain.SeverityDanger.Rank())
+				}
+			},
+		},
+		{
+			name:   "success - search near point sorted by distance",
+			limit:  5,
+			offset: 0,
+			filter: domain.IncidentFilter{
+				Query:   "перекрытие",
+				Near:    &domain.NearPoint{Lat: 50.0, Long: 30.0, RadiusM: 1000},
+				Sort:    domain.IncidentSortDistance,
+				SortAsc: true,
+			},
+			setup: func(t *testing.T) {
+				incidents := []struct {
+					title string
+					lat   float64
+				}{
+					{"Дальнее перекрытие", 50.005},
+					{"Ближнее перекрытие", 50.002},
+					{"Перекрытие за городом", 50.1},
+					{"Пожар рядом", 50.001},
+				}
+				for _, in := range incidents {
+					incident := &domain.Incident{
+						Title:       in.title,
+						Description: "Description",
+						Lat:         in.lat,
+						Long:        30.0,
+						Radius:      100,
+						Active:      true,
+					}
+					err := testRepo.Create(ctx, incident)
+					require.NoError(t, err)
+				}
+			},
+			validate: func(t *testing.T, incidents []domain.Incident, total int) {
+				require.Equal(t, 2, total)
+				require.Len(t, incidents, 2)
+				require.Equal(t, "Ближнее перекрытие", incidents[0].Title)
+				require.Equal(t, "Дальнее перекрытие", incidents[1].Title)
+			},
+		},
+		{
+			name:   "success - filter by bbox and active",
+			limit:  5,
+			offset: 0,
+			filter: domain.IncidentFilter{
+				Active: func() *bool { active := true; return &active }(),
+				BBox:   &domain.BBox{MinLong: 29.9, MinLat: 49.9, MaxLong: 30.1, MaxLat: 50.1},
+			},
+			setup: func(t *testing.T) {
+				incidents := []struct {
+					long   float64
+					active bool
+				}{
+					{30.0, true},
+					{30.0, false},
+					{31.0, true},
+				}
+				for i, in := range incidents {
+					incident := &domain.Incident{
+						Title:       fmt.Sprintf("Incident-#%d", i),
+						Description: "Description",
+						Lat:         50.0,
+						Long:        in.long,
+						Radius:      100,
+						Active:      in.active,
+					}
+					err := testRepo.Create(ctx, incident)
+					require.NoError(t, err)
+				}
+			},
+			validate: func(t *testing.T, incidents []domain.Incident, total int) {
+				require.Equal(t, 1, total)
+				require.Len(t, incidents, 1)
+				require.Equal(t, "Incident-#0", incidents[0].Title)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -434,7 +603,7 @@ func TestIncidentRepository_Paginate(t *testing.T) {
 				tt.setup(t)
 			}
 
-			incidents, total, err := testRepo.Paginate(ctx, tt.limit, tt.offset)
+			incidents, total, err := testRepo.Paginate(ctx, tt.filter, tt.limit, tt.offset)
 
 			if tt.wantErr {
 				require.Error(t, err, "expected error but got none")
@@ -452,6 +621,306 @@ func TestIncidentRepository_Paginate(t *testing.T) {
 	}
 }
 
+func TestIncidentRepository_PaginateCursor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	cleanupTestDB(t)
+
+	for i := 0; i < 5; i++ {
+		incident := &domain.Incident{
+			Title:       fmt.Sprintf("Incident-#%d", i),
+			Description: "Description",
+			Lat:         50.0,
+			Long:        30.0,
+			Radius:      100,
+			Active:      true,
+		}
+		err := testRepo.Create(ctx, incident)
+		require.NoError(t, err)
+	}
+
+	// одинаковый created_at у части записей не должен приводить к пропускам и повторам
+	_, err := testDB.ExecContext(ctx, `UPDATE incidents SET created_at = '2026-10-16 10:00:00' WHERE title IN ('Incident-#1', 'Incident-#2', 'Incident-#3')`)
+	require.NoError(t, err)
+
+	for _, asc := range []bool{false, true} {
+		var seen []int
+		filter := domain.IncidentFilter{SortAsc: asc}
+		for {
+			page, err := testRepo.PaginateCursor(ctx, filter, 2)
+			require.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			for _, incident := range page {
+				seen = append(seen, incident.ID)
+			}
+			last := page[len(page)-1]
+			filter.After = &domain.IncidentCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+
+		require.Len(t, seen, 5)
+		unique := make(map[int]struct{}, len(seen))
+		for _, id := range seen {
+			unique[id] = struct{}{}
+		}
+		require.Len(t, unique, 5)
+	}
+}
+
+func TestIncidentRepository_History(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	cleanupTestDB(t)
+
+	ctx := domain.ContextWithActor(context.Background(), "operator")
+
+	incident := &domain.Incident{
+		Title:       "Incident",
+		Description: "Description",
+		Lat:         50.0,
+		Long:        30.0,
+		Radius:      100,
+		Active:      true,
+	}
+	require.NoError(t, testRepo.Create(ctx, incident))
+	createdAt := time.Now().UTC()
+
+	// разнести моменты изменений, чтобы as_of однозначно попадал между версиями
+	_, err := testDB.Exec(`UPDATE incident_versions SET changed_at = changed_at - INTERVAL '1 hour' WHERE incident_id = $1`, incident.ID)
+	require.NoError(t, err)
+
+	incident.Radius = 500
+	require.NoError(t, testRepo.FullUpdate(context.Background(), incident))
+
+	require.NoError(t, testRepo.Delete(ctx, incident.ID))
+
+	versions, err := testRepo.History(ctx, incident.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+
+	require.Equal(t, domain.IncidentOperationDelete, versions[0].Operation)
+	require.Equal(t, 3, versions[0].Version)
+	require.Equal(t, "operator", versions[0].ChangedBy)
+
+	require.Equal(t, domain.IncidentOperationUpdate, versions[1].Operation)
+	require.Equal(t, 500, versions[1].Radius)
+	require.Equal(t, domain.SystemActor, versions[1].ChangedBy)
+
+	require.Equal(t, domain.IncidentOperationCreate, versions[2].Operation)
+	require.Equal(t, 100, versions[2].Radius)
+	require.Equal(t, "operator", versions[2].ChangedBy)
+
+	old, err := testRepo.GetAsOf(ctx, incident.ID, createdAt.Add(-30*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 100, old.Radius)
+	require.Equal(t, 1, old.Version)
+
+	_, err = testRepo.GetAsOf(ctx, incident.ID, time.Now().UTC().Add(time.Minute))
+	var appErr *domain.AppError
+	require.True(t, errors.As(err, &appErr) && appErr.Code == domain.CodeNotFound, "deleted incident must not be returned")
+
+	_, err = testRepo.GetAsOf(ctx, incident.ID, createdAt.Add(-2*time.Hour))
+	require.True(t, errors.As(err, &appErr) && appErr.Code == domain.CodeNotFound, "incident did not exist yet")
+}
+
+func TestIncidentRepository_SoftDelete(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	cleanupTestDB(t)
+
+	incident := &domain.Incident{
+		Title:       "Incident",
+		Description: "Description",
+		Lat:         50.0,
+		Long:        30.0,
+		Radius:      100,
+		Active:      true,
+	}
+	require.NoError(t, testRepo.Create(ctx, incident))
+
+	check := &domain.LocationCheck{UserID: "colorvax", Lat: 50.0, Long: 30.0}
+	require.NoError(t, testRepoCoor.Check(ctx, check, domain.CheckParams{}))
+	require.True(t, check.InDangerZone)
+
+	require.NoError(t, testRepo.Delete(ctx, incident.ID))
+
+	var appErr *domain.AppError
+	err := testRepo.Delete(ctx, incident.ID)
+	require.True(t, errors.As(err, &appErr) && appErr.Code == domain.CodeNotFound, "archived incident can not be deleted twice")
+
+	// архивный инцидент не участвует в проверках, но связь с прошлой проверкой сохраняется
+	archivedCheck := &domain.LocationCheck{UserID: "colorvax", Lat: 50.0, Long: 30.0}
+	require.NoError(t, testRepoCoor.Check(ctx, archivedCheck, domain.CheckParams{}))
+	require.False(t, archivedCheck.InDangerZone)
+
+	var nearestID sql.NullInt64
+	require.NoError(t, testDB.Get(&nearestID, "SELECT nearest_id FROM location_checks WHERE id = $1", check.ID))
+	require.Equal(t, int64(incident.ID), nearestID.Int64)
+
+	_, total, err := testRepo.Paginate(ctx, domain.IncidentFilter{}, 5, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, total)
+
+	_, total, err = testRepo.Paginate(ctx, domain.IncidentFilter{Deleted: domain.DeletedOnly}, 5, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+
+	// название архивного инцидента можно занять
+	duplicate := &domain.Incident{Title: "Incident", Description: "Description", Lat: 10.0, Long: 10.0, Radius: 100, Active: true}
+	require.NoError(t, testRepo.Create(ctx, duplicate))
+
+	_, err = testRepo.Restore(ctx, incident.ID)
+	require.True(t, errors.As(err, &appErr) && appErr.Code == domain.CodeAlreadyExists, "restore must fail on title conflict")
+
+	require.NoError(t, testRepo.Delete(ctx, duplicate.ID))
+
+	restored, err := testRepo.Restore(ctx, incident.ID)
+	require.NoError(t, err)
+	require.Nil(t, restored.DeletedAt)
+	require.Equal(t, 3, restored.Version)
+
+	versions, err := testRepo.History(ctx, incident.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.IncidentOperationRestore, versions[0].Operation)
+
+	// очистка удаляет только инциденты, пролежавшие в архиве дольше срока хранения
+	_, err = testDB.Exec("UPDATE incidents SET deleted_at = NOW() - INTERVAL '40 days' WHERE id = $1", duplicate.ID)
+	require.NoError(t, err)
+
+	purged, err := testRepo.Purge(ctx, 30)
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+
+	var count int
+	require.NoError(t, testDB.Get(&count, "SELECT COUNT(*) FROM incidents"))
+	require.Equal(t, 1, count)
+}
+
+func TestIncidentRepository_UpsertByTitle(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	cleanupTestDB(t)
+
+	existing := &domain.Incident{Title: "Existing", Description: "old", Lat: 50.0, Long: 30.0, Radius: 100, Active: true}
+	require.NoError(t, testRepo.Create(ctx, existing))
+
+	archived := &domain.Incident{Title: "Archived", Description: "old", Lat: 50.0, Long: 30.0, Radius: 100, Active: true}
+	require.NoError(t, testRepo.Create(ctx, archived))
+	require.NoError(t, testRepo.Delete(ctx, archived.ID))
+
+	polygon := json.RawMessage(`{"type":"Polygon","coordinates":[[[30.0,50.0],[30.01,50.0],[30.01,50.01],[30.0,50.01],[30.0,50.0]]]}`)
+	circle := json.RawMessage{}
+	description, inactive, active := "new", false, true
+	items := []*domain.ImportIncident{
+		{Title: "Existing", Description: &description, Lat: 51.0, Long: 31.0, Radius: 200, Active: &inactive, Boundary: &circle},
+		{Title: "Archived", Description: &description, Lat: 51.0, Long: 31.0, Radius: 200, Active: &active, Boundary: &circle},
+		{Title: "Fresh", Description: &description, Boundary: &polygon},
+	}
+	results, err := testRepo.UpsertByTitle(ctx, items)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, existing.ID, results[0].Previous.ID)
+	require.Equal(t, "old", results[0].Previous.Description)
+	require.Equal(t, 100, results[0].Previous.Radius)
+	require.Nil(t, results[1].Previous)
+	require.Nil(t, results[2].Previous)
+
+	require.Equal(t, existing.ID, results[0].Incident.ID)
+	require.Equal(t, 2, results[0].Incident.Version)
+	require.Equal(t, 200, results[0].Incident.Radius)
+	require.False(t, results[0].Incident.Active)
+	require.NotEqual(t, archived.ID, results[1].Incident.ID, "archived incident is not matched by title")
+	require.InDelta(t, 50.005, results[2].Incident.Lat, 0.001)
+	require.True(t, results[2].Incident.Active, "new incident is active by default")
+
+	updated, err := testRepo.GetByID(ctx, existing.ID)
+	require.NoError(t, err)
+	require.Equal(t, "new", updated.Description)
+	require.False(t, updated.Active)
+
+	versions, err := testRepo.History(ctx, existing.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.IncidentOperationUpdate, versions[0].Operation)
+
+	// ошибка одного элемента откатывает весь импорт
+	startsAt, endsAt := time.Now().Add(time.Hour), time.Now()
+	newer := "newer"
+	broken := []*domain.ImportIncident{
+		{Title: "Existing", Description: &newer, Lat: 52.0, Long: 32.0, Radius: 300, Boundary: &circle},
+		{Title: "Invalid schedule", Lat: 52.0, Long: 32.0, Radius: 300, Boundary: &circle, StartsAt: &startsAt, EndsAt: &endsAt},
+	}
+	_, err = testRepo.UpsertByTitle(ctx, broken)
+	var itemErr *domain.ImportItemError
+	require.True(t, errors.As(err, &itemErr), "wrong error type: %v", err)
+	require.Equal(t, 1, itemErr.Index)
+
+	unchanged, err := testRepo.GetByID(ctx, existing.ID)
+	require.NoError(t, err)
+	require.Equal(t, "new", unchanged.Description)
+	require.Equal(t, 2, unchanged.Version)
+
+	// поля, которых нет в файле, обновляемый инцидент сохраняет
+	buffer, ends := 40, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	zone := &domain.Incident{
+		Title: "Zone", Description: "zone", Radius: 0, Active: true, Severity: domain.SeverityCritical, Category: "fire",
+		Boundary: polygon, WarningBuffer: &buffer, EndsAt: &ends,
+	}
+	require.NoError(t, testRepo.Create(ctx, zone))
+
+	results, err = testRepo.UpsertByTitle(ctx, []*domain.ImportIncident{
+		{Title: "Zone", Lat: 52.0, Long: 32.0, Radius: 300},
+	})
+	require.NoError(t, err)
+
+	kept := results[0].Incident
+	require.Equal(t, zone.Version+1, kept.Version)
+	require.Equal(t, "zone", kept.Description)
+	require.True(t, kept.Active)
+	require.Equal(t, domain.SeverityCritical, kept.Severity)
+	require.Equal(t, "fire", kept.Category)
+	require.NotEmpty(t, kept.Boundary, "polygon boundary is kept")
+	require.InDelta(t, 50.005, kept.Lat, 0.001, "polygon keeps its centroid")
+	require.Zero(t, kept.Radius)
+	require.Equal(t, 40, *kept.WarningBuffer)
+	require.True(t, ends.Equal(*kept.EndsAt))
+
+	stored, err := testRepo.GetByID(ctx, zone.ID)
+	require.NoError(t, err)
+	require.Equal(t, kept.Severity, stored.Severity)
+	require.JSONEq(t, string(kept.Boundary), string(stored.Boundary))
+}
+
 func TestIncidentRepository_Delete(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -589,6 +1058,71 @@ func TestIncidentRepository_FullUpdate(t *testing.T) {
 				require.WithinDuration(t, time.Now(), incident.CreatedAt, 10*time.Second)
 				require.WithinDuration(t, time.Now(), incident.UpdatedAt, 10*time.Second)
 				require.True(t, incident.UpdatedAt.After(incident.CreatedAt))
+				require.Equal(t, 2, incident.Version)
+			},
+		},
+		{
+			name: "success - expected version matches",
+			incident: &domain.Incident{
+				ID:          1,
+				Title:       "Incident-Updated",
+				Description: "Description",
+				Lat:         75.0,
+				Long:        75.0,
+				Radius:      75,
+				Active:      false,
+				Version:     1,
+			},
+			setup: func(t *testing.T) {
+				incident := &domain.Incident{
+					Title:       "Incident",
+					Description: "Description",
+					Lat:         50.0,
+					Long:        50.0,
+					Radius:      50,
+					Active:      true,
+				}
+				err := testRepo.Create(ctx, incident)
+				require.NoError(t, err)
+				require.Equal(t, 1, incident.Version)
+			},
+			validate: func(t *testing.T, incident *domain.Incident) {
+				require.False(t, incident.Active)
+				require.Equal(t, 2, incident.Version)
+			},
+		},
+		{
+			name: "error - expected version is stale",
+			incident: &domain.Incident{
+				ID:          1,
+				Title:       "Incident-Updated",
+				Description: "Description",
+				Lat:         75.0,
+				Long:        75.0,
+				Radius:      75,
+				Active:      false,
+				Version:     1,
+			},
+			setup: func(t *testing.T) {
+				incident := &domain.Incident{
+					Title:       "Incident",
+					Description: "Description",
+					Lat:         50.0,
+					Long:        50.0,
+					Radius:      50,
+					Active:      true,
+				}
+				err := testRepo.Create(ctx, incident)
+				require.NoError(t, err)
+
+				incident.Title = "Incident-Concurrent"
+				err = testRepo.FullUpdate(ctx, incident)
+				require.NoError(t, err)
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodePreconditionFailed
 			},
 		},
 		{
@@ -648,3 +1182,65 @@ func TestIncidentRepository_FullUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestIncidentRepository_ClaimScheduled(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	ctx := context.Background()
+	cleanupTestDB(t)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	create := func(title string, startsAt, endsAt *time.Time, active bool) *domain.Incident {
+		incident := &domain.Incident{
+			Title:       title,
+			Description: "Description",
+			Lat:         50,
+			Long:        50,
+			Radius:      100,
+			Active:      active,
+			StartsAt:    startsAt,
+			EndsAt:      endsAt,
+		}
+		err := testRepo.Create(ctx, incident)
+		require.NoError(t, err)
+		return incident
+	}
+
+	planned := create("Road works", &future, nil, true)
+	finishing := create("Demonstration", &past, &future, true)
+	create("Already started", &past, nil, true)
+	disabled := create("Disabled", &future, nil, false)
+
+	// переносим границы расписания в прошлое, как будто время наступило
+	_, err := testDB.Exec(`UPDATE incidents SET starts_at = NOW() - INTERVAL '1 minute' WHERE id IN ($1, $2)`, planned.ID, disabled.ID)
+	require.NoError(t, err)
+	_, err = testDB.Exec(`UPDATE incidents SET ends_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, finishing.ID)
+	require.NoError(t, err)
+
+	activated, err := testRepo.ClaimActivated(ctx)
+	require.NoError(t, err)
+	require.Len(t, activated, 1)
+	require.Equal(t, planned.ID, activated[0].ID)
+	require.NotNil(t, activated[0].StartsAt)
+
+	expired, err := testRepo.ClaimExpired(ctx)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	require.Equal(t, finishing.ID, expired[0].ID)
+
+	activated, err = testRepo.ClaimActivated(ctx)
+	require.NoError(t, err)
+	require.Empty(t, activated, "incident must be claimed only once")
+
+	expired, err = testRepo.ClaimExpired(ctx)
+	require.NoError(t, err)
+	require.Empty(t, expired, "incident must be claimed only once")
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"red_collar/internal/domain"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	membershipKeyPrefix = "zone:membership:"
+	membershipTTL       = 24 * time.Hour
+	// membershipMaxRetries - сколько раз Update повторяет чтение-запись, если состояние меняли параллельно
+	membershipMaxRetries = 10
+	// membershipCheckedAtField - поле хеша со временем проверки, по которой построено состояние
+	membershipCheckedAtField = "checked_at"
+)
+
+// MembershipRepository хранит, в каких зонах сейчас находится пользователь.
+// Состояние лежит в хеше zone:membership:<user_id>: поле - id инцидента, значение - domain.ZoneMembership,
+// и поле checked_at - время проверки, по которой построено состояние
+type MembershipRepository struct {
+	client *redis.Client
+}
+
+func NewMembershipRepository(client *redis.Client) *MembershipRepository {
+	return &MembershipRepository{
+		client: client,
+	}
+}
+
+// Update атомарно изменяет состояние пользователя по проверке в момент checkedAt: fn получает текущее
+// состояние и возвращает новое, nil - оставить состояние без изменений. Проверка старше той, по которой
+// построено состояние, его не меняет и fn не вызывается. Ключ читается под WATCH, и если до записи
+// его изменил другой запрос, fn вызывается заново на свежем состоянии. Так каждый переход между зонами
+// фиксирует ровно один запрос
+func (m *MembershipRepository) Update(
+	ctx context.Context,
+	userID string,
+	checkedAt time.Time,
+	fn func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership,
+) error {
+	key := membershipKeyPrefix + userID
+
+	update := func(tx *redis.Tx) error {
+		raw, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get zone membership: %w", err)
+		}
+		zones, lastCheckedAt, err := decodeMembership(raw)
+		if err != nil {
+			return err
+		}
+		if checkedAt.Before(lastCheckedAt) {
+			return nil
+		}
+
+		next := fn(zones)
+		if next == nil {
+			return nil
+		}
+		values, err := encodeMembership(next, checkedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			writeMembership(ctx, pipe, key, values)
+			return nil
+		})
+		return err
+	}
+
+	for range membershipMaxRetries {
+		err := m.client.Watch(ctx, update, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update zone membership: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to update zone membership: %w", redis.TxFailedErr)
+}
+
+// decodeMembership разбирает хеш состояния. У состояния, записанного без времени проверки, оно нулевое
+func decodeMembership(raw map[string]string) (map[int]domain.ZoneMembership, time.Time, error) {
+	var checkedAt time.Time
+	zones := make(map[int]domain.ZoneMembership, len(raw))
+	for field, value := range raw {
+		if field == membershipCheckedAtField {
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, time.Time{}, fmt.Errorf("invalid zone membership check time %q: %w", value, err)
+			}
+			checkedAt = t
+			continue
+		}
+
+		incidentID, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("invalid zone membership field %q: %w", field, err)
+		}
+
+		var membership domain.ZoneMembership
+		if err := json.Unmarshal([]byte(value), &membership); err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to unmarshal zone membership: %w", err)
+		}
+		zones[incidentID] = membership
+	}
+	return zones, checkedAt, nil
+}
+
+// encodeMembership собирает поля хеша состояния. Время проверки сохраняется и при пустом состоянии,
+// чтобы более старая проверка не вернула пользователя в зону, из которой он уже вышел
+func encodeMembership(zones map[int]domain.ZoneMembership, checkedAt time.Time) ([]any, error) {
+	values := make([]any, 0, len(zones)*2+2)
+	for incidentID, membership := range zones {
+		data, err := json.Marshal(membership)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal zone membership: %w", err)
+		}
+		values = append(values, strconv.Itoa(incidentID), data)
+	}
+	if !checkedAt.IsZero() {
+		values = append(values, membershipCheckedAtField, checkedAt.UTC().Format(time.RFC3339Nano))
+	}
+	return values, nil
+}
+
+// writeMembership заменяет хеш состояния, состояние без полей удаляет ключ
+func writeMembership(ctx context.Context, pipe redis.Pipeliner, key string, values []any) {
+	pipe.Del(ctx, key)
+	if len(values) > 0 {
+		pipe.HSet(ctx, key, values...)
+		pipe.Expire(ctx, key, membershipTTL)
+	}
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"red_collar/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMembershipRepository_Update(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if testRD == nil {
+		setupTestRD()
+	}
+
+	ctx := context.Background()
+	repo := NewMembershipRepository(testRD)
+	key := "zone:membership:colorvax"
+	enteredAt := time.Now().UTC().Truncate(time.Second)
+	cleanupTestRD(t)
+
+	load := func(t *testing.T) (map[int]domain.ZoneMembership, time.Time) {
+		raw, err := testRD.HGetAll(ctx, key).Result()
+		require.NoError(t, err)
+		zones, checkedAt, err := decodeMembership(raw)
+		require.NoError(t, err)
+		return zones, checkedAt
+	}
+	entry := func(t *testing.T, membership domain.ZoneMembership) []byte {
+		data, err := json.Marshal(membership)
+		require.NoError(t, err)
+		return data
+	}
+
+	require.NoError(t, testRD.HSet(ctx, key, "1", entry(t, domain.ZoneMembership{EnteredAt: enteredAt})).Err())
+
+	// параллельная запись между чтением и записью заставляет повторить fn на свежем состоянии
+	var calls int
+	err := repo.Update(ctx, "colorvax", enteredAt, func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership {
+		calls++
+		if calls == 1 {
+			require.NoError(t, testRD.HSet(ctx, key, "2", entry(t, domain.ZoneMembership{EnteredAt: enteredAt})).Err())
+		}
+		zones[3] = domain.ZoneMembership{EnteredAt: enteredAt, DwellSent: true}
+		return zones
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+
+	res, checkedAt := load(t)
+	require.Len(t, res, 3)
+	require.True(t, res[1].EnteredAt.Equal(enteredAt))
+	require.True(t, res[3].DwellSent)
+	require.True(t, checkedAt.Equal(enteredAt))
+
+	ttl, err := testRD.TTL(ctx, key).Result()
+	require.NoError(t, err)
+	require.Greater(t, ttl, time.Duration(0))
+
+	err = repo.Update(ctx, "colorvax", enteredAt, func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership {
+		return nil
+	})
+	require.NoError(t, err)
+
+	res, _ = load(t)
+	require.Len(t, res, 3, "nil from fn must keep the state")
+
+	// пустое состояние сохраняет время проверки, и более старая проверка его не меняет
+	err = repo.Update(ctx, "colorvax", enteredAt.Add(time.Minute), func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership {
+		return map[int]domain.ZoneMembership{}
+	})
+	require.NoError(t, err)
+
+	err = repo.Update(ctx, "colorvax", enteredAt, func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership {
+		t.Fatal("fn must not be called for an older check")
+		return nil
+	})
+	require.NoError(t, err)
+
+	res, checkedAt = load(t)
+	require.Empty(t, res)
+	require.True(t, checkedAt.Equal(enteredAt.Add(time.Minute)))
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"red_collar/internal/domain"
 	"time"
@@ -14,40 +17,206 @@ const (
 	webhookQueueKey   = "webhook:queue"
 	webhookDelayedKey = "webhook:delayed"
 	webhookDLQKey     = "webhook:dlq"
+	// webhookProcessingKey - задачи, которые воркер забрал, но еще не подтвердил
+	webhookProcessingKey = "webhook:processing"
+	// webhookLeasesKey - срок видимости задач из webhookProcessingKey в миллисекундах Unix
+	webhookLeasesKey = "webhook:processing:leases"
+
+	// сколько задач переносится за один вызов ProcessDelayedTasks и ReapExpired
+	queueBatchSize = 100
 )
 
+// WebhookTask - доставка события. Новое событие попадает в очередь без SubscriptionID,
+// воркер раскладывает его на задачи по подпискам, у каждой из которых свои попытки
 type WebhookTask struct {
-	LocationCheck *domain.LocationCheck `json:"location_check"`
-	Attempt       int                   `json:"attempt"`
-	FirstAttempt  time.Time             `json:"first_attempt"`
-	LastError     string                `json:"last_error,omitempty"`
+	Event          *domain.Event `json:"event"`
+	SubscriptionID *int          `json:"subscription_id,omitempty"`
+	Attempt        int           `json:"attempt"`
+	FirstAttempt   time.Time     `json:"first_attempt"`
+	LastError      string        `json:"last_error,omitempty"`
+	// FailedAt - момент попадания в DLQ
+	FailedAt *time.Time `json:"failed_at,omitempty"`
+
+	// receipt - задача в том виде, в котором она лежит в webhookProcessingKey, для Ack
+	receipt string
+}
+
+// UnmarshalJSON разбирает задачу, в том числе в формате до появления событий: {"location_check": ...}.
+// Такие задачи могли остаться в очередях после обновления, поэтому они превращаются в событие, см. legacyEvent
+func (t *WebhookTask) UnmarshalJSON(data []byte) error {
+	type plain WebhookTask
+	var v struct {
+		plain
+		LocationCheck *domain.LocationCheck `json:"location_check"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*t = WebhookTask(v.plain)
+	if t.Event == nil && v.LocationCheck != nil {
+		t.Event = legacyEvent(v.LocationCheck)
+	}
+	return nil
+}
+
+// legacyEvent - событие для задачи старого формата, которая отправлялась на каждую проверку в опасной зоне.
+// ID выводится из ID проверки, поэтому повторное чтение той же задачи (например, из DLQ) дает то же событие
+func legacyEvent(check *domain.LocationCheck) *domain.Event {
+	event := &domain.Event{
+		ID:            fmt.Sprintf("legacy-check-%d", check.ID),
+		OccurredAt:    check.CheckedAt.UTC(),
+		Type:          domain.EventZoneEntered,
+		LocationCheck: check,
+	}
+	switch {
+	case check.PrimaryID != nil:
+		event.IncidentID = *check.PrimaryID
+	case check.NearestID != nil:
+		event.IncidentID = *check.NearestID
+	}
+	return event
+}
+
+// deadLetter - представление задачи из DLQ. У записей без failed_at временем попадания в DLQ считается первая попытка
+func (t *WebhookTask) deadLetter() domain.DeadLetter {
+	failedAt := t.FirstAttempt
+	if t.FailedAt != nil {
+		failedAt = *t.FailedAt
+	}
+	return domain.DeadLetter{
+		ID:             domain.DeadLetterID(t.Event.ID, t.SubscriptionID),
+		Event:          t.Event,
+		SubscriptionID: t.SubscriptionID,
+		Attempts:       t.Attempt,
+		LastError:      t.LastError,
+		FirstAttempt:   t.FirstAttempt,
+		FailedAt:       failedAt,
+	}
+}
+
+// rawDeadLetter - представление записи DLQ, которую не удалось разобрать. Время попадания в DLQ
+// у нее неизвестно и считается нулевым, поэтому такую запись удаляет любая очистка по времени
+func rawDeadLetter(raw string) domain.DeadLetter {
+	sum := sha256.Sum256([]byte(raw))
+	return domain.DeadLetter{
+		ID:  "raw:" + hex.EncodeToString(sum[:8]),
+		Raw: raw,
+	}
+}
+
+// dlqEntry - запись DLQ вместе с исходной строкой, по которой ее можно удалить из списка.
+// У записи, которую не удалось разобрать, task пустой
+type dlqEntry struct {
+	raw    string
+	task   *WebhookTask
+	letter domain.DeadLetter
 }
 
+// replayDLQScript переносит запись из DLQ в очередь, только если ее еще никто не забрал,
+// поэтому параллельные повторы не отправляют одну задачу дважды
+var replayDLQScript = redis.NewScript(`
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 1 then
+	redis.call('LPUSH', KEYS[2], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// promoteDelayedScript переносит наступившие отложенные задачи в очередь. Перенос атомарный:
+// задача не теряется между удалением из отложенных и добавлением в очередь и не переносится дважды
+var promoteDelayedScript = redis.NewScript(`
+local tasks = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, task in ipairs(tasks) do
+	redis.call('ZREM', KEYS[1], task)
+	redis.call('LPUSH', KEYS[2], task)
+end
+return #tasks
+`)
+
+// reapExpiredScript возвращает в начало очереди задачи, срок видимости которых истек. Задачам в обработке
+// без срока (воркер упал между BLMOVE и ZADD) срок назначается от текущего момента
+var reapExpiredScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+for _, task in ipairs(redis.call('LRANGE', KEYS[1], 0, -1)) do
+	redis.call('ZADD', KEYS[2], 'NX', now + tonumber(ARGV[2]), task)
+end
+
+local reaped = 0
+for _, task in ipairs(redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', now, 'LIMIT', 0, tonumber(ARGV[3]))) do
+	redis.call('ZREM', KEYS[2], task)
+	if redis.call('LREM', KEYS[1], 1, task) == 1 then
+		redis.call('RPUSH', KEYS[3], task)
+		reaped = reaped + 1
+	end
+end
+return reaped
+`)
+
+// Queue - очередь доставки вебхуков с гарантией "хотя бы один раз": Dequeue переносит задачу в список
+// обработки, Ack удаляет ее оттуда, а неподтвержденные за visibilityTimeout задачи ReapExpired возвращает в очередь
 type Queue struct {
-	client *redis.Client
+	client            *redis.Client
+	visibilityTimeout time.Duration
 }
 
-func NewQueue(client *redis.Client) *Queue {
+// NewQueue создает очередь. visibilityTimeout должен быть больше времени обработки одной задачи,
+// иначе задача будет выдана повторно, пока ее еще обрабатывают
+func NewQueue(client *redis.Client, visibilityTimeout time.Duration) *Queue {
 	return &Queue{
-		client: client,
+		client:            client,
+		visibilityTimeout: visibilityTimeout,
 	}
 }
 
-// Добавление таска в обычную очередь
-func (q *Queue) Enqueue(ctx context.Context, check *domain.LocationCheck) error {
+// Добавление таска в обычную очередь. Событию без ID назначается новый ID и время возникновения
+func (q *Queue) Enqueue(ctx context.Context, event *domain.Event) error {
+	now := time.Now()
+	if event.ID == "" {
+		id, err := domain.NewEventID()
+		if err != nil {
+			return err
+		}
+		event.ID = id
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = now.UTC()
+	}
+
 	task := &WebhookTask{
-		LocationCheck: check,
-		Attempt:       0,
-		FirstAttempt:  time.Now(),
+		Event:        event,
+		Attempt:      0,
+		FirstAttempt: now,
 	}
 
 	data, err := json.Marshal(task)
 	if err != nil {
-		return fmt.Errorf("failed to marshal location check: %w", err)
+		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
 	if err := q.client.LPush(ctx, webhookQueueKey, data).Err(); err != nil {
-		return fmt.Errorf("failed to enqueue location check: %w", err)
+		return fmt.Errorf("failed to enqueue event: %w", err)
+	}
+	return nil
+}
+
+// Добавление нескольких тасков в обычную очередь одной командой
+func (q *Queue) EnqueueTasks(ctx context.Context, tasks []*WebhookTask) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	values := make([]any, len(tasks))
+	for i, task := range tasks {
+		data, err := json.Marshal(task)
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook task: %w", err)
+		}
+		values[i] = data
+	}
+
+	if err := q.client.LPush(ctx, webhookQueueKey, values...).Err(); err != nil {
+		return fmt.Errorf("failed to enqueue webhook tasks: %w", err)
 	}
 	return nil
 }
@@ -71,6 +240,9 @@ func (q *Queue) EnqueueWithDelay(ctx context.Context, task *WebhookTask, delay t
 
 // Добавление таска в dlq
 func (q *Queue) EnqueueDLQ(ctx context.Context, task *WebhookTask) error {
+	failedAt := time.Now()
+	task.FailedAt = &failedAt
+
 	data, err := json.Marshal(task)
 	if err != nil {
 		return fmt.Errorf("failed to marshal webhook task: %w", err)
@@ -82,39 +254,111 @@ func (q *Queue) EnqueueDLQ(ctx context.Context, task *WebhookTask) error {
 	return nil
 }
 
-// Логика обработки отложенный задач
-func (q *Queue) ProcessDelayedTasks(ctx context.Context) error {
-	now := time.Now().Unix()
+// ListDLQ возвращает страницу подходящих под фильтр записей DLQ, начиная с последних попавших, и их общее число
+func (q *Queue) ListDLQ(ctx context.Context, filter domain.DeadLetterFilter, offset, limit int) ([]domain.DeadLetter, int, error) {
+	entries, err := q.scanDLQ(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	tasks, err := q.client.ZRangeByScore(ctx, webhookDelayedKey, &redis.ZRangeBy{
-		Min:   "0",
-		Max:   fmt.Sprintf("%d", now),
-		Count: 100,
-	}).Result()
+	letters := make([]domain.DeadLetter, 0, limit)
+	for i := offset; i < len(entries) && len(letters) < limit; i++ {
+		letters = append(letters, entries[i].letter)
+	}
+	return letters, len(entries), nil
+}
 
+// ReplayDLQ переносит подходящие под фильтр записи DLQ в очередь со сброшенными попытками
+// и возвращает число перенесенных. Записи, которые не удалось разобрать, остаются в DLQ:
+// в очереди они сразу вернулись бы обратно
+func (q *Queue) ReplayDLQ(ctx context.Context, filter domain.DeadLetterFilter) (int, error) {
+	entries, err := q.scanDLQ(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("failed to get delayed tasks: %w", err)
+		return 0, err
 	}
 
-	for _, taskData := range tasks {
-		if err := q.client.ZRem(ctx, webhookDelayedKey, taskData).Err(); err != nil {
+	replayed := 0
+	for _, entry := range entries {
+		if entry.task == nil {
 			continue
 		}
 
-		if err := q.client.LPush(ctx, webhookQueueKey, taskData).Err(); err != nil {
-			q.client.ZAdd(ctx, webhookDelayedKey, redis.Z{
-				Score:  float64(now + 5),
-				Member: taskData,
-			})
+		data, err := json.Marshal(&WebhookTask{
+			Event:          entry.task.Event,
+			SubscriptionID: entry.task.SubscriptionID,
+			FirstAttempt:   time.Now(),
+		})
+		if err != nil {
+			return replayed, fmt.Errorf("failed to marshal webhook task: %w", err)
+		}
+
+		moved, err := replayDLQScript.Run(ctx, q.client, []string{webhookDLQKey, webhookQueueKey}, entry.raw, data).Int()
+		if err != nil {
+			return replayed, fmt.Errorf("failed to replay DLQ entry: %w", err)
+		}
+		replayed += moved
+	}
+	return replayed, nil
+}
+
+// DeleteDLQ удаляет подходящие под фильтр записи DLQ и возвращает число удаленных
+func (q *Queue) DeleteDLQ(ctx context.Context, filter domain.DeadLetterFilter) (int, error) {
+	entries, err := q.scanDLQ(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, entry := range entries {
+		removed, err := q.client.LRem(ctx, webhookDLQKey, 1, entry.raw).Result()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete DLQ entry: %w", err)
+		}
+		deleted += int(removed)
+	}
+	return deleted, nil
+}
+
+// scanDLQ читает DLQ целиком и отбирает записи по фильтру. Задачи старого формата разбираются
+// как события, а записи, которые не удалось разобрать, возвращаются в исходном виде, см. rawDeadLetter
+func (q *Queue) scanDLQ(ctx context.Context, filter domain.DeadLetterFilter) ([]dlqEntry, error) {
+	raws, err := q.client.LRange(ctx, webhookDLQKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DLQ: %w", err)
+	}
+
+	var entries []dlqEntry
+	for _, raw := range raws {
+		entry := dlqEntry{raw: raw}
+
+		var task WebhookTask
+		if err := json.Unmarshal([]byte(raw), &task); err != nil || task.Event == nil {
+			entry.letter = rawDeadLetter(raw)
+		} else {
+			entry.task, entry.letter = &task, task.deadLetter()
+		}
+
+		if !filter.Matches(&entry.letter) {
 			continue
 		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Логика обработки отложенный задач
+func (q *Queue) ProcessDelayedTasks(ctx context.Context) error {
+	now := time.Now().Unix()
+
+	if err := promoteDelayedScript.Run(ctx, q.client, []string{webhookDelayedKey, webhookQueueKey}, now, queueBatchSize).Err(); err != nil {
+		return fmt.Errorf("failed to promote delayed tasks: %w", err)
 	}
 	return nil
 }
 
-// Получение таска из очереди
+// Получение таска из очереди. Задача остается в списке обработки до Ack
 func (q *Queue) Dequeue(ctx context.Context) (*WebhookTask, error) {
-	result, err := q.client.BRPop(ctx, 1*time.Second, webhookQueueKey).Result()
+	raw, err := q.client.BLMove(ctx, webhookQueueKey, webhookProcessingKey, "RIGHT", "LEFT", 1*time.Second).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, nil
@@ -122,13 +366,69 @@ func (q *Queue) Dequeue(ctx context.Context) (*WebhookTask, error) {
 		return nil, fmt.Errorf("failed to dequeue webhook task: %w", err)
 	}
 
-	if len(result) < 2 {
-		return nil, fmt.Errorf("invalid queue result")
+	deadline := time.Now().Add(q.visibilityTimeout).UnixMilli()
+	if err := q.client.ZAdd(ctx, webhookLeasesKey, redis.Z{Score: float64(deadline), Member: raw}).Err(); err != nil {
+		return nil, fmt.Errorf("failed to lease webhook task: %w", err)
 	}
 
 	var task WebhookTask
-	if err := json.Unmarshal([]byte(result[1]), &task); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal webhook task: %w", err)
+	if err := json.Unmarshal([]byte(raw), &task); err != nil || task.Event == nil {
+		// Битую задачу невозможно обработать, повторная выдача ничего не изменит.
+		// Она переносится в DLQ как есть, чтобы ее можно было разобрать вручную
+		if err == nil {
+			err = errors.New("task has no event")
+		}
+		if dlqErr := q.deadLetterRaw(ctx, raw); dlqErr != nil {
+			return nil, fmt.Errorf("failed to move invalid webhook task to DLQ: %w", dlqErr)
+		}
+		return nil, fmt.Errorf("invalid webhook task moved to DLQ: %w", err)
 	}
+	task.receipt = raw
 	return &task, nil
 }
+
+// Ack подтверждает обработку задачи, полученной из Dequeue: после этого она не будет выдана повторно.
+// Подтверждать нужно после того, как задача отправлена, отложена на повтор или перенесена в DLQ
+func (q *Queue) Ack(ctx context.Context, task *WebhookTask) error {
+	if task.receipt == "" {
+		return nil
+	}
+	if err := q.release(ctx, task.receipt); err != nil {
+		return fmt.Errorf("failed to ack webhook task: %w", err)
+	}
+	return nil
+}
+
+// ReapExpired возвращает в очередь задачи, которые не подтвердили за visibilityTimeout, например
+// из-за падения воркера во время отправки. Возвращает число возвращенных задач
+func (q *Queue) ReapExpired(ctx context.Context) (int, error) {
+	now := time.Now().UnixMilli()
+	keys := []string{webhookProcessingKey, webhookLeasesKey, webhookQueueKey}
+
+	reaped, err := reapExpiredScript.Run(ctx, q.client, keys, now, q.visibilityTimeout.Milliseconds(), queueBatchSize).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to reap expired webhook tasks: %w", err)
+	}
+	return reaped, nil
+}
+
+// deadLetterRaw переносит задачу из списка обработки в DLQ без изменений
+func (q *Queue) deadLetterRaw(ctx context.Context, raw string) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, webhookProcessingKey, 1, raw)
+		pipe.ZRem(ctx, webhookLeasesKey, raw)
+		pipe.LPush(ctx, webhookDLQKey, raw)
+		return nil
+	})
+	return err
+}
+
+// release удаляет задачу из списка обработки вместе с ее сроком видимости
+func (q *Queue) release(ctx context.Context, raw string) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, webhookProcessingKey, 1, raw)
+		pipe.ZRem(ctx, webhookLeasesKey, raw)
+		return nil
+	})
+	return err
+}
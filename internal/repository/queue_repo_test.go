@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"red_collar/internal/domain"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,14 +26,18 @@ func TestQueueRepository_Enqueue(t *testing.T) {
 
 	tests := []struct {
 		name     string
-		input    *domain.LocationCheck
+		input    *domain.Event
 		validate func(t *testing.T)
 	}{
 		{
 			name: "success",
-			input: &domain.LocationCheck{
-				ID:     1,
-				UserID: "colorvax",
+			input: &domain.Event{
+				Type:       domain.EventZoneEntered,
+				IncidentID: 1,
+				LocationCheck: &domain.LocationCheck{
+					ID:     1,
+					UserID: "colorvax",
+				},
 			},
 			validate: func(t *testing.T) {
 				result, err := testRD.LPop(ctx, "webhook:queue").Result()
@@ -43,8 +48,12 @@ func TestQueueRepository_Enqueue(t *testing.T) {
 				require.NoError(t, err)
 
 				require.NotNil(t, webhookTask)
-				require.Equal(t, 1, webhookTask.LocationCheck.ID)
-				require.Equal(t, "colorvax", webhookTask.LocationCheck.UserID)
+				require.Len(t, webhookTask.Event.ID, 36, "event should get a uuid")
+				require.False(t, webhookTask.Event.OccurredAt.IsZero())
+				require.Equal(t, domain.EventZoneEntered, webhookTask.Event.Type)
+				require.Equal(t, 1, webhookTask.Event.IncidentID)
+				require.Equal(t, 1, webhookTask.Event.LocationCheck.ID)
+				require.Equal(t, "colorvax", webhookTask.Event.LocationCheck.UserID)
 			},
 		},
 	}
@@ -83,9 +92,12 @@ func TestQueueRepository_EnqueueWithDelay(t *testing.T) {
 		{
 			name: "success",
 			task: &WebhookTask{
-				LocationCheck: &domain.LocationCheck{
-					ID:     1,
-					UserID: "colorvax",
+				Event: &domain.Event{
+					Type: domain.EventZoneEntered,
+					LocationCheck: &domain.LocationCheck{
+						ID:     1,
+						UserID: "colorvax",
+					},
 				},
 			},
 			delay: 1 * time.Second,
@@ -104,8 +116,8 @@ func TestQueueRepository_EnqueueWithDelay(t *testing.T) {
 				err = json.Unmarshal([]byte(tasks[0]), &webhook)
 				require.NoError(t, err)
 
-				require.Equal(t, 1, webhook.LocationCheck.ID)
-				require.Equal(t, "colorvax", webhook.LocationCheck.UserID)
+				require.Equal(t, 1, webhook.Event.LocationCheck.ID)
+				require.Equal(t, "colorvax", webhook.Event.LocationCheck.UserID)
 			},
 		},
 	}
@@ -144,9 +156,12 @@ func TestQueueRepository_ProcessDelayedTasks(t *testing.T) {
 			name: "success",
 			setup: func(t *testing.T) {
 				task := &WebhookTask{
-					LocationCheck: &domain.LocationCheck{
-						ID:     1,
-						UserID: "colorvax",
+					Event: &domain.Event{
+						Type: domain.EventZoneEntered,
+						LocationCheck: &domain.LocationCheck{
+							ID:     1,
+							UserID: "colorvax",
+						},
 					},
 				}
 
@@ -169,8 +184,8 @@ func TestQueueRepository_ProcessDelayedTasks(t *testing.T) {
 				err = json.Unmarshal([]byte(res), &webhook)
 				require.NoError(t, err)
 
-				require.Equal(t, 1, webhook.LocationCheck.ID)
-				require.Equal(t, "colorvax", webhook.LocationCheck.UserID)
+				require.Equal(t, 1, webhook.Event.LocationCheck.ID)
+				require.Equal(t, "colorvax", webhook.Event.LocationCheck.UserID)
 			},
 		},
 	}
@@ -207,14 +222,18 @@ func TestQueueRepository_Dequeue(t *testing.T) {
 	tests := []struct {
 		name     string
 		setup    func(t *testing.T)
+		wantErr  bool
 		validate func(t *testing.T, res *WebhookTask)
 	}{
 		{
 			name: "success",
 			setup: func(t *testing.T) {
-				err := testQueueRepo.Enqueue(ctx, &domain.LocationCheck{
-					ID:     1,
-					UserID: "colorvax",
+				err := testQueueRepo.Enqueue(ctx, &domain.Event{
+					Type: domain.EventZoneEntered,
+					LocationCheck: &domain.LocationCheck{
+						ID:     1,
+						UserID: "colorvax",
+					},
 				})
 				require.NoError(t, err)
 			},
@@ -222,8 +241,68 @@ func TestQueueRepository_Dequeue(t *testing.T) {
 				_, err := testRD.LPop(ctx, "webhook:queue").Result()
 				require.Equal(t, redis.Nil, err)
 
-				require.Equal(t, 1, res.LocationCheck.ID)
-				require.Equal(t, "colorvax", res.LocationCheck.UserID)
+				require.Equal(t, 1, res.Event.LocationCheck.ID)
+				require.Equal(t, "colorvax", res.Event.LocationCheck.UserID)
+
+				// до подтверждения задача остается в обработке со сроком видимости
+				processingLen, err := testRD.LLen(ctx, "webhook:processing").Result()
+				require.NoError(t, err)
+				require.Equal(t, int64(1), processingLen)
+				leases, err := testRD.ZCard(ctx, "webhook:processing:leases").Result()
+				require.NoError(t, err)
+				require.Equal(t, int64(1), leases)
+
+				require.NoError(t, testQueueRepo.Ack(ctx, res))
+
+				processingLen, err = testRD.LLen(ctx, "webhook:processing").Result()
+				require.NoError(t, err)
+				require.Zero(t, processingLen)
+				leases, err = testRD.ZCard(ctx, "webhook:processing:leases").Result()
+				require.NoError(t, err)
+				require.Zero(t, leases)
+			},
+		},
+		{
+			name: "legacy location check payload",
+			setup: func(t *testing.T) {
+				payload := `{"location_check":{"id":5,"user_id":"colorvax","checked_at":"2026-10-17T09:30:00Z","lat":55.75,"long":37.61,"in_danger_zone":true,"nearest_id":2},"attempt":1,"first_attempt":"2026-10-17T09:30:01Z"}`
+				require.NoError(t, testRD.LPush(ctx, "webhook:queue", payload).Err())
+			},
+			validate: func(t *testing.T, res *WebhookTask) {
+				require.NotNil(t, res.Event)
+				require.Equal(t, "legacy-check-5", res.Event.ID)
+				require.Equal(t, domain.EventZoneEntered, res.Event.Type)
+				require.Equal(t, 2, res.Event.IncidentID)
+				require.Equal(t, time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC), res.Event.OccurredAt)
+				require.Equal(t, "colorvax", res.Event.LocationCheck.UserID)
+				require.Equal(t, 1, res.Attempt)
+				require.Nil(t, res.SubscriptionID)
+
+				require.NoError(t, testQueueRepo.Ack(ctx, res))
+				processingLen, err := testRD.LLen(ctx, "webhook:processing").Result()
+				require.NoError(t, err)
+				require.Zero(t, processingLen)
+			},
+		},
+		{
+			name: "payload without event moves to DLQ",
+			setup: func(t *testing.T) {
+				require.NoError(t, testRD.LPush(ctx, "webhook:queue", `{"attempt":0}`).Err())
+			},
+			wantErr: true,
+			validate: func(t *testing.T, res *WebhookTask) {
+				require.Nil(t, res)
+
+				processingLen, err := testRD.LLen(ctx, "webhook:processing").Result()
+				require.NoError(t, err)
+				require.Zero(t, processingLen)
+				leases, err := testRD.ZCard(ctx, "webhook:processing:leases").Result()
+				require.NoError(t, err)
+				require.Zero(t, leases)
+
+				dlq, err := testRD.LRange(ctx, "webhook:dlq", 0, -1).Result()
+				require.NoError(t, err)
+				require.Equal(t, []string{`{"attempt":0}`}, dlq)
 			},
 		},
 	}
@@ -237,7 +316,11 @@ func TestQueueRepository_Dequeue(t *testing.T) {
 			}
 
 			res, err := testQueueRepo.Dequeue(ctx)
-			require.NoError(t, err)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
 
 			if tt.validate != nil {
 				tt.validate(t, res)
@@ -245,3 +328,216 @@ func TestQueueRepository_Dequeue(t *testing.T) {
 		})
 	}
 }
+
+func TestQueueRepository_ReapExpired(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if testQueueRepo == nil {
+		setupQueue()
+	}
+
+	ctx := context.Background()
+	cleanupTestRD(t)
+
+	event := &domain.Event{Type: domain.EventZoneEntered, IncidentID: 1}
+	require.NoError(t, testQueueRepo.Enqueue(ctx, event))
+
+	// пока срок видимости не истек, задача остается в обработке
+	task, err := testQueueRepo.Dequeue(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	reaped, err := testQueueRepo.ReapExpired(ctx)
+	require.NoError(t, err)
+	require.Zero(t, reaped)
+
+	// срок видимости истек: воркер, забравший задачу, считается упавшим
+	require.NoError(t, testRD.ZAdd(ctx, "webhook:processing:leases", redis.Z{Score: 0, Member: task.receipt}).Err())
+
+	reaped, err = testQueueRepo.ReapExpired(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, reaped)
+
+	processingLen, err := testRD.LLen(ctx, "webhook:processing").Result()
+	require.NoError(t, err)
+	require.Zero(t, processingLen)
+
+	redelivered, err := testQueueRepo.Dequeue(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, redelivered)
+	require.Equal(t, event.ID, redelivered.Event.ID)
+
+	// задача без срока видимости (воркер упал между BLMOVE и ZADD) получает срок от текущего момента
+	// и не теряется. У очереди с нулевым сроком видимости такая задача сразу считается зависшей
+	require.NoError(t, testRD.ZRem(ctx, "webhook:processing:leases", redelivered.receipt).Err())
+	reaped, err = NewQueue(testRD, 0).ReapExpired(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, reaped)
+
+	queueLen, err := testRD.LLen(ctx, "webhook:queue").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), queueLen)
+}
+
+func TestQueueRepository_DLQ(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if testQueueRepo == nil {
+		setupQueue()
+	}
+
+	ctx := context.Background()
+
+	// fill кладет в DLQ по задаче на подписки 1 и 2 и задачу на подписку 1, попавшую в DLQ сутки назад
+	fill := func(t *testing.T) {
+		cleanupTestRD(t)
+
+		for _, subID := range []int{1, 2} {
+			id := subID
+			err := testQueueRepo.EnqueueDLQ(ctx, &WebhookTask{
+				Event:          &domain.Event{ID: "event-1", Type: domain.EventZoneEntered, IncidentID: 7},
+				SubscriptionID: &id,
+				Attempt:        3,
+				FirstAttempt:   time.Now(),
+				LastError:      "webhook returned status: 503",
+			})
+			require.NoError(t, err)
+		}
+
+		subID := 1
+		old := time.Now().Add(-24 * time.Hour)
+		data, err := json.Marshal(&WebhookTask{
+			Event:          &domain.Event{ID: "event-0", Type: domain.EventIncidentCreated, IncidentID: 3},
+			SubscriptionID: &subID,
+			Attempt:        1,
+			FirstAttempt:   old,
+			LastError:      "webhook returned status: 400",
+			FailedAt:       &old,
+		})
+		require.NoError(t, err)
+		require.NoError(t, testRD.RPush(ctx, "webhook:dlq", data).Err())
+	}
+
+	t.Run("list", func(t *testing.T) {
+		fill(t)
+
+		letters, total, err := testQueueRepo.ListDLQ(ctx, domain.DeadLetterFilter{}, 0, 2)
+		require.NoError(t, err)
+		require.Equal(t, 3, total)
+		require.Len(t, letters, 2)
+		require.Equal(t, "event-1:2", letters[0].ID)
+		require.Equal(t, 3, letters[0].Attempts)
+		require.Equal(t, "webhook returned status: 503", letters[0].LastError)
+
+		subID := 1
+		letters, total, err = testQueueRepo.ListDLQ(ctx, domain.DeadLetterFilter{SubscriptionID: &subID}, 1, 10)
+		require.NoError(t, err)
+		require.Equal(t, 2, total)
+		require.Len(t, letters, 1)
+		require.Equal(t, "event-0:1", letters[0].ID)
+	})
+
+	t.Run("replay one", func(t *testing.T) {
+		fill(t)
+
+		replayed, err := testQueueRepo.ReplayDLQ(ctx, domain.DeadLetterFilter{ID: "event-1:2"})
+		require.NoError(t, err)
+		require.Equal(t, 1, replayed)
+
+		res, err := testRD.LPop(ctx, "webhook:queue").Result()
+		require.NoError(t, err)
+
+		var task WebhookTask
+		require.NoError(t, json.Unmarshal([]byte(res), &task))
+		require.Equal(t, "event-1", task.Event.ID)
+		require.Equal(t, 2, *task.SubscriptionID)
+		require.Zero(t, task.Attempt)
+		require.Empty(t, task.LastError)
+		require.Nil(t, task.FailedAt)
+
+		dlqLen, err := testRD.LLen(ctx, "webhook:dlq").Result()
+		require.NoError(t, err)
+		require.Equal(t, int64(2), dlqLen)
+
+		// повтор уже перенесенной записи ничего не делает
+		replayed, err = testQueueRepo.ReplayDLQ(ctx, domain.DeadLetterFilter{ID: "event-1:2"})
+		require.NoError(t, err)
+		require.Zero(t, replayed)
+	})
+
+	t.Run("replay all", func(t *testing.T) {
+		fill(t)
+
+		replayed, err := testQueueRepo.ReplayDLQ(ctx, domain.DeadLetterFilter{})
+		require.NoError(t, err)
+		require.Equal(t, 3, replayed)
+
+		queueLen, err := testRD.LLen(ctx, "webhook:queue").Result()
+		require.NoError(t, err)
+		require.Equal(t, int64(3), queueLen)
+
+		dlqLen, err := testRD.LLen(ctx, "webhook:dlq").Result()
+		require.NoError(t, err)
+		require.Zero(t, dlqLen)
+	})
+
+	t.Run("delete older than cutoff", func(t *testing.T) {
+		fill(t)
+
+		cutoff := time.Now().Add(-time.Hour)
+		deleted, err := testQueueRepo.DeleteDLQ(ctx, domain.DeadLetterFilter{FailedBefore: &cutoff})
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+
+		letters, total, err := testQueueRepo.ListDLQ(ctx, domain.DeadLetterFilter{}, 0, 10)
+		require.NoError(t, err)
+		require.Equal(t, 2, total)
+		for _, letter := range letters {
+			require.Equal(t, "event-1", letter.Event.ID)
+		}
+	})
+
+	t.Run("legacy and undecodable entries", func(t *testing.T) {
+		cleanupTestRD(t)
+
+		legacy := `{"location_check":{"id":5,"user_id":"colorvax","checked_at":"2026-10-17T09:30:00Z","nearest_id":2},"attempt":3,"first_attempt":"2026-10-17T09:30:01Z","last_error":"webhook returned status: 503"}`
+		broken := `{"attempt":`
+		require.NoError(t, testRD.RPush(ctx, "webhook:dlq", legacy, broken).Err())
+
+		letters, total, err := testQueueRepo.ListDLQ(ctx, domain.DeadLetterFilter{}, 0, 10)
+		require.NoError(t, err)
+		require.Equal(t, 2, total)
+		require.Equal(t, "legacy-check-5", letters[0].ID)
+		require.Equal(t, domain.EventZoneEntered, letters[0].Event.Type)
+		require.Equal(t, 2, letters[0].Event.IncidentID)
+		require.Equal(t, 3, letters[0].Attempts)
+		require.Nil(t, letters[1].Event)
+		require.Equal(t, broken, letters[1].Raw)
+		require.True(t, strings.HasPrefix(letters[1].ID, "raw:"))
+
+		// неразобранную запись повторить нельзя, она остается в DLQ
+		replayed, err := testQueueRepo.ReplayDLQ(ctx, domain.DeadLetterFilter{})
+		require.NoError(t, err)
+		require.Equal(t, 1, replayed)
+
+		res, err := testRD.LPop(ctx, "webhook:queue").Result()
+		require.NoError(t, err)
+		var task WebhookTask
+		require.NoError(t, json.Unmarshal([]byte(res), &task))
+		require.Equal(t, "legacy-check-5", task.Event.ID)
+		require.Zero(t, task.Attempt)
+
+		cutoff := time.Now()
+		deleted, err := testQueueRepo.DeleteDLQ(ctx, domain.DeadLetterFilter{FailedBefore: &cutoff})
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+
+		dlqLen, err := testRD.LLen(ctx, "webhook:dlq").Result()
+		require.NoError(t, err)
+		require.Zero(t, dlqLen)
+	})
+}
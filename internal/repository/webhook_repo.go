@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"red_collar/internal/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// webhookSubscriptionColumns - колонки подписки. Истекший прежний секрет не возвращается
+const webhookSubscriptionColumns = `
+	id, url, secret,
+	CASE WHEN previous_secret_expires_at > NOW() THEN previous_secret END AS previous_secret,
+	CASE WHEN previous_secret_expires_at > NOW() THEN previous_secret_expires_at END AS previous_secret_expires_at,
+	enabled, event_types, incident_ids, severities,
+	bbox_min_long, bbox_min_lat, bbox_max_long, bbox_max_lat,
+	created_at, updated_at
+`
+
+// webhookSubscriptionRow - строка webhook_subscriptions: массивы и область хранятся в отдельных колонках
+type webhookSubscriptionRow struct {
+	ID     int    `db:"id"`
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+	// PreviousSecret/PreviousSecretExpiresAt заполнены только во время ротации
+	PreviousSecret          sql.NullString  `db:"previous_secret"`
+	PreviousSecretExpiresAt *time.Time      `db:"previous_secret_expires_at"`
+	Enabled                 bool            `db:"enabled"`
+	EventTypes              pq.StringArray  `db:"event_types"`
+	IncidentIDs             pq.Int64Array   `db:"incident_ids"`
+	Severities              pq.StringArray  `db:"severities"`
+	MinLong                 sql.NullFloat64 `db:"bbox_min_long"`
+	MinLat                  sql.NullFloat64 `db:"bbox_min_lat"`
+	MaxLong                 sql.NullFloat64 `db:"bbox_max_long"`
+	MaxLat                  sql.NullFloat64 `db:"bbox_max_lat"`
+	CreatedAt               time.Time       `db:"created_at"`
+	UpdatedAt               time.Time       `db:"updated_at"`
+}
+
+func (r *webhookSubscriptionRow) toDomain() domain.WebhookSubscription {
+	sub := domain.WebhookSubscription{
+		ID:                      r.ID,
+		URL:                     r.URL,
+		Secret:                  r.Secret,
+		PreviousSecret:          r.PreviousSecret.String,
+		PreviousSecretExpiresAt: r.PreviousSecretExpiresAt,
+		Enabled:                 r.Enabled,
+		EventTypes:              make([]domain.EventType, len(r.EventTypes)),
+		IncidentIDs:             make([]int, len(r.IncidentIDs)),
+		Severities:              make([]domain.Severity, len(r.Severities)),
+		CreatedAt:               r.CreatedAt,
+		UpdatedAt:               r.UpdatedAt,
+	}
+	for i, eventType := range r.EventTypes {
+		sub.EventTypes[i] = domain.EventType(eventType)
+	}
+	for i, id := range r.IncidentIDs {
+		sub.IncidentIDs[i] = int(id)
+	}
+	for i, severity := range r.Severities {
+		sub.Severities[i] = domain.Severity(severity)
+	}
+	if r.MinLong.Valid {
+		sub.BBox = &domain.BBox{
+			MinLong: r.MinLong.Float64,
+			MinLat:  r.MinLat.Float64,
+			MaxLong: r.MaxLong.Float64,
+			MaxLat:  r.MaxLat.Float64,
+		}
+	}
+	return sub
+}
+
+// webhookSubscriptionArgs - значения колонок подписки в порядке url, secret, enabled, фильтры, область
+func webhookSubscriptionArgs(sub *domain.WebhookSubscription) []any {
+	eventTypes := make([]string, len(sub.EventTypes))
+	for i, eventType := range sub.EventTypes {
+		eventTypes[i] = string(eventType)
+	}
+	incidentIDs := make([]int64, len(sub.IncidentIDs))
+	for i, id := range sub.IncidentIDs {
+		incidentIDs[i] = int64(id)
+	}
+	severities := make([]string, len(sub.Severities))
+	for i, severity := range sub.Severities {
+		severities[i] = string(severity)
+	}
+
+	var minLong, minLat, maxLong, maxLat *float64
+	if sub.BBox != nil {
+		minLong, minLat, maxLong, maxLat = &sub.BBox.MinLong, &sub.BBox.MinLat, &sub.BBox.MaxLong, &sub.BBox.MaxLat
+	}
+
+	return []any{
+		sub.URL, sub.Secret, sub.Enabled,
+		pq.Array(eventTypes), pq.Array(incidentIDs), pq.Array(severities),
+		minLong, minLat, maxLong, maxLat,
+	}
+}
+
+type WebhookSubscriptionRepository struct {
+	db *sqlx.DB
+}
+
+func NewWebhookSubscriptionRepository(db *sqlx.DB) *WebhookSubscriptionRepository {
+	return &WebhookSubscriptionRepository{
+		db: db,
+	}
+}
+
+func (wr *WebhookSubscriptionRepository) Create(ctx context.Context, sub *domain.WebhookSubscription) error {
+	createQuery := `
+		INSERT INTO webhook_subscriptions (
+			url, secret, enabled, event_types, incident_ids, severities,
+			bbox_min_long, bbox_min_lat, bbox_max_long, bbox_max_lat
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`
+
+	return wr.db.QueryRowContext(ctx, createQuery, webhookSubscriptionArgs(sub)...).
+		Scan(&sub.ID, &sub.CreatedAt, &sub.UpdatedAt)
+}
+
+func (wr *WebhookSubscriptionRepository) GetByID(ctx context.Context, id int) (*domain.WebhookSubscription, error) {
+	getQuery := `
+		SELECT ` + webhookSubscriptionColumns + `
+		FROM webhook_subscriptions
+		WHERE id = $1
+	`
+
+	var row webhookSubscriptionRow
+	if err := wr.db.GetContext(ctx, &row, getQuery, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrNotFound("webhook subscription not found")
+		}
+		return nil, err
+	}
+
+	sub := row.toDomain()
+	return &sub, nil
+}
+
+// List возвращает все подписки, enabledOnly - только включенные
+func (wr *WebhookSubscriptionRepository) List(ctx context.Context, enabledOnly bool) ([]domain.WebhookSubscription, error) {
+	listQuery := `
+		SELECT ` + webhookSubscriptionColumns + `
+		FROM webhook_subscriptions
+		WHERE enabled OR NOT $1
+		ORDER BY id
+	`
+
+	var rows []webhookSubscriptionRow
+	if err := wr.db.SelectContext(ctx, &rows, listQuery, enabledOnly); err != nil {
+		return nil, err
+	}
+
+	subs := make([]domain.WebhookSubscription, len(rows))
+	for i := range rows {
+		subs[i] = rows[i].toDomain()
+	}
+	return subs, nil
+}
+
+// Update заменяет адрес, флаг и фильтры подписки. Пустой Secret оставляет текущий секрет
+func (wr *WebhookSubscriptionRepository) Update(ctx context.Context, sub *domain.WebhookSubscription) error {
+	updateQuery := `
+		UPDATE webhook_subscriptions
+		SET url = $1,
+			secret = COALESCE(NULLIF($2, ''), secret),
+			enabled = $3,
+			event_types = $4,
+			incident_ids = $5,
+			severities = $6,
+			bbox_min_long = $7,
+			bbox_min_lat = $8,
+			bbox_max_long = $9,
+			bbox_max_lat = $10,
+			updated_at = NOW()
+		WHERE id = $11
+		RETURNING created_at, updated_at
+	`
+
+	args := append(webhookSubscriptionArgs(sub), sub.ID)
+	err := wr.db.QueryRowContext(ctx, updateQuery, args...).Scan(&sub.CreatedAt, &sub.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return domain.ErrNotFound("webhook subscription not found")
+	}
+	return err
+}
+
+func (wr *WebhookSubscriptionRepository) Delete(ctx context.Context, id int) error {
+	res, err := wr.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrNotFound("webhook subscription not found")
+	}
+	return nil
+}
+
+// RotateSecret заменяет секрет подписки. Прежний секрет действует еще grace, и все это время
+// запросы подписываются обоими секретами
+func (wr *WebhookSubscriptionRepository) RotateSecret(ctx context.Context, id int, secret string, grace time.Duration) (*domain.WebhookSubscription, error) {
+	rotateQuery := `
+		UPDATE webhook_subscriptions
+		SET previous_secret = secret,
+			previous_secret_expires_at = NOW() + $3 * INTERVAL '1 second',
+			secret = $2,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + webhookSubscriptionColumns
+
+	var row webhookSubscriptionRow
+	if err := wr.db.GetContext(ctx, &row, rotateQuery, id, secret, grace.Seconds()); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrNotFound("webhook subscription not found")
+		}
+		return nil, err
+	}
+
+	sub := row.toDomain()
+	return &sub, nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"red_collar/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSubscriptionRepository_CRUD(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping intergration test")
+	}
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	ctx := context.Background()
+	cleanupTestDB(t)
+
+	repo := NewWebhookSubscriptionRepository(testDB)
+
+	filtered := &domain.WebhookSubscription{
+		URL:         "https://example.com/floods",
+		Secret:      "0123456789abcdef",
+		Enabled:     true,
+		EventTypes:  []domain.EventType{domain.EventZoneEntered, domain.EventZoneExited},
+		IncidentIDs: []int{3, 7},
+		Severities:  []domain.Severity{domain.SeverityDanger},
+		BBox:        &domain.BBox{MinLong: 37, MinLat: 55, MaxLong: 38, MaxLat: 56},
+	}
+	err := repo.Create(ctx, filtered)
+	require.NoError(t, err)
+	require.NotZero(t, filtered.ID)
+	require.False(t, filtered.CreatedAt.IsZero())
+
+	disabled := &domain.WebhookSubscription{
+		URL:    "https://example.com/all",
+		Secret: "fedcba9876543210",
+	}
+	err = repo.Create(ctx, disabled)
+	require.NoError(t, err)
+
+	got, err := repo.GetByID(ctx, filtered.ID)
+	require.NoError(t, err)
+	require.Equal(t, filtered.URL, got.URL)
+	require.Equal(t, filtered.Secret, got.Secret)
+	require.Equal(t, filtered.EventTypes, got.EventTypes)
+	require.Equal(t, filtered.IncidentIDs, got.IncidentIDs)
+	require.Equal(t, filtered.Severities, got.Severities)
+	require.Equal(t, filtered.BBox, got.BBox)
+
+	all, err := repo.List(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	require.Nil(t, all[1].BBox)
+	require.Empty(t, all[1].EventTypes)
+
+	enabled, err := repo.List(ctx, true)
+	require.NoError(t, err)
+	require.Len(t, enabled, 1)
+	require.Equal(t, filtered.ID, enabled[0].ID)
+
+	// обновление без секрета сохраняет прежний секрет и снимает фильтры
+	update := &domain.WebhookSubscription{ID: filtered.ID, URL: "https://example.com/v2", Enabled: true}
+	err = repo.Update(ctx, update)
+	require.NoError(t, err)
+
+	got, err = repo.GetByID(ctx, filtered.ID)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/v2", got.URL)
+	require.Equal(t, filtered.Secret, got.Secret)
+	require.Empty(t, got.IncidentIDs)
+	require.Nil(t, got.BBox)
+
+	err = repo.Update(ctx, &domain.WebhookSubscription{ID: 999, URL: "https://example.com"})
+	var appErr *domain.AppError
+	require.True(t, errors.As(err, &appErr) && appErr.Code == domain.CodeNotFound, "missing subscription must not be updated")
+
+	err = repo.Delete(ctx, filtered.ID)
+	require.NoError(t, err)
+
+	_, err = repo.GetByID(ctx, filtered.ID)
+	require.True(t, errors.As(err, &appErr) && appErr.Code == domain.CodeNotFound, "deleted subscription must not be returned")
+
+	err = repo.Delete(ctx, filtered.ID)
+	require.True(t, errors.As(err, &appErr) && appErr.Code == domain.CodeNotFound, "subscription is already deleted")
+}
+
+func TestWebhookSubscriptionRepository_RotateSecret(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping intergration test")
+	}
+
+	if testDB == nil {
+		setupTestDB(t)
+	}
+
+	ctx := context.Background()
+	cleanupTestDB(t)
+
+	repo := NewWebhookSubscriptionRepository(testDB)
+
+	sub := &domain.WebhookSubscription{URL: "https://example.com/webhook", Secret: "old-secret-0123456", Enabled: true}
+	err := repo.Create(ctx, sub)
+	require.NoError(t, err)
+
+	rotated, err := repo.RotateSecret(ctx, sub.ID, "new-secret-0123456", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, "new-secret-0123456", rotated.Secret)
+	require.Equal(t, "old-secret-0123456", rotated.PreviousSecret)
+	require.NotNil(t, rotated.PreviousSecretExpiresAt)
+	require.Equal(t, []string{"new-secret-0123456", "old-secret-0123456"}, rotated.SigningSecrets())
+
+	enabled, err := repo.List(ctx, true)
+	require.NoError(t, err)
+	require.Len(t, enabled, 1)
+	require.Equal(t, "old-secret-0123456", enabled[0].PreviousSecret)
+
+	// без периода ожидания прежний секрет сразу перестает действовать
+	rotated, err = repo.RotateSecret(ctx, sub.ID, "newest-secret-0123", 0)
+	require.NoError(t, err)
+	require.Empty(t, rotated.PreviousSecret)
+	require.Nil(t, rotated.PreviousSecretExpiresAt)
+	require.Equal(t, []string{"newest-secret-0123"}, rotated.SigningSecrets())
+
+	_, err = repo.RotateSecret(ctx, 999, "newest-secret-0123", time.Hour)
+	var appErr *domain.AppError
+	require.True(t, errors.As(err, &appErr) && appErr.Code == domain.CodeNotFound, "missing subscription must not be rotated")
+}
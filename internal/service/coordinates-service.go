@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"red_collar/internal/domain"
+	"slices"
+	"time"
 
 	"github.com/theartofdevel/logging"
 )
@@ -22,7 +24,7 @@ func (s *Service) CheckCoordinates(ctx context.Context, in *CheckCoordinatesRequ
 
 	check := mapCheckInputToDomain(in)
 
-	err := s.coordinates.Check(ctx, check)
+	err := s.coordinates.Check(ctx, check, s.checkParams())
 	if err != nil {
 		s.logger.Error("check coordinates request repository error",
 			logging.StringAttr("userID", in.UserID),
@@ -34,21 +36,64 @@ func (s *Service) CheckCoordinates(ctx context.Context, in *CheckCoordinatesRequ
 		logging.StringAttr("user", in.UserID),
 	)
 
-	if check.InDangerZone {
-		if err := s.queue.Enqueue(ctx, check); err != nil {
-			s.logger.Error("failed to enqueue webhook task",
-				logging.StringAttr("userID", in.UserID),
-				logging.ErrAttr(err),
-			)
-		} else {
-			s.logger.Info("webhook task enqueued",
-				logging.StringAttr("userID", in.UserID),
-			)
-		}
-	}
+	events := s.trackZoneTransitions(ctx, check)
+	s.enqueueEvents(ctx, in.UserID, events)
 	return check, nil
 }
 
+// CheckCoordinatesBatch проверяет пачку точек разных пользователей за один проход по БД.
+// Результаты возвращаются в порядке входных точек, а сохраняются точки и обрабатываются переходы
+// между зонами в порядке времени проверки, поэтому точки одного пользователя могут идти в любом порядке
+func (s *Service) CheckCoordinatesBatch(ctx context.Context, in *CheckCoordinatesBatchRequestInput) ([]*domain.LocationCheck, error) {
+	checkedAts, err := validateCheckBatchInput(in, s.opts.BatchMaxPoints, time.Now())
+	if err != nil {
+		s.logger.Error("check coordinates batch request validation failed",
+			logging.IntAttr("points", len(in.Points)),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to check coordinates batch", logging.IntAttr("points", len(in.Points)))
+
+	checks := make([]*domain.LocationCheck, len(in.Points))
+	for i := range in.Points {
+		checks[i] = mapCheckInputToDomain(&in.Points[i])
+		checks[i].CheckedAt = checkedAts[i]
+	}
+
+	chronological := slices.Clone(checks)
+	slices.SortStableFunc(chronological, func(a, b *domain.LocationCheck) int {
+		return a.CheckedAt.Compare(b.CheckedAt)
+	})
+
+	err = s.coordinates.CheckBatch(ctx, chronological, s.checkParams())
+	if err != nil {
+		s.logger.Error("check coordinates batch request repository error",
+			logging.IntAttr("points", len(in.Points)),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("location batch was successfully checked",
+		logging.IntAttr("points", len(in.Points)),
+	)
+
+	for _, check := range chronological {
+		events := s.trackZoneTransitions(ctx, check)
+		s.enqueueEvents(ctx, check.UserID, events)
+	}
+	return checks, nil
+}
+
+func (s *Service) checkParams() domain.CheckParams {
+	return domain.CheckParams{
+		WarningBufferM: s.opts.WarningBufferM,
+		MaxSegmentGap:  s.opts.MaxSegmentGap,
+	}
+}
+
 func (s *Service) GetStats(ctx context.Context, timeWindowMinutes int) ([]domain.ZoneStat, error) {
 	s.logger.Info("attempt to get stats")
 
@@ -63,3 +108,210 @@ func (s *Service) GetStats(ctx context.Context, timeWindowMinutes int) ([]domain
 	s.logger.Info("successfully got stats")
 	return zones, nil
 }
+
+// GetStatsSeries возвращает временные ряды статистики попаданий в зоны за период, по одному на зону.
+// Если задана зона, ряд для нее возвращается и тогда, когда попаданий не было
+func (s *Service) GetStatsSeries(ctx context.Context, in *StatsSeriesRequestInput) (*StatsSeriesOutput, error) {
+	filter, bucket, err := validateStatsSeriesInput(in, time.Now())
+	if err != nil {
+		s.logger.Error("get stats series validation failed",
+			logging.StringAttr("from", in.From),
+			logging.StringAttr("to", in.To),
+			logging.StringAttr("bucket", in.Bucket),
+			logging.StringAttr("incidentID", in.IncidentID),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to get stats series",
+		logging.StringAttr("bucket", bucket),
+		logging.StringAttr("incidentID", in.IncidentID),
+	)
+
+	buckets, err := s.coordinates.StatsSeries(ctx, filter)
+	if err != nil {
+		s.logger.Error("get stats series repository error",
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	out := &StatsSeriesOutput{
+		From:   filter.From,
+		To:     filter.To,
+		Bucket: bucket,
+		Series: []ZoneStatSeries{},
+	}
+	if filter.IncidentID != nil {
+		out.Series = append(out.Series, ZoneStatSeries{IncidentID: *filter.IncidentID, Buckets: []domain.ZoneStatBucket{}})
+	}
+	// Репозиторий отдает интервалы, отсортированные по зоне и времени
+	for _, b := range buckets {
+		last := len(out.Series) - 1
+		if last < 0 || out.Series[last].IncidentID != b.IncidentID {
+			out.Series = append(out.Series, ZoneStatSeries{IncidentID: b.IncidentID})
+			last++
+		}
+		out.Series[last].Buckets = append(out.Series[last].Buckets, b)
+	}
+
+	s.logger.Info("stats series were successfully got",
+		logging.IntAttr("series", len(out.Series)),
+		logging.IntAttr("buckets", len(buckets)),
+	)
+	return out, nil
+}
+
+// GetHeatmap считает проверки в ячейках сетки над областью за период
+func (s *Service) GetHeatmap(ctx context.Context, in *HeatmapRequestInput) (*HeatmapOutput, error) {
+	filter, err := validateHeatmapInput(in, time.Now())
+	if err != nil {
+		s.logger.Error("get heatmap validation failed",
+			logging.StringAttr("bbox", in.BBox),
+			logging.StringAttr("grid", in.Grid),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to get heatmap",
+		logging.StringAttr("bbox", in.BBox),
+		logging.StringAttr("grid", string(filter.Grid)),
+	)
+
+	cells, err := s.coordinates.Heatmap(ctx, filter)
+	if err != nil {
+		s.logger.Error("get heatmap repository error",
+			logging.StringAttr("bbox", in.BBox),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+	if cells == nil {
+		cells = []domain.HeatmapCell{}
+	}
+
+	out := &HeatmapOutput{
+		Grid:         filter.Grid,
+		Precision:    filter.Precision,
+		SizeM:        filter.SizeM,
+		From:         filter.From,
+		To:           filter.To,
+		InDangerOnly: filter.InDangerOnly,
+		Cells:        cells,
+	}
+	for _, cell := range cells {
+		out.Total += cell.Count
+	}
+
+	s.logger.Info("heatmap was successfully got",
+		logging.IntAttr("cells", len(cells)),
+		logging.IntAttr("total", out.Total),
+	)
+	return out, nil
+}
+
+// GetHeatmapGeoJSON возвращает ту же тепловую карту, что и GetHeatmap, в виде GeoJSON
+func (s *Service) GetHeatmapGeoJSON(ctx context.Context, in *HeatmapRequestInput) (*HeatmapGeoJSONOutput, error) {
+	out, err := s.GetHeatmap(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return buildHeatmapGeoJSON(out), nil
+}
+
+// GetIncidentOccupants возвращает пользователей, которые сейчас находятся в зоне инцидента:
+// их последняя проверка попадает в зону и сделана не раньше, чем окно назад
+func (s *Service) GetIncidentOccupants(ctx context.Context, in *IncidentOccupantsRequestInput) (*IncidentOccupantsOutput, error) {
+	id, window, err := validateOccupantsInput(in, s.opts.OccupantWindowMins)
+	if err != nil {
+		s.logger.Error("get incident occupants validation failed",
+			logging.StringAttr("id", in.ID),
+			logging.StringAttr("minutes", in.Minutes),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to get incident occupants",
+		logging.IntAttr("id", id),
+		logging.IntAttr("windowMinutes", window),
+	)
+
+	occupants, err := s.coordinates.ZoneOccupants(ctx, id, window)
+	if err != nil {
+		s.logger.Error("get incident occupants repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+	if occupants == nil {
+		occupants = []domain.ZoneOccupant{}
+	}
+
+	s.logger.Info("incident occupants were successfully got",
+		logging.IntAttr("id", id),
+		logging.IntAttr("count", len(occupants)),
+	)
+	return &IncidentOccupantsOutput{
+		IncidentID:    id,
+		WindowMinutes: window,
+		Count:         len(occupants),
+		Occupants:     occupants,
+	}, nil
+}
+
+// GetUserChecks возвращает историю проверок пользователя с зонами, в которые попала каждая точка.
+// Страницы отдаются по курсору (checked_at, id), чтобы новые проверки не сдвигали уже полученные
+func (s *Service) GetUserChecks(ctx context.Context, in *UserChecksRequestInput) (*UserChecksOutput, error) {
+	filter, limit, err := validateUserChecksInput(in)
+	if err != nil {
+		s.logger.Error("get user checks validation failed",
+			logging.StringAttr("userID", in.UserID),
+			logging.StringAttr("from", in.From),
+			logging.StringAttr("to", in.To),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to get user checks",
+		logging.StringAttr("userID", in.UserID),
+		logging.IntAttr("limit", limit),
+	)
+
+	checks, err := s.coordinates.UserChecks(ctx, filter, limit+1)
+	if err != nil {
+		s.logger.Error("get user checks repository error",
+			logging.StringAttr("userID", in.UserID),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	out := &UserChecksOutput{Checks: checks}
+	if len(checks) > limit {
+		out.Checks = checks[:limit]
+		out.NextCursor = encodeCheckCursor(checks[limit-1], filter.SortAsc)
+	}
+	if out.Checks == nil {
+		out.Checks = []domain.LocationCheck{}
+	}
+
+	s.logger.Info("user checks were successfully got",
+		logging.StringAttr("userID", in.UserID),
+		logging.IntAttr("count", len(out.Checks)),
+	)
+	return out, nil
+}
+
+// GetUserTrack возвращает ту же страницу истории, что и GetUserChecks, в виде GeoJSON трека
+func (s *Service) GetUserTrack(ctx context.Context, in *UserChecksRequestInput) (*UserTrackOutput, error) {
+	out, err := s.GetUserChecks(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return buildUserTrack(in.UserID, out), nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"red_collar/internal/domain"
 	"testing"
@@ -16,6 +17,7 @@ func TestService_CheckCoordinates(t *testing.T) {
 		input           *CheckCoordinatesRequestInput
 		coordinatesMock func() *mockCoordinatesRepository
 		queueMock       func() *mockQueue
+		membershipMock  func() *mockMembership
 		wantErr         bool
 		errType         func(err error) bool
 		validateResult  func(t *testing.T, result *domain.LocationCheck)
@@ -84,7 +86,7 @@ func TestService_CheckCoordinates(t *testing.T) {
 			},
 			coordinatesMock: func() *mockCoordinatesRepository {
 				return &mockCoordinatesRepository{
-					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck) error {
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
 						return errors.New("failed database connection")
 					},
 				}
@@ -111,7 +113,7 @@ func TestService_CheckCoordinates(t *testing.T) {
 			},
 			coordinatesMock: func() *mockCoordinatesRepository {
 				return &mockCoordinatesRepository{
-					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck) error {
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
 						locCheck.ID = 1
 						locCheck.CheckedAt = time.Now()
 						locCheck.InDangerZone = false
@@ -148,19 +150,20 @@ func TestService_CheckCoordinates(t *testing.T) {
 			},
 			coordinatesMock: func() *mockCoordinatesRepository {
 				return &mockCoordinatesRepository{
-					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck) error {
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
 						locCheck.ID = 1
 						locCheck.CheckedAt = time.Now()
 						locCheck.InDangerZone = true
 						nearestID := 10
 						locCheck.NearestID = &nearestID
+						locCheck.Matches = []domain.IncidentMatch{{IncidentID: 10, DistanceM: 12.5}}
 						return nil
 					},
 				}
 			},
 			queueMock: func() *mockQueue {
 				return &mockQueue{
-					enqueueFunc: func(ctx context.Context, check *domain.LocationCheck) error {
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
 
 						return nil
 					},
@@ -191,19 +194,20 @@ func TestService_CheckCoordinates(t *testing.T) {
 			},
 			coordinatesMock: func() *mockCoordinatesRepository {
 				return &mockCoordinatesRepository{
-					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck) error {
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
 						locCheck.ID = 1
 						locCheck.CheckedAt = time.Now()
 						locCheck.InDangerZone = true
 						nearestID := 10
 						locCheck.NearestID = &nearestID
+						locCheck.Matches = []domain.IncidentMatch{{IncidentID: 10, DistanceM: 12.5}}
 						return nil
 					},
 				}
 			},
 			queueMock: func() *mockQueue {
 				return &mockQueue{
-					enqueueFunc: func(ctx context.Context, check *domain.LocationCheck) error {
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
 						return errors.New("failed queue connection")
 					},
 				}
@@ -224,39 +228,945 @@ func TestService_CheckCoordinates(t *testing.T) {
 				require.Equal(t, "failed to enqueue webhook task", errorLogs[0].msg)
 			},
 		},
+		{
+			name: "success - still in danger zone, no webhook",
+			input: &CheckCoordinatesRequestInput{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   40,
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
+						locCheck.ID = 2
+						locCheck.CheckedAt = time.Now()
+						locCheck.InDangerZone = true
+						nearestID := 10
+						locCheck.NearestID = &nearestID
+						locCheck.Matches = []domain.IncidentMatch{{IncidentID: 10, DistanceM: 12.5}}
+						return nil
+					},
+				}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						return errors.New("should not be called")
+					},
+				}
+			},
+			membershipMock: func() *mockMembership {
+				return &mockMembership{
+					updateFunc: func(ctx context.Context, userID string, checkedAt time.Time, fn func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership) error {
+						fn(map[int]domain.ZoneMembership{
+							10: {EnteredAt: time.Now().Add(-time.Minute)},
+						})
+						return nil
+					},
+				}
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				require.Len(t, logger.GetInfoLogs(), 2, "should log attempt and success only")
+				require.Empty(t, logger.GetErrorLogs())
+			},
+		},
+		{
+			name: "success - concurrent check already recorded the entry, no webhook",
+			input: &CheckCoordinatesRequestInput{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   40,
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
+						locCheck.CheckedAt = time.Now()
+						locCheck.InDangerZone = true
+						locCheck.Matches = []domain.IncidentMatch{{IncidentID: 10, DistanceM: 12.5}}
+						return nil
+					},
+				}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						return errors.New("should not be called")
+					},
+				}
+			},
+			membershipMock: func() *mockMembership {
+				return &mockMembership{
+					updateFunc: func(ctx context.Context, userID string, checkedAt time.Time, fn func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership) error {
+						// первая попытка проиграла параллельной проверке и повторяется на свежем состоянии
+						fn(map[int]domain.ZoneMembership{})
+						fn(map[int]domain.ZoneMembership{
+							10: {EnteredAt: time.Now().Add(-time.Second)},
+						})
+						return nil
+					},
+				}
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				require.Len(t, logger.GetInfoLogs(), 2, "should log attempt and success only")
+				require.Empty(t, logger.GetErrorLogs())
+			},
+		},
+		{
+			name: "success - point older than zone state, no webhook",
+			input: &CheckCoordinatesRequestInput{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   40,
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
+						locCheck.CheckedAt = time.Now().Add(-time.Hour)
+						locCheck.InDangerZone = true
+						locCheck.Matches = []domain.IncidentMatch{{IncidentID: 10, DistanceM: 12.5}}
+						return nil
+					},
+				}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						return errors.New("should not be called")
+					},
+				}
+			},
+			membershipMock: func() *mockMembership {
+				stateCheckedAt := time.Now().Add(-time.Minute)
+				return &mockMembership{
+					updateFunc: func(ctx context.Context, userID string, checkedAt time.Time, fn func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership) error {
+						if checkedAt.Before(stateCheckedAt) {
+							return nil
+						}
+						fn(map[int]domain.ZoneMembership{})
+						return nil
+					},
+				}
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				require.Len(t, logger.GetInfoLogs(), 2, "should log attempt and success only")
+				require.Empty(t, logger.GetErrorLogs())
+			},
+		},
+		{
+			name: "success - left danger zone, exit webhook",
+			input: &CheckCoordinatesRequestInput{
+				UserID: "colorvax",
+				Lat:    45,
+				Long:   30,
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
+						locCheck.ID = 3
+						locCheck.CheckedAt = time.Now()
+						return nil
+					},
+				}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						if event.Type != domain.EventZoneExited || event.IncidentID != 10 {
+							return errors.New("unexpected event")
+						}
+						return nil
+					},
+				}
+			},
+			membershipMock: func() *mockMembership {
+				return &mockMembership{
+					updateFunc: func(ctx context.Context, userID string, checkedAt time.Time, fn func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership) error {
+						fn(map[int]domain.ZoneMembership{
+							10: {EnteredAt: time.Now().Add(-time.Minute)},
+						})
+						return nil
+					},
+				}
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				require.Len(t, logger.GetInfoLogs(), 3, "should log attempt, success, and enqueue")
+				require.Empty(t, logger.GetErrorLogs())
+			},
+		},
+		{
+			name: "success - overlapping zones, webhook per zone",
+			input: &CheckCoordinatesRequestInput{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   40,
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
+						locCheck.ID = 4
+						locCheck.CheckedAt = time.Now()
+						locCheck.InDangerZone = true
+						nearestID := 10
+						locCheck.NearestID = &nearestID
+						locCheck.Matches = []domain.IncidentMatch{
+							{IncidentID: 10, DistanceM: 5},
+							{IncidentID: 11, DistanceM: 40},
+							{IncidentID: 12, DistanceM: 300},
+						}
+						return nil
+					},
+				}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						if event.Type != domain.EventZoneEntered || len(event.LocationCheck.Matches) != 3 {
+							return errors.New("unexpected event")
+						}
+						return nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *domain.LocationCheck) {
+				require.Len(t, result.Matches, 3)
+				require.Equal(t, 10, *result.NearestID)
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				require.Len(t, logger.GetInfoLogs(), 5, "should log attempt, success and 3 enqueues")
+				require.Empty(t, logger.GetErrorLogs())
+			},
+		},
+		{
+			name: "success - approaching zone, warning webhook",
+			input: &CheckCoordinatesRequestInput{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   40,
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
+						if params.WarningBufferM != 200 {
+							return errors.New("unexpected warning buffer")
+						}
+						locCheck.ID = 5
+						locCheck.CheckedAt = time.Now()
+						locCheck.Approaching = []domain.ProximityWarning{
+							{IncidentID: 10, DistanceToEdgeM: 80, BearingDeg: 90},
+						}
+						return nil
+					},
+				}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						if event.Type != domain.EventZoneApproaching || event.IncidentID != 10 {
+							return errors.New("unexpected event")
+						}
+						return nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *domain.LocationCheck) {
+				require.False(t, result.InDangerZone)
+				require.Len(t, result.Approaching, 1)
+				require.Equal(t, 80.0, result.Approaching[0].DistanceToEdgeM)
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				require.Len(t, logger.GetInfoLogs(), 3, "should log attempt, success, and enqueue")
+				require.Empty(t, logger.GetErrorLogs())
+			},
+		},
+		{
+			name: "success - zones crossed between pings",
+			input: &CheckCoordinatesRequestInput{
+				UserID: "colorvax",
+				Lat:    50,
+				Long:   40,
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					checkFunc: func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
+						locCheck.ID = 6
+						locCheck.CheckedAt = time.Now()
+						locCheck.Crossed = []domain.ZoneCrossing{
+							{IncidentID: 12, EstimatedEntryAt: locCheck.CheckedAt.Add(-2 * time.Minute)},
+							{IncidentID: 11, EstimatedEntryAt: locCheck.CheckedAt.Add(-time.Minute)},
+						}
+						return nil
+					},
+				}
+			},
+			queueMock: func() *mockQueue {
+				var enqueued []int
+				return &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						if event.Type != domain.EventZoneCrossed {
+							return errors.New("unexpected event")
+						}
+						enqueued = append(enqueued, event.IncidentID)
+						if len(enqueued) == 2 && enqueued[0] != 12 {
+							return errors.New("crossings must keep path order")
+						}
+						return nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *domain.LocationCheck) {
+				require.False(t, result.InDangerZone)
+				require.Len(t, result.Crossed, 2)
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				require.Len(t, logger.GetInfoLogs(), 4, "should log attempt, success and 2 enqueues")
+				require.Empty(t, logger.GetErrorLogs())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			mockLog := &mockLogger{}
+
+			membership := &mockMembership{}
+			if tt.membershipMock != nil {
+				membership = tt.membershipMock()
+			}
+
+			service := &Service{
+				coordinates: tt.coordinatesMock(),
+				queue:       tt.queueMock(),
+				membership:  membership,
+				logger:      mockLog,
+				opts:        Options{WarningBufferM: 200},
+			}
+
+			result, err := service.CheckCoordinates(ctx, tt.input)
+
+			if tt.validateLogs != nil {
+				tt.validateLogs(t, mockLog)
+			}
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				if tt.errType != nil {
+					require.True(t, tt.errType(err), "wrong error type: %v", err)
+				}
+				require.Nil(t, result, "result should be nil on error")
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.NotNil(t, result, "expected result, got nil")
+
+			if tt.validateResult != nil {
+				tt.validateResult(t, result)
+			}
+		})
+	}
+}
+
+func TestService_CheckCoordinatesBatch(t *testing.T) {
+	isValidationErr := func(err error) bool {
+		var appErr *domain.AppError
+		return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+	}
+
+	tests := []struct {
+		name            string
+		input           *CheckCoordinatesBatchRequestInput
+		coordinatesMock func() *mockCoordinatesRepository
+		queueMock       func() *mockQueue
+		wantErr         bool
+		errType         func(err error) bool
+		validateResult  func(t *testing.T, result []*domain.LocationCheck)
+		validateLogs    func(t *testing.T, logger *mockLogger)
+	}{
+		{
+			name:  "validation error - no points",
+			input: &CheckCoordinatesBatchRequestInput{},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{}
+			},
+			wantErr: true,
+			errType: isValidationErr,
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				errorLogs := logger.GetErrorLogs()
+				require.Len(t, errorLogs, 1)
+				require.Equal(t, "check coordinates batch request validation failed", errorLogs[0].msg)
+			},
+		},
+		{
+			name: "validation error - too many points",
+			input: &CheckCoordinatesBatchRequestInput{
+				Points: []CheckCoordinatesRequestInput{
+					{UserID: "colorvax", Lat: 1, Long: 1},
+					{UserID: "colorvax", Lat: 2, Long: 2},
+					{UserID: "colorvax", Lat: 3, Long: 3},
+				},
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{}
+			},
+			wantErr: true,
+			errType: isValidationErr,
+		},
+		{
+			name: "validation error - invalid point",
+			input: &CheckCoordinatesBatchRequestInput{
+				Points: []CheckCoordinatesRequestInput{
+					{UserID: "colorvax", Lat: 1, Long: 1},
+					{UserID: "colorvax", Lat: 91, Long: 2},
+				},
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				return isValidationErr(err) && err.Error() == "points[1]: lat or long is invalid"
+			},
+		},
+		{
+			name: "validation error - invalid checked_at",
+			input: &CheckCoordinatesBatchRequestInput{
+				Points: []CheckCoordinatesRequestInput{{UserID: "colorvax", Lat: 1, Long: 1, CheckedAt: "yesterday"}},
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				return isValidationErr(err) && err.Error() == "points[0]: checked_at must be RFC3339 timestamp"
+			},
+		},
+		{
+			name: "validation error - checked_at in the future",
+			input: &CheckCoordinatesBatchRequestInput{
+				Points: []CheckCoordinatesRequestInput{
+					{UserID: "colorvax", Lat: 1, Long: 1, CheckedAt: time.Now().Add(time.Hour).Format(time.RFC3339)},
+				},
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				return isValidationErr(err) && err.Error() == "points[0]: checked_at must not be in the future"
+			},
+		},
+		{
+			name: "repository error",
+			input: &CheckCoordinatesBatchRequestInput{
+				Points: []CheckCoordinatesRequestInput{{UserID: "colorvax", Lat: 1, Long: 1}},
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					checkBatchFunc: func(ctx context.Context, checks []*domain.LocationCheck, params domain.CheckParams) error {
+						return errors.New("failed database connection")
+					},
+				}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{}
+			},
+			wantErr: true,
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				errorLogs := logger.GetErrorLogs()
+				require.Len(t, errorLogs, 1)
+				require.Equal(t, "check coordinates batch request repository error", errorLogs[0].msg)
+			},
+		},
+		{
+			name: "success - results in input order",
+			input: &CheckCoordinatesBatchRequestInput{
+				Points: []CheckCoordinatesRequestInput{
+					{UserID: "colorvax", Lat: 50, Long: 40},
+					{UserID: "mike", Lat: 10, Long: 10},
+				},
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					checkBatchFunc: func(ctx context.Context, checks []*domain.LocationCheck, params domain.CheckParams) error {
+						for i, check := range checks {
+							check.ID = i + 1
+							check.CheckedAt = time.Now()
+						}
+						nearestID := 10
+						checks[0].InDangerZone = true
+						checks[0].NearestID = &nearestID
+						checks[0].Matches = []domain.IncidentMatch{{IncidentID: 10, DistanceM: 12.5}}
+						return nil
+					},
+				}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						if event.LocationCheck.UserID != "colorvax" {
+							return errors.New("unexpected event")
+						}
+						return nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result []*domain.LocationCheck) {
+				require.Len(t, result, 2)
+				require.Equal(t, "colorvax", result[0].UserID)
+				require.True(t, result[0].InDangerZone)
+				require.Equal(t, "mike", result[1].UserID)
+				require.False(t, result[1].InDangerZone)
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				require.Len(t, logger.GetInfoLogs(), 3, "should log attempt, success, and enqueue")
+				require.Empty(t, logger.GetErrorLogs())
+			},
+		},
+		{
+			name: "success - points saved in checked_at order",
+			input: &CheckCoordinatesBatchRequestInput{
+				Points: []CheckCoordinatesRequestInput{
+					{UserID: "colorvax", Lat: 50, Long: 40, CheckedAt: "2026-10-16T12:00:00+03:00"},
+					{UserID: "colorvax", Lat: 50, Long: 41, CheckedAt: "2026-10-16T08:59:00Z"},
+				},
+			},
+			coordinatesMock: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					checkBatchFunc: func(ctx context.Context, checks []*domain.LocationCheck, params domain.CheckParams) error {
+						if checks[0].Long != 41 || checks[1].Long != 40 {
+							return errors.New("checks are not in checked_at order")
+						}
+						return nil
+					},
+				}
+			},
+			queueMock: func() *mockQueue {
+				return &mockQueue{}
+			},
+			validateResult: func(t *testing.T, result []*domain.LocationCheck) {
+				require.Len(t, result, 2)
+				require.Equal(t, 40.0, result[0].Long)
+				require.Equal(t, time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), result[0].CheckedAt)
+				require.Equal(t, 41.0, result[1].Long)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			mockLog := &mockLogger{}
+
+			service := &Service{
+				coordinates: tt.coordinatesMock(),
+				queue:       tt.queueMock(),
+				membership:  &mockMembership{},
+				logger:      mockLog,
+				opts:        Options{BatchMaxPoints: 2},
+			}
+
+			result, err := service.CheckCoordinatesBatch(ctx, tt.input)
+
+			if tt.validateLogs != nil {
+				tt.validateLogs(t, mockLog)
+			}
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				if tt.errType != nil {
+					require.True(t, tt.errType(err), "wrong error type: %v", err)
+				}
+				require.Nil(t, result, "result should be nil on error")
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+
+			if tt.validateResult != nil {
+				tt.validateResult(t, result)
+			}
+		})
+	}
+}
+
+func TestService_GetStats(t *testing.T) {
+	tests := []struct {
+		name              string
+		timeWindowMinutes int
+		coordinates       func() *mockCoordinatesRepository
+		wantErr           bool
+		errType           func(err error) bool
+		validateResult    func(t *testing.T, result []domain.ZoneStat)
+		validateLogs      func(t *testing.T, logger *mockLogger)
+	}{
+		{
+			name:              "success",
+			timeWindowMinutes: 10,
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					getStatsFunc: func(ctx context.Context, timeWindowsMinutes int) ([]domain.ZoneStat, error) {
+						zones := []domain.ZoneStat{
+							{ZoneID: 1, UserCount: 5},
+							{ZoneID: 2, UserCount: 10},
+						}
+						return zones, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result []domain.ZoneStat) {
+				require.NotNil(t, result)
+				require.Len(t, result, 2)
+
+				require.Equal(t, 1, result[0].ZoneID)
+				require.Equal(t, 5, result[0].UserCount)
+
+				require.Equal(t, 2, result[1].ZoneID)
+				require.Equal(t, 10, result[1].UserCount)
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				infoLogs := logger.GetInfoLogs()
+				require.Len(t, infoLogs, 2, "should log attempt and success")
+			},
+		},
+		{
+			name:              "repository error",
+			timeWindowMinutes: 10,
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					getStatsFunc: func(ctx context.Context, timeWindowsMinutes int) ([]domain.ZoneStat, error) {
+						return nil, errors.New("failed database connection")
+					},
+				}
+			},
+			wantErr: true,
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				infoLogs := logger.GetInfoLogs()
+				require.Len(t, infoLogs, 1, "should log attempt")
+
+				errorLogs := logger.GetErrorLogs()
+				require.Len(t, errorLogs, 1, "should log repository error")
+				require.Equal(t, "failed to get stat repository error", errorLogs[0].msg)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockLog := &mockLogger{}
+			service := &Service{
+				coordinates: tt.coordinates(),
+				logger:      mockLog,
+			}
+
+			ctx := context.Background()
+
+			zones, err := service.GetStats(ctx, tt.timeWindowMinutes)
+
+			if tt.validateLogs != nil {
+				tt.validateLogs(t, mockLog)
+			}
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				if tt.errType != nil {
+					require.True(t, tt.errType(err), "wrong error type: %v", err)
+				}
+				require.Nil(t, zones, "zones should be nil on error")
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.NotNil(t, zones, "expected zones, got nil")
+
+			if tt.validateResult != nil {
+				tt.validateResult(t, zones)
+			}
+		})
+	}
+}
+
+func TestService_GetStatsSeries(t *testing.T) {
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		in             StatsSeriesRequestInput
+		coordinates    func() *mockCoordinatesRepository
+		wantErr        bool
+		validateResult func(t *testing.T, result *StatsSeriesOutput)
+	}{
+		{
+			name:        "validation error - unknown bucket",
+			in:          StatsSeriesRequestInput{Bucket: "15m", WindowMinutes: 10},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - from after to",
+			in:          StatsSeriesRequestInput{From: "2026-10-16T12:00:00Z", To: "2026-10-16T10:00:00Z"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - too many buckets",
+			in:          StatsSeriesRequestInput{From: "2026-10-01T00:00:00Z", To: "2026-10-16T00:00:00Z", Bucket: "1m"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - invalid incident_id",
+			in:          StatsSeriesRequestInput{IncidentID: "abc", WindowMinutes: 10},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name: "repository error",
+			in:   StatsSeriesRequestInput{WindowMinutes: 10},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					statsSeriesFunc: func(ctx context.Context, filter domain.StatsFilter) ([]domain.ZoneStatBucket, error) {
+						return nil, errors.New("failed database connection")
+					},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "success - bucket is chosen by period and from is aligned",
+			in:   StatsSeriesRequestInput{From: "2026-10-16T10:07:30+03:00", To: "2026-10-17T10:00:00Z"},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					statsSeriesFunc: func(ctx context.Context, filter domain.StatsFilter) ([]domain.ZoneStatBucket, error) {
+						require.Equal(t, 5*time.Minute, filter.Bucket)
+						require.Equal(t, time.Date(2026, 10, 16, 7, 5, 0, 0, time.UTC), filter.From)
+						require.Nil(t, filter.IncidentID)
+						return nil, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *StatsSeriesOutput) {
+				require.Equal(t, "5m", result.Bucket)
+				require.NotNil(t, result.Series)
+				require.Empty(t, result.Series)
+			},
+		},
+		{
+			name: "success - series grouped by incident",
+			in:   StatsSeriesRequestInput{Bucket: "1h", WindowMinutes: 600},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					statsSeriesFunc: func(ctx context.Context, filter domain.StatsFilter) ([]domain.ZoneStatBucket, error) {
+						return []domain.ZoneStatBucket{
+							{IncidentID: 1, Start: start, UniqueUsers: 2, TotalChecks: 5},
+							{IncidentID: 1, Start: start.Add(time.Hour), UniqueUsers: 1, TotalChecks: 1},
+							{IncidentID: 3, Start: start, UniqueUsers: 4, TotalChecks: 4},
+						}, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *StatsSeriesOutput) {
+				require.Len(t, result.Series, 2)
+				require.Equal(t, 1, result.Series[0].IncidentID)
+				require.Len(t, result.Series[0].Buckets, 2)
+				require.Equal(t, 3, result.Series[1].IncidentID)
+				require.Equal(t, 4, result.Series[1].Buckets[0].UniqueUsers)
+			},
+		},
+		{
+			name: "success - empty series for requested incident",
+			in:   StatsSeriesRequestInput{IncidentID: "7", Bucket: "1d", WindowMinutes: 10},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					statsSeriesFunc: func(ctx context.Context, filter domain.StatsFilter) ([]domain.ZoneStatBucket, error) {
+						require.Equal(t, 7, *filter.IncidentID)
+						return nil, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *StatsSeriesOutput) {
+				require.Len(t, result.Series, 1)
+				require.Equal(t, 7, result.Series[0].IncidentID)
+				require.NotNil(t, result.Series[0].Buckets)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &Service{
+				coordinates: tt.coordinates(),
+				logger:      &mockLogger{},
+			}
+
+			result, err := service.GetStatsSeries(context.Background(), &tt.in)
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				require.Nil(t, result)
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			if tt.validateResult != nil {
+				tt.validateResult(t, result)
+			}
+		})
+	}
+}
+
+func TestService_GetHeatmap(t *testing.T) {
+	const bbox = "30.4,50.4,30.7,50.5"
+
+	tests := []struct {
+		name           string
+		in             HeatmapRequestInput
+		coordinates    func() *mockCoordinatesRepository
+		wantErr        bool
+		validateResult func(t *testing.T, result *HeatmapOutput)
+	}{
+		{
+			name:        "validation error - bbox is required",
+			in:          HeatmapRequestInput{},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - unknown grid",
+			in:          HeatmapRequestInput{BBox: bbox, Grid: "triangle"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - precision for square grid",
+			in:          HeatmapRequestInput{BBox: bbox, Grid: "square", Precision: "6"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - cell too small",
+			in:          HeatmapRequestInput{BBox: bbox, Grid: "hex", SizeM: "5"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - too many geohash cells",
+			in:          HeatmapRequestInput{BBox: bbox, Precision: "9"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - too many square cells",
+			in:          HeatmapRequestInput{BBox: "-10,-10,10,10", Grid: "square", SizeM: "1000"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - bbox outside web mercator",
+			in:          HeatmapRequestInput{BBox: "0,80,10,89", Grid: "hex", SizeM: "100000"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - invalid in_danger",
+			in:          HeatmapRequestInput{BBox: bbox, InDanger: "maybe"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name: "repository error",
+			in:   HeatmapRequestInput{BBox: bbox},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					heatmapFunc: func(ctx context.Context, filter domain.HeatmapFilter) ([]domain.HeatmapCell, error) {
+						return nil, errors.New("failed database connection")
+					},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "success - defaults",
+			in:   HeatmapRequestInput{BBox: bbox, To: "2026-10-16T10:00:00Z"},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					heatmapFunc: func(ctx context.Context, filter domain.HeatmapFilter) ([]domain.HeatmapCell, error) {
+						require.Equal(t, domain.HeatmapGridGeohash, filter.Grid)
+						require.Equal(t, defaultGeohashPrecision, filter.Precision)
+						require.Equal(t, time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC), filter.From)
+						require.Equal(t, 50.4, filter.BBox.MinLat)
+						require.False(t, filter.InDangerOnly)
+						return nil, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *HeatmapOutput) {
+				require.NotNil(t, result.Cells)
+				require.Equal(t, 0, result.Total)
+			},
+		},
+		{
+			name: "success - hex grid in danger only",
+			in:   HeatmapRequestInput{BBox: bbox, Grid: "hex", SizeM: "250", InDanger: "true"},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					heatmapFunc: func(ctx context.Context, filter domain.HeatmapFilter) ([]domain.HeatmapCell, error) {
+						require.Equal(t, domain.HeatmapGridHex, filter.Grid)
+						require.Equal(t, 250, filter.SizeM)
+						require.True(t, filter.InDangerOnly)
+						return []domain.HeatmapCell{{ID: "1:2", Count: 5}, {ID: "1:3", Count: 2}}, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *HeatmapOutput) {
+				require.Equal(t, 7, result.Total)
+				require.Equal(t, 250, result.SizeM)
+				require.Len(t, result.Cells, 2)
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			ctx := context.Background()
-			mockLog := &mockLogger{}
-
 			service := &Service{
-				coordinates: tt.coordinatesMock(),
-				queue:       tt.queueMock(),
-				logger:      mockLog,
+				coordinates: tt.coordinates(),
+				logger:      &mockLogger{},
 			}
 
-			result, err := service.CheckCoordinates(ctx, tt.input)
-
-			if tt.validateLogs != nil {
-				tt.validateLogs(t, mockLog)
-			}
+			result, err := service.GetHeatmap(context.Background(), &tt.in)
 
 			if tt.wantErr {
 				require.Error(t, err, "expected error but got none")
-				if tt.errType != nil {
-					require.True(t, tt.errType(err), "wrong error type: %v", err)
-				}
-				require.Nil(t, result, "result should be nil on error")
+				require.Nil(t, result)
 				return
 			}
 
 			require.NoError(t, err, "unexpected error: %v", err)
-			require.NotNil(t, result, "expected result, got nil")
-
 			if tt.validateResult != nil {
 				tt.validateResult(t, result)
 			}
@@ -264,63 +1174,108 @@ func TestService_CheckCoordinates(t *testing.T) {
 	}
 }
 
-func TestService_GetStats(t *testing.T) {
+func TestBuildHeatmapGeoJSON(t *testing.T) {
+	geometry := json.RawMessage(`{"type":"Polygon","coordinates":[[[30,50],[30.1,50],[30.1,50.1],[30,50]]]}`)
+	out := &HeatmapOutput{Cells: []domain.HeatmapCell{{ID: "u8vxn", Count: 3, UniqueUsers: 2, Geometry: geometry}}}
+
+	collection := buildHeatmapGeoJSON(out)
+	require.Equal(t, "FeatureCollection", collection.Type)
+	require.Len(t, collection.Features, 1)
+	require.JSONEq(t, string(geometry), string(collection.Features[0].Geometry))
+	require.Equal(t, HeatmapCellProperties{CellID: "u8vxn", Count: 3, UniqueUsers: 2}, collection.Features[0].Properties)
+}
+
+func TestService_GetUserChecks(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	checksPage := func(n int) []domain.LocationCheck {
+		checks := make([]domain.LocationCheck, n)
+		for i := range checks {
+			checks[i] = domain.LocationCheck{ID: n - i, UserID: "mike", CheckedAt: base.Add(-time.Duration(i) * time.Minute)}
+		}
+		return checks
+	}
+
 	tests := []struct {
-		name              string
-		timeWindowMinutes int
-		coordinates       func() *mockCoordinatesRepository
-		wantErr           bool
-		errType           func(err error) bool
-		validateResult    func(t *testing.T, result []domain.ZoneStat)
-		validateLogs      func(t *testing.T, logger *mockLogger)
+		name           string
+		in             UserChecksRequestInput
+		coordinates    func() *mockCoordinatesRepository
+		wantErr        bool
+		validateResult func(t *testing.T, result *UserChecksOutput)
 	}{
 		{
-			name:              "success",
-			timeWindowMinutes: 10,
+			name:        "validation error - invalid from",
+			in:          UserChecksRequestInput{UserID: "mike", From: "yesterday"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - from after to",
+			in:          UserChecksRequestInput{UserID: "mike", From: "2026-10-16T12:00:00Z", To: "2026-10-16T10:00:00Z"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - invalid cursor",
+			in:          UserChecksRequestInput{UserID: "mike", Cursor: "???"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name: "validation error - cursor issued for another order",
+			in: UserChecksRequestInput{
+				UserID: "mike",
+				Cursor: encodeCheckCursor(domain.LocationCheck{ID: 1, CheckedAt: time.Now()}, true),
+			},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name: "repository error",
+			in:   UserChecksRequestInput{UserID: "mike"},
 			coordinates: func() *mockCoordinatesRepository {
 				return &mockCoordinatesRepository{
-					getStatsFunc: func(ctx context.Context, timeWindowsMinutes int) ([]domain.ZoneStat, error) {
-						zones := []domain.ZoneStat{
-							{ZoneID: 1, UserCount: 5},
-							{ZoneID: 2, UserCount: 10},
-						}
-						return zones, nil
+					userChecksFunc: func(ctx context.Context, filter domain.CheckFilter, limit int) ([]domain.LocationCheck, error) {
+						return nil, errors.New("failed database connection")
 					},
 				}
 			},
-			validateResult: func(t *testing.T, result []domain.ZoneStat) {
-				require.NotNil(t, result)
-				require.Len(t, result, 2)
-
-				require.Equal(t, 1, result[0].ZoneID)
-				require.Equal(t, 5, result[0].UserCount)
-
-				require.Equal(t, 2, result[1].ZoneID)
-				require.Equal(t, 10, result[1].UserCount)
+			wantErr: true,
+		},
+		{
+			name: "success - last page",
+			in:   UserChecksRequestInput{UserID: "mike", From: "2026-10-16T00:00:00+03:00", Order: "asc"},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					userChecksFunc: func(ctx context.Context, filter domain.CheckFilter, limit int) ([]domain.LocationCheck, error) {
+						require.Equal(t, "mike", filter.UserID)
+						require.True(t, filter.SortAsc)
+						require.Equal(t, time.Date(2026, 10, 15, 21, 0, 0, 0, time.UTC), *filter.From)
+						require.Equal(t, defaultCheckLimit+1, limit)
+						return nil, nil
+					},
+				}
 			},
-			validateLogs: func(t *testing.T, logger *mockLogger) {
-				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 2, "should log attempt and success")
+			validateResult: func(t *testing.T, result *UserChecksOutput) {
+				require.NotNil(t, result.Checks)
+				require.Empty(t, result.NextCursor)
 			},
 		},
 		{
-			name:              "repository error",
-			timeWindowMinutes: 10,
+			name: "success - next cursor",
+			in:   UserChecksRequestInput{UserID: "mike", Limit: "2"},
 			coordinates: func() *mockCoordinatesRepository {
 				return &mockCoordinatesRepository{
-					getStatsFunc: func(ctx context.Context, timeWindowsMinutes int) ([]domain.ZoneStat, error) {
-						return nil, errors.New("failed database connection")
+					userChecksFunc: func(ctx context.Context, filter domain.CheckFilter, limit int) ([]domain.LocationCheck, error) {
+						return checksPage(limit), nil
 					},
 				}
 			},
-			wantErr: true,
-			validateLogs: func(t *testing.T, logger *mockLogger) {
-				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 1, "should log attempt")
-
-				errorLogs := logger.GetErrorLogs()
-				require.Len(t, errorLogs, 1, "should log repository error")
-				require.Equal(t, "failed to get stat repository error", errorLogs[0].msg)
+			validateResult: func(t *testing.T, result *UserChecksOutput) {
+				require.Len(t, result.Checks, 2)
+				cursor, err := decodeCheckCursor(result.NextCursor, false)
+				require.NoError(t, err)
+				require.Equal(t, result.Checks[1].ID, cursor.ID)
+				require.True(t, result.Checks[1].CheckedAt.Equal(cursor.CheckedAt))
 			},
 		},
 	}
@@ -329,35 +1284,412 @@ func TestService_GetStats(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			mockLog := &mockLogger{}
 			service := &Service{
 				coordinates: tt.coordinates(),
-				logger:      mockLog,
+				logger:      &mockLogger{},
 			}
 
-			ctx := context.Background()
+			result, err := service.GetUserChecks(context.Background(), &tt.in)
 
-			zones, err := service.GetStats(ctx, tt.timeWindowMinutes)
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				require.Nil(t, result)
+				return
+			}
 
-			if tt.validateLogs != nil {
-				tt.validateLogs(t, mockLog)
+			require.NoError(t, err, "unexpected error: %v", err)
+			if tt.validateResult != nil {
+				tt.validateResult(t, result)
+			}
+		})
+	}
+}
+
+func TestService_GetIncidentOccupants(t *testing.T) {
+	tests := []struct {
+		name           string
+		in             IncidentOccupantsRequestInput
+		coordinates    func() *mockCoordinatesRepository
+		wantErr        bool
+		validateResult func(t *testing.T, result *IncidentOccupantsOutput)
+	}{
+		{
+			name:        "validation error - invalid id",
+			in:          IncidentOccupantsRequestInput{ID: "abc"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name:        "validation error - window too large",
+			in:          IncidentOccupantsRequestInput{ID: "1", Minutes: "1441"},
+			coordinates: func() *mockCoordinatesRepository { return &mockCoordinatesRepository{} },
+			wantErr:     true,
+		},
+		{
+			name: "incident not found",
+			in:   IncidentOccupantsRequestInput{ID: "1"},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					occupantsFunc: func(ctx context.Context, incidentID int, windowMinutes int) ([]domain.ZoneOccupant, error) {
+						return nil, domain.ErrNotFound("incident not found")
+					},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "success - default window",
+			in:   IncidentOccupantsRequestInput{ID: "1"},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					occupantsFunc: func(ctx context.Context, incidentID int, windowMinutes int) ([]domain.ZoneOccupant, error) {
+						require.Equal(t, 1, incidentID)
+						require.Equal(t, 15, windowMinutes)
+						return nil, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *IncidentOccupantsOutput) {
+				require.Equal(t, 15, result.WindowMinutes)
+				require.NotNil(t, result.Occupants)
+				require.Equal(t, 0, result.Count)
+			},
+		},
+		{
+			name: "success - custom window",
+			in:   IncidentOccupantsRequestInput{ID: "2", Minutes: "60"},
+			coordinates: func() *mockCoordinatesRepository {
+				return &mockCoordinatesRepository{
+					occupantsFunc: func(ctx context.Context, incidentID int, windowMinutes int) ([]domain.ZoneOccupant, error) {
+						require.Equal(t, 60, windowMinutes)
+						return []domain.ZoneOccupant{{UserID: "mike"}, {UserID: "colorvax"}}, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *IncidentOccupantsOutput) {
+				require.Equal(t, 2, result.IncidentID)
+				require.Equal(t, 2, result.Count)
+				require.Equal(t, "mike", result.Occupants[0].UserID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &Service{
+				coordinates: tt.coordinates(),
+				logger:      &mockLogger{},
+				opts:        Options{OccupantWindowMins: 15},
 			}
 
+			result, err := service.GetIncidentOccupants(context.Background(), &tt.in)
+
 			if tt.wantErr {
 				require.Error(t, err, "expected error but got none")
-				if tt.errType != nil {
-					require.True(t, tt.errType(err), "wrong error type: %v", err)
-				}
-				require.Nil(t, zones, "zones should be nil on error")
+				require.Nil(t, result)
 				return
 			}
 
 			require.NoError(t, err, "unexpected error: %v", err)
-			require.NotNil(t, zones, "expected zones, got nil")
-
 			if tt.validateResult != nil {
-				tt.validateResult(t, zones)
+				tt.validateResult(t, result)
+			}
+		})
+	}
+}
+
+func TestBuildUserTrack(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	primary := 7
+	out := &UserChecksOutput{
+		Checks: []domain.LocationCheck{
+			{ID: 2, CheckedAt: base.Add(time.Minute), Lat: 50.01, Long: 30.01, InDangerZone: true, PrimaryID: &primary,
+				Matches: []domain.IncidentMatch{{IncidentID: 3}, {IncidentID: 7}}},
+			{ID: 1, CheckedAt: base, Lat: 50, Long: 30},
+		},
+		NextCursor: "next",
+	}
+
+	track := buildUserTrack("mike", out)
+	require.Equal(t, "FeatureCollection", track.Type)
+	require.Equal(t, "next", track.NextCursor)
+	require.Len(t, track.Features, 3)
+
+	line := track.Features[0]
+	require.Equal(t, "LineString", line.Geometry.Type)
+	require.Equal(t, [][2]float64{{30, 50}, {30.01, 50.01}}, line.Geometry.Coordinates)
+	require.Equal(t, base, line.Properties.(TrackLineProperties).From)
+
+	first := track.Features[1].Properties.(TrackPointProperties)
+	require.Equal(t, 1, first.CheckID)
+	require.False(t, first.InDangerZone)
+	require.Empty(t, first.IncidentIDs)
+
+	second := track.Features[2].Properties.(TrackPointProperties)
+	require.True(t, second.InDangerZone)
+	require.Equal(t, []int{3, 7}, second.IncidentIDs)
+	require.Equal(t, 7, *second.PrimaryID)
+
+	single := buildUserTrack("mike", &UserChecksOutput{Checks: out.Checks[:1]})
+	require.Len(t, single.Features, 1, "line needs at least two points")
+}
+
+func TestDiffZoneMembership(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		prev        map[int]domain.ZoneMembership
+		inside      []int
+		approaching []int
+		dwell       time.Duration
+		wantEvents  []domain.Event
+		wantNext    map[int]domain.ZoneMembership
+	}{
+		{
+			name:       "enter zone",
+			inside:     []int{1},
+			wantEvents: []domain.Event{{Type: domain.EventZoneEntered, IncidentID: 1}},
+			wantNext:   map[int]domain.ZoneMembership{1: {EnteredAt: now}},
+		},
+		{
+			name:     "stay in zone",
+			prev:     map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-time.Minute)}},
+			inside:   []int{1},
+			wantNext: map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-time.Minute)}},
+		},
+		{
+			name:   "move between zones",
+			prev:   map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-time.Minute)}},
+			inside: []int{2},
+			wantEvents: []domain.Event{
+				{Type: domain.EventZoneExited, IncidentID: 1},
+				{Type: domain.EventZoneEntered, IncidentID: 2},
+			},
+			wantNext: map[int]domain.ZoneMembership{2: {EnteredAt: now}},
+		},
+		{
+			name:       "dwell once",
+			prev:       map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-20 * time.Minute)}},
+			inside:     []int{1},
+			dwell:      15 * time.Minute,
+			wantEvents: []domain.Event{{Type: domain.EventZoneDwell, IncidentID: 1}},
+			wantNext:   map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-20 * time.Minute), DwellSent: true}},
+		},
+		{
+			name:     "dwell already sent",
+			prev:     map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-40 * time.Minute), DwellSent: true}},
+			inside:   []int{1},
+			dwell:    15 * time.Minute,
+			wantNext: map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-40 * time.Minute), DwellSent: true}},
+		},
+		{
+			name:     "dwell disabled",
+			prev:     map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-40 * time.Minute)}},
+			inside:   []int{1},
+			wantNext: map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-40 * time.Minute)}},
+		},
+		{
+			name:        "approach zone",
+			approaching: []int{1},
+			wantEvents:  []domain.Event{{Type: domain.EventZoneApproaching, IncidentID: 1}},
+			wantNext:    map[int]domain.ZoneMembership{1: {EnteredAt: now, Approaching: true}},
+		},
+		{
+			name:        "stay in warning band",
+			prev:        map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-time.Minute), Approaching: true}},
+			approaching: []int{1},
+			wantNext:    map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-time.Minute), Approaching: true}},
+		},
+		{
+			name:       "enter zone from warning band",
+			prev:       map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-time.Minute), Approaching: true}},
+			inside:     []int{1},
+			wantEvents: []domain.Event{{Type: domain.EventZoneEntered, IncidentID: 1}},
+			wantNext:   map[int]domain.ZoneMembership{1: {EnteredAt: now}},
+		},
+		{
+			name:        "exit zone into warning band",
+			prev:        map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-time.Minute)}},
+			approaching: []int{1},
+			wantEvents:  []domain.Event{{Type: domain.EventZoneExited, IncidentID: 1}},
+			wantNext:    map[int]domain.ZoneMembership{1: {EnteredAt: now, Approaching: true}},
+		},
+		{
+			name:     "leave warning band",
+			prev:     map[int]domain.ZoneMembership{1: {EnteredAt: now.Add(-time.Minute), Approaching: true}},
+			wantNext: map[int]domain.ZoneMembership{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, events := diffZoneMembership(tt.prev, tt.inside, tt.approaching, now, tt.dwell)
+
+			require.Equal(t, tt.wantNext, next)
+			require.Len(t, events, len(tt.wantEvents))
+			for i, event := range events {
+				require.Equal(t, tt.wantEvents[i].Type, event.Type)
+				require.Equal(t, tt.wantEvents[i].IncidentID, event.IncidentID)
+			}
+		})
+	}
+}
+
+func TestDescribeZones(t *testing.T) {
+	check := &domain.LocationCheck{
+		Matches: []domain.IncidentMatch{{IncidentID: 2, Severity: domain.SeverityCritical, Category: "fire"}},
+	}
+	prev := map[int]domain.ZoneMembership{
+		1: {Severity: domain.SeverityInfo, Category: "road_works"},
+	}
+	next := map[int]domain.ZoneMembership{2: {}}
+	events := []*domain.Event{
+		{Type: domain.EventZoneExited, IncidentID: 1},
+		{Type: domain.EventZoneEntered, IncidentID: 2},
+	}
+
+	describeZones(check, prev, next, events)
+
+	require.Equal(t, domain.SeverityInfo, events[0].Severity)
+	require.Equal(t, "road_works", events[0].Category)
+	require.Equal(t, domain.SeverityCritical, events[1].Severity)
+	require.Equal(t, "fire", events[1].Category)
+	require.Equal(t, domain.SeverityCritical, next[2].Severity)
+}
+
+func TestService_AlertZoneOccupants(t *testing.T) {
+	seen := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	incident := &domain.Incident{ID: 1, Severity: domain.SeverityCritical, Category: "fire"}
+
+	tests := []struct {
+		name        string
+		occupants   []domain.ZoneOccupant
+		occupantErr error
+		membership  map[int]domain.ZoneMembership
+		updateErr   error
+		wantUsers   []string
+		wantSaved   map[int]domain.ZoneMembership
+	}{
+		{
+			name:       "user already in another zone",
+			occupants:  []domain.ZoneOccupant{{UserID: "mike", CheckID: 10, Lat: 50, Long: 50, LastSeenAt: seen}},
+			membership: map[int]domain.ZoneMembership{2: {EnteredAt: seen.Add(-time.Hour)}},
+			wantUsers:  []string{"mike"},
+			wantSaved: map[int]domain.ZoneMembership{
+				1: {EnteredAt: seen, Severity: domain.SeverityCritical, Category: "fire"},
+				2: {EnteredAt: seen.Add(-time.Hour)},
+			},
+		},
+		{
+			name:       "user was approaching the zone",
+			occupants:  []domain.ZoneOccupant{{UserID: "mike", LastSeenAt: seen}},
+			membership: map[int]domain.ZoneMembership{1: {EnteredAt: seen.Add(-time.Hour), Approaching: true}},
+			wantUsers:  []string{"mike"},
+			wantSaved:  map[int]domain.ZoneMembership{1: {EnteredAt: seen, Severity: domain.SeverityCritical, Category: "fire"}},
+		},
+		{
+			name:       "user already notified",
+			occupants:  []domain.ZoneOccupant{{UserID: "mike", LastSeenAt: seen}},
+			membership: map[int]domain.ZoneMembership{1: {EnteredAt: seen.Add(-time.Hour)}},
+		},
+		{
+			name:      "membership error - event is sent without saving",
+			occupants: []domain.ZoneOccupant{{UserID: "mike", LastSeenAt: seen}},
+			updateErr: errors.New("redis is down"),
+			wantUsers: []string{"mike"},
+		},
+		{
+			name:        "occupants error",
+			occupantErr: errors.New("failed database connection"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var events []*domain.Event
+			var saved map[int]domain.ZoneMembership
+			service := &Service{
+				coordinates: &mockCoordinatesRepository{
+					occupantsFunc: func(ctx context.Context, incidentID int, windowMinutes int) ([]domain.ZoneOccupant, error) {
+						require.Equal(t, incident.ID, incidentID)
+						require.Equal(t, 15, windowMinutes)
+						return tt.occupants, tt.occupantErr
+					},
+				},
+				membership: &mockMembership{
+					updateFunc: func(ctx context.Context, userID string, checkedAt time.Time, fn func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership) error {
+						if tt.updateErr != nil {
+							return tt.updateErr
+						}
+						saved = fn(tt.membership)
+						return nil
+					},
+				},
+				queue: &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						events = append(events, event)
+						return nil
+					},
+				},
+				logger: &mockLogger{},
+				opts:   Options{OccupantWindowMins: 15},
+			}
+
+			service.alertZoneOccupants(context.Background(), incident)
+
+			require.Len(t, events, len(tt.wantUsers))
+			for i, event := range events {
+				require.Equal(t, domain.EventZoneEntered, event.Type)
+				require.Equal(t, incident.ID, event.IncidentID)
+				require.Equal(t, domain.SeverityCritical, event.Severity)
+				require.True(t, event.Retroactive)
+				require.Equal(t, tt.wantUsers[i], event.LocationCheck.UserID)
+				require.True(t, event.LocationCheck.InDangerZone)
+				require.Equal(t, seen, event.LocationCheck.CheckedAt)
 			}
+			require.Equal(t, tt.wantSaved, saved)
+		})
+	}
+}
+
+func TestZoneExpanded(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	circle := func(modify func(i *domain.Incident)) *domain.Incident {
+		incident := &domain.Incident{Lat: 50, Long: 50, Radius: 100, Active: true}
+		if modify != nil {
+			modify(incident)
+		}
+		return incident
+	}
+	polygon := json.RawMessage(`{"type":"Polygon","coordinates":[[[50,50],[50.1,50],[50.1,50.1],[50,50]]]}`)
+
+	tests := []struct {
+		name string
+		prev *domain.Incident
+		next *domain.Incident
+		want bool
+	}{
+		{"title change", circle(nil), circle(nil), false},
+		{"radius growth", circle(nil), circle(func(i *domain.Incident) { i.Radius = 200 }), true},
+		{"radius shrink", circle(nil), circle(func(i *domain.Incident) { i.Radius = 50 }), false},
+		{"center moved", circle(nil), circle(func(i *domain.Incident) { i.Lat = 50.01 }), true},
+		{"activated", circle(func(i *domain.Incident) { i.Active = false }), circle(nil), true},
+		{"deactivated", circle(nil), circle(func(i *domain.Incident) { i.Active = false }), false},
+		{"schedule moved to now", circle(func(i *domain.Incident) { i.StartsAt = &future }), circle(nil), true},
+		{"not started yet", circle(nil), circle(func(i *domain.Incident) { i.Radius = 200; i.StartsAt = &future }), false},
+		{"boundary set", circle(nil), circle(func(i *domain.Incident) { i.Boundary = polygon }), true},
+		{"boundary unchanged", circle(func(i *domain.Incident) { i.Boundary = polygon }), circle(func(i *domain.Incident) { i.Boundary = polygon }), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, zoneExpanded(tt.prev, tt.next, now))
 		})
 	}
 }
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"red_collar/internal/domain"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const csvContentType = "text/csv; charset=utf-8"
+
+// csvColumns - колонки CSV в порядке выгрузки, совпадают с полями handler.IncidentJSON
+var csvColumns = []string{
+	"title", "description", "lat", "long", "radius_m", "active", "severity", "category",
+	"boundary", "warning_buffer_m", "starts_at", "ends_at",
+}
+
+// decodeCSVIncidents разбирает CSV с заголовком. Порядок колонок произвольный, обязательна только title,
+// пустая ячейка означает незаданное поле. Разделитель - запятая или точка с запятой (выгрузка Excel)
+func decodeCSVIncidents(data []byte) ([]importItem, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, domain.ErrInvalidRequest("csv header is required")
+	}
+	if err != nil {
+		return nil, domain.ErrInvalidRequest("invalid csv: " + err.Error())
+	}
+	if len(header) == 1 && strings.Contains(header[0], ";") {
+		reader = csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		reader.Comma = ';'
+		if header, err = reader.Read(); err != nil {
+			return nil, domain.ErrInvalidRequest("invalid csv: " + err.Error())
+		}
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvColumns, name) {
+			return nil, domain.ErrInvalidRequest(fmt.Sprintf("unknown csv column %q", name))
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, domain.ErrInvalidRequest("csv column title is required")
+	}
+
+	var items []importItem
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, domain.ErrInvalidRequest("invalid csv: " + err.Error())
+		}
+
+		var item importItem
+		if len(record) != len(header) {
+			item.Err = domain.ErrInvalidValidation(fmt.Sprintf("row has %d fields, header has %d", len(record), len(header)))
+		} else {
+			item.Err = decodeCSVRecord(record, columns, &item.Input)
+			item.BoundaryOmitted = len(item.Input.Boundary) == 0
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func decodeCSVRecord(record []string, columns map[string]int, in *CreateIncidentRequestInput) error {
+	value := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	in.Title = value("title")
+	in.Severity = value("severity")
+	in.Category = value("category")
+	if raw := value("description"); raw != "" {
+		in.Description = &raw
+	}
+	if raw := value("boundary"); raw != "" {
+		in.Boundary = json.RawMessage(raw)
+	}
+
+	var err error
+	if raw := value("lat"); raw != "" {
+		if in.Lat, err = strconv.ParseFloat(raw, 64); err != nil {
+			return domain.ErrInvalidValidation("invalid lat format, must be number")
+		}
+	}
+	if raw := value("long"); raw != "" {
+		if in.Long, err = strconv.ParseFloat(raw, 64); err != nil {
+			return domain.ErrInvalidValidation("invalid long format, must be number")
+		}
+	}
+	if raw := value("radius_m"); raw != "" {
+		// ГИС и табличные редакторы часто сохраняют целые числа как 150.0
+		radius, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return domain.ErrInvalidValidation("invalid radius_m format, must be number")
+		}
+		in.Radius = int(math.Round(radius))
+	}
+	if raw := value("active"); raw != "" {
+		active, err := strconv.ParseBool(raw)
+		if err != nil {
+			return domain.ErrInvalidValidation("invalid active format, must be true or false")
+		}
+		in.Active = &active
+	}
+	if raw := value("warning_buffer_m"); raw != "" {
+		buffer, err := strconv.Atoi(raw)
+		if err != nil {
+			return domain.ErrInvalidValidation("invalid warning_buffer_m format, must be integer")
+		}
+		in.WarningBuffer = &buffer
+	}
+	if in.StartsAt, err = parseOptionalTime("starts_at", value("starts_at")); err != nil {
+		return err
+	}
+	if in.EndsAt, err = parseOptionalTime("ends_at", value("ends_at")); err != nil {
+		return err
+	}
+	return nil
+}
+
+// encodeCSVIncidents выгружает инциденты в колонках csvColumns, которые можно загрузить обратно импортом
+func encodeCSVIncidents(incidents []domain.Incident) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write(csvColumns); err != nil {
+		return nil, err
+	}
+	for _, incident := range incidents {
+		record := []string{
+			incident.Title,
+			incident.Description,
+			strconv.FormatFloat(incident.Lat, 'f', -1, 64),
+			strconv.FormatFloat(incident.Long, 'f', -1, 64),
+			strconv.Itoa(incident.Radius),
+			strconv.FormatBool(incident.Active),
+			string(incident.Severity),
+			incident.Category,
+			string(incident.Boundary),
+			"",
+			formatOptionalTime(incident.StartsAt),
+			formatOptionalTime(incident.EndsAt),
+		}
+		if incident.WarningBuffer != nil {
+			record[9] = strconv.Itoa(*incident.WarningBuffer)
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func parseOptionalTime(name, raw string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, domain.ErrInvalidValidation(fmt.Sprintf("%s must be RFC3339 timestamp", name))
+	}
+	return &t, nil
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"red_collar/internal/domain"
+	"time"
+)
+
+// cursorPayload - содержимое непрозрачного курсора списка, упорядоченного по (момент, id).
+// Поле сортировки и направление сохраняются в курсоре: курсор, выданный для одного порядка,
+// в запросе с другим порядком пропустил бы или повторил записи, поэтому такой запрос отклоняется
+type cursorPayload struct {
+	At   time.Time `json:"c"`
+	ID   int       `json:"i"`
+	Sort string    `json:"s"`
+	Asc  bool      `json:"a,omitempty"`
+}
+
+func encodeCursor(sort string, asc bool, at time.Time, id int) string {
+	raw, _ := json.Marshal(cursorPayload{At: at, ID: id, Sort: sort, Asc: asc})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(cursor, sort string, asc bool) (cursorPayload, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return cursorPayload{}, domain.ErrInvalidValidation("invalid cursor")
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.ID <= 0 {
+		return cursorPayload{}, domain.ErrInvalidValidation("invalid cursor")
+	}
+
+	if payload.Sort != sort || payload.Asc != asc {
+		return cursorPayload{}, domain.ErrInvalidValidation("cursor does not match sort and order of the request")
+	}
+	return payload, nil
+}
+
+func encodeIncidentCursor(incident domain.Incident, asc bool) string {
+	return encodeCursor(string(domain.IncidentSortCreatedAt), asc, incident.CreatedAt, incident.ID)
+}
+
+func decodeIncidentCursor(cursor string, asc bool) (*domain.IncidentCursor, error) {
+	payload, err := decodeCursor(cursor, string(domain.IncidentSortCreatedAt), asc)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.IncidentCursor{CreatedAt: payload.At, ID: payload.ID}, nil
+}
+
+// checkCursorSort - поле сортировки списка проверок в курсоре
+const checkCursorSort = "checked_at"
+
+func encodeCheckCursor(check domain.LocationCheck, asc bool) string {
+	return encodeCursor(checkCursorSort, asc, check.CheckedAt, check.ID)
+}
+
+func decodeCheckCursor(cursor string, asc bool) (*domain.CheckCursor, error) {
+	payload, err := decodeCursor(cursor, checkCursorSort, asc)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.CheckCursor{CheckedAt: payload.At, ID: payload.ID}, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"red_collar/internal/domain"
+
+	"github.com/theartofdevel/logging"
+)
+
+// ListDeadLetters возвращает страницу записей DLQ с последней ошибкой и числом попыток
+func (s *Service) ListDeadLetters(ctx context.Context, in *DeadLettersRequestInput) (*DeadLettersOutput, error) {
+	filter, offset, limit, err := validateDeadLettersInput(in)
+	if err != nil {
+		s.logger.Error("list dead letters validation failed",
+			logging.StringAttr("subscriptionID", in.SubscriptionID),
+			logging.StringAttr("event", in.EventType),
+			logging.StringAttr("incidentID", in.IncidentID),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to list dead letters",
+		logging.IntAttr("offset", offset),
+		logging.IntAttr("limit", limit),
+	)
+
+	letters, total, err := s.queue.ListDLQ(ctx, filter, offset, limit)
+	if err != nil {
+		s.logger.Error("list dead letters queue error",
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+	if letters == nil {
+		letters = []domain.DeadLetter{}
+	}
+
+	s.logger.Info("dead letters were successfully listed",
+		logging.IntAttr("count", len(letters)),
+		logging.IntAttr("total", total),
+	)
+	return &DeadLettersOutput{Entries: letters, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+// ReplayDeadLetter возвращает в очередь одну запись DLQ со сброшенными попытками
+func (s *Service) ReplayDeadLetter(ctx context.Context, id string) error {
+	if id == "" {
+		err := domain.ErrInvalidValidation("id is required")
+		s.logger.Error("replay dead letter validation failed", logging.ErrAttr(err))
+		return err
+	}
+
+	s.logger.Info("attempt to replay dead letter",
+		logging.StringAttr("id", id),
+	)
+
+	replayed, err := s.queue.ReplayDLQ(ctx, domain.DeadLetterFilter{ID: id})
+	if err != nil {
+		s.logger.Error("replay dead letter queue error",
+			logging.StringAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return err
+	}
+	if replayed == 0 {
+		s.logger.Error("replay dead letter not found",
+			logging.StringAttr("id", id),
+		)
+		return domain.ErrNotFound("dlq entry not found")
+	}
+
+	s.logger.Info("dead letter was successfully replayed",
+		logging.StringAttr("id", id),
+	)
+	return nil
+}
+
+// ReplayDeadLetters возвращает в очередь подходящие под фильтр записи DLQ или всю DLQ
+func (s *Service) ReplayDeadLetters(ctx context.Context, in *ReplayDeadLettersRequestInput) (int, error) {
+	filter, err := validateReplayDeadLettersInput(in)
+	if err != nil {
+		s.logger.Error("replay dead letters validation failed",
+			logging.StringAttr("event", in.EventType),
+			logging.BoolAttr("all", in.All),
+			logging.ErrAttr(err),
+		)
+		return 0, err
+	}
+
+	s.logger.Info("attempt to replay dead letters",
+		logging.StringAttr("event", in.EventType),
+		logging.BoolAttr("all", in.All),
+	)
+
+	replayed, err := s.queue.ReplayDLQ(ctx, filter)
+	if err != nil {
+		s.logger.Error("replay dead letters queue error",
+			logging.IntAttr("replayed", replayed),
+			logging.ErrAttr(err),
+		)
+		return 0, err
+	}
+
+	s.logger.Info("dead letters were successfully replayed",
+		logging.IntAttr("replayed", replayed),
+	)
+	return replayed, nil
+}
+
+// DeleteDeadLetters удаляет записи, попавшие в DLQ раньше заданного момента
+func (s *Service) DeleteDeadLetters(ctx context.Context, in *DeleteDeadLettersRequestInput) (int, error) {
+	filter, err := validateDeleteDeadLettersInput(in)
+	if err != nil {
+		s.logger.Error("delete dead letters validation failed",
+			logging.StringAttr("before", in.Before),
+			logging.ErrAttr(err),
+		)
+		return 0, err
+	}
+
+	s.logger.Info("attempt to delete dead letters",
+		logging.StringAttr("before", in.Before),
+	)
+
+	deleted, err := s.queue.DeleteDLQ(ctx, filter)
+	if err != nil {
+		s.logger.Error("delete dead letters queue error",
+			logging.IntAttr("deleted", deleted),
+			logging.ErrAttr(err),
+		)
+		return 0, err
+	}
+
+	s.logger.Info("dead letters were successfully deleted",
+		logging.IntAttr("deleted", deleted),
+	)
+	return deleted, nil
+}
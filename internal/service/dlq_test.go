@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"red_collar/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_ListDeadLetters(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         DeadLettersRequestInput
+		wantErr    bool
+		wantFilter domain.DeadLetterFilter
+		wantOffset int
+		wantLimit  int
+	}{
+		{
+			name:    "validation error - unknown event type",
+			in:      DeadLettersRequestInput{EventType: "zone.left"},
+			wantErr: true,
+		},
+		{
+			name:    "validation error - negative subscription id",
+			in:      DeadLettersRequestInput{SubscriptionID: "-1"},
+			wantErr: true,
+		},
+		{
+			name:    "validation error - invalid offset",
+			in:      DeadLettersRequestInput{Offset: "-5"},
+			wantErr: true,
+		},
+		{
+			name:      "success - defaults",
+			wantLimit: defaultDLQLimit,
+		},
+		{
+			name: "success - filters and clamped limit",
+			in: DeadLettersRequestInput{
+				SubscriptionID: "0",
+				EventType:      "zone.entered",
+				IncidentID:     "7",
+				Limit:          "100000",
+				Offset:         "10",
+			},
+			wantFilter: domain.DeadLetterFilter{
+				SubscriptionID: ptr(0),
+				EventType:      domain.EventZoneEntered,
+				IncidentID:     ptr(7),
+			},
+			wantOffset: 10,
+			wantLimit:  maxDLQLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var called bool
+			service := &Service{
+				queue: &mockQueue{
+					listDLQFunc: func(ctx context.Context, filter domain.DeadLetterFilter, offset, limit int) ([]domain.DeadLetter, int, error) {
+						called = true
+						require.Equal(t, tt.wantFilter, filter)
+						require.Equal(t, tt.wantOffset, offset)
+						require.Equal(t, tt.wantLimit, limit)
+						return nil, 0, nil
+					},
+				},
+				logger: &mockLogger{},
+			}
+
+			out, err := service.ListDeadLetters(context.Background(), &tt.in)
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				require.False(t, called, "queue must not be called")
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.NotNil(t, out.Entries)
+			require.Equal(t, tt.wantLimit, out.Limit)
+		})
+	}
+}
+
+func TestService_ReplayDeadLetters(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           ReplayDeadLettersRequestInput
+		replayed     int
+		queueErr     error
+		wantErr      bool
+		wantReplayed int
+	}{
+		{
+			name:    "validation error - no filter without all",
+			wantErr: true,
+		},
+		{
+			name:    "validation error - all with filter",
+			in:      ReplayDeadLettersRequestInput{All: true, EventType: "zone.entered"},
+			wantErr: true,
+		},
+		{
+			name:    "validation error - unknown event type",
+			in:      ReplayDeadLettersRequestInput{EventType: "zone.left"},
+			wantErr: true,
+		},
+		{
+			name:     "queue error",
+			in:       ReplayDeadLettersRequestInput{All: true},
+			queueErr: errors.New("connection refused"),
+			wantErr:  true,
+		},
+		{
+			name:         "success - all",
+			in:           ReplayDeadLettersRequestInput{All: true},
+			replayed:     3,
+			wantReplayed: 3,
+		},
+		{
+			name:         "success - by subscription",
+			in:           ReplayDeadLettersRequestInput{SubscriptionID: ptr(2)},
+			replayed:     1,
+			wantReplayed: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &Service{
+				queue: &mockQueue{
+					replayDLQFunc: func(ctx context.Context, filter domain.DeadLetterFilter) (int, error) {
+						require.Equal(t, tt.in.SubscriptionID, filter.SubscriptionID)
+						return tt.replayed, tt.queueErr
+					},
+				},
+				logger: &mockLogger{},
+			}
+
+			replayed, err := service.ReplayDeadLetters(context.Background(), &tt.in)
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.Equal(t, tt.wantReplayed, replayed)
+		})
+	}
+}
+
+func TestService_ReplayDeadLetter(t *testing.T) {
+	service := &Service{
+		queue: &mockQueue{
+			replayDLQFunc: func(ctx context.Context, filter domain.DeadLetterFilter) (int, error) {
+				if filter.ID == "event:1" {
+					return 1, nil
+				}
+				return 0, nil
+			},
+		},
+		logger: &mockLogger{},
+	}
+
+	require.NoError(t, service.ReplayDeadLetter(context.Background(), "event:1"))
+
+	err := service.ReplayDeadLetter(context.Background(), "event:2")
+	var appErr *domain.AppError
+	require.True(t, errors.As(err, &appErr) && appErr.Code == domain.CodeNotFound, "expected not found, got %v", err)
+}
+
+func TestService_DeleteDeadLetters(t *testing.T) {
+	tests := []struct {
+		name    string
+		before  string
+		wantErr bool
+	}{
+		{name: "validation error - missing before", wantErr: true},
+		{name: "validation error - invalid before", before: "yesterday", wantErr: true},
+		{name: "success", before: "2026-10-17T10:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &Service{
+				queue: &mockQueue{
+					deleteDLQFunc: func(ctx context.Context, filter domain.DeadLetterFilter) (int, error) {
+						require.NotNil(t, filter.FailedBefore)
+						require.True(t, filter.FailedBefore.Equal(time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)))
+						return 2, nil
+					},
+				},
+				logger: &mockLogger{},
+			}
+
+			deleted, err := service.DeleteDeadLetters(context.Background(), &DeleteDeadLettersRequestInput{Before: tt.before})
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.Equal(t, 2, deleted)
+		})
+	}
+}
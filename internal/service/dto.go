@@ -1,6 +1,10 @@
 package service
 
-import "red_collar/internal/domain"
+import (
+	"encoding/json"
+	"red_collar/internal/domain"
+	"time"
+)
 
 // Input
 type CreateIncidentRequestInput struct {
@@ -10,22 +14,179 @@ type CreateIncidentRequestInput struct {
 	Long        float64
 	Radius      int
 	Active      *bool
+	Severity    string
+	Category    string
+	Boundary    json.RawMessage
+	// WarningBuffer - собственный буфер предупреждения зоны, nil - глобальное значение
+	WarningBuffer *int
+	StartsAt      *time.Time
+	EndsAt        *time.Time
 }
 
 type FullUpdateIncidentRequestInput struct {
-	ID          string
-	Title       string
-	Description *string
-	Lat         float64
-	Long        float64
-	Radius      int
-	Active      *bool
+	ID            string
+	Title         string
+	Description   *string
+	Lat           float64
+	Long          float64
+	Radius        int
+	Active        *bool
+	Severity      string
+	Category      string
+	Boundary      json.RawMessage
+	WarningBuffer *int
+	StartsAt      *time.Time
+	EndsAt        *time.Time
+	// ExpectedVersions - версии из If-Match, пустой список - обновление без проверки версии
+	ExpectedVersions []int
+}
+
+type PatchIncidentRequestInput struct {
+	ID string
+	// Patch - JSON Merge Patch (RFC 7396)
+	Patch            json.RawMessage
+	ExpectedVersions []int
+}
+
+type PaginateIncidentsRequestInput struct {
+	Limit string
+	Page  string
+	// Severity - список уровней через запятую
+	Severity    string
+	Category    string
+	Active      string
+	CreatedFrom string
+	CreatedTo   string
+	UpdatedFrom string
+	UpdatedTo   string
+	Query       string
+	// BBox - minLon,minLat,maxLon,maxLat
+	BBox string
+	// Lat, Long, Radius - зоны не дальше Radius метров от точки
+	Lat    string
+	Long   string
+	Radius string
+	Sort   string
+	Order  string
+	// Deleted - exclude (по умолчанию), include или only
+	Deleted string
+	// Cursor - курсор следующей страницы, nil - постраничный режим page/limit.
+	// Пустая строка запрашивает первую страницу в курсорном режиме
+	Cursor *string
+}
+
+type ImportIncidentsRequestInput struct {
+	// Format - geojson (по умолчанию), csv или kml
+	Format string
+	Data   []byte
+	// DryRun - только проверить данные, ничего не записывая
+	DryRun string
+}
+
+type ExportIncidentsRequestInput struct {
+	Format string
+	// Filter - те же фильтры и сортировка, что у списка. Пагинация не применяется
+	Filter PaginateIncidentsRequestInput
 }
 
 type CheckCoordinatesRequestInput struct {
 	UserID string
 	Lat    float64
 	Long   float64
+	// CheckedAt - время снятия координат в RFC3339, учитывается только в пакетной проверке.
+	// Пустая строка - время запроса
+	CheckedAt string
+}
+
+type CheckCoordinatesBatchRequestInput struct {
+	Points []CheckCoordinatesRequestInput
+}
+
+type UserChecksRequestInput struct {
+	UserID string
+	// From, To - границы периода в RFC3339, To не включается
+	From  string
+	To    string
+	Limit string
+	// Cursor - курсор следующей страницы, пустая строка - первая страница
+	Cursor string
+	// Order - desc (по умолчанию, сначала новые) или asc
+	Order string
+}
+
+type StatsSeriesRequestInput struct {
+	// From, To - границы периода в RFC3339, To не включается. Без From берется окно WindowMinutes до To
+	From string
+	To   string
+	// Bucket - 1m, 5m, 1h или 1d, пустая строка - самый мелкий интервал, при котором ряд не слишком длинный
+	Bucket        string
+	IncidentID    string
+	WindowMinutes int
+}
+
+type HeatmapRequestInput struct {
+	// BBox - область minLon,minLat,maxLon,maxLat
+	BBox string
+	// From, To - период в RFC3339, To не включается. По умолчанию последние сутки
+	From string
+	To   string
+	// Grid - geohash (по умолчанию), square или hex
+	Grid string
+	// Precision - длина geohash, SizeM - размер квадрата или сторона шестиугольника в метрах
+	Precision string
+	SizeM     string
+	// InDanger - учитывать только проверки в опасных зонах
+	InDanger string
+}
+
+type IncidentOccupantsRequestInput struct {
+	ID string
+	// Minutes - окно актуальности последней проверки, пустая строка - значение из настроек
+	Minutes string
+}
+
+type WebhookSubscriptionRequestInput struct {
+	// ID - только для обновления
+	ID     string
+	URL    string
+	Secret string
+	// Enabled - nil означает включенную подписку
+	Enabled     *bool
+	EventTypes  []string
+	IncidentIDs []int
+	Severities  []string
+	// BBox - [minLon, minLat, maxLon, maxLat], пустой - без ограничения по области
+	BBox []float64
+}
+
+type RotateWebhookSecretRequestInput struct {
+	ID string
+	// Secret - новый секрет, пустой - сгенерировать
+	Secret string
+	// GracePeriodSecs - сколько секунд прежний секрет еще действует, nil - значение по умолчанию
+	GracePeriodSecs *int
+}
+
+type DeadLettersRequestInput struct {
+	SubscriptionID string
+	EventType      string
+	IncidentID     string
+	Limit          string
+	Offset         string
+}
+
+// ReplayDeadLettersRequestInput - отбор записей DLQ для повторной отправки.
+// Без фильтров нужен явный All, чтобы случайный запрос не повторил всю DLQ
+type ReplayDeadLettersRequestInput struct {
+	SubscriptionID *int
+	EventType      string
+	IncidentID     *int
+	All            bool
+}
+
+type DeleteDeadLettersRequestInput struct {
+	// Before - удаляются записи, попавшие в DLQ раньше этого момента (RFC3339)
+	Before string
 }
 
 // OutPut
@@ -38,5 +199,73 @@ type Pagination struct {
 
 type PaginateIncidentsOutput struct {
 	Incidents  []domain.Incident `json:"data"`
-	Pagination *Pagination       `json:"pagination"`
+	Pagination *Pagination       `json:"pagination,omitempty"`
+	// NextCursor - курсор следующей страницы в курсорном режиме, отсутствует на последней странице
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// StatsSeriesOutput - временные ряды статистики, по одному на зону
+type StatsSeriesOutput struct {
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Bucket string           `json:"bucket"`
+	Series []ZoneStatSeries `json:"series"`
+}
+
+// ZoneStatSeries - интервалы зоны по возрастанию времени. Интервалы без проверок пропускаются
+type ZoneStatSeries struct {
+	IncidentID int                     `json:"incident_id"`
+	Buckets    []domain.ZoneStatBucket `json:"buckets"`
+}
+
+// IncidentOccupantsOutput - пользователи внутри зоны, от недавно замеченных к давно
+type IncidentOccupantsOutput struct {
+	IncidentID    int                   `json:"incident_id"`
+	WindowMinutes int                   `json:"window_minutes"`
+	Count         int                   `json:"count"`
+	Occupants     []domain.ZoneOccupant `json:"data"`
+}
+
+type UserChecksOutput struct {
+	Checks []domain.LocationCheck `json:"data"`
+	// NextCursor - курсор следующей страницы, отсутствует на последней странице
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ImportIncidentsOutput - отчет об импорте. Если Errors не пуст или DryRun, ни один инцидент не записан
+type ImportIncidentsOutput struct {
+	DryRun  bool                `json:"dry_run"`
+	Total   int                 `json:"total"`
+	Created int                 `json:"created"`
+	Updated int                 `json:"updated"`
+	Items   []ImportItemResult  `json:"items,omitempty"`
+	Errors  []ImportItemFailure `json:"errors,omitempty"`
+}
+
+// ImportItemResult - записанный инцидент. В режиме dry run ID и Action не заполняются
+type ImportItemResult struct {
+	Index  int    `json:"index"`
+	ID     int    `json:"id,omitempty"`
+	Title  string `json:"title"`
+	Action string `json:"action,omitempty"`
+}
+
+// ImportItemFailure - ошибка элемента импорта. Index - номер объекта в файле, начиная с 0
+type ImportItemFailure struct {
+	Index   int    `json:"index"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+type ExportIncidentsOutput struct {
+	ContentType string
+	Data        []byte
+}
+
+// DeadLettersOutput - страница записей DLQ, сначала последние попавшие
+type DeadLettersOutput struct {
+	Entries []domain.DeadLetter `json:"entries"`
+	Total   int                 `json:"total"`
+	Limit   int                 `json:"limit"`
+	Offset  int                 `json:"offset"`
 }
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"red_collar/internal/domain"
+	"time"
+)
+
+const (
+	geoJSONFeatureCollectionType = "FeatureCollection"
+	geoJSONFeatureType           = "Feature"
+
+	geoJSONContentType = "application/geo+json"
+)
+
+type geoJSONFeatureCollection struct {
+	Type     string            `json:"type"`
+	Features []json.RawMessage `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                    `json:"type"`
+	ID         int                       `json:"id,omitempty"`
+	Geometry   json.RawMessage           `json:"geometry"`
+	Properties geoJSONIncidentProperties `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// geoJSONIncidentProperties - свойства объекта карты. При импорте учитываются только поля инцидента,
+// id, version и даты экспортируются для справки. radius - синоним radius_m для слоев из ГИС
+type geoJSONIncidentProperties struct {
+	ID            int        `json:"id,omitempty"`
+	Title         string     `json:"title"`
+	Description   *string    `json:"description,omitempty"`
+	Radius        *float64   `json:"radius_m,omitempty"`
+	RadiusAlias   *float64   `json:"radius,omitempty"`
+	Active        *bool      `json:"active,omitempty"`
+	Severity      string     `json:"severity,omitempty"`
+	Category      string     `json:"category,omitempty"`
+	WarningBuffer *int       `json:"warning_buffer_m,omitempty"`
+	StartsAt      *time.Time `json:"starts_at,omitempty"`
+	EndsAt        *time.Time `json:"ends_at,omitempty"`
+	Version       int        `json:"version,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+// importItem - инцидент, прочитанный из импортируемого файла. Err - ошибка разбора именно этого элемента
+type importItem struct {
+	Input CreateIncidentRequestInput
+	// BoundaryOmitted - граница в файле не задана (пустая ячейка CSV), а не заменена точкой,
+	// поэтому обновляемая полигональная зона ее сохраняет
+	BoundaryOmitted bool
+	Err             error
+}
+
+// decodeGeoJSONIncidents разбирает FeatureCollection. Точка превращается в круговую зону
+// с радиусом из свойств, Polygon/MultiPolygon - в полигональную. Ошибки отдельных объектов
+// не прерывают разбор, а возвращаются в importItem.Err
+func decodeGeoJSONIncidents(data []byte) ([]importItem, error) {
+	var collection geoJSONFeatureCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, domain.ErrInvalidRequest("invalid geojson: " + err.Error())
+	}
+	if collection.Type != geoJSONFeatureCollectionType {
+		return nil, domain.ErrInvalidRequest("geojson must be a FeatureCollection")
+	}
+
+	items := make([]importItem, len(collection.Features))
+	for i, raw := range collection.Features {
+		in, err := decodeGeoJSONFeature(raw)
+		if err != nil {
+			items[i].Err = err
+			continue
+		}
+		items[i].Input = *in
+	}
+	return items, nil
+}
+
+func decodeGeoJSONFeature(raw json.RawMessage) (*CreateIncidentRequestInput, error) {
+	var feature geoJSONFeature
+	if err := json.Unmarshal(raw, &feature); err != nil {
+		return nil, domain.ErrInvalidValidation("invalid feature: " + err.Error())
+	}
+	if feature.Type != geoJSONFeatureType {
+		return nil, domain.ErrInvalidValidation("object must be a Feature")
+	}
+
+	props := feature.Properties
+	in := &CreateIncidentRequestInput{
+		Title:         props.Title,
+		Description:   props.Description,
+		Active:        props.Active,
+		Severity:      props.Severity,
+		Category:      props.Category,
+		WarningBuffer: props.WarningBuffer,
+		StartsAt:      props.StartsAt,
+		EndsAt:        props.EndsAt,
+	}
+
+	radius := props.Radius
+	if radius == nil {
+		radius = props.RadiusAlias
+	}
+	if radius != nil {
+		in.Radius = int(math.Round(*radius))
+	}
+
+	if len(feature.Geometry) == 0 || bytes.Equal(feature.Geometry, []byte("null")) {
+		return nil, domain.ErrInvalidValidation("feature geometry is required")
+	}
+
+	var geometry geoJSONGeometry
+	if err := json.Unmarshal(feature.Geometry, &geometry); err != nil {
+		return nil, domain.ErrInvalidValidation("invalid geometry: " + err.Error())
+	}
+
+	switch geometry.Type {
+	case "Point":
+		var coords []float64
+		if err := json.Unmarshal(geometry.Coordinates, &coords); err != nil || len(coords) < 2 {
+			return nil, domain.ErrInvalidValidation("point coordinates must be [long, lat]")
+		}
+		in.Long, in.Lat = coords[0], coords[1]
+	case "Polygon", "MultiPolygon":
+		in.Boundary = feature.Geometry
+	default:
+		return nil, domain.ErrInvalidValidation(fmt.Sprintf("unsupported geometry type %q, must be Point, Polygon or MultiPolygon", geometry.Type))
+	}
+	return in, nil
+}
+
+// encodeGeoJSONIncidents собирает FeatureCollection для слоя карты: круговая зона
+// отдается точкой центра с radius_m в свойствах, полигональная - своей границей
+func encodeGeoJSONIncidents(incidents []domain.Incident) ([]byte, error) {
+	features := make([]geoJSONFeature, 0, len(incidents))
+	for _, incident := range incidents {
+		geometry := incident.Boundary
+		if len(geometry) == 0 {
+			point, err := json.Marshal(struct {
+				Type        string     `json:"type"`
+				Coordinates [2]float64 `json:"coordinates"`
+			}{Type: "Point", Coordinates: [2]float64{incident.Long, incident.Lat}})
+			if err != nil {
+				return nil, err
+			}
+			geometry = point
+		}
+
+		radius := float64(incident.Radius)
+		description := incident.Description
+		active := incident.Active
+		createdAt, updatedAt := incident.CreatedAt, incident.UpdatedAt
+		features = append(features, geoJSONFeature{
+			Type:     geoJSONFeatureType,
+			ID:       incident.ID,
+			Geometry: geometry,
+			Properties: geoJSONIncidentProperties{
+				ID:            incident.ID,
+				Title:         incident.Title,
+				Description:   &description,
+				Radius:        &radius,
+				Active:        &active,
+				Severity:      string(incident.Severity),
+				Category:      incident.Category,
+				WarningBuffer: incident.WarningBuffer,
+				StartsAt:      incident.StartsAt,
+				EndsAt:        incident.EndsAt,
+				Version:       incident.Version,
+				CreatedAt:     &createdAt,
+				UpdatedAt:     &updatedAt,
+			},
+		})
+	}
+
+	return json.Marshal(struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}{Type: geoJSONFeatureCollectionType, Features: features})
+}
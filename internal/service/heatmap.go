@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/json"
+	"math"
+	"red_collar/internal/domain"
+	"time"
+)
+
+const (
+	// earthRadiusM - радиус сферы Web Mercator (EPSG:3857)
+	earthRadiusM = 6378137.0
+	// maxMercatorLat - широта, за которой Web Mercator не определен
+	maxMercatorLat = 85.05112878
+)
+
+// HeatmapOutput - ячейки тепловой карты от самых плотных к пустым. Ячейки без проверок не возвращаются
+type HeatmapOutput struct {
+	Grid         domain.HeatmapGrid   `json:"grid"`
+	Precision    int                  `json:"precision,omitempty"`
+	SizeM        int                  `json:"size_m,omitempty"`
+	From         time.Time            `json:"from"`
+	To           time.Time            `json:"to"`
+	InDangerOnly bool                 `json:"in_danger_only"`
+	Total        int                  `json:"total"`
+	Cells        []domain.HeatmapCell `json:"cells"`
+}
+
+// HeatmapGeoJSONOutput - те же ячейки как GeoJSON FeatureCollection полигонов
+type HeatmapGeoJSONOutput struct {
+	Type     string           `json:"type"`
+	Features []HeatmapFeature `json:"features"`
+}
+
+type HeatmapFeature struct {
+	Type       string                `json:"type"`
+	Geometry   json.RawMessage       `json:"geometry" swaggertype:"object"`
+	Properties HeatmapCellProperties `json:"properties"`
+}
+
+type HeatmapCellProperties struct {
+	CellID      string `json:"cell_id"`
+	Count       int    `json:"count"`
+	UniqueUsers int    `json:"unique_users"`
+}
+
+func buildHeatmapGeoJSON(out *HeatmapOutput) *HeatmapGeoJSONOutput {
+	collection := &HeatmapGeoJSONOutput{
+		Type:     geoJSONFeatureCollectionType,
+		Features: make([]HeatmapFeature, len(out.Cells)),
+	}
+	for i, cell := range out.Cells {
+		collection.Features[i] = HeatmapFeature{
+			Type:     geoJSONFeatureType,
+			Geometry: cell.Geometry,
+			Properties: HeatmapCellProperties{
+				CellID:      cell.ID,
+				Count:       cell.Count,
+				UniqueUsers: cell.UniqueUsers,
+			},
+		}
+	}
+	return collection
+}
+
+// estimateHeatmapCells оценивает сверху, сколько ячеек сетки покрывает область
+func estimateHeatmapCells(filter domain.HeatmapFilter) int {
+	bbox := filter.BBox
+
+	if filter.Grid == domain.HeatmapGridGeohash {
+		// каждый символ geohash - 5 бит, которые делятся между долготой и широтой начиная с долготы
+		bits := 5 * filter.Precision
+		cellLong := 360 / math.Exp2(float64((bits+1)/2))
+		cellLat := 180 / math.Exp2(float64(bits/2))
+		return int(math.Ceil((bbox.MaxLong-bbox.MinLong)/cellLong+1) * math.Ceil((bbox.MaxLat-bbox.MinLat)/cellLat+1))
+	}
+
+	size := float64(filter.SizeM)
+	width := mercatorX(bbox.MaxLong) - mercatorX(bbox.MinLong) + 2*size
+	height := mercatorY(bbox.MaxLat) - mercatorY(bbox.MinLat) + 2*size
+	cellArea := size * size
+	if filter.Grid == domain.HeatmapGridHex {
+		// SizeM - сторона шестиугольника
+		cellArea = 3 * math.Sqrt(3) / 2 * size * size
+	}
+	return int(math.Ceil(width * height / cellArea))
+}
+
+func mercatorX(long float64) float64 {
+	return earthRadiusM * long * math.Pi / 180
+}
+
+func mercatorY(lat float64) float64 {
+	lat = math.Max(-maxMercatorLat, math.Min(maxMercatorLat, lat))
+	return earthRadiusM * math.Log(math.Tan(math.Pi/4+lat*math.Pi/360))
+}
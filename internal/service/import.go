@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"red_collar/internal/domain"
+	"time"
+
+	"github.com/theartofdevel/logging"
+)
+
+// Форматы импорта и экспорта инцидентов
+const (
+	FormatGeoJSON = "geojson"
+	FormatCSV     = "csv"
+	FormatKML     = "kml"
+)
+
+// incidentCodec - чтение и запись инцидентов в одном из форматов обмена
+type incidentCodec struct {
+	contentType string
+	decode      func(data []byte) ([]importItem, error)
+	encode      func(incidents []domain.Incident) ([]byte, error)
+}
+
+var incidentCodecs = map[string]incidentCodec{
+	FormatGeoJSON: {contentType: geoJSONContentType, decode: decodeGeoJSONIncidents, encode: encodeGeoJSONIncidents},
+	FormatCSV:     {contentType: csvContentType, decode: decodeCSVIncidents, encode: encodeCSVIncidents},
+	FormatKML:     {contentType: kmlContentType, decode: decodeKMLIncidents, encode: encodeKMLIncidents},
+}
+
+// codecForFormat возвращает кодек формата, пустой формат означает geojson
+func codecForFormat(format string) (incidentCodec, error) {
+	if format == "" {
+		format = FormatGeoJSON
+	}
+	codec, ok := incidentCodecs[format]
+	if !ok {
+		return incidentCodec{}, domain.ErrInvalidValidation(fmt.Sprintf("unsupported format %q, must be geojson, csv or kml", format))
+	}
+	return codec, nil
+}
+
+// ImportIncidents создает или обновляет инциденты из файла одной транзакцией, сопоставляя их по названию.
+// Все элементы проверяются по правилам создания инцидента; при любой ошибке ничего не записывается,
+// а ошибки возвращаются в отчете по каждому элементу. В режиме dry run выполняется только проверка
+func (s *Service) ImportIncidents(ctx context.Context, in *ImportIncidentsRequestInput) (*ImportIncidentsOutput, error) {
+	items, dryRun, err := validateImportInput(in, s.opts.ImportMaxIncidents)
+	if err != nil {
+		s.logger.Error("import incidents validation failed",
+			logging.StringAttr("format", in.Format),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to import incidents",
+		logging.StringAttr("format", in.Format),
+		logging.IntAttr("total", len(items)),
+		logging.BoolAttr("dryRun", dryRun),
+	)
+
+	out := &ImportIncidentsOutput{Total: len(items), DryRun: dryRun}
+	out.Errors = validateImportItems(items, s.opts.Categories)
+	if len(out.Errors) > 0 {
+		s.logger.Warn("import incidents rejected",
+			logging.IntAttr("errors", len(out.Errors)),
+		)
+		return out, nil
+	}
+
+	if dryRun {
+		out.Items = make([]ImportItemResult, len(items))
+		for i := range items {
+			out.Items[i] = ImportItemResult{Index: i, Title: items[i].Input.Title}
+		}
+		s.logger.Info("import incidents dry run passed",
+			logging.IntAttr("total", len(items)),
+		)
+		return out, nil
+	}
+
+	// Поля, которых нет в файле, новый инцидент получает по умолчанию, а существующий сохраняет
+	incidents := make([]*domain.ImportIncident, len(items))
+	for i := range items {
+		incidents[i] = mapImportItemToDomain(&items[i])
+	}
+
+	results, err := s.incidents.UpsertByTitle(ctx, incidents)
+	if err != nil {
+		var itemErr *domain.ImportItemError
+		var appErr *domain.AppError
+		if errors.As(err, &itemErr) && errors.As(itemErr.Err, &appErr) {
+			s.logger.Warn("import incidents rejected by repository",
+				logging.IntAttr("index", itemErr.Index),
+				logging.ErrAttr(err),
+			)
+			out.Errors = []ImportItemFailure{{
+				Index:   itemErr.Index,
+				Title:   items[itemErr.Index].Input.Title,
+				Message: appErr.Message,
+			}}
+			return out, nil
+		}
+
+		s.logger.Error("import incidents repository error",
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	// Пользователи, уже находящиеся в новой или расширенной зоне, получают zone.entered, как при создании и обновлении
+	now := time.Now()
+	out.Items = make([]ImportItemResult, len(results))
+	for i, result := range results {
+		incident := result.Incident
+		out.Items[i] = ImportItemResult{
+			Index: i,
+			ID:    incident.ID,
+			Title: incident.Title,
+		}
+		if prev := result.Previous; prev != nil {
+			out.Items[i].Action = domain.ImportActionUpdated
+			out.Updated++
+			s.deleteIncidenFromCache(ctx, incidentCacheKey(incident.ID))
+			s.enqueueIncidentEvent(ctx, domain.EventIncidentUpdated, incident.ID, incident)
+			if zoneExpanded(prev, incident, now) {
+				s.alertZoneOccupants(ctx, incident)
+			}
+		} else {
+			out.Items[i].Action = domain.ImportActionCreated
+			out.Created++
+			s.enqueueIncidentEvent(ctx, domain.EventIncidentCreated, incident.ID, incident)
+			s.alertZoneOccupants(ctx, incident)
+		}
+	}
+
+	s.logger.Info("incidents were successfully imported",
+		logging.IntAttr("created", out.Created),
+		logging.IntAttr("updated", out.Updated),
+	)
+	return out, nil
+}
+
+// ExportIncidents выгружает все инциденты, подходящие под фильтры списка, в заданном формате
+func (s *Service) ExportIncidents(ctx context.Context, in *ExportIncidentsRequestInput) (*ExportIncidentsOutput, error) {
+	codec, err := codecForFormat(in.Format)
+	if err != nil {
+		s.logger.Error("export incidents validation failed",
+			logging.StringAttr("format", in.Format),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	filter, err := validateIncidentFilter(&in.Filter, s.opts.Categories)
+	if err != nil {
+		s.logger.Error("export incidents validation failed",
+			logging.StringAttr("format", in.Format),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to export incidents",
+		logging.StringAttr("format", in.Format),
+	)
+
+	incidents, err := s.incidents.PaginateCursor(ctx, filter, s.opts.ExportMaxIncidents+1)
+	if err != nil {
+		s.logger.Error("export incidents repository error",
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+	if len(incidents) > s.opts.ExportMaxIncidents {
+		return nil, domain.ErrInvalidValidation(fmt.Sprintf("too many incidents to export, narrow the filter (max %d)", s.opts.ExportMaxIncidents))
+	}
+
+	data, err := codec.encode(incidents)
+	if err != nil {
+		s.logger.Error("export incidents encoding error",
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("incidents were successfully exported",
+		logging.IntAttr("count", len(incidents)),
+	)
+	return &ExportIncidentsOutput{ContentType: codec.contentType, Data: data}, nil
+}
@@ -0,0 +1,468 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"red_collar/internal/domain"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPolygon = `{"type":"Polygon","coordinates":[[[37.60,55.75],[37.62,55.75],[37.62,55.76],[37.60,55.76],[37.60,55.75]]]}`
+
+func TestDecodeGeoJSONIncidents(t *testing.T) {
+	t.Run("not a feature collection", func(t *testing.T) {
+		_, err := decodeGeoJSONIncidents([]byte(`{"type":"Feature"}`))
+		var appErr *domain.AppError
+		require.True(t, errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidRequest, "wrong error: %v", err)
+	})
+
+	t.Run("features", func(t *testing.T) {
+		data := `{"type":"FeatureCollection","features":[
+			{"type":"Feature","geometry":{"type":"Point","coordinates":[37.61,55.75]},
+			 "properties":{"title":"Пожар","description":"склад","radius_m":150,"active":false,"category":"fire"}},
+			{"type":"Feature","geometry":` + testPolygon + `,"properties":{"title":"Перекрытие"}},
+			{"type":"Feature","geometry":{"type":"Point","coordinates":[37.61,55.75]},"properties":{"title":"ГИС","radius":99.6}},
+			{"type":"Feature","geometry":null,"properties":{"title":"Без геометрии"}},
+			{"type":"Feature","geometry":{"type":"LineString","coordinates":[[37.6,55.7],[37.7,55.8]]},"properties":{"title":"Линия"}},
+			{"type":"Feature","geometry":{"type":"Point","coordinates":[37.61,55.75]},"properties":{"title":1}}
+		]}`
+
+		items, err := decodeGeoJSONIncidents([]byte(data))
+		require.NoError(t, err)
+		require.Len(t, items, 6)
+
+		require.NoError(t, items[0].Err)
+		require.Equal(t, "Пожар", items[0].Input.Title)
+		require.Equal(t, "склад", *items[0].Input.Description)
+		require.Equal(t, 55.75, items[0].Input.Lat)
+		require.Equal(t, 37.61, items[0].Input.Long)
+		require.Equal(t, 150, items[0].Input.Radius)
+		require.False(t, *items[0].Input.Active)
+		require.Equal(t, "fire", items[0].Input.Category)
+
+		require.NoError(t, items[1].Err)
+		require.JSONEq(t, testPolygon, string(items[1].Input.Boundary))
+
+		require.NoError(t, items[2].Err)
+		require.Equal(t, 100, items[2].Input.Radius)
+
+		require.Error(t, items[3].Err)
+		require.Error(t, items[4].Err)
+		require.Error(t, items[5].Err)
+	})
+}
+
+func TestDecodeCSVIncidents(t *testing.T) {
+	t.Run("unknown column", func(t *testing.T) {
+		_, err := decodeCSVIncidents([]byte("title,colour\nПожар,red\n"))
+		require.Error(t, err)
+	})
+
+	t.Run("title column is required", func(t *testing.T) {
+		_, err := decodeCSVIncidents([]byte("lat,long\n55.75,37.61\n"))
+		require.Error(t, err)
+	})
+
+	t.Run("rows", func(t *testing.T) {
+		data := "\xef\xbb\xbftitle;lat;long;radius_m;active;boundary;starts_at\n" +
+			"Пожар;55.75;37.61;150.0;false;;2026-10-16T10:00:00Z\n" +
+			`Перекрытие;;;;;"` + strings.ReplaceAll(testPolygon, `"`, `""`) + `";` + "\n" +
+			"Ошибка;north;37.61;150;;;\n" +
+			"Короткая;55.75\n"
+
+		items, err := decodeCSVIncidents([]byte(data))
+		require.NoError(t, err)
+		require.Len(t, items, 4)
+
+		require.NoError(t, items[0].Err)
+		require.Equal(t, "Пожар", items[0].Input.Title)
+		require.Equal(t, 55.75, items[0].Input.Lat)
+		require.Equal(t, 150, items[0].Input.Radius)
+		require.False(t, *items[0].Input.Active)
+		require.Nil(t, items[0].Input.Description)
+		require.NotNil(t, items[0].Input.StartsAt)
+
+		require.NoError(t, items[1].Err)
+		require.JSONEq(t, testPolygon, string(items[1].Input.Boundary))
+
+		require.Error(t, items[2].Err)
+		require.Error(t, items[3].Err)
+	})
+}
+
+func TestDecodeKMLIncidents(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+  <Document>
+    <Folder>
+      <Placemark>
+        <name>Пожар</name>
+        <description>склад</description>
+        <ExtendedData>
+          <Data name="radius_m"><value>150</value></Data>
+          <Data name="category"><value>fire</value></Data>
+        </ExtendedData>
+        <Point><coordinates>37.61,55.75,0</coordinates></Point>
+      </Placemark>
+    </Folder>
+    <Placemark>
+      <name>Перекрытие</name>
+      <ExtendedData>
+        <SchemaData schemaUrl="#zones"><SimpleData name="active">false</SimpleData></SchemaData>
+      </ExtendedData>
+      <Polygon>
+        <outerBoundaryIs><LinearRing><coordinates>
+          37.60,55.75 37.62,55.75 37.62,55.76 37.60,55.76 37.60,55.75
+        </coordinates></LinearRing></outerBoundaryIs>
+      </Polygon>
+    </Placemark>
+    <Placemark>
+      <name>Линия</name>
+      <LineString><coordinates>37.6,55.7 37.7,55.8</coordinates></LineString>
+    </Placemark>
+  </Document>
+</kml>`
+
+	items, err := decodeKMLIncidents([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+
+	require.NoError(t, items[0].Err)
+	require.Equal(t, "Пожар", items[0].Input.Title)
+	require.Equal(t, "склад", *items[0].Input.Description)
+	require.Equal(t, 55.75, items[0].Input.Lat)
+	require.Equal(t, 37.61, items[0].Input.Long)
+	require.Equal(t, 150, items[0].Input.Radius)
+	require.Equal(t, "fire", items[0].Input.Category)
+
+	require.NoError(t, items[1].Err)
+	require.False(t, *items[1].Input.Active)
+	require.JSONEq(t, testPolygon, string(items[1].Input.Boundary))
+
+	require.Error(t, items[2].Err)
+
+	_, err = decodeKMLIncidents([]byte("<kml><Placemark>"))
+	require.Error(t, err)
+}
+
+func TestService_ImportIncidents(t *testing.T) {
+	point := func(title string, radius int) string {
+		return `{"type":"Feature","geometry":{"type":"Point","coordinates":[37.61,55.75]},"properties":{"title":"` +
+			title + `","radius_m":` + strconv.Itoa(radius) + `}}`
+	}
+	collection := func(features ...string) []byte {
+		data := `{"type":"FeatureCollection","features":[`
+		for i, f := range features {
+			if i > 0 {
+				data += ","
+			}
+			data += f
+		}
+		return []byte(data + `]}`)
+	}
+
+	tests := []struct {
+		name          string
+		data          []byte
+		format        string
+		dryRun        string
+		incidents     func() *mockIncidentsRepository
+		wantErr       bool
+		errType       func(err error) bool
+		wantFailures  []int
+		wantCreated   int
+		wantUpdated   int
+		wantCacheKeys []string
+		// wantAlerted - инциденты, по которым ищутся уже находящиеся в зоне пользователи
+		wantAlerted []int
+	}{
+		{
+			name:      "validation error - unsupported format",
+			data:      collection(point("a", 100)),
+			format:    "shp",
+			incidents: func() *mockIncidentsRepository { return &mockIncidentsRepository{} },
+			wantErr:   true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name:      "validation error - invalid dry_run",
+			data:      collection(point("a", 100)),
+			dryRun:    "maybe",
+			incidents: func() *mockIncidentsRepository { return &mockIncidentsRepository{} },
+			wantErr:   true,
+		},
+		{
+			name:      "validation error - empty collection",
+			data:      collection(),
+			incidents: func() *mockIncidentsRepository { return &mockIncidentsRepository{} },
+			wantErr:   true,
+		},
+		{
+			name:      "validation error - too many features",
+			data:      collection(point("a", 100), point("b", 100), point("c", 100), point("d", 100)),
+			incidents: func() *mockIncidentsRepository { return &mockIncidentsRepository{} },
+			wantErr:   true,
+		},
+		{
+			name: "per-feature errors - nothing is written",
+			data: collection(point("a", 100), point("", 100), point("a", 10)),
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					upsertByTitleFunc: func(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error) {
+						panic("repository must not be called")
+					},
+				}
+			},
+			wantFailures: []int{1, 2},
+		},
+		{
+			name:   "dry run - only validation",
+			data:   collection(point("a", 100), point("b", 100)),
+			dryRun: "true",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					upsertByTitleFunc: func(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error) {
+						panic("repository must not be called")
+					},
+				}
+			},
+		},
+		{
+			name:   "dry run - per-feature errors",
+			data:   collection(point("a", 100), point("b", 10)),
+			dryRun: "true",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantFailures: []int{1},
+		},
+		{
+			name: "repository rejects feature",
+			data: collection(point("a", 100), point("b", 100)),
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					upsertByTitleFunc: func(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error) {
+						return nil, &domain.ImportItemError{Index: 1, Err: domain.ErrAlreadyExists("incident already exists")}
+					},
+				}
+			},
+			wantFailures: []int{1},
+		},
+		{
+			name: "repository error",
+			data: collection(point("a", 100)),
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					upsertByTitleFunc: func(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error) {
+						return nil, &domain.ImportItemError{Index: 0, Err: errors.New("connection reset")}
+					},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name:   "success - csv without active keeps current value",
+			data:   []byte("title;lat;long;radius_m;active\na;50;30;100;\nb;50;30;100;false\n"),
+			format: FormatCSV,
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					upsertByTitleFunc: func(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error) {
+						if items[0].Active != nil || items[1].Active == nil || *items[1].Active {
+							return nil, errors.New("unexpected active")
+						}
+						// зоны не изменились, поэтому находящихся в них пользователей не уведомляют
+						return []domain.ImportResult{
+							{
+								Incident: &domain.Incident{Title: "a", Lat: 50, Long: 30, Radius: 100, Active: true},
+								Previous: &domain.Incident{Title: "a", Lat: 50, Long: 30, Radius: 100, Active: true},
+							},
+							{
+								Incident: &domain.Incident{Title: "b", Lat: 50, Long: 30, Radius: 100},
+								Previous: &domain.Incident{Title: "b", Lat: 50, Long: 30, Radius: 100, Active: true},
+							},
+						}, nil
+					},
+				}
+			},
+			wantUpdated:   2,
+			wantCacheKeys: []string{"incidentID:0", "incidentID:0"},
+		},
+		{
+			name:   "success - csv without severity and boundary keeps current values",
+			data:   []byte("title;lat;long;radius_m\na;50;30;100\n"),
+			format: FormatCSV,
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					upsertByTitleFunc: func(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error) {
+						item := items[0]
+						if item.Severity != nil || item.Category != nil || item.Description != nil || item.Boundary != nil ||
+							item.WarningBuffer != nil || item.StartsAt != nil || item.EndsAt != nil {
+							return nil, errors.New("omitted fields must stay nil")
+						}
+						polygon := &domain.Incident{ID: 4, Title: "a", Lat: 55.755, Long: 37.61, Severity: domain.SeverityCritical, Boundary: []byte(testPolygon), Active: true}
+						return []domain.ImportResult{{Incident: polygon, Previous: polygon}}, nil
+					},
+				}
+			},
+			wantUpdated:   1,
+			wantCacheKeys: []string{"incidentID:4"},
+		},
+		{
+			name: "success - created and updated",
+			data: collection(point("a", 100), point("b", 100)),
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					upsertByTitleFunc: func(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error) {
+						// точка GeoJSON задает круговую зону явно
+						if items[0].Boundary == nil || len(*items[0].Boundary) > 0 {
+							return nil, errors.New("unexpected boundary")
+						}
+						created := items[0].NewIncident()
+						created.ID = 10
+						return []domain.ImportResult{
+							{Incident: created},
+							{
+								Incident: &domain.Incident{ID: 7, Title: "b", Lat: 55.75, Long: 37.61, Radius: 100, Active: true},
+								Previous: &domain.Incident{ID: 7, Title: "b", Lat: 55.75, Long: 37.61, Radius: 50, Active: true},
+							},
+						}, nil
+					},
+				}
+			},
+			wantCreated:   1,
+			wantUpdated:   1,
+			wantCacheKeys: []string{"incidentID:7"},
+			wantAlerted:   []int{10, 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var deletedKeys []string
+			var alerted []int
+			service := &Service{
+				queue:     &mockQueue{},
+				incidents: tt.incidents(),
+				coordinates: &mockCoordinatesRepository{
+					occupantsFunc: func(ctx context.Context, incidentID int, windowMinutes int) ([]domain.ZoneOccupant, error) {
+						alerted = append(alerted, incidentID)
+						return nil, nil
+					},
+				},
+				membership: &mockMembership{},
+				cache: &mockCache{
+					deleteFunc: func(ctx context.Context, key string) (bool, error) {
+						deletedKeys = append(deletedKeys, key)
+						return true, nil
+					},
+				},
+				logger: &mockLogger{},
+				opts:   Options{ImportMaxIncidents: 3},
+			}
+
+			out, err := service.ImportIncidents(context.Background(), &ImportIncidentsRequestInput{
+				Format: tt.format,
+				Data:   tt.data,
+				DryRun: tt.dryRun,
+			})
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				if tt.errType != nil {
+					require.True(t, tt.errType(err), "wrong error type: %v", err)
+				}
+				require.Nil(t, out)
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			if len(tt.wantFailures) > 0 {
+				require.Len(t, out.Errors, len(tt.wantFailures))
+				for i, index := range tt.wantFailures {
+					require.Equal(t, index, out.Errors[i].Index)
+				}
+				require.Empty(t, out.Items)
+				require.Empty(t, deletedKeys)
+				return
+			}
+
+			require.Empty(t, out.Errors)
+			require.Equal(t, tt.dryRun == "true", out.DryRun)
+			require.Equal(t, tt.wantCreated, out.Created)
+			require.Equal(t, tt.wantUpdated, out.Updated)
+			require.Len(t, out.Items, out.Total)
+			require.Equal(t, tt.wantCacheKeys, deletedKeys)
+			require.Equal(t, tt.wantAlerted, alerted)
+		})
+	}
+}
+
+func TestService_ExportIncidents(t *testing.T) {
+	incidents := []domain.Incident{
+		{ID: 1, Title: "Пожар", Lat: 55.75, Long: 37.61, Radius: 150, Active: true, Severity: domain.SeverityDanger, Category: "fire"},
+		{ID: 2, Title: "Перекрытие", Lat: 55.755, Long: 37.61, Radius: 700, Boundary: []byte(testPolygon)},
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		service := &Service{incidents: &mockIncidentsRepository{}, logger: &mockLogger{}}
+		_, err := service.ExportIncidents(context.Background(), &ExportIncidentsRequestInput{Format: "shp"})
+		require.Error(t, err)
+	})
+
+	t.Run("too many incidents", func(t *testing.T) {
+		service := &Service{
+			incidents: &mockIncidentsRepository{
+				paginateCursorFunc: func(ctx context.Context, filter domain.IncidentFilter, limit int) ([]domain.Incident, error) {
+					require.Equal(t, 2, limit)
+					return incidents, nil
+				},
+			},
+			logger: &mockLogger{},
+			opts:   Options{ExportMaxIncidents: 1},
+		}
+		_, err := service.ExportIncidents(context.Background(), &ExportIncidentsRequestInput{Format: FormatGeoJSON})
+		require.Error(t, err)
+	})
+
+	for _, format := range []string{FormatGeoJSON, FormatCSV, FormatKML} {
+		t.Run("round trip "+format, func(t *testing.T) {
+			service := &Service{
+				incidents: &mockIncidentsRepository{
+					paginateCursorFunc: func(ctx context.Context, filter domain.IncidentFilter, limit int) ([]domain.Incident, error) {
+						require.Equal(t, "fire", filter.Category)
+						return incidents, nil
+					},
+				},
+				logger: &mockLogger{},
+				opts:   Options{ExportMaxIncidents: 10, Categories: []string{"fire"}},
+			}
+
+			out, err := service.ExportIncidents(context.Background(), &ExportIncidentsRequestInput{
+				Format: format,
+				Filter: PaginateIncidentsRequestInput{Category: "fire"},
+			})
+			require.NoError(t, err)
+			require.Equal(t, incidentCodecs[format].contentType, out.ContentType)
+
+			items, err := incidentCodecs[format].decode(out.Data)
+			require.NoError(t, err)
+			require.Len(t, items, 2)
+			require.NoError(t, items[0].Err)
+			require.Equal(t, "Пожар", items[0].Input.Title)
+			require.Equal(t, 150, items[0].Input.Radius)
+			require.Equal(t, 37.61, items[0].Input.Long)
+			require.Equal(t, "danger", items[0].Input.Severity)
+			require.NoError(t, items[1].Err)
+			require.NoError(t, validateBoundary(items[1].Input.Boundary))
+		})
+	}
+}
@@ -3,7 +3,10 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"red_collar/internal/domain"
+	"strconv"
+	"time"
 
 	"github.com/theartofdevel/logging"
 )
@@ -12,8 +15,13 @@ const (
 	cacheKeyIncidentID = "incidentID:"
 )
 
+// incidentCacheKey строит ключ кеша из числового ID, чтобы "7" и "007" не давали разные записи
+func incidentCacheKey(id int) string {
+	return cacheKeyIncidentID + strconv.Itoa(id)
+}
+
 func (s *Service) CreateIncident(ctx context.Context, in *CreateIncidentRequestInput) (*domain.Incident, error) {
-	if err := validateCreateIncidentInput(in); err != nil {
+	if err := validateCreateIncidentInput(in, s.opts.Categories); err != nil {
 		s.logger.Error("create incident request validation failed",
 			logging.StringAttr("title", in.Title),
 			logging.Float64Attr("lat", in.Lat),
@@ -42,6 +50,9 @@ func (s *Service) CreateIncident(ctx context.Context, in *CreateIncidentRequestI
 	s.logger.Info("incident was successfully created",
 		logging.StringAttr("title", in.Title),
 	)
+
+	s.enqueueIncidentEvent(ctx, domain.EventIncidentCreated, incident.ID, incident)
+	s.alertZoneOccupants(ctx, incident)
 	return incident, nil
 }
 
@@ -59,7 +70,7 @@ func (s *Service) GetIncidentByID(ctx context.Context, rawID string) (*domain.In
 		logging.IntAttr("ID", id),
 	)
 
-	key := cacheKeyIncidentID + rawID
+	key := incidentCacheKey(id)
 	incident, err := s.getIncidentFromCache(ctx, key)
 	if incident != nil {
 		return incident, nil
@@ -82,12 +93,91 @@ func (s *Service) GetIncidentByID(ctx context.Context, rawID string) (*domain.In
 	return incident, nil
 }
 
-func (s *Service) PaginateIncident(ctx context.Context, rawLimit, rawPage string) (*PaginateIncidentsOutput, error) {
-	offset, limit, page, err := validatePaginate(rawLimit, rawPage)
+// GetIncidentAsOf возвращает инцидент в состоянии на момент rawAt (RFC3339) из истории изменений
+func (s *Service) GetIncidentAsOf(ctx context.Context, rawID, rawAt string) (*domain.Incident, error) {
+	id, at, err := validateIncidentAsOf(rawID, rawAt)
+	if err != nil {
+		s.logger.Error("get incident as of validation failed",
+			logging.StringAttr("id", rawID),
+			logging.StringAttr("asOf", rawAt),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to get incident as of",
+		logging.IntAttr("id", id),
+		logging.StringAttr("asOf", rawAt),
+	)
+
+	incident, err := s.incidents.GetAsOf(ctx, id, at)
+	if err != nil {
+		s.logger.Error("get incident as of repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("incident version was successfully got",
+		logging.IntAttr("id", id),
+		logging.IntAttr("version", incident.Version),
+	)
+	return incident, nil
+}
+
+func (s *Service) GetIncidentHistory(ctx context.Context, rawID string) ([]domain.IncidentVersion, error) {
+	id, err := validateID(rawID)
+	if err != nil {
+		s.logger.Error("get incident history validation failed",
+			logging.StringAttr("id", rawID),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to get incident history",
+		logging.IntAttr("id", id),
+	)
+
+	versions, err := s.incidents.History(ctx, id)
+	if err != nil {
+		s.logger.Error("get incident history repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("incident history was successfully got",
+		logging.IntAttr("id", id),
+		logging.IntAttr("versions", len(versions)),
+	)
+	return versions, nil
+}
+
+func (s *Service) PaginateIncident(ctx context.Context, in *PaginateIncidentsRequestInput) (*PaginateIncidentsOutput, error) {
+	if in.Cursor != nil {
+		return s.paginateIncidentsByCursor(ctx, in)
+	}
+
+	offset, limit, page, err := validatePaginate(in.Limit, in.Page)
+	if err != nil {
+		s.logger.Error("paginate incidents validation failed",
+			logging.StringAttr("rawLimit", in.Limit),
+			logging.StringAttr("rawPage", in.Page),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	filter, err := validateIncidentFilter(in, s.opts.Categories)
 	if err != nil {
 		s.logger.Error("paginate incidents validation failed",
-			logging.StringAttr("rawLimit", rawLimit),
-			logging.StringAttr("rawPage", rawPage),
+			logging.StringAttr("severity", in.Severity),
+			logging.StringAttr("category", in.Category),
+			logging.StringAttr("bbox", in.BBox),
+			logging.StringAttr("sort", in.Sort),
 			logging.ErrAttr(err),
 		)
 		return nil, err
@@ -98,11 +188,11 @@ func (s *Service) PaginateIncident(ctx context.Context, rawLimit, rawPage string
 		logging.IntAttr("offset", offset),
 	)
 
-	incidents, total, err := s.incidents.Paginate(ctx, limit, offset)
+	incidents, total, err := s.incidents.Paginate(ctx, filter, limit, offset)
 	if err != nil {
 		s.logger.Error("paginate repository error",
-			logging.StringAttr("rawLimit", rawLimit),
-			logging.StringAttr("rawPage", rawPage),
+			logging.StringAttr("rawLimit", in.Limit),
+			logging.StringAttr("rawPage", in.Page),
 			logging.ErrAttr(err),
 		)
 		return nil, err
@@ -122,6 +212,53 @@ func (s *Service) PaginateIncident(ctx context.Context, rawLimit, rawPage string
 	return out, nil
 }
 
+// paginateIncidentsByCursor отдает страницу по ключу (created_at, id) без COUNT(*).
+// Запрашивается на одну запись больше, чтобы понять, есть ли следующая страница
+func (s *Service) paginateIncidentsByCursor(ctx context.Context, in *PaginateIncidentsRequestInput) (*PaginateIncidentsOutput, error) {
+	filter, err := validateIncidentFilter(in, s.opts.Categories)
+	if err != nil {
+		s.logger.Error("paginate incidents validation failed",
+			logging.StringAttr("severity", in.Severity),
+			logging.StringAttr("category", in.Category),
+			logging.StringAttr("sort", in.Sort),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	limit, err := validateCursorPaginate(in.Limit, *in.Cursor, &filter)
+	if err != nil {
+		s.logger.Error("paginate incidents validation failed",
+			logging.StringAttr("rawLimit", in.Limit),
+			logging.StringAttr("cursor", *in.Cursor),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to paginate by cursor",
+		logging.IntAttr("limit", limit),
+	)
+
+	incidents, err := s.incidents.PaginateCursor(ctx, filter, limit+1)
+	if err != nil {
+		s.logger.Error("paginate repository error",
+			logging.IntAttr("limit", limit),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	out := &PaginateIncidentsOutput{Incidents: incidents}
+	if len(incidents) > limit {
+		out.Incidents = incidents[:limit]
+		out.NextCursor = encodeIncidentCursor(incidents[limit-1], filter.SortAsc)
+	}
+
+	s.logger.Info("incidents was successfully paginated")
+	return out, nil
+}
+
 func (s *Service) DeleteIncident(ctx context.Context, rawID string) error {
 	id, err := validateID(rawID)
 	if err != nil {
@@ -136,8 +273,7 @@ func (s *Service) DeleteIncident(ctx context.Context, rawID string) error {
 		logging.IntAttr("id", id),
 	)
 
-	key := cacheKeyIncidentID + rawID
-	s.deleteIncidenFromCache(ctx, key)
+	s.deleteIncidenFromCache(ctx, incidentCacheKey(id))
 
 	if err := s.incidents.Delete(ctx, id); err != nil {
 		s.logger.Error("delete repository error",
@@ -150,11 +286,66 @@ func (s *Service) DeleteIncident(ctx context.Context, rawID string) error {
 	s.logger.Info("incident was successfully deleted",
 		logging.IntAttr("id", id),
 	)
+
+	s.enqueueIncidentEvent(ctx, domain.EventIncidentDeleted, id, nil)
 	return nil
 }
 
+func (s *Service) RestoreIncident(ctx context.Context, rawID string) (*domain.Incident, error) {
+	id, err := validateID(rawID)
+	if err != nil {
+		s.logger.Error("restore incident validation failed",
+			logging.StringAttr("id", rawID),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to restore incident",
+		logging.IntAttr("id", id),
+	)
+
+	incident, err := s.incidents.Restore(ctx, id)
+	if err != nil {
+		s.logger.Error("restore incident repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.deleteIncidenFromCache(ctx, incidentCacheKey(id))
+
+	s.logger.Info("incident was successfully restored",
+		logging.IntAttr("id", id),
+	)
+
+	s.enqueueIncidentEvent(ctx, domain.EventIncidentUpdated, id, incident)
+	return incident, nil
+}
+
+// PurgeIncidents окончательно удаляет инциденты, которые находятся в архиве дольше PurgeRetentionDays
+func (s *Service) PurgeIncidents(ctx context.Context) (int, error) {
+	s.logger.Info("attempt to purge deleted incidents",
+		logging.IntAttr("retentionDays", s.opts.PurgeRetentionDays),
+	)
+
+	purged, err := s.incidents.Purge(ctx, s.opts.PurgeRetentionDays)
+	if err != nil {
+		s.logger.Error("purge incidents repository error",
+			logging.ErrAttr(err),
+		)
+		return 0, err
+	}
+
+	s.logger.Info("deleted incidents were successfully purged",
+		logging.IntAttr("purged", purged),
+	)
+	return purged, nil
+}
+
 func (s *Service) FullUpdateIncident(ctx context.Context, in *FullUpdateIncidentRequestInput) (*domain.Incident, error) {
-	id, err := validateFullUpdateIncidentInput(in)
+	id, err := validateFullUpdateIncidentInput(in, s.opts.Categories)
 	if err != nil {
 		s.logger.Error("full update incident request validation failed",
 			logging.StringAttr("id", in.ID),
@@ -171,7 +362,30 @@ func (s *Service) FullUpdateIncident(ctx context.Context, in *FullUpdateIncident
 		logging.StringAttr("id", in.ID),
 	)
 
+	// Прежнее состояние нужно, чтобы понять, расширилась ли зона
+	prev, err := s.incidents.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("full update incident request repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	if !versionMatches(prev.Version, in.ExpectedVersions) {
+		s.logger.Error("full update incident precondition failed",
+			logging.IntAttr("id", id),
+			logging.IntAttr("version", prev.Version),
+			logging.StringAttr("expectedVersions", fmt.Sprint(in.ExpectedVersions)),
+		)
+		return nil, domain.ErrPreconditionFailed("incident version does not match If-Match")
+	}
+
 	incident := mapFullUpdateIncident(in, id)
+	if len(in.ExpectedVersions) > 0 {
+		// версия зафиксирована, чтобы репозиторий отклонил запись, если инцидент успели изменить
+		incident.Version = prev.Version
+	}
 	if err := s.incidents.FullUpdate(ctx, incident); err != nil {
 		s.logger.Error("full update incident request repository error",
 			logging.IntAttr("id", id),
@@ -180,12 +394,16 @@ func (s *Service) FullUpdateIncident(ctx context.Context, in *FullUpdateIncident
 		return nil, err
 	}
 
-	key := cacheKeyIncidentID + in.ID
-	s.deleteIncidenFromCache(ctx, key)
+	s.deleteIncidenFromCache(ctx, incidentCacheKey(id))
 
 	s.logger.Info("incident was successfully full updated",
 		logging.IntAttr("id", id),
 	)
+
+	s.enqueueIncidentEvent(ctx, domain.EventIncidentUpdated, id, incident)
+	if zoneExpanded(prev, incident, time.Now()) {
+		s.alertZoneOccupants(ctx, incident)
+	}
 	return incident, nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"red_collar/internal/domain"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -81,6 +82,150 @@ func TestService_CreateIncident(t *testing.T) {
 				require.Equal(t, "create incident request validation failed", errorLogs[0].msg)
 			},
 		},
+		{
+			name: "validation error - boundary ring is not closed",
+			input: &CreateIncidentRequestInput{
+				Title:    "Color",
+				Boundary: json.RawMessage(`{"type":"Polygon","coordinates":[[[30,50],[30.1,50],[30.1,50.1],[30,50.1]]]}`),
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				errorLogs := logger.GetErrorLogs()
+				require.Len(t, errorLogs, 1)
+				require.Equal(t, "create incident request validation failed", errorLogs[0].msg)
+			},
+		},
+		{
+			name: "validation error - boundary is not a polygon",
+			input: &CreateIncidentRequestInput{
+				Title:    "Color",
+				Boundary: json.RawMessage(`{"type":"LineString","coordinates":[[30,50],[30.1,50]]}`),
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name: "validation error - severity is invalid",
+			input: &CreateIncidentRequestInput{
+				Title:    "Color",
+				Lat:      -20,
+				Long:     100,
+				Radius:   100,
+				Severity: "apocalyptic",
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name: "validation error - category is unknown",
+			input: &CreateIncidentRequestInput{
+				Title:    "Color",
+				Lat:      -20,
+				Long:     100,
+				Radius:   100,
+				Category: "aliens",
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name: "validation error - warning buffer is negative",
+			input: &CreateIncidentRequestInput{
+				Title:         "Color",
+				Lat:           -20,
+				Long:          100,
+				Radius:        100,
+				WarningBuffer: func() *int { v := -1; return &v }(),
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name: "success - severity and category defaults",
+			input: &CreateIncidentRequestInput{
+				Title:  "Color",
+				Lat:    -20,
+				Long:   100,
+				Radius: 100,
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			validateResult: func(t *testing.T, result *domain.Incident) {
+				require.Equal(t, domain.SeverityWarning, result.Severity)
+				require.Equal(t, domain.DefaultCategory, result.Category)
+			},
+		},
+		{
+			name: "success - schedule is stored in UTC",
+			input: &CreateIncidentRequestInput{
+				Title:    "Color",
+				Lat:      -20,
+				Long:     100,
+				Radius:   100,
+				StartsAt: ptr(time.Date(2026, 10, 17, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))),
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					createFunc: func(ctx context.Context, incident *domain.Incident) error {
+						if incident.StartsAt.Location() != time.UTC || incident.StartsAt.Hour() != 9 {
+							return errors.New("starts_at is not in UTC")
+						}
+						return nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *domain.Incident) {
+				require.Equal(t, time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), *result.StartsAt)
+				require.Nil(t, result.EndsAt)
+			},
+		},
+		{
+			name: "success - polygon boundary without radius",
+			input: &CreateIncidentRequestInput{
+				Title:    "Color",
+				Radius:   10,
+				Boundary: json.RawMessage(`{"type":"MultiPolygon","coordinates":[[[[30,50],[30.1,50],[30.1,50.1],[30,50.1],[30,50]]]]}`),
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			validateResult: func(t *testing.T, result *domain.Incident) {
+				require.NotNil(t, result.Boundary)
+				require.Zero(t, result.Radius)
+			},
+		},
 		{
 			name: "success - active & description is empty",
 			input: &CreateIncidentRequestInput{
@@ -103,7 +248,7 @@ func TestService_CreateIncident(t *testing.T) {
 			},
 			validateLogs: func(t *testing.T, logger *mockLogger) {
 				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 2, "should log attempt and success")
+				require.Len(t, infoLogs, 3, "should log attempt, success and webhook enqueue")
 			},
 		},
 		{
@@ -134,7 +279,7 @@ func TestService_CreateIncident(t *testing.T) {
 			},
 			validateLogs: func(t *testing.T, logger *mockLogger) {
 				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 2, "should log attempt and success")
+				require.Len(t, infoLogs, 3, "should log attempt, success and webhook enqueue")
 			},
 		},
 		{
@@ -169,8 +314,10 @@ func TestService_CreateIncident(t *testing.T) {
 			mockLog := &mockLogger{}
 
 			service := &Service{
-				incidents: tt.incidents(),
-				logger:    mockLog,
+				queue:       &mockQueue{},
+				incidents:   tt.incidents(),
+				coordinates: &mockCoordinatesRepository{},
+				logger:      mockLog,
 			}
 
 			result, err := service.CreateIncident(ctx, tt.input)
@@ -476,6 +623,7 @@ func TestService_PaginateIncident(t *testing.T) {
 		name           string
 		rawLimit       string
 		rawPage        string
+		filter         PaginateIncidentsRequestInput
 		incidents      func() *mockIncidentsRepository
 		wantErr        bool
 		errType        func(err error) bool
@@ -518,181 +666,474 @@ func TestService_PaginateIncident(t *testing.T) {
 			},
 		},
 		{
-			name:     "repository error",
+			name:     "validation error - severity filter is invalid",
 			rawLimit: "5",
 			rawPage:  "1",
+			filter:   PaginateIncidentsRequestInput{Severity: "warning,apocalyptic"},
 			incidents: func() *mockIncidentsRepository {
-				return &mockIncidentsRepository{
-					paginateFunc: func(ctx context.Context, limit, offset int) ([]domain.Incident, int, error) {
-						return nil, 0, errors.New("failed database connection")
-					},
-				}
+				return &mockIncidentsRepository{}
 			},
 			wantErr: true,
-			validateLogs: func(t *testing.T, logger *mockLogger) {
-				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 1, "should log attempt")
-
-				errorLogs := logger.GetErrorLogs()
-				require.Len(t, errorLogs, 1, "should log repository error")
-				require.Equal(t, "paginate repository error", errorLogs[0].msg)
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
 			},
 		},
 		{
-			name:     "success",
+			name:     "success - filter by severity and category",
 			rawLimit: "5",
 			rawPage:  "1",
+			filter:   PaginateIncidentsRequestInput{Severity: "danger, critical", Category: "other"},
 			incidents: func() *mockIncidentsRepository {
 				return &mockIncidentsRepository{
-					paginateFunc: func(ctx context.Context, limit, offset int) ([]domain.Incident, int, error) {
-						incidents := []domain.Incident{
-							{ID: 1, Title: "Color1"},
-							{ID: 2, Title: "Color2"},
+					paginateFunc: func(ctx context.Context, filter domain.IncidentFilter, limit, offset int) ([]domain.Incident, int, error) {
+						if len(filter.Severities) != 2 || filter.Severities[1] != domain.SeverityCritical || filter.Category != "other" {
+							return nil, 0, errors.New("unexpected filter")
 						}
-						return incidents, 2, nil
+						return []domain.Incident{{ID: 1, Severity: domain.SeverityCritical}}, 1, nil
 					},
 				}
 			},
 			validateResult: func(t *testing.T, result *PaginateIncidentsOutput) {
-				require.NotNil(t, result)
-				require.NotNil(t, result.Incidents)
-				require.Len(t, result.Incidents, 2)
-
-				require.Equal(t, 1, result.Incidents[0].ID)
-				require.Equal(t, "Color1", result.Incidents[0].Title)
-
-				require.Equal(t, 2, result.Incidents[1].ID)
-				require.Equal(t, "Color2", result.Incidents[1].Title)
-
-				require.NotNil(t, result.Pagination)
-				require.Equal(t, 2, result.Pagination.Total)
-				require.Equal(t, 5, result.Pagination.Limit)
-				require.Equal(t, 1, result.Pagination.Page)
-			},
-			validateLogs: func(t *testing.T, logger *mockLogger) {
-				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 2, "should log attempt and success")
+				require.Len(t, result.Incidents, 1)
 			},
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-
-			ctx := context.Background()
-			mockLog := &mockLogger{}
-
-			service := &Service{
-				incidents: tt.incidents(),
-				logger:    mockLog,
-			}
-
-			result, err := service.PaginateIncident(ctx, tt.rawLimit, tt.rawPage)
-
-			if tt.validateLogs != nil {
-				tt.validateLogs(t, mockLog)
-			}
-
-			if tt.wantErr {
-				require.Error(t, err, "expected error but got none")
-				if tt.errType != nil {
-					require.True(t, tt.errType(err), "wrong error type: %v", err)
-				}
-				require.Nil(t, result, "result should be nil on error")
-				return
-			}
-
-			require.NoError(t, err, "unexpected error: %v", err)
-			require.NotNil(t, result, "expected result, got nil")
-
-			if tt.validateResult != nil {
-				tt.validateResult(t, result)
-			}
-		})
-	}
-}
-
-func TestService_DeleteIncident(t *testing.T) {
-	tests := []struct {
-		name         string
-		rawID        string
-		incidents    func() *mockIncidentsRepository
-		cache        func() *mockCache
-		wantErr      bool
-		errType      func(err error) bool
-		validateLogs func(t *testing.T, logger *mockLogger)
-	}{
 		{
-			name:  "validation error - id is invalid",
-			rawID: "1-2",
+			name:     "validation error - bbox has wrong number of values",
+			rawLimit: "5",
+			rawPage:  "1",
+			filter:   PaginateIncidentsRequestInput{BBox: "30,50,31"},
 			incidents: func() *mockIncidentsRepository {
 				return &mockIncidentsRepository{}
 			},
-			cache: func() *mockCache {
-				return &mockCache{}
-			},
 			wantErr: true,
 			errType: func(err error) bool {
 				var appErr *domain.AppError
 				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
 			},
-			validateLogs: func(t *testing.T, logger *mockLogger) {
-				errorLogs := logger.GetErrorLogs()
-				require.Len(t, errorLogs, 1, "should have exactly 1 error log")
-				require.Equal(t, "delete incident validation failed", errorLogs[0].msg)
-			},
 		},
 		{
-			name:  "repository error",
-			rawID: "1",
+			name:     "validation error - bbox min is greater than max",
+			rawLimit: "5",
+			rawPage:  "1",
+			filter:   PaginateIncidentsRequestInput{BBox: "31,50,30,51"},
 			incidents: func() *mockIncidentsRepository {
-				return &mockIncidentsRepository{
-					deleteFunc: func(ctx context.Context, id int) error {
-						return errors.New("failed database connection")
-					},
-				}
-			},
-			cache: func() *mockCache {
-				return &mockCache{
-					deleteFunc: func(ctx context.Context, key string) (bool, error) {
-						return true, nil
-					},
-				}
+				return &mockIncidentsRepository{}
 			},
 			wantErr: true,
-			validateLogs: func(t *testing.T, logger *mockLogger) {
-				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 2, "should log attempt")
-
-				errorLogs := logger.GetErrorLogs()
-				require.Len(t, errorLogs, 1, "should log repository error")
-				require.Equal(t, "delete repository error", errorLogs[0].msg)
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
 			},
 		},
 		{
-			name:  "success - but cache error",
-			rawID: "1",
+			name:     "validation error - near point without radius",
+			rawLimit: "5",
+			rawPage:  "1",
+			filter:   PaginateIncidentsRequestInput{Lat: "50", Long: "30"},
 			incidents: func() *mockIncidentsRepository {
-				return &mockIncidentsRepository{
-					deleteFunc: func(ctx context.Context, id int) error {
-						return nil
-					},
-				}
+				return &mockIncidentsRepository{}
 			},
-			cache: func() *mockCache {
-				return &mockCache{
-					deleteFunc: func(ctx context.Context, key string) (bool, error) {
-						return false, errors.New("error")
-					},
-				}
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
 			},
-			validateLogs: func(t *testing.T, logger *mockLogger) {
-				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 2, "should log attempt and success")
-
-				errorLogs := logger.GetErrorLogs()
-				require.Len(t, errorLogs, 1, "should log cache delete error")
+		},
+		{
+			name:     "validation error - date is not RFC3339",
+			rawLimit: "5",
+			rawPage:  "1",
+			filter:   PaginateIncidentsRequestInput{CreatedFrom: "2026-10-16"},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name:     "validation error - unknown deleted mode",
+			rawLimit: "5",
+			rawPage:  "1",
+			filter:   PaginateIncidentsRequestInput{Deleted: "purged"},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name:     "success - only deleted incidents",
+			rawLimit: "5",
+			rawPage:  "1",
+			filter:   PaginateIncidentsRequestInput{Deleted: "only"},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					paginateFunc: func(ctx context.Context, filter domain.IncidentFilter, limit, offset int) ([]domain.Incident, int, error) {
+						if filter.Deleted != domain.DeletedOnly {
+							return nil, 0, errors.New("unexpected deleted mode")
+						}
+						return []domain.Incident{{ID: 1}}, 1, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *PaginateIncidentsOutput) {
+				require.Len(t, result.Incidents, 1)
+			},
+		},
+		{
+			name:     "validation error - sort by distance without near point",
+			rawLimit: "5",
+			rawPage:  "1",
+			filter:   PaginateIncidentsRequestInput{Sort: "distance"},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name:     "validation error - unknown sort field",
+			rawLimit: "5",
+			rawPage:  "1",
+			filter:   PaginateIncidentsRequestInput{Sort: "radius_m"},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name:     "success - search, bbox, near point and sort",
+			rawLimit: "5",
+			rawPage:  "1",
+			filter: PaginateIncidentsRequestInput{
+				Active:      "true",
+				CreatedFrom: "2026-10-01T00:00:00+03:00",
+				Query:       "  перекрытие дороги ",
+				BBox:        "30.1,50.2,30.9,50.8",
+				Lat:         "50.5",
+				Long:        "30.5",
+				Radius:      "1000",
+				Sort:        "distance",
+				Order:       "asc",
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					paginateFunc: func(ctx context.Context, filter domain.IncidentFilter, limit, offset int) ([]domain.Incident, int, error) {
+						switch {
+						case filter.Active == nil || !*filter.Active:
+							return nil, 0, errors.New("unexpected active filter")
+						case filter.CreatedFrom == nil || !filter.CreatedFrom.Equal(time.Date(2026, 9, 30, 21, 0, 0, 0, time.UTC)):
+							return nil, 0, errors.New("unexpected created_from filter")
+						case filter.Query != "перекрытие дороги":
+							return nil, 0, errors.New("unexpected search query")
+						case filter.BBox == nil || *filter.BBox != (domain.BBox{MinLong: 30.1, MinLat: 50.2, MaxLong: 30.9, MaxLat: 50.8}):
+							return nil, 0, errors.New("unexpected bbox")
+						case filter.Near == nil || *filter.Near != (domain.NearPoint{Lat: 50.5, Long: 30.5, RadiusM: 1000}):
+							return nil, 0, errors.New("unexpected near point")
+						case filter.Sort != domain.IncidentSortDistance || !filter.SortAsc:
+							return nil, 0, errors.New("unexpected sort")
+						}
+						return []domain.Incident{{ID: 1}}, 1, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *PaginateIncidentsOutput) {
+				require.Len(t, result.Incidents, 1)
+			},
+		},
+		{
+			name:     "validation error - cursor is malformed",
+			rawLimit: "5",
+			filter:   PaginateIncidentsRequestInput{Cursor: func() *string { c := "not-a-cursor!"; return &c }()},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name:     "validation error - cursor issued for another order",
+			rawLimit: "5",
+			filter: PaginateIncidentsRequestInput{Order: "asc", Cursor: func() *string {
+				c := encodeIncidentCursor(domain.Incident{ID: 42, CreatedAt: time.Now()}, false)
+				return &c
+			}()},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation &&
+					appErr.Message == "cursor does not match sort and order of the request"
+			},
+		},
+		{
+			name:     "validation error - cursor with sort by title",
+			rawLimit: "5",
+			filter:   PaginateIncidentsRequestInput{Sort: "title", Cursor: func() *string { c := ""; return &c }()},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name:     "success - cursor page with next cursor",
+			rawLimit: "5",
+			filter: PaginateIncidentsRequestInput{Cursor: func() *string {
+				c := encodeIncidentCursor(domain.Incident{ID: 42, CreatedAt: time.Date(2026, 10, 16, 10, 0, 0, 123456000, time.UTC)}, false)
+				return &c
+			}()},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					paginateFunc: func(ctx context.Context, filter domain.IncidentFilter, limit, offset int) ([]domain.Incident, int, error) {
+						return nil, 0, errors.New("offset pagination must not be used")
+					},
+					paginateCursorFunc: func(ctx context.Context, filter domain.IncidentFilter, limit int) ([]domain.Incident, error) {
+						if filter.After == nil || filter.After.ID != 42 ||
+							!filter.After.CreatedAt.Equal(time.Date(2026, 10, 16, 10, 0, 0, 123456000, time.UTC)) {
+							return nil, errors.New("unexpected cursor")
+						}
+						if limit != 6 {
+							return nil, errors.New("expected one extra row")
+						}
+						incidents := make([]domain.Incident, limit)
+						for i := range incidents {
+							incidents[i] = domain.Incident{ID: 41 - i, CreatedAt: time.Date(2026, 10, 16, 9, 0, i, 0, time.UTC)}
+						}
+						return incidents, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *PaginateIncidentsOutput) {
+				require.Len(t, result.Incidents, 5)
+				require.Nil(t, result.Pagination)
+
+				next, err := decodeIncidentCursor(result.NextCursor, false)
+				require.NoError(t, err)
+				require.Equal(t, 37, next.ID)
+			},
+		},
+		{
+			name:     "success - last cursor page",
+			rawLimit: "5",
+			filter:   PaginateIncidentsRequestInput{Cursor: func() *string { c := ""; return &c }()},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					paginateCursorFunc: func(ctx context.Context, filter domain.IncidentFilter, limit int) ([]domain.Incident, error) {
+						if filter.After != nil {
+							return nil, errors.New("first page must not have cursor")
+						}
+						return []domain.Incident{{ID: 2}, {ID: 1}}, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *PaginateIncidentsOutput) {
+				require.Len(t, result.Incidents, 2)
+				require.Empty(t, result.NextCursor)
+			},
+		},
+		{
+			name:     "repository error",
+			rawLimit: "5",
+			rawPage:  "1",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					paginateFunc: func(ctx context.Context, filter domain.IncidentFilter, limit, offset int) ([]domain.Incident, int, error) {
+						return nil, 0, errors.New("failed database connection")
+					},
+				}
+			},
+			wantErr: true,
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				infoLogs := logger.GetInfoLogs()
+				require.Len(t, infoLogs, 1, "should log attempt")
+
+				errorLogs := logger.GetErrorLogs()
+				require.Len(t, errorLogs, 1, "should log repository error")
+				require.Equal(t, "paginate repository error", errorLogs[0].msg)
+			},
+		},
+		{
+			name:     "success",
+			rawLimit: "5",
+			rawPage:  "1",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					paginateFunc: func(ctx context.Context, filter domain.IncidentFilter, limit, offset int) ([]domain.Incident, int, error) {
+						incidents := []domain.Incident{
+							{ID: 1, Title: "Color1"},
+							{ID: 2, Title: "Color2"},
+						}
+						return incidents, 2, nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *PaginateIncidentsOutput) {
+				require.NotNil(t, result)
+				require.NotNil(t, result.Incidents)
+				require.Len(t, result.Incidents, 2)
+
+				require.Equal(t, 1, result.Incidents[0].ID)
+				require.Equal(t, "Color1", result.Incidents[0].Title)
+
+				require.Equal(t, 2, result.Incidents[1].ID)
+				require.Equal(t, "Color2", result.Incidents[1].Title)
+
+				require.NotNil(t, result.Pagination)
+				require.Equal(t, 2, result.Pagination.Total)
+				require.Equal(t, 5, result.Pagination.Limit)
+				require.Equal(t, 1, result.Pagination.Page)
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				infoLogs := logger.GetInfoLogs()
+				require.Len(t, infoLogs, 2, "should log attempt and success")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			mockLog := &mockLogger{}
+
+			service := &Service{
+				incidents: tt.incidents(),
+				logger:    mockLog,
+			}
+
+			in := tt.filter
+			in.Limit = tt.rawLimit
+			in.Page = tt.rawPage
+
+			result, err := service.PaginateIncident(ctx, &in)
+
+			if tt.validateLogs != nil {
+				tt.validateLogs(t, mockLog)
+			}
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				if tt.errType != nil {
+					require.True(t, tt.errType(err), "wrong error type: %v", err)
+				}
+				require.Nil(t, result, "result should be nil on error")
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.NotNil(t, result, "expected result, got nil")
+
+			if tt.validateResult != nil {
+				tt.validateResult(t, result)
+			}
+		})
+	}
+}
+
+func TestService_DeleteIncident(t *testing.T) {
+	tests := []struct {
+		name         string
+		rawID        string
+		incidents    func() *mockIncidentsRepository
+		cache        func() *mockCache
+		wantErr      bool
+		errType      func(err error) bool
+		validateLogs func(t *testing.T, logger *mockLogger)
+	}{
+		{
+			name:  "validation error - id is invalid",
+			rawID: "1-2",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			cache: func() *mockCache {
+				return &mockCache{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				errorLogs := logger.GetErrorLogs()
+				require.Len(t, errorLogs, 1, "should have exactly 1 error log")
+				require.Equal(t, "delete incident validation failed", errorLogs[0].msg)
+			},
+		},
+		{
+			name:  "repository error",
+			rawID: "1",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					deleteFunc: func(ctx context.Context, id int) error {
+						return errors.New("failed database connection")
+					},
+				}
+			},
+			cache: func() *mockCache {
+				return &mockCache{
+					deleteFunc: func(ctx context.Context, key string) (bool, error) {
+						return true, nil
+					},
+				}
+			},
+			wantErr: true,
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				infoLogs := logger.GetInfoLogs()
+				require.Len(t, infoLogs, 2, "should log attempt")
+
+				errorLogs := logger.GetErrorLogs()
+				require.Len(t, errorLogs, 1, "should log repository error")
+				require.Equal(t, "delete repository error", errorLogs[0].msg)
+			},
+		},
+		{
+			name:  "success - but cache error",
+			rawID: "1",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					deleteFunc: func(ctx context.Context, id int) error {
+						return nil
+					},
+				}
+			},
+			cache: func() *mockCache {
+				return &mockCache{
+					deleteFunc: func(ctx context.Context, key string) (bool, error) {
+						return false, errors.New("error")
+					},
+				}
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				infoLogs := logger.GetInfoLogs()
+				require.Len(t, infoLogs, 3, "should log attempt, success and webhook enqueue")
+
+				errorLogs := logger.GetErrorLogs()
+				require.Len(t, errorLogs, 1, "should log cache delete error")
 				require.Equal(t, "failed to delete incident from cache", errorLogs[0].msg)
 			},
 		},
@@ -715,7 +1156,7 @@ func TestService_DeleteIncident(t *testing.T) {
 			},
 			validateLogs: func(t *testing.T, logger *mockLogger) {
 				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 3, "should log attempt, cache delete, and success")
+				require.Len(t, infoLogs, 4, "should log attempt, cache delete, success and webhook enqueue")
 				errorLogs := logger.GetErrorLogs()
 				require.Empty(t, errorLogs, "should not have errors")
 			},
@@ -730,6 +1171,7 @@ func TestService_DeleteIncident(t *testing.T) {
 			mockLog := &mockLogger{}
 
 			service := &Service{
+				queue:     &mockQueue{},
 				incidents: tt.incidents(),
 				cache:     tt.cache(),
 				logger:    mockLog,
@@ -820,6 +1262,9 @@ func TestService_FullUpdateIncident(t *testing.T) {
 			},
 			incidents: func() *mockIncidentsRepository {
 				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return &domain.Incident{ID: id, Title: "Color", Lat: 50, Long: 50, Radius: 50}, nil
+					},
 					fullUpdateFunc: func(ctx context.Context, incident *domain.Incident) error {
 						return errors.New("error")
 					},
@@ -838,6 +1283,71 @@ func TestService_FullUpdateIncident(t *testing.T) {
 				require.Equal(t, "full update incident request repository error", errorLogs[0].msg)
 			},
 		},
+		{
+			name: "precondition failed - no If-Match version matches",
+			input: &FullUpdateIncidentRequestInput{
+				ID:               "1",
+				Title:            "Color",
+				Lat:              50,
+				Long:             50,
+				Radius:           50,
+				ExpectedVersions: []int{1, 2},
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return &domain.Incident{ID: id, Title: "Color", Lat: 50, Long: 50, Radius: 50, Version: 3}, nil
+					},
+					fullUpdateFunc: func(ctx context.Context, incident *domain.Incident) error {
+						return errors.New("must not update stale version")
+					},
+				}
+			},
+			cache: func() *mockCache {
+				return &mockCache{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodePreconditionFailed
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				errorLogs := logger.GetErrorLogs()
+				require.Len(t, errorLogs, 1)
+				require.Equal(t, "full update incident precondition failed", errorLogs[0].msg)
+			},
+		},
+		{
+			name: "success - one of If-Match versions matches",
+			input: &FullUpdateIncidentRequestInput{
+				ID:               "1",
+				Title:            "Color",
+				Lat:              50,
+				Long:             50,
+				Radius:           50,
+				ExpectedVersions: []int{2, 3},
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return &domain.Incident{ID: id, Title: "Color", Lat: 50, Long: 50, Radius: 50, Version: 3}, nil
+					},
+					fullUpdateFunc: func(ctx context.Context, incident *domain.Incident) error {
+						if incident.Version != 3 {
+							return errors.New("matched version must be passed to repository")
+						}
+						incident.Version++
+						return nil
+					},
+				}
+			},
+			cache: func() *mockCache {
+				return &mockCache{}
+			},
+			validateResult: func(t *testing.T, result *domain.Incident) {
+				require.Equal(t, 4, result.Version)
+			},
+		},
 		{
 			name: "success - but cache error",
 			input: &FullUpdateIncidentRequestInput{
@@ -849,6 +1359,9 @@ func TestService_FullUpdateIncident(t *testing.T) {
 			},
 			incidents: func() *mockIncidentsRepository {
 				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return &domain.Incident{ID: id, Title: "Color", Lat: 50, Long: 50, Radius: 50}, nil
+					},
 					fullUpdateFunc: func(ctx context.Context, incident *domain.Incident) error {
 						return nil
 					},
@@ -863,7 +1376,7 @@ func TestService_FullUpdateIncident(t *testing.T) {
 			},
 			validateLogs: func(t *testing.T, logger *mockLogger) {
 				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 2, "should log attempt and success")
+				require.Len(t, infoLogs, 3, "should log attempt, success and webhook enqueue")
 
 				errorLogs := logger.GetErrorLogs()
 				require.Len(t, errorLogs, 1, "should log cache delete error")
@@ -881,6 +1394,9 @@ func TestService_FullUpdateIncident(t *testing.T) {
 			},
 			incidents: func() *mockIncidentsRepository {
 				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return &domain.Incident{ID: id, Title: "Color", Lat: 50, Long: 50, Radius: 50}, nil
+					},
 					fullUpdateFunc: func(ctx context.Context, incident *domain.Incident) error {
 						return nil
 					},
@@ -895,7 +1411,7 @@ func TestService_FullUpdateIncident(t *testing.T) {
 			},
 			validateLogs: func(t *testing.T, logger *mockLogger) {
 				infoLogs := logger.GetInfoLogs()
-				require.Len(t, infoLogs, 3, "should log attempt, cache delete, and success")
+				require.Len(t, infoLogs, 4, "should log attempt, cache delete, success and webhook enqueue")
 				errorLogs := logger.GetErrorLogs()
 				require.Empty(t, errorLogs, "should not have errors")
 			},
@@ -910,9 +1426,11 @@ func TestService_FullUpdateIncident(t *testing.T) {
 			mockLog := &mockLogger{}
 
 			service := &Service{
-				incidents: tt.incidents(),
-				cache:     tt.cache(),
-				logger:    mockLog,
+				queue:       &mockQueue{},
+				incidents:   tt.incidents(),
+				coordinates: &mockCoordinatesRepository{},
+				cache:       tt.cache(),
+				logger:      mockLog,
 			}
 
 			result, err := service.FullUpdateIncident(ctx, tt.input)
@@ -941,3 +1459,284 @@ func TestService_FullUpdateIncident(t *testing.T) {
 }
 
 func ptr[T any](v T) *T { return &v }
+
+func TestService_GetIncidentHistory(t *testing.T) {
+	tests := []struct {
+		name      string
+		rawID     string
+		incidents func() *mockIncidentsRepository
+		wantErr   bool
+		errType   func(err error) bool
+		wantLen   int
+	}{
+		{
+			name:  "validation error - id is invalid",
+			rawID: "abc",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name:  "not found",
+			rawID: "1",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					historyFunc: func(ctx context.Context, id int) ([]domain.IncidentVersion, error) {
+						return nil, domain.ErrNotFound("incident is not exists")
+					},
+				}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeNotFound
+			},
+		},
+		{
+			name:  "success",
+			rawID: "1",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					historyFunc: func(ctx context.Context, id int) ([]domain.IncidentVersion, error) {
+						return []domain.IncidentVersion{
+							{Incident: domain.Incident{ID: id, Version: 2}, Operation: domain.IncidentOperationUpdate, ChangedBy: "operator"},
+							{Incident: domain.Incident{ID: id, Version: 1}, Operation: domain.IncidentOperationCreate, ChangedBy: "default"},
+						}, nil
+					},
+				}
+			},
+			wantLen: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &Service{
+				incidents: tt.incidents(),
+				logger:    &mockLogger{},
+			}
+
+			result, err := service.GetIncidentHistory(context.Background(), tt.rawID)
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				if tt.errType != nil {
+					require.True(t, tt.errType(err), "wrong error type: %v", err)
+				}
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.Len(t, result, tt.wantLen)
+		})
+	}
+}
+
+func TestService_GetIncidentAsOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		rawID     string
+		rawAt     string
+		incidents func() *mockIncidentsRepository
+		wantErr   bool
+		errType   func(err error) bool
+	}{
+		{
+			name:  "validation error - as_of is not RFC3339",
+			rawID: "1",
+			rawAt: "yesterday",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name:  "repository error",
+			rawID: "1",
+			rawAt: "2026-10-16T10:00:00Z",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					getAsOfFunc: func(ctx context.Context, id int, at time.Time) (*domain.Incident, error) {
+						return nil, errors.New("failed database connection")
+					},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name:  "success - time converted to UTC",
+			rawID: "1",
+			rawAt: "2026-10-16T13:00:00+03:00",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					getAsOfFunc: func(ctx context.Context, id int, at time.Time) (*domain.Incident, error) {
+						if !at.Equal(time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)) || at.Location() != time.UTC {
+							return nil, errors.New("unexpected time")
+						}
+						return &domain.Incident{ID: id, Version: 1}, nil
+					},
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &Service{
+				incidents: tt.incidents(),
+				logger:    &mockLogger{},
+			}
+
+			result, err := service.GetIncidentAsOf(context.Background(), tt.rawID, tt.rawAt)
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				if tt.errType != nil {
+					require.True(t, tt.errType(err), "wrong error type: %v", err)
+				}
+				require.Nil(t, result)
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.Equal(t, 1, result.ID)
+		})
+	}
+}
+
+func TestService_RestoreIncident(t *testing.T) {
+	tests := []struct {
+		name         string
+		rawID        string
+		incidents    func() *mockIncidentsRepository
+		wantErr      bool
+		errType      func(err error) bool
+		wantCacheKey string
+	}{
+		{
+			name:  "validation error - id is invalid",
+			rawID: "1a",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeInvalidValidation
+			},
+		},
+		{
+			name:  "not found - incident is not deleted",
+			rawID: "1",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					restoreFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return nil, domain.ErrNotFound("deleted incident not found")
+					},
+				}
+			},
+			wantErr: true,
+			errType: func(err error) bool {
+				var appErr *domain.AppError
+				return errors.As(err, &appErr) && appErr.Code == domain.CodeNotFound
+			},
+		},
+		{
+			name:  "success",
+			rawID: "1",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					restoreFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return &domain.Incident{ID: id, Version: 3}, nil
+					},
+				}
+			},
+			wantCacheKey: "incidentID:1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var deletedKey string
+			service := &Service{
+				queue:     &mockQueue{},
+				incidents: tt.incidents(),
+				cache: &mockCache{
+					deleteFunc: func(ctx context.Context, key string) (bool, error) {
+						deletedKey = key
+						return true, nil
+					},
+				},
+				logger: &mockLogger{},
+			}
+
+			result, err := service.RestoreIncident(context.Background(), tt.rawID)
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				if tt.errType != nil {
+					require.True(t, tt.errType(err), "wrong error type: %v", err)
+				}
+				require.Nil(t, result)
+				require.Empty(t, deletedKey)
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.Equal(t, 1, result.ID)
+			require.Equal(t, tt.wantCacheKey, deletedKey)
+		})
+	}
+}
+
+func TestService_PurgeIncidents(t *testing.T) {
+	t.Run("uses configured retention", func(t *testing.T) {
+		service := &Service{
+			incidents: &mockIncidentsRepository{
+				purgeFunc: func(ctx context.Context, retentionDays int) (int, error) {
+					if retentionDays != 30 {
+						return 0, errors.New("unexpected retention")
+					}
+					return 2, nil
+				},
+			},
+			logger: &mockLogger{},
+			opts:   Options{PurgeRetentionDays: 30},
+		}
+
+		purged, err := service.PurgeIncidents(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 2, purged)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockLog := &mockLogger{}
+		service := &Service{
+			incidents: &mockIncidentsRepository{
+				purgeFunc: func(ctx context.Context, retentionDays int) (int, error) {
+					return 0, errors.New("failed database connection")
+				},
+			},
+			logger: mockLog,
+		}
+
+		_, err := service.PurgeIncidents(context.Background())
+		require.Error(t, err)
+		require.Equal(t, "purge incidents repository error", mockLog.GetErrorLogs()[0].msg)
+	})
+}
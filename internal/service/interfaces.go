@@ -3,19 +3,42 @@ package service
 import (
 	"context"
 	"red_collar/internal/domain"
+	"time"
 )
 
 type IncidentRepositoryInterface interface {
 	Create(ctx context.Context, incedent *domain.Incident) error
 	GetByID(ctx context.Context, id int) (*domain.Incident, error)
-	Paginate(ctx context.Context, limit, offset int) ([]domain.Incident, int, error)
+	Paginate(ctx context.Context, filter domain.IncidentFilter, limit, offset int) ([]domain.Incident, int, error)
+	PaginateCursor(ctx context.Context, filter domain.IncidentFilter, limit int) ([]domain.Incident, error)
+	History(ctx context.Context, id int) ([]domain.IncidentVersion, error)
+	GetAsOf(ctx context.Context, id int, at time.Time) (*domain.Incident, error)
 	Delete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) (*domain.Incident, error)
+	Purge(ctx context.Context, retentionDays int) (int, error)
 	FullUpdate(ctx context.Context, incident *domain.Incident) error
+	UpsertByTitle(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error)
+	ClaimActivated(ctx context.Context) ([]domain.Incident, error)
+	ClaimExpired(ctx context.Context) ([]domain.Incident, error)
 }
 
 type CoordinatesRepositoryInterface interface {
-	Check(ctx context.Context, locCheck *domain.LocationCheck) error
+	Check(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error
+	CheckBatch(ctx context.Context, checks []*domain.LocationCheck, params domain.CheckParams) error
 	GetStats(ctx context.Context, timeWindowMinutes int) ([]domain.ZoneStat, error)
+	StatsSeries(ctx context.Context, filter domain.StatsFilter) ([]domain.ZoneStatBucket, error)
+	Heatmap(ctx context.Context, filter domain.HeatmapFilter) ([]domain.HeatmapCell, error)
+	UserChecks(ctx context.Context, filter domain.CheckFilter, limit int) ([]domain.LocationCheck, error)
+	ZoneOccupants(ctx context.Context, incidentID int, windowMinutes int) ([]domain.ZoneOccupant, error)
+}
+
+type WebhookSubscriptionRepositoryInterface interface {
+	Create(ctx context.Context, sub *domain.WebhookSubscription) error
+	GetByID(ctx context.Context, id int) (*domain.WebhookSubscription, error)
+	List(ctx context.Context, enabledOnly bool) ([]domain.WebhookSubscription, error)
+	Update(ctx context.Context, sub *domain.WebhookSubscription) error
+	Delete(ctx context.Context, id int) error
+	RotateSecret(ctx context.Context, id int, secret string, grace time.Duration) (*domain.WebhookSubscription, error)
 }
 
 type LoggerInterfaces interface {
@@ -26,7 +49,17 @@ type LoggerInterfaces interface {
 }
 
 type QueueInterface interface {
-	Enqueue(ctx context.Context, check *domain.LocationCheck) error
+	Enqueue(ctx context.Context, event *domain.Event) error
+	ListDLQ(ctx context.Context, filter domain.DeadLetterFilter, offset, limit int) ([]domain.DeadLetter, int, error)
+	ReplayDLQ(ctx context.Context, filter domain.DeadLetterFilter) (int, error)
+	DeleteDLQ(ctx context.Context, filter domain.DeadLetterFilter) (int, error)
+}
+
+type MembershipInterface interface {
+	// Update атомарно заменяет состояние пользователя результатом fn, nil от fn - без изменений.
+	// При параллельном изменении fn может быть вызвана повторно, а для проверки старше той,
+	// по которой построено состояние, не вызывается
+	Update(ctx context.Context, userID string, checkedAt time.Time, fn func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership) error
 }
 
 type CacheInterface interface {
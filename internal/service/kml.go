@@ -0,0 +1,292 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"red_collar/internal/domain"
+	"strconv"
+	"strings"
+)
+
+const (
+	kmlContentType = "application/vnd.google-earth.kml+xml"
+	kmlNamespace   = "http://www.opengis.net/kml/2.2"
+)
+
+type kmlDocument struct {
+	XMLName    xml.Name       `xml:"kml"`
+	Namespace  string         `xml:"xmlns,attr"`
+	Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+}
+
+// kmlPlacemark - объект KML. Свойства инцидента берутся из ExtendedData/Data
+// (так пишет Google Earth) или ExtendedData/SchemaData/SimpleData (так пишут ГИС)
+type kmlPlacemark struct {
+	Name          string            `xml:"name"`
+	Description   string            `xml:"description,omitempty"`
+	Data          []kmlData         `xml:"ExtendedData>Data"`
+	SimpleData    []kmlSimpleData   `xml:"ExtendedData>SchemaData>SimpleData"`
+	Point         *kmlPoint         `xml:"Point"`
+	Polygon       *kmlPolygon       `xml:"Polygon"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlSimpleData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPolygon struct {
+	Outer kmlBoundary   `xml:"outerBoundaryIs"`
+	Inner []kmlBoundary `xml:"innerBoundaryIs"`
+}
+
+type kmlBoundary struct {
+	Coordinates string `xml:"LinearRing>coordinates"`
+}
+
+type kmlMultiGeometry struct {
+	Polygons []kmlPolygon `xml:"Polygon"`
+}
+
+// decodeKMLIncidents разбирает все Placemark документа, в том числе вложенные в папки.
+// Point становится круговой зоной с radius_m из ExtendedData, Polygon и MultiGeometry из полигонов - полигональной
+func decodeKMLIncidents(data []byte) ([]importItem, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var items []importItem
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, domain.ErrInvalidRequest("invalid kml: " + err.Error())
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Placemark" {
+			continue
+		}
+
+		var placemark kmlPlacemark
+		if err := decoder.DecodeElement(&placemark, &start); err != nil {
+			return nil, domain.ErrInvalidRequest("invalid kml: " + err.Error())
+		}
+
+		var item importItem
+		item.Err = decodeKMLPlacemark(&placemark, &item.Input)
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func decodeKMLPlacemark(placemark *kmlPlacemark, in *CreateIncidentRequestInput) error {
+	// ExtendedData разбирается как строка CSV с колонками из имен полей.
+	// Название, описание и координаты берутся только из самого Placemark
+	columns := map[string]int{}
+	var record []string
+	for _, d := range placemark.Data {
+		columns[d.Name] = len(record)
+		record = append(record, d.Value)
+	}
+	for _, d := range placemark.SimpleData {
+		columns[d.Name] = len(record)
+		record = append(record, d.Value)
+	}
+	for _, name := range []string{"title", "description", "lat", "long", "boundary"} {
+		delete(columns, name)
+	}
+	if err := decodeCSVRecord(record, columns, in); err != nil {
+		return err
+	}
+
+	in.Title = strings.TrimSpace(placemark.Name)
+	if description := strings.TrimSpace(placemark.Description); description != "" {
+		in.Description = &description
+	}
+
+	switch {
+	case placemark.Point != nil:
+		positions, err := parseKMLCoordinates(placemark.Point.Coordinates)
+		if err != nil {
+			return err
+		}
+		if len(positions) != 1 {
+			return domain.ErrInvalidValidation("point must contain exactly one position")
+		}
+		in.Long, in.Lat = positions[0][0], positions[0][1]
+	case placemark.Polygon != nil:
+		polygon, err := kmlPolygonToGeoJSON(placemark.Polygon)
+		if err != nil {
+			return err
+		}
+		in.Boundary, err = json.Marshal(map[string]any{"type": "Polygon", "coordinates": polygon})
+		if err != nil {
+			return err
+		}
+	case placemark.MultiGeometry != nil && len(placemark.MultiGeometry.Polygons) > 0:
+		polygons := make([][][][]float64, 0, len(placemark.MultiGeometry.Polygons))
+		for i := range placemark.MultiGeometry.Polygons {
+			polygon, err := kmlPolygonToGeoJSON(&placemark.MultiGeometry.Polygons[i])
+			if err != nil {
+				return err
+			}
+			polygons = append(polygons, polygon)
+		}
+		var err error
+		in.Boundary, err = json.Marshal(map[string]any{"type": "MultiPolygon", "coordinates": polygons})
+		if err != nil {
+			return err
+		}
+	default:
+		return domain.ErrInvalidValidation("placemark geometry must be Point, Polygon or MultiGeometry of polygons")
+	}
+	return nil
+}
+
+func kmlPolygonToGeoJSON(polygon *kmlPolygon) ([][][]float64, error) {
+	outer, err := parseKMLCoordinates(polygon.Outer.Coordinates)
+	if err != nil {
+		return nil, err
+	}
+
+	rings := [][][]float64{outer}
+	for _, inner := range polygon.Inner {
+		ring, err := parseKMLCoordinates(inner.Coordinates)
+		if err != nil {
+			return nil, err
+		}
+		rings = append(rings, ring)
+	}
+	return rings, nil
+}
+
+// parseKMLCoordinates разбирает кортежи "long,lat[,alt]", разделенные пробелами. Высота отбрасывается
+func parseKMLCoordinates(raw string) ([][]float64, error) {
+	var positions [][]float64
+	for _, tuple := range strings.Fields(raw) {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 {
+			return nil, domain.ErrInvalidValidation(fmt.Sprintf("invalid kml coordinates %q", tuple))
+		}
+
+		long, errLong := strconv.ParseFloat(parts[0], 64)
+		lat, errLat := strconv.ParseFloat(parts[1], 64)
+		if errLong != nil || errLat != nil {
+			return nil, domain.ErrInvalidValidation(fmt.Sprintf("invalid kml coordinates %q", tuple))
+		}
+		positions = append(positions, []float64{long, lat})
+	}
+	if len(positions) == 0 {
+		return nil, domain.ErrInvalidValidation("kml coordinates are empty")
+	}
+	return positions, nil
+}
+
+// encodeKMLIncidents выгружает инциденты в документ KML: круговая зона - точкой центра,
+// полигональная - полигонами границы. Остальные поля пишутся в ExtendedData
+func encodeKMLIncidents(incidents []domain.Incident) ([]byte, error) {
+	doc := kmlDocument{
+		Namespace:  kmlNamespace,
+		Placemarks: make([]kmlPlacemark, 0, len(incidents)),
+	}
+
+	for _, incident := range incidents {
+		placemark := kmlPlacemark{
+			Name:        incident.Title,
+			Description: incident.Description,
+			Data: []kmlData{
+				{Name: "radius_m", Value: strconv.Itoa(incident.Radius)},
+				{Name: "active", Value: strconv.FormatBool(incident.Active)},
+				{Name: "severity", Value: string(incident.Severity)},
+				{Name: "category", Value: incident.Category},
+			},
+		}
+		if incident.WarningBuffer != nil {
+			placemark.Data = append(placemark.Data, kmlData{Name: "warning_buffer_m", Value: strconv.Itoa(*incident.WarningBuffer)})
+		}
+		if incident.StartsAt != nil {
+			placemark.Data = append(placemark.Data, kmlData{Name: "starts_at", Value: formatOptionalTime(incident.StartsAt)})
+		}
+		if incident.EndsAt != nil {
+			placemark.Data = append(placemark.Data, kmlData{Name: "ends_at", Value: formatOptionalTime(incident.EndsAt)})
+		}
+
+		if len(incident.Boundary) == 0 {
+			placemark.Point = &kmlPoint{Coordinates: formatKMLCoordinates([][]float64{{incident.Long, incident.Lat}})}
+		} else {
+			polygons, err := boundaryPolygons(incident.Boundary)
+			if err != nil {
+				return nil, err
+			}
+			kmlPolygons := make([]kmlPolygon, len(polygons))
+			for i, polygon := range polygons {
+				kmlPolygons[i].Outer.Coordinates = formatKMLCoordinates(polygon[0])
+				for _, ring := range polygon[1:] {
+					kmlPolygons[i].Inner = append(kmlPolygons[i].Inner, kmlBoundary{Coordinates: formatKMLCoordinates(ring)})
+				}
+			}
+			if len(kmlPolygons) == 1 {
+				placemark.Polygon = &kmlPolygons[0]
+			} else {
+				placemark.MultiGeometry = &kmlMultiGeometry{Polygons: kmlPolygons}
+			}
+		}
+		doc.Placemarks = append(doc.Placemarks, placemark)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// boundaryPolygons возвращает полигоны границы в формате GeoJSON как список MultiPolygon
+func boundaryPolygons(raw json.RawMessage) ([][][][]float64, error) {
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(raw, &geometry); err != nil {
+		return nil, err
+	}
+
+	switch geometry.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil {
+			return nil, err
+		}
+		return [][][][]float64{polygon}, nil
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygons); err != nil {
+			return nil, err
+		}
+		return polygons, nil
+	default:
+		return nil, fmt.Errorf("unexpected boundary type %q", geometry.Type)
+	}
+}
+
+func formatKMLCoordinates(positions [][]float64) string {
+	tuples := make([]string, len(positions))
+	for i, position := range positions {
+		tuples[i] = strconv.FormatFloat(position[0], 'f', -1, 64) + "," + strconv.FormatFloat(position[1], 'f', -1, 64)
+	}
+	return strings.Join(tuples, " ")
+}
@@ -1,9 +1,13 @@
 package service
 
-import "red_collar/internal/domain"
+import (
+	"encoding/json"
+	"red_collar/internal/domain"
+	"time"
+)
 
 func mapCreateIncidentInputToDomain(in *CreateIncidentRequestInput) *domain.Incident {
-	desc := "without description"
+	desc := domain.DefaultDescription
 	if in.Description != nil {
 		desc = *in.Description
 	}
@@ -13,18 +17,56 @@ func mapCreateIncidentInputToDomain(in *CreateIncidentRequestInput) *domain.Inci
 		active = *in.Active
 	}
 
-	return &domain.Incident{
-		Title:       in.Title,
-		Description: desc,
-		Lat:         in.Lat,
-		Long:        in.Long,
-		Radius:      in.Radius,
-		Active:      active,
+	incident := &domain.Incident{
+		Title:         in.Title,
+		Description:   desc,
+		Lat:           in.Lat,
+		Long:          in.Long,
+		Radius:        radiusForShape(in.Radius, in.Boundary),
+		Active:        active,
+		Severity:      domain.Severity(in.Severity),
+		Category:      in.Category,
+		Boundary:      in.Boundary,
+		WarningBuffer: in.WarningBuffer,
+		StartsAt:      utcTime(in.StartsAt),
+		EndsAt:        utcTime(in.EndsAt),
 	}
+	incident.SetDefaults()
+	return incident
+}
+
+// mapImportItemToDomain переносит элемент импорта, оставляя незаданные в файле поля пустыми,
+// чтобы обновляемый инцидент сохранил их текущие значения
+func mapImportItemToDomain(item *importItem) *domain.ImportIncident {
+	in := &item.Input
+	incident := &domain.ImportIncident{
+		Title:         in.Title,
+		Description:   in.Description,
+		Lat:           in.Lat,
+		Long:          in.Long,
+		Radius:        radiusForShape(in.Radius, in.Boundary),
+		Active:        in.Active,
+		WarningBuffer: in.WarningBuffer,
+		StartsAt:      utcTime(in.StartsAt),
+		EndsAt:        utcTime(in.EndsAt),
+	}
+	if in.Severity != "" {
+		severity := domain.Severity(in.Severity)
+		incident.Severity = &severity
+	}
+	if in.Category != "" {
+		category := in.Category
+		incident.Category = &category
+	}
+	if !item.BoundaryOmitted {
+		boundary := in.Boundary
+		incident.Boundary = &boundary
+	}
+	return incident
 }
 
 func mapFullUpdateIncident(in *FullUpdateIncidentRequestInput, id int) *domain.Incident {
-	desc := "without description"
+	desc := domain.DefaultDescription
 	if in.Description != nil {
 		desc = *in.Description
 	}
@@ -34,15 +76,23 @@ func mapFullUpdateIncident(in *FullUpdateIncidentRequestInput, id int) *domain.I
 		active = *in.Active
 	}
 
-	return &domain.Incident{
-		ID:          id,
-		Title:       in.Title,
-		Description: desc,
-		Lat:         in.Lat,
-		Long:        in.Long,
-		Radius:      in.Radius,
-		Active:      active,
+	incident := &domain.Incident{
+		ID:            id,
+		Title:         in.Title,
+		Description:   desc,
+		Lat:           in.Lat,
+		Long:          in.Long,
+		Radius:        radiusForShape(in.Radius, in.Boundary),
+		Active:        active,
+		Severity:      domain.Severity(in.Severity),
+		Category:      in.Category,
+		Boundary:      in.Boundary,
+		WarningBuffer: in.WarningBuffer,
+		StartsAt:      utcTime(in.StartsAt),
+		EndsAt:        utcTime(in.EndsAt),
 	}
+	incident.SetDefaults()
+	return incident
 }
 
 func mapCheckInputToDomain(in *CheckCoordinatesRequestInput) *domain.LocationCheck {
@@ -52,3 +102,21 @@ func mapCheckInputToDomain(in *CheckCoordinatesRequestInput) *domain.LocationChe
 		Long:   in.Long,
 	}
 }
+
+// utcTime переводит время расписания в UTC: колонки starts_at/ends_at без часового пояса,
+// и смещение клиента при записи иначе отбрасывается
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// Для полигональной зоны радиус не используется
+func radiusForShape(radius int, boundary json.RawMessage) int {
+	if len(boundary) > 0 {
+		return 0
+	}
+	return radius
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"red_collar/internal/domain"
+	"sort"
+	"time"
+
+	"github.com/theartofdevel/logging"
+)
+
+// trackZoneTransitions сравнивает текущие зоны пользователя с сохраненным состоянием
+// и возвращает события входа/выхода/пребывания/приближения/пересечения. Состояние меняется атомарно,
+// поэтому при параллельных проверках одного пользователя переход достается только одной из них.
+// Точка старше той, по которой построено состояние, его не меняет и переходов не дает.
+// Если состояние недоступно, считаем, что пользователь ни в одной зоне не был:
+// лучше отправить лишнее уведомление, чем потерять нужное
+func (s *Service) trackZoneTransitions(ctx context.Context, check *domain.LocationCheck) []*domain.Event {
+	// Пересечения произошли до текущей точки, поэтому их события идут первыми и в порядке пути
+	var events []*domain.Event
+	for _, crossing := range check.Crossed {
+		events = append(events, &domain.Event{Type: domain.EventZoneCrossed, IncidentID: crossing.IncidentID})
+	}
+
+	var transitions []*domain.Event
+	var diffed bool
+	diff := func(prev map[int]domain.ZoneMembership) map[int]domain.ZoneMembership {
+		diffed = true
+		var next map[int]domain.ZoneMembership
+		next, transitions = diffZoneMembership(prev, insideZoneIDs(check), approachingZoneIDs(check), check.CheckedAt, s.opts.DwellThreshold)
+		describeZones(check, prev, next, append(events, transitions...))
+		return next
+	}
+
+	if err := s.membership.Update(ctx, check.UserID, check.CheckedAt, diff); err != nil {
+		s.logger.Error("failed to update zone membership",
+			logging.StringAttr("userID", check.UserID),
+			logging.ErrAttr(err),
+		)
+		if !diffed {
+			diff(nil)
+		}
+	}
+	events = append(events, transitions...)
+
+	for _, event := range events {
+		event.LocationCheck = check
+	}
+	return events
+}
+
+type zoneDetails struct {
+	severity domain.Severity
+	category string
+}
+
+// describeZones проставляет уровень опасности и категорию зон в события и новое состояние.
+// Данные берутся из текущей проверки, а для зон, которые пользователь покинул, - из прошлого состояния
+func describeZones(check *domain.LocationCheck, prev, next map[int]domain.ZoneMembership, events []*domain.Event) {
+	details := make(map[int]zoneDetails, len(check.Matches)+len(check.Approaching)+len(check.Crossed))
+	for _, m := range check.Matches {
+		details[m.IncidentID] = zoneDetails{severity: m.Severity, category: m.Category}
+	}
+	for _, w := range check.Approaching {
+		details[w.IncidentID] = zoneDetails{severity: w.Severity, category: w.Category}
+	}
+	for _, c := range check.Crossed {
+		details[c.IncidentID] = zoneDetails{severity: c.Severity, category: c.Category}
+	}
+
+	for id, membership := range next {
+		if d, ok := details[id]; ok {
+			membership.Severity = d.severity
+			membership.Category = d.category
+			next[id] = membership
+		}
+	}
+
+	for _, event := range events {
+		if d, ok := details[event.IncidentID]; ok {
+			event.Severity = d.severity
+			event.Category = d.category
+		} else if membership, ok := prev[event.IncidentID]; ok {
+			event.Severity = membership.Severity
+			event.Category = membership.Category
+		}
+	}
+}
+
+func (s *Service) enqueueEvents(ctx context.Context, userID string, events []*domain.Event) {
+	for _, event := range events {
+		if err := s.queue.Enqueue(ctx, event); err != nil {
+			s.logger.Error("failed to enqueue webhook task",
+				logging.StringAttr("userID", userID),
+				logging.StringAttr("event", string(event.Type)),
+				logging.ErrAttr(err),
+			)
+			continue
+		}
+
+		s.logger.Info("webhook task enqueued",
+			logging.StringAttr("userID", userID),
+			logging.StringAttr("event", string(event.Type)),
+		)
+	}
+}
+
+// alertZoneOccupants отправляет zone.entered пользователям, которые уже находятся в зоне инцидента
+// по последней проверке в окне OccupantWindowMins, и отмечает зону в их состоянии, чтобы следующая
+// проверка не повторила событие. Тем, у кого зона уже отмечена, событие не отправляется.
+// Ошибки только логируются: изменение инцидента уже сохранено
+func (s *Service) alertZoneOccupants(ctx context.Context, incident *domain.Incident) {
+	occupants, err := s.coordinates.ZoneOccupants(ctx, incident.ID, s.opts.OccupantWindowMins)
+	if err != nil {
+		s.logger.Error("failed to get zone occupants",
+			logging.IntAttr("incidentID", incident.ID),
+			logging.ErrAttr(err),
+		)
+		return
+	}
+
+	for _, occupant := range occupants {
+		var notified bool
+		err := s.membership.Update(ctx, occupant.UserID, occupant.LastSeenAt, func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership {
+			membership, ok := zones[incident.ID]
+			notified = ok && !membership.Approaching
+			if notified {
+				return nil
+			}
+			if zones == nil {
+				zones = make(map[int]domain.ZoneMembership, 1)
+			}
+			zones[incident.ID] = domain.ZoneMembership{
+				EnteredAt: occupant.LastSeenAt,
+				Severity:  incident.Severity,
+				Category:  incident.Category,
+			}
+			return zones
+		})
+		if err != nil {
+			// Состояние не удалось дополнить, не затерев другие зоны,
+			// поэтому событие отправляется, а состояние обновит следующая проверка
+			s.logger.Error("failed to update zone membership",
+				logging.StringAttr("userID", occupant.UserID),
+				logging.ErrAttr(err),
+			)
+		} else if notified {
+			continue
+		}
+
+		event := &domain.Event{
+			Type:        domain.EventZoneEntered,
+			IncidentID:  incident.ID,
+			Severity:    incident.Severity,
+			Category:    incident.Category,
+			Retroactive: true,
+			LocationCheck: &domain.LocationCheck{
+				ID:           occupant.CheckID,
+				UserID:       occupant.UserID,
+				CheckedAt:    occupant.LastSeenAt,
+				Lat:          occupant.Lat,
+				Long:         occupant.Long,
+				InDangerZone: true,
+				Matches: []domain.IncidentMatch{{
+					IncidentID: incident.ID,
+					DistanceM:  occupant.DistanceM,
+					Severity:   incident.Severity,
+					Category:   incident.Category,
+				}},
+			},
+		}
+		s.enqueueEvents(ctx, occupant.UserID, []*domain.Event{event})
+	}
+}
+
+// zoneExpanded сообщает, могли ли после обновления в зоне оказаться пользователи, которых в ней не было:
+// инцидент начал действовать (включен или перенесено расписание) или зона выросла, сместилась или получила
+// другую границу. Граница сравнивается как текст GeoJSON, поэтому та же граница в другой записи тоже
+// считается изменением: это лишний запрос, но не лишнее уведомление
+func zoneExpanded(prev, next *domain.Incident, now time.Time) bool {
+	if !incidentInEffect(next, now) {
+		return false
+	}
+	if !incidentInEffect(prev, now) {
+		return true
+	}
+	if len(prev.Boundary) > 0 || len(next.Boundary) > 0 {
+		return !bytes.Equal(prev.Boundary, next.Boundary)
+	}
+	return next.Radius > prev.Radius || next.Lat != prev.Lat || next.Long != prev.Long
+}
+
+// incidentInEffect - инцидент включен и действует по расписанию в момент now
+func incidentInEffect(incident *domain.Incident, now time.Time) bool {
+	return incident.Active &&
+		(incident.StartsAt == nil || !incident.StartsAt.After(now)) &&
+		(incident.EndsAt == nil || incident.EndsAt.After(now))
+}
+
+func insideZoneIDs(check *domain.LocationCheck) []int {
+	ids := make([]int, 0, len(check.Matches))
+	for _, match := range check.Matches {
+		ids = append(ids, match.IncidentID)
+	}
+	return ids
+}
+
+func approachingZoneIDs(check *domain.LocationCheck) []int {
+	ids := make([]int, 0, len(check.Approaching))
+	for _, warning := range check.Approaching {
+		ids = append(ids, warning.IncidentID)
+	}
+	return ids
+}
+
+// diffZoneMembership строит новое состояние пребывания в зонах и список переходов.
+// zone.dwell отправляется один раз, когда пользователь находится в зоне дольше dwell.
+// zone.approaching отправляется один раз при попадании в полосу предупреждения снаружи зоны;
+// выход из зоны в полосу предупреждения - это zone.exited, а уход из полосы событий не создает
+func diffZoneMembership(
+	prev map[int]domain.ZoneMembership,
+	inside []int,
+	approaching []int,
+	at time.Time,
+	dwell time.Duration,
+) (map[int]domain.ZoneMembership, []*domain.Event) {
+	next := make(map[int]domain.ZoneMembership, len(inside)+len(approaching))
+	var entered, exited, dwelled, approached []int
+
+	for _, id := range inside {
+		membership, ok := prev[id]
+		if !ok || membership.Approaching {
+			next[id] = domain.ZoneMembership{EnteredAt: at}
+			entered = append(entered, id)
+			continue
+		}
+
+		if dwell > 0 && !membership.DwellSent && at.Sub(membership.EnteredAt) >= dwell {
+			membership.DwellSent = true
+			dwelled = append(dwelled, id)
+		}
+		next[id] = membership
+	}
+
+	for _, id := range approaching {
+		membership, ok := prev[id]
+		switch {
+		case !ok:
+			approached = append(approached, id)
+			next[id] = domain.ZoneMembership{EnteredAt: at, Approaching: true}
+		case !membership.Approaching:
+			exited = append(exited, id)
+			next[id] = domain.ZoneMembership{EnteredAt: at, Approaching: true}
+		default:
+			next[id] = membership
+		}
+	}
+
+	for id, membership := range prev {
+		if _, ok := next[id]; !ok && !membership.Approaching {
+			exited = append(exited, id)
+		}
+	}
+
+	var events []*domain.Event
+	events = appendZoneEvents(events, domain.EventZoneExited, exited)
+	events = appendZoneEvents(events, domain.EventZoneApproaching, approached)
+	events = appendZoneEvents(events, domain.EventZoneEntered, entered)
+	events = appendZoneEvents(events, domain.EventZoneDwell, dwelled)
+	return next, events
+}
+
+func appendZoneEvents(events []*domain.Event, eventType domain.EventType, ids []int) []*domain.Event {
+	sort.Ints(ids)
+	for _, id := range ids {
+		events = append(events, &domain.Event{Type: eventType, IncidentID: id})
+	}
+	return events
+}
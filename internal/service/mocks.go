@@ -3,15 +3,24 @@ package service
 import (
 	"context"
 	"red_collar/internal/domain"
+	"time"
 )
 
 // моки репозитория инцедентов
 type mockIncidentsRepository struct {
-	createFunc     func(ctx context.Context, incedent *domain.Incident) error
-	getByIDFunc    func(ctx context.Context, id int) (*domain.Incident, error)
-	paginateFunc   func(ctx context.Context, limit, offset int) ([]domain.Incident, int, error)
-	deleteFunc     func(ctx context.Context, id int) error
-	fullUpdateFunc func(ctx context.Context, incident *domain.Incident) error
+	createFunc         func(ctx context.Context, incedent *domain.Incident) error
+	getByIDFunc        func(ctx context.Context, id int) (*domain.Incident, error)
+	paginateFunc       func(ctx context.Context, filter domain.IncidentFilter, limit, offset int) ([]domain.Incident, int, error)
+	paginateCursorFunc func(ctx context.Context, filter domain.IncidentFilter, limit int) ([]domain.Incident, error)
+	historyFunc        func(ctx context.Context, id int) ([]domain.IncidentVersion, error)
+	getAsOfFunc        func(ctx context.Context, id int, at time.Time) (*domain.Incident, error)
+	deleteFunc         func(ctx context.Context, id int) error
+	restoreFunc        func(ctx context.Context, id int) (*domain.Incident, error)
+	purgeFunc          func(ctx context.Context, retentionDays int) (int, error)
+	fullUpdateFunc     func(ctx context.Context, incident *domain.Incident) error
+	upsertByTitleFunc  func(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error)
+	claimActivatedFunc func(ctx context.Context) ([]domain.Incident, error)
+	claimExpiredFunc   func(ctx context.Context) ([]domain.Incident, error)
 }
 
 func (m *mockIncidentsRepository) Create(ctx context.Context, incedent *domain.Incident) error {
@@ -28,13 +37,34 @@ func (m *mockIncidentsRepository) GetByID(ctx context.Context, id int) (*domain.
 	return nil, nil
 }
 
-func (m *mockIncidentsRepository) Paginate(ctx context.Context, limit, offset int) ([]domain.Incident, int, error) {
+func (m *mockIncidentsRepository) Paginate(ctx context.Context, filter domain.IncidentFilter, limit, offset int) ([]domain.Incident, int, error) {
 	if m.paginateFunc != nil {
-		return m.paginateFunc(ctx, limit, offset)
+		return m.paginateFunc(ctx, filter, limit, offset)
 	}
 	return nil, 0, nil
 }
 
+func (m *mockIncidentsRepository) PaginateCursor(ctx context.Context, filter domain.IncidentFilter, limit int) ([]domain.Incident, error) {
+	if m.paginateCursorFunc != nil {
+		return m.paginateCursorFunc(ctx, filter, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockIncidentsRepository) History(ctx context.Context, id int) ([]domain.IncidentVersion, error) {
+	if m.historyFunc != nil {
+		return m.historyFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockIncidentsRepository) GetAsOf(ctx context.Context, id int, at time.Time) (*domain.Incident, error) {
+	if m.getAsOfFunc != nil {
+		return m.getAsOfFunc(ctx, id, at)
+	}
+	return nil, nil
+}
+
 func (m *mockIncidentsRepository) Delete(ctx context.Context, id int) error {
 	if m.deleteFunc != nil {
 		return m.deleteFunc(ctx, id)
@@ -42,6 +72,20 @@ func (m *mockIncidentsRepository) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
+func (m *mockIncidentsRepository) Restore(ctx context.Context, id int) (*domain.Incident, error) {
+	if m.restoreFunc != nil {
+		return m.restoreFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockIncidentsRepository) Purge(ctx context.Context, retentionDays int) (int, error) {
+	if m.purgeFunc != nil {
+		return m.purgeFunc(ctx, retentionDays)
+	}
+	return 0, nil
+}
+
 func (m *mockIncidentsRepository) FullUpdate(ctx context.Context, incident *domain.Incident) error {
 	if m.fullUpdateFunc != nil {
 		return m.fullUpdateFunc(ctx, incident)
@@ -49,15 +93,48 @@ func (m *mockIncidentsRepository) FullUpdate(ctx context.Context, incident *doma
 	return nil
 }
 
+func (m *mockIncidentsRepository) UpsertByTitle(ctx context.Context, items []*domain.ImportIncident) ([]domain.ImportResult, error) {
+	if m.upsertByTitleFunc != nil {
+		return m.upsertByTitleFunc(ctx, items)
+	}
+	return nil, nil
+}
+
+func (m *mockIncidentsRepository) ClaimActivated(ctx context.Context) ([]domain.Incident, error) {
+	if m.claimActivatedFunc != nil {
+		return m.claimActivatedFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockIncidentsRepository) ClaimExpired(ctx context.Context) ([]domain.Incident, error) {
+	if m.claimExpiredFunc != nil {
+		return m.claimExpiredFunc(ctx)
+	}
+	return nil, nil
+}
+
 // моки репозитория координат
 type mockCoordinatesRepository struct {
-	checkFunc    func(ctx context.Context, locCheck *domain.LocationCheck) error
-	getStatsFunc func(ctx context.Context, timeWindowsMinutes int) ([]domain.ZoneStat, error)
+	checkFunc       func(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error
+	checkBatchFunc  func(ctx context.Context, checks []*domain.LocationCheck, params domain.CheckParams) error
+	getStatsFunc    func(ctx context.Context, timeWindowsMinutes int) ([]domain.ZoneStat, error)
+	statsSeriesFunc func(ctx context.Context, filter domain.StatsFilter) ([]domain.ZoneStatBucket, error)
+	heatmapFunc     func(ctx context.Context, filter domain.HeatmapFilter) ([]domain.HeatmapCell, error)
+	userChecksFunc  func(ctx context.Context, filter domain.CheckFilter, limit int) ([]domain.LocationCheck, error)
+	occupantsFunc   func(ctx context.Context, incidentID int, windowMinutes int) ([]domain.ZoneOccupant, error)
 }
 
-func (m *mockCoordinatesRepository) Check(ctx context.Context, locCheck *domain.LocationCheck) error {
+func (m *mockCoordinatesRepository) Check(ctx context.Context, locCheck *domain.LocationCheck, params domain.CheckParams) error {
 	if m.checkFunc != nil {
-		return m.checkFunc(ctx, locCheck)
+		return m.checkFunc(ctx, locCheck, params)
+	}
+	return nil
+}
+
+func (m *mockCoordinatesRepository) CheckBatch(ctx context.Context, checks []*domain.LocationCheck, params domain.CheckParams) error {
+	if m.checkBatchFunc != nil {
+		return m.checkBatchFunc(ctx, checks, params)
 	}
 	return nil
 }
@@ -69,18 +146,135 @@ func (m *mockCoordinatesRepository) GetStats(ctx context.Context, timeWindowsMin
 	return nil, nil
 }
 
+func (m *mockCoordinatesRepository) StatsSeries(ctx context.Context, filter domain.StatsFilter) ([]domain.ZoneStatBucket, error) {
+	if m.statsSeriesFunc != nil {
+		return m.statsSeriesFunc(ctx, filter)
+	}
+	return nil, nil
+}
+
+func (m *mockCoordinatesRepository) Heatmap(ctx context.Context, filter domain.HeatmapFilter) ([]domain.HeatmapCell, error) {
+	if m.heatmapFunc != nil {
+		return m.heatmapFunc(ctx, filter)
+	}
+	return nil, nil
+}
+
+func (m *mockCoordinatesRepository) UserChecks(ctx context.Context, filter domain.CheckFilter, limit int) ([]domain.LocationCheck, error) {
+	if m.userChecksFunc != nil {
+		return m.userChecksFunc(ctx, filter, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockCoordinatesRepository) ZoneOccupants(ctx context.Context, incidentID int, windowMinutes int) ([]domain.ZoneOccupant, error) {
+	if m.occupantsFunc != nil {
+		return m.occupantsFunc(ctx, incidentID, windowMinutes)
+	}
+	return nil, nil
+}
+
 // моки репозитория очереди
 type mockQueue struct {
-	enqueueFunc func(ctx context.Context, check *domain.LocationCheck) error
+	enqueueFunc   func(ctx context.Context, event *domain.Event) error
+	listDLQFunc   func(ctx context.Context, filter domain.DeadLetterFilter, offset, limit int) ([]domain.DeadLetter, int, error)
+	replayDLQFunc func(ctx context.Context, filter domain.DeadLetterFilter) (int, error)
+	deleteDLQFunc func(ctx context.Context, filter domain.DeadLetterFilter) (int, error)
 }
 
-func (m *mockQueue) Enqueue(ctx context.Context, check *domain.LocationCheck) error {
+func (m *mockQueue) Enqueue(ctx context.Context, event *domain.Event) error {
 	if m.enqueueFunc != nil {
-		return m.enqueueFunc(ctx, check)
+		return m.enqueueFunc(ctx, event)
+	}
+	return nil
+}
+
+func (m *mockQueue) ListDLQ(ctx context.Context, filter domain.DeadLetterFilter, offset, limit int) ([]domain.DeadLetter, int, error) {
+	if m.listDLQFunc != nil {
+		return m.listDLQFunc(ctx, filter, offset, limit)
+	}
+	return nil, 0, nil
+}
+
+func (m *mockQueue) ReplayDLQ(ctx context.Context, filter domain.DeadLetterFilter) (int, error) {
+	if m.replayDLQFunc != nil {
+		return m.replayDLQFunc(ctx, filter)
+	}
+	return 0, nil
+}
+
+func (m *mockQueue) DeleteDLQ(ctx context.Context, filter domain.DeadLetterFilter) (int, error) {
+	if m.deleteDLQFunc != nil {
+		return m.deleteDLQFunc(ctx, filter)
+	}
+	return 0, nil
+}
+
+// моки репозитория состояния зон
+type mockMembership struct {
+	updateFunc func(ctx context.Context, userID string, checkedAt time.Time, fn func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership) error
+}
+
+func (m *mockMembership) Update(ctx context.Context, userID string, checkedAt time.Time, fn func(zones map[int]domain.ZoneMembership) map[int]domain.ZoneMembership) error {
+	if m.updateFunc != nil {
+		return m.updateFunc(ctx, userID, checkedAt, fn)
+	}
+	fn(nil)
+	return nil
+}
+
+// моки репозитория подписок на вебхуки
+type mockWebhookSubscriptions struct {
+	createFunc  func(ctx context.Context, sub *domain.WebhookSubscription) error
+	getByIDFunc func(ctx context.Context, id int) (*domain.WebhookSubscription, error)
+	listFunc    func(ctx context.Context, enabledOnly bool) ([]domain.WebhookSubscription, error)
+	updateFunc  func(ctx context.Context, sub *domain.WebhookSubscription) error
+	deleteFunc  func(ctx context.Context, id int) error
+	rotateFunc  func(ctx context.Context, id int, secret string, grace time.Duration) (*domain.WebhookSubscription, error)
+}
+
+func (m *mockWebhookSubscriptions) Create(ctx context.Context, sub *domain.WebhookSubscription) error {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, sub)
 	}
 	return nil
 }
 
+func (m *mockWebhookSubscriptions) GetByID(ctx context.Context, id int) (*domain.WebhookSubscription, error) {
+	if m.getByIDFunc != nil {
+		return m.getByIDFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockWebhookSubscriptions) List(ctx context.Context, enabledOnly bool) ([]domain.WebhookSubscription, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, enabledOnly)
+	}
+	return nil, nil
+}
+
+func (m *mockWebhookSubscriptions) Update(ctx context.Context, sub *domain.WebhookSubscription) error {
+	if m.updateFunc != nil {
+		return m.updateFunc(ctx, sub)
+	}
+	return nil
+}
+
+func (m *mockWebhookSubscriptions) Delete(ctx context.Context, id int) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockWebhookSubscriptions) RotateSecret(ctx context.Context, id int, secret string, grace time.Duration) (*domain.WebhookSubscription, error) {
+	if m.rotateFunc != nil {
+		return m.rotateFunc(ctx, id, secret, grace)
+	}
+	return nil, nil
+}
+
 // моки репозитория логгера
 type mockLogger struct {
 	infoLogs  []logCall
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"red_collar/internal/domain"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/theartofdevel/logging"
+)
+
+// maxPatchAttempts - сколько раз повторить патч без If-Match, если инцидент
+// изменили между чтением и записью
+const maxPatchAttempts = 3
+
+// incidentDocument - представление инцидента, к которому применяется JSON Merge Patch.
+// Поля совпадают с телом PUT-запроса
+type incidentDocument struct {
+	Title         string          `json:"title"`
+	Description   *string         `json:"description,omitempty"`
+	Lat           float64         `json:"lat"`
+	Long          float64         `json:"long"`
+	Radius        int             `json:"radius_m"`
+	Active        *bool           `json:"active,omitempty"`
+	Severity      string          `json:"severity,omitempty"`
+	Category      string          `json:"category,omitempty"`
+	Boundary      json.RawMessage `json:"boundary,omitempty"`
+	WarningBuffer *int            `json:"warning_buffer_m,omitempty"`
+	StartsAt      *time.Time      `json:"starts_at,omitempty"`
+	EndsAt        *time.Time      `json:"ends_at,omitempty"`
+}
+
+func (s *Service) PatchIncident(ctx context.Context, in *PatchIncidentRequestInput) (*domain.Incident, error) {
+	id, err := validatePatchIncidentInput(in)
+	if err != nil {
+		s.logger.Error("patch incident request validation failed",
+			logging.StringAttr("id", in.ID),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to patch incident",
+		logging.IntAttr("id", id),
+	)
+
+	for attempt := 1; ; attempt++ {
+		incident, err := s.patchIncidentOnce(ctx, id, in)
+		if err == nil {
+			s.deleteIncidenFromCache(ctx, incidentCacheKey(id))
+
+			s.logger.Info("incident was successfully patched",
+				logging.IntAttr("id", id),
+				logging.IntAttr("version", incident.Version),
+			)
+
+			s.enqueueIncidentEvent(ctx, domain.EventIncidentUpdated, id, incident)
+			return incident, nil
+		}
+
+		var appErr *domain.AppError
+		conflict := errors.As(err, &appErr) && appErr.Code == domain.CodePreconditionFailed
+		if !conflict || len(in.ExpectedVersions) > 0 || attempt == maxPatchAttempts {
+			return nil, err
+		}
+
+		s.logger.Warn("incident was modified during patch, retrying",
+			logging.IntAttr("id", id),
+			logging.IntAttr("attempt", attempt),
+		)
+	}
+}
+
+// patchIncidentOnce читает текущую версию инцидента, применяет к ней патч
+// и записывает результат только при неизменной версии
+func (s *Service) patchIncidentOnce(ctx context.Context, id int, in *PatchIncidentRequestInput) (*domain.Incident, error) {
+	current, err := s.incidents.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("patch incident repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	if !versionMatches(current.Version, in.ExpectedVersions) {
+		s.logger.Error("patch incident precondition failed",
+			logging.IntAttr("id", id),
+			logging.IntAttr("version", current.Version),
+			logging.StringAttr("expectedVersions", fmt.Sprint(in.ExpectedVersions)),
+		)
+		return nil, domain.ErrPreconditionFailed("incident version does not match If-Match")
+	}
+
+	update, err := applyIncidentPatch(current, in.Patch)
+	if err == nil {
+		_, err = validateFullUpdateIncidentInput(update, s.opts.Categories)
+	}
+	if err != nil {
+		s.logger.Error("patch incident request validation failed",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	incident := mapFullUpdateIncident(update, id)
+	incident.Version = current.Version
+	if err := s.incidents.FullUpdate(ctx, incident); err != nil {
+		s.logger.Error("patch incident repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	if zoneExpanded(current, incident, time.Now()) {
+		s.alertZoneOccupants(ctx, incident)
+	}
+	return incident, nil
+}
+
+// versionMatches сообщает, совпадает ли версия инцидента с одной из версий If-Match.
+// Пустой список означает обновление без проверки версии
+func versionMatches(version int, expected []int) bool {
+	return len(expected) == 0 || slices.Contains(expected, version)
+}
+
+// applyIncidentPatch применяет JSON Merge Patch (RFC 7396) к текущему состоянию инцидента
+// и возвращает результат в виде запроса на полное обновление
+func applyIncidentPatch(current *domain.Incident, patch json.RawMessage) (*FullUpdateIncidentRequestInput, error) {
+	description := current.Description
+	active := current.Active
+	doc := incidentDocument{
+		Title:         current.Title,
+		Description:   &description,
+		Lat:           current.Lat,
+		Long:          current.Long,
+		Radius:        current.Radius,
+		Active:        &active,
+		Severity:      string(current.Severity),
+		Category:      current.Category,
+		Boundary:      current.Boundary,
+		WarningBuffer: current.WarningBuffer,
+		StartsAt:      current.StartsAt,
+		EndsAt:        current.EndsAt,
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var target, changes any
+	if err := json.Unmarshal(raw, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, domain.ErrInvalidRequest("invalid json payload")
+	}
+
+	merged, err := json.Marshal(mergePatch(target, changes))
+	if err != nil {
+		return nil, err
+	}
+
+	var patched incidentDocument
+	dec := json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patched); err != nil {
+		return nil, domain.ErrInvalidValidation("invalid patch: " + err.Error())
+	}
+
+	return &FullUpdateIncidentRequestInput{
+		ID:            strconv.Itoa(current.ID),
+		Title:         patched.Title,
+		Description:   patched.Description,
+		Lat:           patched.Lat,
+		Long:          patched.Long,
+		Radius:        patched.Radius,
+		Active:        patched.Active,
+		Severity:      patched.Severity,
+		Category:      patched.Category,
+		Boundary:      patched.Boundary,
+		WarningBuffer: patched.WarningBuffer,
+		StartsAt:      patched.StartsAt,
+		EndsAt:        patched.EndsAt,
+	}, nil
+}
+
+// mergePatch реализует алгоритм MergePatch из RFC 7396: null удаляет поле,
+// объекты сливаются рекурсивно, остальные значения заменяются целиком
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any, len(patchObj))
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"red_collar/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	// примеры из приложения A RFC 7396
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.patch, func(t *testing.T) {
+			var target, patch any
+			require.NoError(t, json.Unmarshal([]byte(tt.target), &target))
+			require.NoError(t, json.Unmarshal([]byte(tt.patch), &patch))
+
+			got, err := json.Marshal(mergePatch(target, patch))
+			require.NoError(t, err)
+			require.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestService_PatchIncident(t *testing.T) {
+	current := func() *domain.Incident {
+		buffer := 150
+		return &domain.Incident{
+			ID:            1,
+			Title:         "Color",
+			Description:   "Description",
+			Lat:           50,
+			Long:          30,
+			Radius:        100,
+			Active:        true,
+			Severity:      domain.SeverityDanger,
+			Category:      "fire",
+			WarningBuffer: &buffer,
+			Version:       3,
+		}
+	}
+	versions := func(v ...int) []int { return v }
+
+	isCode := func(code domain.ErrorCode) func(err error) bool {
+		return func(err error) bool {
+			var appErr *domain.AppError
+			return errors.As(err, &appErr) && appErr.Code == code
+		}
+	}
+
+	tests := []struct {
+		name           string
+		input          *PatchIncidentRequestInput
+		incidents      func() *mockIncidentsRepository
+		wantErr        bool
+		errType        func(err error) bool
+		wantCacheKey   string
+		validateResult func(t *testing.T, result *domain.Incident)
+	}{
+		{
+			name:    "validation error - id is invalid",
+			input:   &PatchIncidentRequestInput{ID: "1-2", Patch: json.RawMessage(`{}`)},
+			wantErr: true,
+			errType: isCode(domain.CodeInvalidValidation),
+		},
+		{
+			name:    "validation error - patch is not an object",
+			input:   &PatchIncidentRequestInput{ID: "1", Patch: json.RawMessage(`[{"op":"replace"}]`)},
+			wantErr: true,
+			errType: isCode(domain.CodeInvalidRequest),
+		},
+		{
+			name:  "not found",
+			input: &PatchIncidentRequestInput{ID: "1", Patch: json.RawMessage(`{"active":false}`)},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return nil, domain.ErrNotFound("incident not found")
+					},
+				}
+			},
+			wantErr: true,
+			errType: isCode(domain.CodeNotFound),
+		},
+		{
+			name: "precondition failed - stale If-Match",
+			input: &PatchIncidentRequestInput{
+				ID:               "1",
+				Patch:            json.RawMessage(`{"active":false}`),
+				ExpectedVersions: versions(1, 2),
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return current(), nil
+					},
+					fullUpdateFunc: func(ctx context.Context, incident *domain.Incident) error {
+						return errors.New("must not update stale version")
+					},
+				}
+			},
+			wantErr: true,
+			errType: isCode(domain.CodePreconditionFailed),
+		},
+		{
+			name: "validation error - unknown field",
+			input: &PatchIncidentRequestInput{
+				ID:    "1",
+				Patch: json.RawMessage(`{"created_at":"2026-10-16T10:00:00Z"}`),
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return current(), nil
+					},
+				}
+			},
+			wantErr: true,
+			errType: isCode(domain.CodeInvalidValidation),
+		},
+		{
+			name: "validation error - patched value is invalid",
+			input: &PatchIncidentRequestInput{
+				ID:    "1",
+				Patch: json.RawMessage(`{"radius_m":-5}`),
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return current(), nil
+					},
+				}
+			},
+			wantErr: true,
+			errType: isCode(domain.CodeInvalidValidation),
+		},
+		{
+			name: "success - toggle active keeps other fields",
+			input: &PatchIncidentRequestInput{
+				ID:               "1",
+				Patch:            json.RawMessage(`{"active":false,"warning_buffer_m":null}`),
+				ExpectedVersions: versions(2, 3),
+			},
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return current(), nil
+					},
+					fullUpdateFunc: func(ctx context.Context, incident *domain.Incident) error {
+						if incident.Version != 3 {
+							return errors.New("expected version must be passed to repository")
+						}
+						incident.Version++
+						return nil
+					},
+				}
+			},
+			wantCacheKey: "incidentID:1",
+			validateResult: func(t *testing.T, result *domain.Incident) {
+				require.False(t, result.Active)
+				require.Nil(t, result.WarningBuffer)
+				require.Equal(t, "Color", result.Title)
+				require.Equal(t, "Description", result.Description)
+				require.Equal(t, 100, result.Radius)
+				require.Equal(t, domain.SeverityDanger, result.Severity)
+				require.Equal(t, "fire", result.Category)
+				require.Equal(t, 4, result.Version)
+			},
+		},
+		{
+			name: "success - retry after concurrent update without If-Match",
+			input: &PatchIncidentRequestInput{
+				ID:    "01",
+				Patch: json.RawMessage(`{"title":"Renamed"}`),
+			},
+			incidents: func() *mockIncidentsRepository {
+				calls := 0
+				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						incident := current()
+						incident.Version += calls
+						return incident, nil
+					},
+					fullUpdateFunc: func(ctx context.Context, incident *domain.Incident) error {
+						calls++
+						if calls == 1 {
+							return domain.ErrPreconditionFailed("incident was modified by another request")
+						}
+						if incident.Version != 4 {
+							return errors.New("retry must use fresh version")
+						}
+						return nil
+					},
+				}
+			},
+			wantCacheKey: "incidentID:1",
+			validateResult: func(t *testing.T, result *domain.Incident) {
+				require.Equal(t, "Renamed", result.Title)
+			},
+		},
+		{
+			name: "precondition failed - concurrent update with If-Match is not retried",
+			input: &PatchIncidentRequestInput{
+				ID:               "1",
+				Patch:            json.RawMessage(`{"title":"Renamed"}`),
+				ExpectedVersions: versions(3),
+			},
+			incidents: func() *mockIncidentsRepository {
+				calls := 0
+				return &mockIncidentsRepository{
+					getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+						return current(), nil
+					},
+					fullUpdateFunc: func(ctx context.Context, incident *domain.Incident) error {
+						calls++
+						if calls > 1 {
+							return errors.New("must not retry")
+						}
+						return domain.ErrPreconditionFailed("incident was modified by another request")
+					},
+				}
+			},
+			wantErr: true,
+			errType: isCode(domain.CodePreconditionFailed),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			incidents := &mockIncidentsRepository{}
+			if tt.incidents != nil {
+				incidents = tt.incidents()
+			}
+
+			var deletedKey string
+			service := &Service{
+				queue:       &mockQueue{},
+				incidents:   incidents,
+				coordinates: &mockCoordinatesRepository{},
+				cache: &mockCache{
+					deleteFunc: func(ctx context.Context, key string) (bool, error) {
+						deletedKey = key
+						return true, nil
+					},
+				},
+				logger: &mockLogger{},
+				opts:   Options{Categories: []string{"fire"}},
+			}
+
+			result, err := service.PatchIncident(ctx, tt.input)
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				if tt.errType != nil {
+					require.True(t, tt.errType(err), "wrong error type: %v", err)
+				}
+				require.Nil(t, result, "result should be nil on error")
+				require.Empty(t, deletedKey, "cache must not be invalidated on error")
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.Equal(t, tt.wantCacheKey, deletedKey)
+
+			if tt.validateResult != nil {
+				tt.validateResult(t, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"red_collar/internal/domain"
+
+	"github.com/theartofdevel/logging"
+)
+
+// ProcessIncidentSchedule находит инциденты, у которых наступило время начала или окончания,
+// и ставит в очередь события incident.activated/incident.expired, а для начавших действовать -
+// zone.entered пользователям, которые уже в зоне. Каждый инцидент репозиторий отдает один раз,
+// поэтому вызов безопасно повторять по таймеру
+func (s *Service) ProcessIncidentSchedule(ctx context.Context) error {
+	activated, err := s.incidents.ClaimActivated(ctx)
+	if err != nil {
+		s.logger.Error("failed to claim activated incidents", logging.ErrAttr(err))
+		return err
+	}
+	s.enqueueIncidentEvents(ctx, domain.EventIncidentActivated, activated)
+	for i := range activated {
+		s.alertZoneOccupants(ctx, &activated[i])
+	}
+
+	expired, err := s.incidents.ClaimExpired(ctx)
+	if err != nil {
+		s.logger.Error("failed to claim expired incidents", logging.ErrAttr(err))
+		return err
+	}
+	s.enqueueIncidentEvents(ctx, domain.EventIncidentExpired, expired)
+	return nil
+}
+
+func (s *Service) enqueueIncidentEvents(ctx context.Context, eventType domain.EventType, incidents []domain.Incident) {
+	for i := range incidents {
+		s.enqueueIncidentEvent(ctx, eventType, incidents[i].ID, &incidents[i])
+	}
+}
+
+// enqueueIncidentEvent ставит в очередь событие incident.*. Для удаленного инцидента incident равен nil,
+// и событие содержит только его ID. Ошибка очереди только логируется: изменение инцидента уже записано
+func (s *Service) enqueueIncidentEvent(ctx context.Context, eventType domain.EventType, incidentID int, incident *domain.Incident) {
+	event := &domain.Event{
+		Type:       eventType,
+		IncidentID: incidentID,
+		Incident:   incident,
+	}
+	if incident != nil {
+		event.Severity = incident.Severity
+		event.Category = incident.Category
+	}
+
+	if err := s.queue.Enqueue(ctx, event); err != nil {
+		s.logger.Error("failed to enqueue webhook task",
+			logging.IntAttr("incidentID", incidentID),
+			logging.StringAttr("event", string(eventType)),
+			logging.ErrAttr(err),
+		)
+		return
+	}
+
+	s.logger.Info("webhook task enqueued",
+		logging.IntAttr("incidentID", incidentID),
+		logging.StringAttr("event", string(eventType)),
+	)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"red_collar/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_ProcessIncidentSchedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		incidents    func() *mockIncidentsRepository
+		wantErr      bool
+		wantEvents   []domain.Event
+		validateLogs func(t *testing.T, logger *mockLogger)
+	}{
+		{
+			name: "success",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					claimActivatedFunc: func(ctx context.Context) ([]domain.Incident, error) {
+						return []domain.Incident{{ID: 1, Title: "Road works"}}, nil
+					},
+					claimExpiredFunc: func(ctx context.Context) ([]domain.Incident, error) {
+						return []domain.Incident{{ID: 2, Title: "Demonstration"}}, nil
+					},
+				}
+			},
+			wantEvents: []domain.Event{
+				{Type: domain.EventIncidentActivated, IncidentID: 1},
+				{Type: domain.EventIncidentExpired, IncidentID: 2},
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				require.Len(t, logger.GetInfoLogs(), 2)
+				require.Empty(t, logger.GetErrorLogs())
+			},
+		},
+		{
+			name: "success - nothing to notify",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{}
+			},
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				require.Empty(t, logger.GetInfoLogs())
+				require.Empty(t, logger.GetErrorLogs())
+			},
+		},
+		{
+			name: "repository error",
+			incidents: func() *mockIncidentsRepository {
+				return &mockIncidentsRepository{
+					claimActivatedFunc: func(ctx context.Context) ([]domain.Incident, error) {
+						return nil, errors.New("failed database connection")
+					},
+				}
+			},
+			wantErr: true,
+			validateLogs: func(t *testing.T, logger *mockLogger) {
+				errorLogs := logger.GetErrorLogs()
+				require.Len(t, errorLogs, 1)
+				require.Equal(t, "failed to claim activated incidents", errorLogs[0].msg)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			mockLog := &mockLogger{}
+
+			var events []*domain.Event
+			service := &Service{
+				incidents:   tt.incidents(),
+				coordinates: &mockCoordinatesRepository{},
+				queue: &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						events = append(events, event)
+						return nil
+					},
+				},
+				logger: mockLog,
+			}
+
+			err := service.ProcessIncidentSchedule(ctx)
+
+			if tt.validateLogs != nil {
+				tt.validateLogs(t, mockLog)
+			}
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.Len(t, events, len(tt.wantEvents))
+			for i, event := range events {
+				require.Equal(t, tt.wantEvents[i].Type, event.Type)
+				require.Equal(t, tt.wantEvents[i].IncidentID, event.IncidentID)
+				require.NotNil(t, event.Incident)
+				require.Nil(t, event.LocationCheck)
+			}
+		})
+	}
+}
+
+func TestService_IncidentLifecycleEvents(t *testing.T) {
+	incidents := &mockIncidentsRepository{
+		createFunc: func(ctx context.Context, incident *domain.Incident) error {
+			incident.ID = 5
+			return nil
+		},
+		getByIDFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+			return &domain.Incident{ID: id, Title: "Road works", Lat: 55, Long: 37, Radius: 100}, nil
+		},
+		restoreFunc: func(ctx context.Context, id int) (*domain.Incident, error) {
+			return &domain.Incident{ID: id, Title: "Road works", Severity: domain.SeverityDanger}, nil
+		},
+	}
+
+	tests := []struct {
+		name      string
+		call      func(s *Service) error
+		wantEvent domain.Event
+		wantErr   bool
+	}{
+		{
+			name: "create",
+			call: func(s *Service) error {
+				_, err := s.CreateIncident(context.Background(), &CreateIncidentRequestInput{
+					Title: "Road works", Lat: 55, Long: 37, Radius: 100,
+				})
+				return err
+			},
+			wantEvent: domain.Event{Type: domain.EventIncidentCreated, IncidentID: 5, Severity: domain.SeverityWarning},
+		},
+		{
+			name: "full update",
+			call: func(s *Service) error {
+				_, err := s.FullUpdateIncident(context.Background(), &FullUpdateIncidentRequestInput{
+					ID: "5", Title: "Road works", Lat: 55, Long: 37, Radius: 100,
+				})
+				return err
+			},
+			wantEvent: domain.Event{Type: domain.EventIncidentUpdated, IncidentID: 5, Severity: domain.SeverityWarning},
+		},
+		{
+			name: "restore",
+			call: func(s *Service) error {
+				_, err := s.RestoreIncident(context.Background(), "5")
+				return err
+			},
+			wantEvent: domain.Event{Type: domain.EventIncidentUpdated, IncidentID: 5, Severity: domain.SeverityDanger},
+		},
+		{
+			name: "delete",
+			call: func(s *Service) error {
+				return s.DeleteIncident(context.Background(), "5")
+			},
+			wantEvent: domain.Event{Type: domain.EventIncidentDeleted, IncidentID: 5},
+		},
+		{
+			name: "validation error - nothing published",
+			call: func(s *Service) error {
+				return s.DeleteIncident(context.Background(), "abc")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var events []*domain.Event
+			service := &Service{
+				incidents:   incidents,
+				coordinates: &mockCoordinatesRepository{},
+				cache:       &mockCache{},
+				queue: &mockQueue{
+					enqueueFunc: func(ctx context.Context, event *domain.Event) error {
+						events = append(events, event)
+						return nil
+					},
+				},
+				logger: &mockLogger{},
+			}
+
+			err := tt.call(service)
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				require.Empty(t, events)
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.Len(t, events, 1)
+			require.Equal(t, tt.wantEvent.Type, events[0].Type)
+			require.Equal(t, tt.wantEvent.IncidentID, events[0].IncidentID)
+			require.Equal(t, tt.wantEvent.Severity, events[0].Severity)
+			if tt.wantEvent.Type == domain.EventIncidentDeleted {
+				require.Nil(t, events[0].Incident)
+			} else {
+				require.NotNil(t, events[0].Incident)
+			}
+		})
+	}
+}
@@ -1,11 +1,40 @@
 package service
 
+import "time"
+
+// Options - настраиваемые параметры бизнес-логики
+type Options struct {
+	// DwellThreshold - через сколько времени непрерывного пребывания в зоне
+	// отправляется событие zone.dwell. Нулевое значение отключает событие
+	DwellThreshold time.Duration
+	// WarningBufferM - ширина полосы предупреждения вокруг зоны в метрах
+	// для инцидентов без собственного значения. Нулевое значение отключает zone.approaching
+	WarningBufferM int
+	// MaxSegmentGap - до какого интервала между проверками искать зоны, пересеченные по пути
+	MaxSegmentGap time.Duration
+	// BatchMaxPoints - максимальное количество точек в одной пакетной проверке
+	BatchMaxPoints int
+	// Categories - допустимые категории инцидентов (кроме domain.DefaultCategory, она допустима всегда)
+	Categories []string
+	// PurgeRetentionDays - сколько дней архивный инцидент хранится до окончательного удаления
+	PurgeRetentionDays int
+	// ImportMaxIncidents/ExportMaxIncidents - ограничения на количество инцидентов в одном импорте и экспорте
+	ImportMaxIncidents int
+	ExportMaxIncidents int
+	// OccupantWindowMins - сколько минут последняя проверка пользователя считается актуальной
+	// для списка пользователей в зоне, если окно не задано в запросе
+	OccupantWindowMins int
+}
+
 type Service struct {
 	incidents   IncidentRepositoryInterface
 	coordinates CoordinatesRepositoryInterface
 	queue       QueueInterface
 	cache       CacheInterface
+	membership  MembershipInterface
+	webhooks    WebhookSubscriptionRepositoryInterface
 	logger      LoggerInterfaces
+	opts        Options
 }
 
 func NewService(
@@ -13,13 +42,19 @@ func NewService(
 	coordinates CoordinatesRepositoryInterface,
 	queue QueueInterface,
 	cache CacheInterface,
+	membership MembershipInterface,
+	webhooks WebhookSubscriptionRepositoryInterface,
 	logger LoggerInterfaces,
+	opts Options,
 ) *Service {
 	return &Service{
 		incidents:   incidents,
 		coordinates: coordinates,
 		queue:       queue,
 		cache:       cache,
+		membership:  membership,
+		webhooks:    webhooks,
 		logger:      logger,
+		opts:        opts,
 	}
 }
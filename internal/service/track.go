@@ -0,0 +1,97 @@
+package service
+
+import (
+	"red_collar/internal/domain"
+	"slices"
+	"time"
+)
+
+// UserTrackOutput - трек пользователя как GeoJSON FeatureCollection: линия через все точки страницы
+// в порядке времени и отдельная точка на каждую проверку с признаком попадания в опасные зоны
+type UserTrackOutput struct {
+	Type     string         `json:"type"`
+	Features []TrackFeature `json:"features"`
+	// NextCursor - курсор следующей страницы истории, отсутствует на последней странице
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type TrackFeature struct {
+	Type       string        `json:"type"`
+	Geometry   TrackGeometry `json:"geometry"`
+	Properties any           `json:"properties"`
+}
+
+type TrackGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates" swaggertype:"array,number"`
+}
+
+// TrackLineProperties - свойства линии трека
+type TrackLineProperties struct {
+	UserID string    `json:"user_id"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Points int       `json:"points"`
+}
+
+// TrackPointProperties - свойства точки трека. IncidentIDs - все зоны, в которые попала проверка
+type TrackPointProperties struct {
+	CheckID      int       `json:"check_id"`
+	CheckedAt    time.Time `json:"checked_at"`
+	InDangerZone bool      `json:"in_danger_zone"`
+	PrimaryID    *int      `json:"primary_id,omitempty"`
+	IncidentIDs  []int     `json:"incident_ids"`
+}
+
+// buildUserTrack собирает трек из страницы истории. Линия добавляется, только если точек хотя бы две
+func buildUserTrack(userID string, out *UserChecksOutput) *UserTrackOutput {
+	checks := slices.Clone(out.Checks)
+	slices.SortFunc(checks, func(a, b domain.LocationCheck) int {
+		if c := a.CheckedAt.Compare(b.CheckedAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+
+	track := &UserTrackOutput{
+		Type:       geoJSONFeatureCollectionType,
+		Features:   make([]TrackFeature, 0, len(checks)+1),
+		NextCursor: out.NextCursor,
+	}
+
+	if len(checks) >= 2 {
+		line := make([][2]float64, len(checks))
+		for i, check := range checks {
+			line[i] = [2]float64{check.Long, check.Lat}
+		}
+		track.Features = append(track.Features, TrackFeature{
+			Type:     geoJSONFeatureType,
+			Geometry: TrackGeometry{Type: "LineString", Coordinates: line},
+			Properties: TrackLineProperties{
+				UserID: userID,
+				From:   checks[0].CheckedAt,
+				To:     checks[len(checks)-1].CheckedAt,
+				Points: len(checks),
+			},
+		})
+	}
+
+	for _, check := range checks {
+		incidentIDs := make([]int, len(check.Matches))
+		for i, m := range check.Matches {
+			incidentIDs[i] = m.IncidentID
+		}
+		track.Features = append(track.Features, TrackFeature{
+			Type:     geoJSONFeatureType,
+			Geometry: TrackGeometry{Type: "Point", Coordinates: [2]float64{check.Long, check.Lat}},
+			Properties: TrackPointProperties{
+				CheckID:      check.ID,
+				CheckedAt:    check.CheckedAt,
+				InDangerZone: check.InDangerZone,
+				PrimaryID:    check.PrimaryID,
+				IncidentIDs:  incidentIDs,
+			},
+		})
+	}
+	return track
+}
@@ -1,21 +1,85 @@
 package service
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
 	"red_collar/internal/domain"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
 	defaultLimit = 5
 	maxLimit     = 50
+
+	maxSearchQueryLen = 200
+
+	// история проверок пользователя отдается крупнее, чтобы трек строился за несколько запросов
+	defaultCheckLimit = 100
+	maxCheckLimit     = 1000
+
+	// окно, в котором последняя проверка пользователя считается актуальной, не больше суток
+	maxOccupantWindowMins = 24 * 60
+
+	// ограничение длины временного ряда статистики: сутки по минуте
+	maxStatsBuckets = 1440
+
+	defaultHeatmapPeriod    = 24 * time.Hour
+	defaultGeohashPrecision = 6
+	maxGeohashPrecision     = 9
+	defaultHeatmapCellM     = 500
+	minHeatmapCellM         = 10
+	// ограничение числа ячеек сетки над областью, чтобы один запрос не строил миллионы ячеек
+	maxHeatmapCells = 10000
+
+	// секрет подписки короче 16 символов слишком легко подобрать
+	minWebhookSecretLen = 16
+	// по умолчанию получатели успевают сменить секрет за сутки, дольше недели старый секрет не живет
+	defaultSecretGracePeriod = 24 * time.Hour
+	maxSecretGracePeriod     = 7 * 24 * time.Hour
+
+	defaultDLQLimit = 50
+	maxDLQLimit     = 500
 )
 
-func validateCreateIncidentInput(in *CreateIncidentRequestInput) error {
+type statsBucket struct {
+	name string
+	size time.Duration
+}
+
+// statsBuckets - интервалы временного ряда статистики от мелкого к крупному
+var statsBuckets = []statsBucket{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+	{"1d", 24 * time.Hour},
+}
+
+func validateCreateIncidentInput(in *CreateIncidentRequestInput, categories []string) error {
 	if strings.TrimSpace(in.Title) == "" {
 		return domain.ErrInvalidValidation("title is required")
 	}
 
+	if err := validateSeverityAndCategory(in.Severity, in.Category, categories); err != nil {
+		return err
+	}
+
+	if err := validateWarningBuffer(in.WarningBuffer); err != nil {
+		return err
+	}
+
+	if err := validateSchedule(in.StartsAt, in.EndsAt); err != nil {
+		return err
+	}
+
+	if len(in.Boundary) > 0 {
+		return validateBoundary(in.Boundary)
+	}
+
 	if err := validateLatLong(in.Lat, in.Long); err != nil {
 		return err
 	}
@@ -26,7 +90,61 @@ func validateCreateIncidentInput(in *CreateIncidentRequestInput) error {
 	return nil
 }
 
-func validateFullUpdateIncidentInput(in *FullUpdateIncidentRequestInput) (int, error) {
+// validateImportInput разбирает файл импорта и флаг dry_run
+func validateImportInput(in *ImportIncidentsRequestInput, maxIncidents int) ([]importItem, bool, error) {
+	var dryRun bool
+	if in.DryRun != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(in.DryRun); err != nil {
+			return nil, false, domain.ErrInvalidValidation("invalid dry_run format, must be true or false")
+		}
+	}
+
+	codec, err := codecForFormat(in.Format)
+	if err != nil {
+		return nil, false, err
+	}
+
+	items, err := codec.decode(in.Data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(items) == 0 {
+		return nil, false, domain.ErrInvalidValidation("nothing to import")
+	}
+	if len(items) > maxIncidents {
+		return nil, false, domain.ErrInvalidValidation(fmt.Sprintf("too many incidents in one import, max %d", maxIncidents))
+	}
+	return items, dryRun, nil
+}
+
+// validateImportItems проверяет каждый элемент импорта по правилам создания инцидента.
+// Названия в одном импорте должны быть уникальны, так как по ним выполняется сопоставление
+func validateImportItems(items []importItem, categories []string) []ImportItemFailure {
+	var failures []ImportItemFailure
+	titles := make(map[string]int, len(items))
+	for i := range items {
+		in := &items[i].Input
+		err := items[i].Err
+		if err == nil {
+			err = validateCreateIncidentInput(in, categories)
+		}
+		if err == nil {
+			if first, ok := titles[in.Title]; ok {
+				err = domain.ErrInvalidValidation(fmt.Sprintf("duplicate title, already used by item %d", first))
+			} else {
+				titles[in.Title] = i
+			}
+		}
+		if err != nil {
+			failures = append(failures, ImportItemFailure{Index: i, Title: in.Title, Message: err.Error()})
+		}
+	}
+	return failures
+}
+
+func validateFullUpdateIncidentInput(in *FullUpdateIncidentRequestInput, categories []string) (int, error) {
 	id, err := validateID(in.ID)
 	if err != nil {
 		return 0, err
@@ -36,6 +154,22 @@ func validateFullUpdateIncidentInput(in *FullUpdateIncidentRequestInput) (int, e
 		return 0, domain.ErrInvalidValidation("title is required")
 	}
 
+	if err := validateSeverityAndCategory(in.Severity, in.Category, categories); err != nil {
+		return 0, err
+	}
+
+	if err := validateWarningBuffer(in.WarningBuffer); err != nil {
+		return 0, err
+	}
+
+	if err := validateSchedule(in.StartsAt, in.EndsAt); err != nil {
+		return 0, err
+	}
+
+	if len(in.Boundary) > 0 {
+		return id, validateBoundary(in.Boundary)
+	}
+
 	if err := validateLatLong(in.Lat, in.Long); err != nil {
 		return 0, err
 	}
@@ -58,6 +192,650 @@ func validateID(id string) (int, error) {
 	return idInt, nil
 }
 
+// validateSeverityAndCategory проверяет уровень опасности и категорию. Пустые значения допустимы:
+// для них подставляются значения по умолчанию
+func validateSeverityAndCategory(severity, category string, categories []string) error {
+	if severity != "" && !domain.Severity(severity).Valid() {
+		return domain.ErrInvalidValidation("severity must be one of info, warning, danger, critical")
+	}
+
+	if category != "" && !validCategory(category, categories) {
+		return domain.ErrInvalidValidation(fmt.Sprintf("unknown category %q", category))
+	}
+	return nil
+}
+
+func validCategory(category string, categories []string) bool {
+	return category == domain.DefaultCategory || slices.Contains(categories, category)
+}
+
+// validateIncidentFilter разбирает фильтры списка инцидентов
+func validateIncidentFilter(in *PaginateIncidentsRequestInput, categories []string) (domain.IncidentFilter, error) {
+	var filter domain.IncidentFilter
+
+	if in.Severity != "" {
+		for _, raw := range strings.Split(in.Severity, ",") {
+			severity := domain.Severity(strings.TrimSpace(raw))
+			if !severity.Valid() {
+				return filter, domain.ErrInvalidValidation("severity must be one of info, warning, danger, critical")
+			}
+			filter.Severities = append(filter.Severities, severity)
+		}
+	}
+
+	if in.Category != "" {
+		if !validCategory(in.Category, categories) {
+			return filter, domain.ErrInvalidValidation(fmt.Sprintf("unknown category %q", in.Category))
+		}
+		filter.Category = in.Category
+	}
+
+	if in.Active != "" {
+		active, err := strconv.ParseBool(in.Active)
+		if err != nil {
+			return filter, domain.ErrInvalidValidation("active must be true or false")
+		}
+		filter.Active = &active
+	}
+
+	dates := []struct {
+		name  string
+		raw   string
+		value **time.Time
+	}{
+		{"created_from", in.CreatedFrom, &filter.CreatedFrom},
+		{"created_to", in.CreatedTo, &filter.CreatedTo},
+		{"updated_from", in.UpdatedFrom, &filter.UpdatedFrom},
+		{"updated_to", in.UpdatedTo, &filter.UpdatedTo},
+	}
+	for _, date := range dates {
+		if date.raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, date.raw)
+		if err != nil {
+			return filter, domain.ErrInvalidValidation(fmt.Sprintf("%s must be RFC3339 timestamp", date.name))
+		}
+		t = t.UTC()
+		*date.value = &t
+	}
+
+	filter.Query = strings.TrimSpace(in.Query)
+	if len(filter.Query) > maxSearchQueryLen {
+		return filter, domain.ErrInvalidValidation(fmt.Sprintf("q must be at most %d characters", maxSearchQueryLen))
+	}
+
+	if in.BBox != "" {
+		bbox, err := parseBBox(in.BBox)
+		if err != nil {
+			return filter, err
+		}
+		filter.BBox = bbox
+	}
+
+	if in.Lat != "" || in.Long != "" || in.Radius != "" {
+		near, err := parseNearPoint(in.Lat, in.Long, in.Radius)
+		if err != nil {
+			return filter, err
+		}
+		filter.Near = near
+	}
+
+	if in.Sort != "" {
+		sort := domain.IncidentSort(in.Sort)
+		switch sort {
+		case domain.IncidentSortCreatedAt, domain.IncidentSortUpdatedAt, domain.IncidentSortTitle,
+			domain.IncidentSortActive, domain.IncidentSortSeverity, domain.IncidentSortCategory:
+		case domain.IncidentSortDistance:
+			if filter.Near == nil {
+				return filter, domain.ErrInvalidValidation("sort by distance requires lat, long and radius_m")
+			}
+		case domain.IncidentSortRelevance:
+			if filter.Query == "" {
+				return filter, domain.ErrInvalidValidation("sort by relevance requires q")
+			}
+		default:
+			return filter, domain.ErrInvalidValidation(fmt.Sprintf("unknown sort field %q", in.Sort))
+		}
+		filter.Sort = sort
+	}
+
+	switch in.Deleted {
+	case "", "exclude":
+	case "include":
+		filter.Deleted = domain.DeletedInclude
+	case "only":
+		filter.Deleted = domain.DeletedOnly
+	default:
+		return filter, domain.ErrInvalidValidation("deleted must be exclude, include or only")
+	}
+
+	switch in.Order {
+	case "", "desc":
+	case "asc":
+		filter.SortAsc = true
+	default:
+		return filter, domain.ErrInvalidValidation("order must be asc or desc")
+	}
+	return filter, nil
+}
+
+func parseBBox(raw string) (*domain.BBox, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return nil, domain.ErrInvalidValidation("bbox must be minLon,minLat,maxLon,maxLat")
+	}
+
+	var values [4]float64
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, domain.ErrInvalidValidation("bbox must be minLon,minLat,maxLon,maxLat")
+		}
+		values[i] = value
+	}
+
+	bbox := &domain.BBox{MinLong: values[0], MinLat: values[1], MaxLong: values[2], MaxLat: values[3]}
+	if err := validateBBox(bbox); err != nil {
+		return nil, err
+	}
+	return bbox, nil
+}
+
+func validateBBox(bbox *domain.BBox) error {
+	if err := validateLatLong(bbox.MinLat, bbox.MinLong); err != nil {
+		return domain.ErrInvalidValidation("bbox: " + err.Error())
+	}
+	if err := validateLatLong(bbox.MaxLat, bbox.MaxLong); err != nil {
+		return domain.ErrInvalidValidation("bbox: " + err.Error())
+	}
+	if bbox.MinLong >= bbox.MaxLong || bbox.MinLat >= bbox.MaxLat {
+		return domain.ErrInvalidValidation("bbox min values must be less than max values")
+	}
+	return nil
+}
+
+func parseNearPoint(rawLat, rawLong, rawRadius string) (*domain.NearPoint, error) {
+	if rawLat == "" || rawLong == "" || rawRadius == "" {
+		return nil, domain.ErrInvalidValidation("lat, long and radius_m must be set together")
+	}
+
+	lat, err := strconv.ParseFloat(rawLat, 64)
+	if err != nil {
+		return nil, domain.ErrInvalidValidation("invalid lat format, must be number")
+	}
+
+	long, err := strconv.ParseFloat(rawLong, 64)
+	if err != nil {
+		return nil, domain.ErrInvalidValidation("invalid long format, must be number")
+	}
+
+	if err := validateLatLong(lat, long); err != nil {
+		return nil, err
+	}
+
+	radius, err := strconv.Atoi(rawRadius)
+	if err != nil || radius < 0 {
+		return nil, domain.ErrInvalidValidation("radius_m must be non-negative integer")
+	}
+	return &domain.NearPoint{Lat: lat, Long: long, RadiusM: radius}, nil
+}
+
+func validateIncidentAsOf(rawID, rawAt string) (int, time.Time, error) {
+	id, err := validateID(rawID)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	at, err := time.Parse(time.RFC3339, rawAt)
+	if err != nil {
+		return 0, time.Time{}, domain.ErrInvalidValidation("as_of must be RFC3339 timestamp")
+	}
+	return id, at.UTC(), nil
+}
+
+func validatePatchIncidentInput(in *PatchIncidentRequestInput) (int, error) {
+	id, err := validateID(in.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(in.Patch, &patch); err != nil || patch == nil {
+		return 0, domain.ErrInvalidRequest("merge patch must be a JSON object")
+	}
+	return id, nil
+}
+
+// validateUserChecksInput разбирает фильтры и параметры курсорной пагинации истории проверок пользователя
+func validateUserChecksInput(in *UserChecksRequestInput) (domain.CheckFilter, int, error) {
+	filter := domain.CheckFilter{UserID: in.UserID}
+	if strings.TrimSpace(in.UserID) == "" {
+		return filter, 0, domain.ErrInvalidValidation("user_id is required")
+	}
+
+	limit := defaultCheckLimit
+	if in.Limit != "" {
+		var err error
+		if limit, err = strconv.Atoi(in.Limit); err != nil {
+			return filter, 0, domain.ErrInvalidValidation("invalid limit format, must be integer")
+		}
+		limit = min(max(limit, 1), maxCheckLimit)
+	}
+
+	dates := []struct {
+		name  string
+		raw   string
+		value **time.Time
+	}{
+		{"from", in.From, &filter.From},
+		{"to", in.To, &filter.To},
+	}
+	for _, date := range dates {
+		if date.raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, date.raw)
+		if err != nil {
+			return filter, 0, domain.ErrInvalidValidation(fmt.Sprintf("%s must be RFC3339 timestamp", date.name))
+		}
+		t = t.UTC()
+		*date.value = &t
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, 0, domain.ErrInvalidValidation("from must be before to")
+	}
+
+	switch in.Order {
+	case "", "desc":
+	case "asc":
+		filter.SortAsc = true
+	default:
+		return filter, 0, domain.ErrInvalidValidation("order must be asc or desc")
+	}
+
+	if in.Cursor != "" {
+		after, err := decodeCheckCursor(in.Cursor, filter.SortAsc)
+		if err != nil {
+			return filter, 0, err
+		}
+		filter.After = after
+	}
+	return filter, limit, nil
+}
+
+// validateStatsSeriesInput разбирает период, интервал и зону временного ряда статистики.
+// Начало периода выравнивается по границе интервала (в UTC), чтобы интервалы совпадали между запросами
+func validateStatsSeriesInput(in *StatsSeriesRequestInput, now time.Time) (domain.StatsFilter, string, error) {
+	filter := domain.StatsFilter{To: now.UTC()}
+	if in.To != "" {
+		to, err := time.Parse(time.RFC3339, in.To)
+		if err != nil {
+			return filter, "", domain.ErrInvalidValidation("to must be RFC3339 timestamp")
+		}
+		filter.To = to.UTC()
+	}
+
+	filter.From = filter.To.Add(-time.Duration(in.WindowMinutes) * time.Minute)
+	if in.From != "" {
+		from, err := time.Parse(time.RFC3339, in.From)
+		if err != nil {
+			return filter, "", domain.ErrInvalidValidation("from must be RFC3339 timestamp")
+		}
+		filter.From = from.UTC()
+	}
+	if !filter.From.Before(filter.To) {
+		return filter, "", domain.ErrInvalidValidation("from must be before to")
+	}
+
+	if in.IncidentID != "" {
+		id, err := strconv.Atoi(in.IncidentID)
+		if err != nil {
+			return filter, "", domain.ErrInvalidValidation("invalid incident_id format, must be integer")
+		}
+		filter.IncidentID = &id
+	}
+
+	bucketCount := func(size time.Duration) int {
+		span := filter.To.Sub(filter.From.Truncate(size))
+		return int((span + size - 1) / size)
+	}
+
+	bucket := in.Bucket
+	if bucket == "" {
+		// самый мелкий интервал, при котором ряд укладывается в ограничение
+		bucket = statsBuckets[len(statsBuckets)-1].name
+		for _, b := range statsBuckets {
+			if bucketCount(b.size) <= maxStatsBuckets {
+				bucket = b.name
+				break
+			}
+		}
+	}
+
+	i := slices.IndexFunc(statsBuckets, func(b statsBucket) bool { return b.name == bucket })
+	if i < 0 {
+		return filter, "", domain.ErrInvalidValidation("bucket must be 1m, 5m, 1h or 1d")
+	}
+	filter.Bucket = statsBuckets[i].size
+
+	if count := bucketCount(filter.Bucket); count > maxStatsBuckets {
+		return filter, "", domain.ErrInvalidValidation(fmt.Sprintf("too many buckets (%d, max %d), use a larger bucket or a shorter period", count, maxStatsBuckets))
+	}
+	filter.From = filter.From.Truncate(filter.Bucket)
+	return filter, bucket, nil
+}
+
+// validateHeatmapInput разбирает область, период и сетку тепловой карты и проверяет,
+// что сетка над областью не слишком мелкая
+func validateHeatmapInput(in *HeatmapRequestInput, now time.Time) (domain.HeatmapFilter, error) {
+	filter := domain.HeatmapFilter{
+		To:   now.UTC(),
+		Grid: domain.HeatmapGrid(in.Grid),
+	}
+
+	if in.BBox == "" {
+		return filter, domain.ErrInvalidValidation("bbox is required")
+	}
+	bbox, err := parseBBox(in.BBox)
+	if err != nil {
+		return filter, err
+	}
+	filter.BBox = *bbox
+
+	if in.To != "" {
+		to, err := time.Parse(time.RFC3339, in.To)
+		if err != nil {
+			return filter, domain.ErrInvalidValidation("to must be RFC3339 timestamp")
+		}
+		filter.To = to.UTC()
+	}
+	filter.From = filter.To.Add(-defaultHeatmapPeriod)
+	if in.From != "" {
+		from, err := time.Parse(time.RFC3339, in.From)
+		if err != nil {
+			return filter, domain.ErrInvalidValidation("from must be RFC3339 timestamp")
+		}
+		filter.From = from.UTC()
+	}
+	if !filter.From.Before(filter.To) {
+		return filter, domain.ErrInvalidValidation("from must be before to")
+	}
+
+	if in.InDanger != "" {
+		if filter.InDangerOnly, err = strconv.ParseBool(in.InDanger); err != nil {
+			return filter, domain.ErrInvalidValidation("invalid in_danger format, must be true or false")
+		}
+	}
+
+	switch filter.Grid {
+	case "", domain.HeatmapGridGeohash:
+		filter.Grid = domain.HeatmapGridGeohash
+		if in.SizeM != "" {
+			return filter, domain.ErrInvalidValidation("size_m is only for square and hex grids")
+		}
+		filter.Precision = defaultGeohashPrecision
+		if in.Precision != "" {
+			if filter.Precision, err = strconv.Atoi(in.Precision); err != nil {
+				return filter, domain.ErrInvalidValidation("invalid precision format, must be integer")
+			}
+			if filter.Precision < 1 || filter.Precision > maxGeohashPrecision {
+				return filter, domain.ErrInvalidValidation(fmt.Sprintf("precision must be between 1 and %d", maxGeohashPrecision))
+			}
+		}
+	case domain.HeatmapGridSquare, domain.HeatmapGridHex:
+		if in.Precision != "" {
+			return filter, domain.ErrInvalidValidation("precision is only for geohash grid")
+		}
+		if math.Abs(filter.BBox.MinLat) > maxMercatorLat || math.Abs(filter.BBox.MaxLat) > maxMercatorLat {
+			return filter, domain.ErrInvalidValidation(fmt.Sprintf("bbox latitude must be within ±%g for square and hex grids", maxMercatorLat))
+		}
+		filter.SizeM = defaultHeatmapCellM
+		if in.SizeM != "" {
+			if filter.SizeM, err = strconv.Atoi(in.SizeM); err != nil {
+				return filter, domain.ErrInvalidValidation("invalid size_m format, must be integer")
+			}
+			if filter.SizeM < minHeatmapCellM {
+				return filter, domain.ErrInvalidValidation(fmt.Sprintf("size_m must be at least %d meters", minHeatmapCellM))
+			}
+		}
+	default:
+		return filter, domain.ErrInvalidValidation("grid must be geohash, square or hex")
+	}
+
+	if cells := estimateHeatmapCells(filter); cells > maxHeatmapCells {
+		return filter, domain.ErrInvalidValidation(fmt.Sprintf("grid is too fine for bbox (about %d cells, max %d), use a larger cell or a smaller bbox", cells, maxHeatmapCells))
+	}
+	return filter, nil
+}
+
+// validateOccupantsInput разбирает ID инцидента и окно в минутах. Пустое окно заменяется defaultWindow
+func validateOccupantsInput(in *IncidentOccupantsRequestInput, defaultWindow int) (int, int, error) {
+	id, err := validateID(in.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if in.Minutes == "" {
+		return id, defaultWindow, nil
+	}
+	window, err := strconv.Atoi(in.Minutes)
+	if err != nil {
+		return 0, 0, domain.ErrInvalidValidation("invalid minutes format, must be integer")
+	}
+	if window < 1 || window > maxOccupantWindowMins {
+		return 0, 0, domain.ErrInvalidValidation(fmt.Sprintf("minutes must be between 1 and %d", maxOccupantWindowMins))
+	}
+	return id, window, nil
+}
+
+// validateWebhookSubscriptionInput проверяет адрес и фильтры подписки. Секрет необязателен:
+// при создании пустой секрет генерируется, при обновлении - остается прежним
+func validateWebhookSubscriptionInput(in *WebhookSubscriptionRequestInput) (*domain.WebhookSubscription, error) {
+	target, err := url.Parse(in.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, domain.ErrInvalidValidation("url must be an absolute http or https URL")
+	}
+
+	if in.Secret != "" && len(in.Secret) < minWebhookSecretLen {
+		return nil, domain.ErrInvalidValidation(fmt.Sprintf("secret must be at least %d characters", minWebhookSecretLen))
+	}
+
+	sub := &domain.WebhookSubscription{
+		URL:         in.URL,
+		Secret:      in.Secret,
+		Enabled:     true,
+		EventTypes:  make([]domain.EventType, len(in.EventTypes)),
+		IncidentIDs: in.IncidentIDs,
+		Severities:  make([]domain.Severity, len(in.Severities)),
+	}
+	if in.Enabled != nil {
+		sub.Enabled = *in.Enabled
+	}
+	if sub.IncidentIDs == nil {
+		sub.IncidentIDs = []int{}
+	}
+
+	for i, raw := range in.EventTypes {
+		eventType := domain.EventType(raw)
+		if !eventType.Valid() {
+			return nil, domain.ErrInvalidValidation(fmt.Sprintf("event_types[%d]: unknown event type %q", i, raw))
+		}
+		sub.EventTypes[i] = eventType
+	}
+	for i, id := range in.IncidentIDs {
+		if id <= 0 {
+			return nil, domain.ErrInvalidValidation(fmt.Sprintf("incident_ids[%d]: id must be positive", i))
+		}
+	}
+	for i, raw := range in.Severities {
+		severity := domain.Severity(raw)
+		if !severity.Valid() {
+			return nil, domain.ErrInvalidValidation(fmt.Sprintf("severities[%d]: severity must be info, warning, danger or critical", i))
+		}
+		sub.Severities[i] = severity
+	}
+
+	if len(in.BBox) > 0 {
+		if len(in.BBox) != 4 {
+			return nil, domain.ErrInvalidValidation("bbox must be [minLon, minLat, maxLon, maxLat]")
+		}
+		sub.BBox = &domain.BBox{MinLong: in.BBox[0], MinLat: in.BBox[1], MaxLong: in.BBox[2], MaxLat: in.BBox[3]}
+		if err := validateBBox(sub.BBox); err != nil {
+			return nil, err
+		}
+	}
+	return sub, nil
+}
+
+// validateRotateSecretInput разбирает ID подписки, новый секрет и срок действия прежнего секрета
+func validateRotateSecretInput(in *RotateWebhookSecretRequestInput) (int, time.Duration, error) {
+	id, err := validateID(in.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if in.Secret != "" && len(in.Secret) < minWebhookSecretLen {
+		return 0, 0, domain.ErrInvalidValidation(fmt.Sprintf("secret must be at least %d characters", minWebhookSecretLen))
+	}
+
+	if in.GracePeriodSecs == nil {
+		return id, defaultSecretGracePeriod, nil
+	}
+	grace := time.Duration(*in.GracePeriodSecs) * time.Second
+	if grace < 0 || grace > maxSecretGracePeriod {
+		return 0, 0, domain.ErrInvalidValidation(fmt.Sprintf("grace_period_seconds must be between 0 and %d", int(maxSecretGracePeriod.Seconds())))
+	}
+	return id, grace, nil
+}
+
+// validateDeadLettersInput разбирает фильтры и страницу списка DLQ
+func validateDeadLettersInput(in *DeadLettersRequestInput) (domain.DeadLetterFilter, int, int, error) {
+	var filter domain.DeadLetterFilter
+	if in.SubscriptionID != "" {
+		id, err := strconv.Atoi(in.SubscriptionID)
+		if err != nil || id < 0 {
+			return filter, 0, 0, domain.ErrInvalidValidation("subscription_id must be a non-negative integer")
+		}
+		filter.SubscriptionID = &id
+	}
+	if in.IncidentID != "" {
+		id, err := validateID(in.IncidentID)
+		if err != nil {
+			return filter, 0, 0, err
+		}
+		filter.IncidentID = &id
+	}
+	if in.EventType != "" {
+		filter.EventType = domain.EventType(in.EventType)
+		if !filter.EventType.Valid() {
+			return filter, 0, 0, domain.ErrInvalidValidation(fmt.Sprintf("unknown event type %q", in.EventType))
+		}
+	}
+
+	limit := defaultDLQLimit
+	if in.Limit != "" {
+		var err error
+		if limit, err = strconv.Atoi(in.Limit); err != nil {
+			return filter, 0, 0, domain.ErrInvalidValidation("invalid limit format, must be integer")
+		}
+		limit = min(max(limit, 1), maxDLQLimit)
+	}
+
+	offset := 0
+	if in.Offset != "" {
+		var err error
+		if offset, err = strconv.Atoi(in.Offset); err != nil || offset < 0 {
+			return filter, 0, 0, domain.ErrInvalidValidation("offset must be a non-negative integer")
+		}
+	}
+	return filter, offset, limit, nil
+}
+
+// validateReplayDeadLettersInput собирает фильтр повтора. Пустой фильтр допустим только с All
+func validateReplayDeadLettersInput(in *ReplayDeadLettersRequestInput) (domain.DeadLetterFilter, error) {
+	filter := domain.DeadLetterFilter{
+		SubscriptionID: in.SubscriptionID,
+		IncidentID:     in.IncidentID,
+		EventType:      domain.EventType(in.EventType),
+	}
+	if filter.SubscriptionID != nil && *filter.SubscriptionID < 0 {
+		return filter, domain.ErrInvalidValidation("subscription_id must not be negative")
+	}
+	if filter.IncidentID != nil && *filter.IncidentID <= 0 {
+		return filter, domain.ErrInvalidValidation("incident_id must be positive")
+	}
+	if filter.EventType != "" && !filter.EventType.Valid() {
+		return filter, domain.ErrInvalidValidation(fmt.Sprintf("unknown event type %q", in.EventType))
+	}
+
+	empty := filter.SubscriptionID == nil && filter.IncidentID == nil && filter.EventType == ""
+	if empty && !in.All {
+		return filter, domain.ErrInvalidValidation("set subscription_id, event_type or incident_id, or all=true to replay the whole DLQ")
+	}
+	if !empty && in.All {
+		return filter, domain.ErrInvalidValidation("all can not be combined with filters")
+	}
+	return filter, nil
+}
+
+func validateDeleteDeadLettersInput(in *DeleteDeadLettersRequestInput) (domain.DeadLetterFilter, error) {
+	if in.Before == "" {
+		return domain.DeadLetterFilter{}, domain.ErrInvalidValidation("before is required")
+	}
+	before, err := time.Parse(time.RFC3339, in.Before)
+	if err != nil {
+		return domain.DeadLetterFilter{}, domain.ErrInvalidValidation("before must be RFC3339 timestamp")
+	}
+	return domain.DeadLetterFilter{FailedBefore: &before}, nil
+}
+
+func validateWarningBuffer(buffer *int) error {
+	if buffer != nil && *buffer < 0 {
+		return domain.ErrInvalidValidation("warning_buffer_m must not be negative")
+	}
+	return nil
+}
+
+// validateCheckBatchInput возвращает время каждой точки в UTC: переданное или now
+func validateCheckBatchInput(in *CheckCoordinatesBatchRequestInput, maxPoints int, now time.Time) ([]time.Time, error) {
+	if len(in.Points) == 0 {
+		return nil, domain.ErrInvalidValidation("points are required")
+	}
+
+	if maxPoints > 0 && len(in.Points) > maxPoints {
+		return nil, domain.ErrInvalidValidation(fmt.Sprintf("too many points, max %d", maxPoints))
+	}
+
+	checkedAts := make([]time.Time, len(in.Points))
+	for i, point := range in.Points {
+		if err := validateLatLong(point.Lat, point.Long); err != nil {
+			return nil, domain.ErrInvalidValidation(fmt.Sprintf("points[%d]: %s", i, err.Error()))
+		}
+
+		checkedAts[i] = now.UTC()
+		if point.CheckedAt == "" {
+			continue
+		}
+		checkedAt, err := time.Parse(time.RFC3339, point.CheckedAt)
+		if err != nil {
+			return nil, domain.ErrInvalidValidation(fmt.Sprintf("points[%d]: checked_at must be RFC3339 timestamp", i))
+		}
+		if checkedAt.After(now) {
+			return nil, domain.ErrInvalidValidation(fmt.Sprintf("points[%d]: checked_at must not be in the future", i))
+		}
+		checkedAts[i] = checkedAt.UTC()
+	}
+	return checkedAts, nil
+}
+
+func validateSchedule(startsAt, endsAt *time.Time) error {
+	if startsAt != nil && endsAt != nil && !startsAt.Before(*endsAt) {
+		return domain.ErrInvalidValidation("starts_at must be before ends_at")
+	}
+	return nil
+}
+
 func validateLatLong(lat, long float64) error {
 	if lat < -90 || lat > 90 || long < -180 || long > 180 {
 		return domain.ErrInvalidValidation("lat or long is invalid")
@@ -65,10 +843,94 @@ func validateLatLong(lat, long float64) error {
 	return nil
 }
 
+// validateBoundary проверяет GeoJSON-геометрию зоны: допускаются только
+// Polygon и MultiPolygon с замкнутыми кольцами из валидных координат
+func validateBoundary(raw json.RawMessage) error {
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(raw, &geometry); err != nil {
+		return domain.ErrInvalidValidation("boundary must be a GeoJSON geometry")
+	}
+
+	var polygons [][][][]float64
+	switch geometry.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil {
+			return domain.ErrInvalidValidation("invalid polygon coordinates")
+		}
+		polygons = append(polygons, polygon)
+	case "MultiPolygon":
+		if err := json.Unmarshal(geometry.Coordinates, &polygons); err != nil {
+			return domain.ErrInvalidValidation("invalid multipolygon coordinates")
+		}
+	default:
+		return domain.ErrInvalidValidation("boundary type must be Polygon or MultiPolygon")
+	}
+
+	if len(polygons) == 0 {
+		return domain.ErrInvalidValidation("boundary must contain at least one polygon")
+	}
+
+	for _, polygon := range polygons {
+		if len(polygon) == 0 {
+			return domain.ErrInvalidValidation("polygon must contain at least one ring")
+		}
+		for _, ring := range polygon {
+			if err := validateRing(ring); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateRing(ring [][]float64) error {
+	if len(ring) < 4 {
+		return domain.ErrInvalidValidation("polygon ring must contain at least 4 positions")
+	}
+
+	for _, position := range ring {
+		if len(position) < 2 {
+			return domain.ErrInvalidValidation("position must contain long and lat")
+		}
+		if err := validateLatLong(position[1], position[0]); err != nil {
+			return err
+		}
+	}
+
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		return domain.ErrInvalidValidation("polygon ring must be closed")
+	}
+	return nil
+}
+
 func validatePaginate(rawLimit, rawPage string) (int, int, int, error) {
+	limit, err := validateLimit(rawLimit)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	page, err := strconv.Atoi(rawPage)
+	if err != nil {
+		return 0, 0, 0, domain.ErrInvalidValidation("invalid page format, must be integer")
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	offset := (page - 1) * limit
+	return offset, limit, page, nil
+}
+
+func validateLimit(rawLimit string) (int, error) {
 	limit, err := strconv.Atoi(rawLimit)
 	if err != nil {
-		return 0, 0, 0, domain.ErrInvalidValidation("invalid limit format, must be integer")
+		return 0, domain.ErrInvalidValidation("invalid limit format, must be integer")
 	}
 
 	if limit < defaultLimit {
@@ -78,16 +940,26 @@ func validatePaginate(rawLimit, rawPage string) (int, int, int, error) {
 	if limit > maxLimit {
 		limit = maxLimit
 	}
+	return limit, nil
+}
 
-	page, err := strconv.Atoi(rawPage)
+// validateCursorPaginate проверяет параметры курсорного режима и дополняет фильтр ключом курсора
+func validateCursorPaginate(rawLimit, cursor string, filter *domain.IncidentFilter) (int, error) {
+	limit, err := validateLimit(rawLimit)
 	if err != nil {
-		return 0, 0, 0, domain.ErrInvalidValidation("invalid page format, must be integer")
+		return 0, err
 	}
 
-	if page < 1 {
-		page = 1
+	if filter.Sort != "" && filter.Sort != domain.IncidentSortCreatedAt {
+		return 0, domain.ErrInvalidValidation("cursor pagination supports only sort by created_at")
 	}
 
-	offset := (page - 1) * limit
-	return offset, limit, page, nil
+	if cursor != "" {
+		after, err := decodeIncidentCursor(cursor, filter.SortAsc)
+		if err != nil {
+			return 0, err
+		}
+		filter.After = after
+	}
+	return limit, nil
 }
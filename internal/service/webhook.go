@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"red_collar/internal/domain"
+
+	"github.com/theartofdevel/logging"
+)
+
+// webhookSecretBytes - длина генерируемого секрета подписки до кодирования в hex
+const webhookSecretBytes = 32
+
+// CreateWebhookSubscription создает подписку. Если секрет не передан, он генерируется;
+// секрет возвращается только в ответах на создание и ротацию
+func (s *Service) CreateWebhookSubscription(ctx context.Context, in *WebhookSubscriptionRequestInput) (*domain.WebhookSubscription, error) {
+	sub, err := validateWebhookSubscriptionInput(in)
+	if err != nil {
+		s.logger.Error("create webhook subscription validation failed",
+			logging.StringAttr("url", in.URL),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	if sub.Secret == "" {
+		if sub.Secret, err = generateWebhookSecret(); err != nil {
+			s.logger.Error("failed to generate webhook secret", logging.ErrAttr(err))
+			return nil, err
+		}
+	}
+
+	s.logger.Info("attempt to create webhook subscription",
+		logging.StringAttr("url", sub.URL),
+	)
+
+	if err := s.webhooks.Create(ctx, sub); err != nil {
+		s.logger.Error("create webhook subscription repository error",
+			logging.StringAttr("url", sub.URL),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("webhook subscription was successfully created",
+		logging.IntAttr("id", sub.ID),
+	)
+	return sub, nil
+}
+
+func (s *Service) GetWebhookSubscription(ctx context.Context, rawID string) (*domain.WebhookSubscription, error) {
+	id, err := validateID(rawID)
+	if err != nil {
+		s.logger.Error("get webhook subscription validation failed",
+			logging.StringAttr("id", rawID),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("attempt to get webhook subscription",
+		logging.IntAttr("id", id),
+	)
+
+	sub, err := s.webhooks.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("get webhook subscription repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+	sub.Secret = ""
+
+	s.logger.Info("webhook subscription was successfully got",
+		logging.IntAttr("id", id),
+	)
+	return sub, nil
+}
+
+func (s *Service) ListWebhookSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	s.logger.Info("attempt to list webhook subscriptions")
+
+	subs, err := s.webhooks.List(ctx, false)
+	if err != nil {
+		s.logger.Error("list webhook subscriptions repository error",
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+
+	s.logger.Info("webhook subscriptions were successfully listed",
+		logging.IntAttr("count", len(subs)),
+	)
+	return subs, nil
+}
+
+// UpdateWebhookSubscription заменяет адрес, флаг и фильтры подписки. Пустой секрет оставляет прежний
+func (s *Service) UpdateWebhookSubscription(ctx context.Context, in *WebhookSubscriptionRequestInput) (*domain.WebhookSubscription, error) {
+	id, err := validateID(in.ID)
+	if err != nil {
+		s.logger.Error("update webhook subscription validation failed",
+			logging.StringAttr("id", in.ID),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	sub, err := validateWebhookSubscriptionInput(in)
+	if err != nil {
+		s.logger.Error("update webhook subscription validation failed",
+			logging.IntAttr("id", id),
+			logging.StringAttr("url", in.URL),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+	sub.ID = id
+
+	s.logger.Info("attempt to update webhook subscription",
+		logging.IntAttr("id", id),
+	)
+
+	if err := s.webhooks.Update(ctx, sub); err != nil {
+		s.logger.Error("update webhook subscription repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+	sub.Secret = ""
+
+	s.logger.Info("webhook subscription was successfully updated",
+		logging.IntAttr("id", id),
+	)
+	return sub, nil
+}
+
+func (s *Service) DeleteWebhookSubscription(ctx context.Context, rawID string) error {
+	id, err := validateID(rawID)
+	if err != nil {
+		s.logger.Error("delete webhook subscription validation failed",
+			logging.StringAttr("id", rawID),
+			logging.ErrAttr(err),
+		)
+		return err
+	}
+
+	s.logger.Info("attempt to delete webhook subscription",
+		logging.IntAttr("id", id),
+	)
+
+	if err := s.webhooks.Delete(ctx, id); err != nil {
+		s.logger.Error("delete webhook subscription repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return err
+	}
+
+	s.logger.Info("webhook subscription was successfully deleted",
+		logging.IntAttr("id", id),
+	)
+	return nil
+}
+
+// RotateWebhookSecret заменяет секрет подписки. Прежний секрет действует еще GracePeriodSecs
+// (по умолчанию сутки), и все это время запросы подписываются обоими секретами
+func (s *Service) RotateWebhookSecret(ctx context.Context, in *RotateWebhookSecretRequestInput) (*domain.WebhookSubscription, error) {
+	id, grace, err := validateRotateSecretInput(in)
+	if err != nil {
+		s.logger.Error("rotate webhook secret validation failed",
+			logging.StringAttr("id", in.ID),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	secret := in.Secret
+	if secret == "" {
+		if secret, err = generateWebhookSecret(); err != nil {
+			s.logger.Error("failed to generate webhook secret", logging.ErrAttr(err))
+			return nil, err
+		}
+	}
+
+	s.logger.Info("attempt to rotate webhook secret",
+		logging.IntAttr("id", id),
+		logging.StringAttr("grace", grace.String()),
+	)
+
+	sub, err := s.webhooks.RotateSecret(ctx, id, secret, grace)
+	if err != nil {
+		s.logger.Error("rotate webhook secret repository error",
+			logging.IntAttr("id", id),
+			logging.ErrAttr(err),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("webhook secret was successfully rotated",
+		logging.IntAttr("id", id),
+	)
+	return sub, nil
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"red_collar/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CreateWebhookSubscription(t *testing.T) {
+	disabled := false
+
+	tests := []struct {
+		name           string
+		in             WebhookSubscriptionRequestInput
+		webhooks       func() *mockWebhookSubscriptions
+		wantErr        bool
+		validateResult func(t *testing.T, result *domain.WebhookSubscription)
+	}{
+		{
+			name:     "validation error - relative url",
+			in:       WebhookSubscriptionRequestInput{URL: "/webhook"},
+			webhooks: func() *mockWebhookSubscriptions { return &mockWebhookSubscriptions{} },
+			wantErr:  true,
+		},
+		{
+			name:     "validation error - unsupported scheme",
+			in:       WebhookSubscriptionRequestInput{URL: "ftp://example.com/webhook"},
+			webhooks: func() *mockWebhookSubscriptions { return &mockWebhookSubscriptions{} },
+			wantErr:  true,
+		},
+		{
+			name:     "validation error - short secret",
+			in:       WebhookSubscriptionRequestInput{URL: "https://example.com/webhook", Secret: "short"},
+			webhooks: func() *mockWebhookSubscriptions { return &mockWebhookSubscriptions{} },
+			wantErr:  true,
+		},
+		{
+			name:     "validation error - unknown event type",
+			in:       WebhookSubscriptionRequestInput{URL: "https://example.com/webhook", EventTypes: []string{"zone.left"}},
+			webhooks: func() *mockWebhookSubscriptions { return &mockWebhookSubscriptions{} },
+			wantErr:  true,
+		},
+		{
+			name:     "validation error - invalid incident id",
+			in:       WebhookSubscriptionRequestInput{URL: "https://example.com/webhook", IncidentIDs: []int{1, 0}},
+			webhooks: func() *mockWebhookSubscriptions { return &mockWebhookSubscriptions{} },
+			wantErr:  true,
+		},
+		{
+			name:     "validation error - unknown severity",
+			in:       WebhookSubscriptionRequestInput{URL: "https://example.com/webhook", Severities: []string{"extreme"}},
+			webhooks: func() *mockWebhookSubscriptions { return &mockWebhookSubscriptions{} },
+			wantErr:  true,
+		},
+		{
+			name:     "validation error - bbox with three values",
+			in:       WebhookSubscriptionRequestInput{URL: "https://example.com/webhook", BBox: []float64{37, 55, 38}},
+			webhooks: func() *mockWebhookSubscriptions { return &mockWebhookSubscriptions{} },
+			wantErr:  true,
+		},
+		{
+			name:     "validation error - inverted bbox",
+			in:       WebhookSubscriptionRequestInput{URL: "https://example.com/webhook", BBox: []float64{38, 55, 37, 56}},
+			webhooks: func() *mockWebhookSubscriptions { return &mockWebhookSubscriptions{} },
+			wantErr:  true,
+		},
+		{
+			name: "repository error",
+			in:   WebhookSubscriptionRequestInput{URL: "https://example.com/webhook"},
+			webhooks: func() *mockWebhookSubscriptions {
+				return &mockWebhookSubscriptions{
+					createFunc: func(ctx context.Context, sub *domain.WebhookSubscription) error {
+						return errors.New("failed database connection")
+					},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "success - generated secret, no filters",
+			in:   WebhookSubscriptionRequestInput{URL: "https://example.com/webhook"},
+			webhooks: func() *mockWebhookSubscriptions {
+				return &mockWebhookSubscriptions{
+					createFunc: func(ctx context.Context, sub *domain.WebhookSubscription) error {
+						sub.ID = 1
+						return nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *domain.WebhookSubscription) {
+				require.Equal(t, 1, result.ID)
+				require.True(t, result.Enabled)
+				require.Len(t, result.Secret, 2*webhookSecretBytes)
+				require.Empty(t, result.EventTypes)
+				require.NotNil(t, result.IncidentIDs)
+				require.Nil(t, result.BBox)
+			},
+		},
+		{
+			name: "success - filters",
+			in: WebhookSubscriptionRequestInput{
+				URL:         "http://localhost:9090/webhook",
+				Secret:      "0123456789abcdef",
+				Enabled:     &disabled,
+				EventTypes:  []string{"zone.entered", "incident.expired"},
+				IncidentIDs: []int{3},
+				Severities:  []string{"danger", "critical"},
+				BBox:        []float64{37, 55, 38, 56},
+			},
+			webhooks: func() *mockWebhookSubscriptions {
+				return &mockWebhookSubscriptions{
+					createFunc: func(ctx context.Context, sub *domain.WebhookSubscription) error {
+						sub.ID = 2
+						return nil
+					},
+				}
+			},
+			validateResult: func(t *testing.T, result *domain.WebhookSubscription) {
+				require.False(t, result.Enabled)
+				require.Equal(t, "0123456789abcdef", result.Secret)
+				require.Equal(t, []domain.EventType{domain.EventZoneEntered, domain.EventIncidentExpired}, result.EventTypes)
+				require.Equal(t, []int{3}, result.IncidentIDs)
+				require.Equal(t, []domain.Severity{domain.SeverityDanger, domain.SeverityCritical}, result.Severities)
+				require.Equal(t, &domain.BBox{MinLong: 37, MinLat: 55, MaxLong: 38, MaxLat: 56}, result.BBox)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &Service{
+				webhooks: tt.webhooks(),
+				logger:   &mockLogger{},
+			}
+
+			result, err := service.CreateWebhookSubscription(context.Background(), &tt.in)
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				require.Nil(t, result)
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			if tt.validateResult != nil {
+				tt.validateResult(t, result)
+			}
+		})
+	}
+}
+
+func TestService_UpdateWebhookSubscription(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       WebhookSubscriptionRequestInput
+		webhooks func() *mockWebhookSubscriptions
+		wantErr  bool
+	}{
+		{
+			name:     "validation error - invalid id",
+			in:       WebhookSubscriptionRequestInput{ID: "abc", URL: "https://example.com/webhook"},
+			webhooks: func() *mockWebhookSubscriptions { return &mockWebhookSubscriptions{} },
+			wantErr:  true,
+		},
+		{
+			name: "not found",
+			in:   WebhookSubscriptionRequestInput{ID: "1", URL: "https://example.com/webhook"},
+			webhooks: func() *mockWebhookSubscriptions {
+				return &mockWebhookSubscriptions{
+					updateFunc: func(ctx context.Context, sub *domain.WebhookSubscription) error {
+						return domain.ErrNotFound("webhook subscription not found")
+					},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "success - secret is kept and not returned",
+			in:   WebhookSubscriptionRequestInput{ID: "5", URL: "https://example.com/webhook"},
+			webhooks: func() *mockWebhookSubscriptions {
+				return &mockWebhookSubscriptions{
+					updateFunc: func(ctx context.Context, sub *domain.WebhookSubscription) error {
+						require.Equal(t, 5, sub.ID)
+						require.Empty(t, sub.Secret)
+						return nil
+					},
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := &Service{
+				webhooks: tt.webhooks(),
+				logger:   &mockLogger{},
+			}
+
+			result, err := service.UpdateWebhookSubscription(context.Background(), &tt.in)
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				require.Nil(t, result)
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.Equal(t, 5, result.ID)
+			require.Empty(t, result.Secret)
+		})
+	}
+}
+
+func TestService_ListWebhookSubscriptions(t *testing.T) {
+	service := &Service{
+		webhooks: &mockWebhookSubscriptions{
+			listFunc: func(ctx context.Context, enabledOnly bool) ([]domain.WebhookSubscription, error) {
+				require.False(t, enabledOnly)
+				return []domain.WebhookSubscription{{ID: 1, Secret: "0123456789abcdef"}, {ID: 2, Secret: "fedcba9876543210"}}, nil
+			},
+		},
+		logger: &mockLogger{},
+	}
+
+	result, err := service.ListWebhookSubscriptions(context.Background())
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	for _, sub := range result {
+		require.Empty(t, sub.Secret)
+	}
+}
+
+func TestWebhookSubscription_Matches(t *testing.T) {
+	event := &domain.Event{
+		Type:       domain.EventZoneEntered,
+		IncidentID: 3,
+		Severity:   domain.SeverityDanger,
+		LocationCheck: &domain.LocationCheck{
+			Lat:  55.75,
+			Long: 37.61,
+		},
+	}
+
+	tests := []struct {
+		name string
+		sub  domain.WebhookSubscription
+		want bool
+	}{
+		{name: "no filters", sub: domain.WebhookSubscription{}, want: true},
+		{name: "event type matches", sub: domain.WebhookSubscription{EventTypes: []domain.EventType{domain.EventZoneExited, domain.EventZoneEntered}}, want: true},
+		{name: "event type differs", sub: domain.WebhookSubscription{EventTypes: []domain.EventType{domain.EventZoneExited}}, want: false},
+		{name: "incident differs", sub: domain.WebhookSubscription{IncidentIDs: []int{1, 2}}, want: false},
+		{name: "severity matches", sub: domain.WebhookSubscription{Severities: []domain.Severity{domain.SeverityDanger}}, want: true},
+		{name: "severity differs", sub: domain.WebhookSubscription{Severities: []domain.Severity{domain.SeverityCritical}}, want: false},
+		{name: "point inside bbox", sub: domain.WebhookSubscription{BBox: &domain.BBox{MinLong: 37, MinLat: 55, MaxLong: 38, MaxLat: 56}}, want: true},
+		{name: "point outside bbox", sub: domain.WebhookSubscription{BBox: &domain.BBox{MinLong: 30, MinLat: 59, MaxLong: 31, MaxLat: 60}}, want: false},
+		{
+			name: "all filters match",
+			sub: domain.WebhookSubscription{
+				EventTypes:  []domain.EventType{domain.EventZoneEntered},
+				IncidentIDs: []int{3},
+				Severities:  []domain.Severity{domain.SeverityDanger},
+				BBox:        &domain.BBox{MinLong: 37, MinLat: 55, MaxLong: 38, MaxLat: 56},
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.sub.Matches(event))
+		})
+	}
+
+	noPoint := &domain.Event{Type: domain.EventZoneCrossed, IncidentID: 3}
+	sub := domain.WebhookSubscription{BBox: &domain.BBox{MinLong: 37, MinLat: 55, MaxLong: 38, MaxLat: 56}}
+	require.False(t, sub.Matches(noPoint))
+}
+
+func TestService_RotateWebhookSecret(t *testing.T) {
+	grace := func(secs int) *int { return &secs }
+
+	tests := []struct {
+		name      string
+		in        RotateWebhookSecretRequestInput
+		wantGrace time.Duration
+		wantErr   bool
+	}{
+		{
+			name:    "validation error - invalid id",
+			in:      RotateWebhookSecretRequestInput{ID: "abc"},
+			wantErr: true,
+		},
+		{
+			name:    "validation error - short secret",
+			in:      RotateWebhookSecretRequestInput{ID: "1", Secret: "short"},
+			wantErr: true,
+		},
+		{
+			name:    "validation error - grace period too long",
+			in:      RotateWebhookSecretRequestInput{ID: "1", GracePeriodSecs: grace(8 * 24 * 3600)},
+			wantErr: true,
+		},
+		{
+			name:    "validation error - negative grace period",
+			in:      RotateWebhookSecretRequestInput{ID: "1", GracePeriodSecs: grace(-1)},
+			wantErr: true,
+		},
+		{
+			name:      "success - default grace period",
+			in:        RotateWebhookSecretRequestInput{ID: "1"},
+			wantGrace: 24 * time.Hour,
+		},
+		{
+			name:      "success - immediate rotation",
+			in:        RotateWebhookSecretRequestInput{ID: "1", Secret: "0123456789abcdef", GracePeriodSecs: grace(0)},
+			wantGrace: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotSecret string
+			service := &Service{
+				webhooks: &mockWebhookSubscriptions{
+					rotateFunc: func(ctx context.Context, id int, secret string, grace time.Duration) (*domain.WebhookSubscription, error) {
+						require.Equal(t, 1, id)
+						require.Equal(t, tt.wantGrace, grace)
+						gotSecret = secret
+						return &domain.WebhookSubscription{ID: id, Secret: secret}, nil
+					},
+				},
+				logger: &mockLogger{},
+			}
+
+			result, err := service.RotateWebhookSecret(context.Background(), &tt.in)
+
+			if tt.wantErr {
+				require.Error(t, err, "expected error but got none")
+				require.Nil(t, result)
+				return
+			}
+
+			require.NoError(t, err, "unexpected error: %v", err)
+			require.Equal(t, gotSecret, result.Secret)
+			if tt.in.Secret != "" {
+				require.Equal(t, tt.in.Secret, gotSecret)
+			} else {
+				require.Len(t, gotSecret, 2*webhookSecretBytes)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Package signature подписывает тела вебхуков HMAC-SHA256 и проверяет подпись на стороне получателя.
+// Подписывается строка "<X-Timestamp>.<тело запроса>", поэтому подпись нельзя перенести на другое тело
+// или повторить запрос позже допустимого окна
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Timestamp"
+
+	// scheme - префикс каждой подписи в X-Signature, по которому получатель узнает алгоритм
+	scheme = "sha256="
+)
+
+var (
+	ErrMissing  = errors.New("signature headers are missing")
+	ErrStale    = errors.New("timestamp is outside of the allowed window")
+	ErrMismatch = errors.New("signature does not match")
+)
+
+// Sign возвращает HMAC-SHA256 от "<timestamp>.<body>" в hex
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Header собирает значение X-Signature. Во время ротации передаются оба секрета,
+// и получатель, который еще не сменил секрет или уже сменил его, проверяет запрос своим
+func Header(timestamp int64, body []byte, secrets ...string) string {
+	signatures := make([]string, len(secrets))
+	for i, secret := range secrets {
+		signatures[i] = scheme + Sign(secret, timestamp, body)
+	}
+	return strings.Join(signatures, ",")
+}
+
+// Verify проверяет, что одна из подписей X-Signature сделана секретом secret,
+// а X-Timestamp отличается от now не больше чем на tolerance
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, tolerance time.Duration, now time.Time) error {
+	if timestampHeader == "" || signatureHeader == "" {
+		return ErrMissing
+	}
+
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrMissing
+	}
+	if diff := now.Sub(time.Unix(timestamp, 0)); diff > tolerance || diff < -tolerance {
+		return ErrStale
+	}
+
+	expected := []byte(Sign(secret, timestamp, body))
+	for _, part := range strings.Split(signatureHeader, ",") {
+		signature, ok := strings.CutPrefix(strings.TrimSpace(part), scheme)
+		if ok && hmac.Equal([]byte(signature), expected) {
+			return nil
+		}
+	}
+	return ErrMismatch
+}
@@ -0,0 +1,102 @@
+package signature
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"zone.entered"}`)
+	ts := now.Unix()
+	tsHeader := strconv.FormatInt(ts, 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		header    string
+		body      []byte
+		wantErr   error
+	}{
+		{
+			name:      "valid signature",
+			secret:    "current-secret-16",
+			timestamp: tsHeader,
+			header:    Header(ts, body, "current-secret-16"),
+			body:      body,
+		},
+		{
+			name:      "rotation - receiver still on previous secret",
+			secret:    "previous-secret-16",
+			timestamp: tsHeader,
+			header:    Header(ts, body, "current-secret-16", "previous-secret-16"),
+			body:      body,
+		},
+		{
+			name:      "wrong secret",
+			secret:    "another-secret-16",
+			timestamp: tsHeader,
+			header:    Header(ts, body, "current-secret-16"),
+			body:      body,
+			wantErr:   ErrMismatch,
+		},
+		{
+			name:      "tampered body",
+			secret:    "current-secret-16",
+			timestamp: tsHeader,
+			header:    Header(ts, body, "current-secret-16"),
+			body:      []byte(`{"type":"zone.exited"}`),
+			wantErr:   ErrMismatch,
+		},
+		{
+			name:      "timestamp replaced",
+			secret:    "current-secret-16",
+			timestamp: strconv.FormatInt(ts+60, 10),
+			header:    Header(ts, body, "current-secret-16"),
+			body:      body,
+			wantErr:   ErrMismatch,
+		},
+		{
+			name:      "stale timestamp",
+			secret:    "current-secret-16",
+			timestamp: strconv.FormatInt(ts-301, 10),
+			header:    Header(ts-301, body, "current-secret-16"),
+			body:      body,
+			wantErr:   ErrStale,
+		},
+		{
+			name:      "timestamp from the future",
+			secret:    "current-secret-16",
+			timestamp: strconv.FormatInt(ts+301, 10),
+			header:    Header(ts+301, body, "current-secret-16"),
+			body:      body,
+			wantErr:   ErrStale,
+		},
+		{
+			name:      "missing signature",
+			secret:    "current-secret-16",
+			timestamp: tsHeader,
+			body:      body,
+			wantErr:   ErrMissing,
+		},
+		{
+			name:      "invalid timestamp",
+			secret:    "current-secret-16",
+			timestamp: "yesterday",
+			header:    Header(ts, body, "current-secret-16"),
+			body:      body,
+			wantErr:   ErrMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.header, tt.body, 5*time.Minute, now)
+			require.Equal(t, tt.wantErr, err)
+		})
+	}
+}
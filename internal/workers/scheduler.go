@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"red_collar/internal/service"
+	"time"
+
+	"github.com/theartofdevel/logging"
+)
+
+// ScheduleWorker периодически отправляет события о начале и окончании действия инцидентов
+type ScheduleWorker struct {
+	svc      *service.Service
+	interval time.Duration
+	logger   service.LoggerInterfaces
+}
+
+func NewScheduleWorker(svc *service.Service, interval time.Duration, logger service.LoggerInterfaces) *ScheduleWorker {
+	return &ScheduleWorker{
+		svc:      svc,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (w *ScheduleWorker) Start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	w.logger.Info("schedule worker started", logging.StringAttr("interval", w.interval.String()))
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				w.logger.Info("schedule worker stopping...")
+				return
+			case <-ticker.C:
+				// ошибка уже залогирована сервисом, следующая попытка - на следующем тике
+				_ = w.svc.ProcessIncidentSchedule(ctx)
+			}
+		}
+	}()
+	return done
+}
@@ -11,6 +11,9 @@ import (
 	"red_collar/internal/domain"
 	"red_collar/internal/repository"
 	"red_collar/internal/service"
+	"red_collar/internal/signature"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/theartofdevel/logging"
@@ -19,16 +22,35 @@ import (
 const (
 	maxRetries = 3
 	baseDelay  = 1 * time.Second
+
+	// subscriptionsTTL - как долго воркер использует загруженный список подписок.
+	// Изменения подписок начинают действовать не позже, чем через это время
+	subscriptionsTTL = 5 * time.Second
+	// defaultSubscriptionID - подписка без фильтров на WEBHOOK_URL из конфигурации
+	defaultSubscriptionID = 0
 )
 
+// subscriptionLister - хранилище подписок на вебхуки
+type subscriptionLister interface {
+	List(ctx context.Context, enabledOnly bool) ([]domain.WebhookSubscription, error)
+}
+
 type WebhookWorker struct {
-	queue      *repository.Queue
-	webhookURL string
-	logger     service.LoggerInterfaces
-	client     *http.Client
+	queue         *repository.Queue
+	subscriptions subscriptionLister
+	defaultURL    string
+	defaultSecret string
+	logger        service.LoggerInterfaces
+	client        *http.Client
+
+	// cached - включенные подписки на момент cachedAt, читаются только из цикла обработки
+	cached   []domain.WebhookSubscription
+	cachedAt time.Time
 }
 
-func NewWebhookWorker(queue *repository.Queue, webhookURL string, logger service.LoggerInterfaces) *WebhookWorker {
+// NewWebhookWorker создает воркер доставки. defaultURL, если задан, получает все события
+// в дополнение к подпискам, запросы на него подписываются defaultSecret; subscriptions может быть nil
+func NewWebhookWorker(queue *repository.Queue, subscriptions subscriptionLister, defaultURL, defaultSecret string, logger service.LoggerInterfaces) *WebhookWorker {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: true,
@@ -36,9 +58,11 @@ func NewWebhookWorker(queue *repository.Queue, webhookURL string, logger service
 	}
 
 	return &WebhookWorker{
-		queue:      queue,
-		webhookURL: webhookURL,
-		logger:     logger,
+		queue:         queue,
+		subscriptions: subscriptions,
+		defaultURL:    defaultURL,
+		defaultSecret: defaultSecret,
+		logger:        logger,
 		client: &http.Client{
 			Timeout:   10 * time.Second,
 			Transport: tr,
@@ -49,7 +73,7 @@ func NewWebhookWorker(queue *repository.Queue, webhookURL string, logger service
 func (w *WebhookWorker) Start(ctx context.Context) <-chan struct{} {
 	done := make(chan struct{})
 
-	w.logger.Info("webhook worker started", logging.StringAttr("webhook_url", w.webhookURL))
+	w.logger.Info("webhook worker started", logging.StringAttr("default_url", w.defaultURL))
 
 	go func() {
 		ticker := time.NewTicker(1 * time.Second)
@@ -63,6 +87,11 @@ func (w *WebhookWorker) Start(ctx context.Context) <-chan struct{} {
 				if err := w.queue.ProcessDelayedTasks(ctx); err != nil {
 					w.logger.Error("failed to process delayed tasks", logging.ErrAttr(err))
 				}
+				if reaped, err := w.queue.ReapExpired(ctx); err != nil {
+					w.logger.Error("failed to reap expired webhook tasks", logging.ErrAttr(err))
+				} else if reaped > 0 {
+					w.logger.Warn("unacknowledged webhook tasks returned to queue", logging.IntAttr("count", reaped))
+				}
 			}
 		}
 	}()
@@ -92,28 +121,142 @@ func (w *WebhookWorker) Start(ctx context.Context) <-chan struct{} {
 			if data == nil {
 				continue
 			}
-
-			if data.Attempt > 0 {
-				w.logger.Info("retrying webhook",
-					logging.IntAttr("attempt", data.Attempt),
-					logging.StringAttr("user_id", data.LocationCheck.UserID),
-				)
-			}
-
-			if err := w.sendWebhook(ctx, data.LocationCheck); err != nil {
-				w.handleWebhookError(ctx, data, err)
+			if data.Event == nil {
+				// Dequeue не выдает задачи без события, но раскладка и логи без него упадут
+				w.logger.Error("webhook task without event dropped")
+				w.ack(ctx, data)
 				continue
 			}
 
-			w.logger.Info("webhook sent successfully",
-				logging.StringAttr("user_id", data.LocationCheck.UserID),
-				logging.IntAttr("check_id", data.LocationCheck.ID),
-			)
+			if data.SubscriptionID == nil {
+				w.fanOut(ctx, data)
+			} else {
+				w.deliver(ctx, data)
+			}
+			w.ack(ctx, data)
 		}
 	}()
 	return done
 }
 
+// fanOut раскладывает новое событие на задачи для всех подходящих подписок.
+// Если подписки не удалось загрузить, событие повторяется по общим правилам
+func (w *WebhookWorker) fanOut(ctx context.Context, task *repository.WebhookTask) {
+	subs, err := w.loadSubscriptions(ctx)
+	if err != nil {
+		w.handleWebhookError(ctx, task, err)
+		return
+	}
+
+	var tasks []*repository.WebhookTask
+	for i := range subs {
+		if !subs[i].Matches(task.Event) {
+			continue
+		}
+		id := subs[i].ID
+		tasks = append(tasks, &repository.WebhookTask{
+			Event:          task.Event,
+			SubscriptionID: &id,
+			FirstAttempt:   task.FirstAttempt,
+		})
+	}
+
+	if len(tasks) == 0 {
+		w.logger.Debug("no webhook subscriptions matched event", eventLogAttrs(task.Event)...)
+		return
+	}
+
+	if err := w.queue.EnqueueTasks(ctx, tasks); err != nil {
+		w.handleWebhookError(ctx, task, err)
+		return
+	}
+
+	w.logger.Debug("event fanned out to webhook subscriptions",
+		append(eventLogAttrs(task.Event), logging.IntAttr("subscriptions", len(tasks)))...,
+	)
+}
+
+// deliver отправляет событие одной подписке. Задача удаленной или выключенной подписки отбрасывается
+func (w *WebhookWorker) deliver(ctx context.Context, task *repository.WebhookTask) {
+	subs, err := w.loadSubscriptions(ctx)
+	if err != nil {
+		w.handleWebhookError(ctx, task, err)
+		return
+	}
+
+	idx := slices.IndexFunc(subs, func(sub domain.WebhookSubscription) bool {
+		return sub.ID == *task.SubscriptionID
+	})
+	if idx < 0 {
+		w.logger.Warn("webhook subscription is disabled or deleted, task dropped", taskLogAttrs(task)...)
+		return
+	}
+
+	if task.Attempt > 0 {
+		w.logger.Info("retrying webhook",
+			append(taskLogAttrs(task), logging.IntAttr("attempt", task.Attempt))...,
+		)
+	}
+
+	if err := w.sendWebhook(ctx, &subs[idx], task.Event); err != nil {
+		if ctx.Err() != nil {
+			// Отправку прервала остановка воркера: задача не подтверждается и вернется в очередь
+			return
+		}
+		w.handleWebhookError(ctx, task, err)
+		return
+	}
+
+	w.logger.Info("webhook sent successfully", taskLogAttrs(task)...)
+}
+
+// ack подтверждает обработку задачи. Если воркер останавливается, задача остается неподтвержденной
+// и после таймаута видимости будет выдана снова
+func (w *WebhookWorker) ack(ctx context.Context, task *repository.WebhookTask) {
+	if ctx.Err() != nil {
+		return
+	}
+	if err := w.queue.Ack(ctx, task); err != nil {
+		w.logger.Error("failed to ack webhook task",
+			append(taskLogAttrs(task), logging.ErrAttr(err))...,
+		)
+	}
+}
+
+// loadSubscriptions возвращает включенные подписки, перечитывая их не чаще раза в subscriptionsTTL.
+// Если хранилище недоступно, до следующего перечитывания используется прежний список
+func (w *WebhookWorker) loadSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	if !w.cachedAt.IsZero() && time.Since(w.cachedAt) < subscriptionsTTL {
+		return w.cached, nil
+	}
+
+	var subs []domain.WebhookSubscription
+	if w.defaultURL != "" {
+		subs = append(subs, domain.WebhookSubscription{
+			ID:      defaultSubscriptionID,
+			URL:     w.defaultURL,
+			Secret:  w.defaultSecret,
+			Enabled: true,
+		})
+	}
+
+	if w.subscriptions != nil {
+		stored, err := w.subscriptions.List(ctx, true)
+		if err != nil {
+			if w.cachedAt.IsZero() {
+				return nil, fmt.Errorf("failed to load webhook subscriptions: %w", err)
+			}
+			w.logger.Error("failed to reload webhook subscriptions, using previous list", logging.ErrAttr(err))
+			w.cachedAt = time.Now()
+			return w.cached, nil
+		}
+		subs = append(subs, stored...)
+	}
+
+	w.cached, w.cachedAt = subs, time.Now()
+	return subs, nil
+}
+
 func (w *WebhookWorker) handleWebhookError(ctx context.Context, task *repository.WebhookTask, err error) {
 	task.Attempt++
 	task.LastError = err.Error()
@@ -121,15 +264,15 @@ func (w *WebhookWorker) handleWebhookError(ctx context.Context, task *repository
 	if w.isRetryable(err) && task.Attempt < maxRetries {
 		if err := w.queue.EnqueueWithDelay(ctx, task, w.calculateBackoff(task.Attempt)); err != nil {
 			w.logger.Error("failed to enqueue retry",
-				logging.StringAttr("user_id", task.LocationCheck.UserID),
-				logging.IntAttr("attempt", task.Attempt),
-				logging.ErrAttr(err),
+				append(taskLogAttrs(task),
+					logging.IntAttr("attempt", task.Attempt),
+					logging.ErrAttr(err),
+				)...,
 			)
 			w.sendToDLQ(ctx, task)
 		} else {
 			w.logger.Info("webhook task scheduled for retry",
-				logging.StringAttr("user_id", task.LocationCheck.UserID),
-				logging.IntAttr("attempt", task.Attempt),
+				append(taskLogAttrs(task), logging.IntAttr("attempt", task.Attempt))...,
 			)
 		}
 	} else {
@@ -141,30 +284,38 @@ func (w *WebhookWorker) handleWebhookError(ctx context.Context, task *repository
 func (w *WebhookWorker) sendToDLQ(ctx context.Context, task *repository.WebhookTask) {
 	if err := w.queue.EnqueueDLQ(ctx, task); err != nil {
 		w.logger.Error("failed to send task to DLQ",
-			logging.StringAttr("user_id", task.LocationCheck.UserID),
-			logging.ErrAttr(err),
+			append(taskLogAttrs(task), logging.ErrAttr(err))...,
 		)
 	} else {
 		w.logger.Warn("webhook task moved to DLQ",
-			logging.StringAttr("user_id", task.LocationCheck.UserID),
-			logging.IntAttr("check_id", task.LocationCheck.ID),
-			logging.IntAttr("final_attempt", task.Attempt),
-			logging.StringAttr("last_error", task.LastError),
+			append(taskLogAttrs(task),
+				logging.IntAttr("final_attempt", task.Attempt),
+				logging.StringAttr("last_error", task.LastError),
+			)...,
 		)
 	}
 }
 
-func (w *WebhookWorker) sendWebhook(ctx context.Context, check *domain.LocationCheck) error {
-	data, err := json.Marshal(check)
+// sendWebhook отправляет подписке событие в конверте. Подпись считается при каждой попытке, чтобы X-Timestamp
+// повторной отправки не выходил за допустимое окно получателя
+func (w *WebhookWorker) sendWebhook(ctx context.Context, sub *domain.WebhookSubscription, event *domain.Event) error {
+	data, err := json.Marshal(event.Envelope())
 	if err != nil {
-		return fmt.Errorf("failed to marshal location check: %w", err)
+		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.webhookURL, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewBuffer(data))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(domain.EventVersionHeader, domain.EventVersion)
+
+	timestamp := time.Now().Unix()
+	req.Header.Set(signature.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	if secrets := sub.SigningSecrets(); len(secrets) > 0 {
+		req.Header.Set(signature.SignatureHeader, signature.Header(timestamp, data, secrets...))
+	}
 
 	resp, err := w.client.Do(req)
 	if err != nil {
@@ -181,6 +332,34 @@ func (w *WebhookWorker) sendWebhook(ctx context.Context, check *domain.LocationC
 	return nil
 }
 
+// eventLogAttrs - атрибуты лога для события. Не у всех событий есть проверка координат
+func eventLogAttrs(event *domain.Event) []any {
+	if event == nil {
+		return nil
+	}
+	attrs := []any{
+		logging.StringAttr("event_id", event.ID),
+		logging.StringAttr("event", string(event.Type)),
+		logging.IntAttr("incident_id", event.IncidentID),
+	}
+	if event.LocationCheck != nil {
+		attrs = append(attrs,
+			logging.StringAttr("user_id", event.LocationCheck.UserID),
+			logging.IntAttr("check_id", event.LocationCheck.ID),
+		)
+	}
+	return attrs
+}
+
+// taskLogAttrs - атрибуты лога для задачи доставки
+func taskLogAttrs(task *repository.WebhookTask) []any {
+	attrs := eventLogAttrs(task.Event)
+	if task.SubscriptionID != nil {
+		attrs = append(attrs, logging.IntAttr("subscription_id", *task.SubscriptionID))
+	}
+	return attrs
+}
+
 func (w *WebhookWorker) isRetryable(err error) bool {
 	if err == nil {
 		return false
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -15,6 +16,7 @@ import (
 	"red_collar/internal/repository"
 	redisRepo "red_collar/internal/repository/redis"
 	"red_collar/internal/service"
+	"red_collar/internal/signature"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
@@ -23,6 +25,9 @@ import (
 	"github.com/theartofdevel/logging"
 )
 
+// testVisibilityTimeout - короткий срок видимости, чтобы тест повторной выдачи не ждал минуту
+const testVisibilityTimeout = 2 * time.Second
+
 var (
 	wbOnce sync.Once
 
@@ -88,7 +93,7 @@ func setup() error {
 	})
 
 	rdTestClient = rdTest.Client()
-	queueTestRepo = repository.NewQueue(rdTestClient)
+	queueTestRepo = repository.NewQueue(rdTestClient, testVisibilityTimeout)
 	return err
 }
 
@@ -98,15 +103,19 @@ func cleanupTestRD(t *testing.T) {
 	require.NoError(t, err)
 }
 
-func createTestLocationCheck(id int, userID string) *domain.LocationCheck {
-	return &domain.LocationCheck{
-		ID:           id,
-		UserID:       userID,
-		CheckedAt:    time.Now(),
-		Lat:          55.7558,
-		Long:         37.6173,
-		InDangerZone: true,
-		NearestID:    func() *int { v := 1; return &v }(),
+func createTestEvent(id int, userID string) *domain.Event {
+	return &domain.Event{
+		Type:       domain.EventZoneEntered,
+		IncidentID: 1,
+		LocationCheck: &domain.LocationCheck{
+			ID:           id,
+			UserID:       userID,
+			CheckedAt:    time.Now(),
+			Lat:          55.7558,
+			Long:         37.6173,
+			InDangerZone: true,
+			NearestID:    func() *int { v := 1; return &v }(),
+		},
 	}
 }
 
@@ -123,27 +132,29 @@ func TestWebhookWorker_Success(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	webhookReceived := make(chan *domain.LocationCheck, 1)
+	webhookReceived := make(chan *domain.EventEnvelope, 1)
 
 	server := createTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		var check domain.LocationCheck
-		err := json.NewDecoder(r.Body).Decode(&check)
+		require.Equal(t, domain.EventVersion, r.Header.Get(domain.EventVersionHeader))
+
+		var event domain.EventEnvelope
+		err := json.NewDecoder(r.Body).Decode(&event)
 		require.NoError(t, err)
 
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{"message": "webhook received"})
 
 		select {
-		case webhookReceived <- &check:
+		case webhookReceived <- &event:
 		default:
 		}
 	})
 
 	logger := createTestLogger()
-	worker := NewWebhookWorker(queueTestRepo, server.URL, logger)
+	worker := NewWebhookWorker(queueTestRepo, nil, server.URL, "", logger)
 
-	check := createTestLocationCheck(1, "colorvax")
-	err := queueTestRepo.Enqueue(ctx, check)
+	event := createTestEvent(1, "colorvax")
+	err := queueTestRepo.Enqueue(ctx, event)
 	require.NoError(t, err)
 
 	queueLenBefore, err := rdTestClient.LLen(ctx, "webhook:queue").Result()
@@ -153,7 +164,7 @@ func TestWebhookWorker_Success(t *testing.T) {
 	done := worker.Start(ctx)
 	time.Sleep(50 * time.Millisecond)
 
-	var received *domain.LocationCheck
+	var received *domain.EventEnvelope
 	select {
 	case received = <-webhookReceived:
 	case <-time.After(5 * time.Second):
@@ -161,8 +172,11 @@ func TestWebhookWorker_Success(t *testing.T) {
 	}
 
 	require.NotNil(t, received)
-	require.Equal(t, 1, received.ID)
-	require.Equal(t, "colorvax", received.UserID)
+	require.Equal(t, event.ID, received.ID)
+	require.Equal(t, domain.EventVersion, received.Version)
+	require.Equal(t, domain.EventZoneEntered, received.Type)
+	require.Equal(t, 1, received.Data.LocationCheck.ID)
+	require.Equal(t, "colorvax", received.Data.LocationCheck.UserID)
 
 	queueLen, err := rdTestClient.LLen(ctx, "webhook:queue").Result()
 	require.NoError(t, err)
@@ -195,7 +209,7 @@ func TestWebhookWorker_RetryOnInternalServerError(t *testing.T) {
 	defer cancel()
 
 	attemptCount := 0
-	webhookReceived := make(chan *domain.LocationCheck, 1)
+	webhookReceived := make(chan *domain.EventEnvelope, 1)
 
 	server := createTestServer(t, func(w http.ResponseWriter, r *http.Request) {
 		attemptCount++
@@ -205,24 +219,24 @@ func TestWebhookWorker_RetryOnInternalServerError(t *testing.T) {
 			return
 		}
 
-		var check domain.LocationCheck
-		err := json.NewDecoder(r.Body).Decode(&check)
+		var event domain.EventEnvelope
+		err := json.NewDecoder(r.Body).Decode(&event)
 		require.NoError(t, err)
 
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{"message": "webhook received"})
 
 		select {
-		case webhookReceived <- &check:
+		case webhookReceived <- &event:
 		default:
 		}
 	})
 
 	logger := createTestLogger()
-	worker := NewWebhookWorker(queueTestRepo, server.URL, logger)
+	worker := NewWebhookWorker(queueTestRepo, nil, server.URL, "", logger)
 
-	check := createTestLocationCheck(1, "colorvax")
-	err := queueTestRepo.Enqueue(ctx, check)
+	event := createTestEvent(1, "colorvax")
+	err := queueTestRepo.Enqueue(ctx, event)
 	require.NoError(t, err)
 
 	queueLenBefore, err := rdTestClient.LLen(ctx, "webhook:queue").Result()
@@ -232,7 +246,7 @@ func TestWebhookWorker_RetryOnInternalServerError(t *testing.T) {
 	done := worker.Start(ctx)
 	time.Sleep(50 * time.Millisecond)
 
-	var received *domain.LocationCheck
+	var received *domain.EventEnvelope
 	select {
 	case received = <-webhookReceived:
 	case <-time.After(5 * time.Second):
@@ -242,8 +256,11 @@ func TestWebhookWorker_RetryOnInternalServerError(t *testing.T) {
 	require.GreaterOrEqual(t, attemptCount, 2)
 
 	require.NotNil(t, received)
-	require.Equal(t, 1, received.ID)
-	require.Equal(t, "colorvax", received.UserID)
+	require.Equal(t, event.ID, received.ID)
+	require.Equal(t, domain.EventVersion, received.Version)
+	require.Equal(t, domain.EventZoneEntered, received.Type)
+	require.Equal(t, 1, received.Data.LocationCheck.ID)
+	require.Equal(t, "colorvax", received.Data.LocationCheck.UserID)
 
 	queueLen, err := rdTestClient.LLen(ctx, "webhook:queue").Result()
 	require.NoError(t, err)
@@ -280,10 +297,10 @@ func TestWebhook_MoveToDLQAfterMaxRetries(t *testing.T) {
 	})
 
 	logger := createTestLogger()
-	worker := NewWebhookWorker(queueTestRepo, server.URL, logger)
+	worker := NewWebhookWorker(queueTestRepo, nil, server.URL, "", logger)
 
-	check := createTestLocationCheck(1, "colorvax")
-	err := queueTestRepo.Enqueue(ctx, check)
+	event := createTestEvent(1, "colorvax")
+	err := queueTestRepo.Enqueue(ctx, event)
 	require.NoError(t, err)
 
 	queueLenBefore, err := rdTestClient.LLen(ctx, "webhook:queue").Result()
@@ -346,10 +363,10 @@ func TestWebhook_NotRetryableError(t *testing.T) {
 	})
 
 	logger := createTestLogger()
-	worker := NewWebhookWorker(queueTestRepo, server.URL, logger)
+	worker := NewWebhookWorker(queueTestRepo, nil, server.URL, "", logger)
 
-	check := createTestLocationCheck(1, "colorvax")
-	err := queueTestRepo.Enqueue(ctx, check)
+	event := createTestEvent(1, "colorvax")
+	err := queueTestRepo.Enqueue(ctx, event)
 	require.NoError(t, err)
 
 	queueLenBefore, err := rdTestClient.LLen(ctx, "webhook:queue").Result()
@@ -407,29 +424,29 @@ func TestWebhook_DelayedTaskProcessing(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	webhookReceived := make(chan *domain.LocationCheck, 1)
+	webhookReceived := make(chan *domain.EventEnvelope, 1)
 
 	server := createTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		var check domain.LocationCheck
-		err := json.NewDecoder(r.Body).Decode(&check)
+		var event domain.EventEnvelope
+		err := json.NewDecoder(r.Body).Decode(&event)
 		require.NoError(t, err)
 
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{"message": "webhook received"})
 
 		select {
-		case webhookReceived <- &check:
+		case webhookReceived <- &event:
 		default:
 		}
 	})
 
 	logger := createTestLogger()
-	worker := NewWebhookWorker(queueTestRepo, server.URL, logger)
+	worker := NewWebhookWorker(queueTestRepo, nil, server.URL, "", logger)
 
 	task := &repository.WebhookTask{
-		LocationCheck: createTestLocationCheck(1, "colorvax"),
-		Attempt:       1,
-		FirstAttempt:  time.Now(),
+		Event:        createTestEvent(1, "colorvax"),
+		Attempt:      1,
+		FirstAttempt: time.Now(),
 	}
 
 	err := queueTestRepo.EnqueueWithDelay(ctx, task, 100*time.Millisecond)
@@ -442,7 +459,7 @@ func TestWebhook_DelayedTaskProcessing(t *testing.T) {
 	done := worker.Start(ctx)
 	time.Sleep(50 * time.Millisecond)
 
-	var received *domain.LocationCheck
+	var received *domain.EventEnvelope
 	select {
 	case received = <-webhookReceived:
 	case <-time.After(2 * time.Second):
@@ -450,8 +467,9 @@ func TestWebhook_DelayedTaskProcessing(t *testing.T) {
 	}
 
 	require.NotNil(t, received)
-	require.Equal(t, 1, received.ID)
-	require.Equal(t, "colorvax", received.UserID)
+	require.Equal(t, domain.EventZoneEntered, received.Type)
+	require.Equal(t, 1, received.Data.LocationCheck.ID)
+	require.Equal(t, "colorvax", received.Data.LocationCheck.UserID)
 
 	queueLen, err := rdTestClient.LLen(ctx, "webhook:queue").Result()
 	require.NoError(t, err)
@@ -474,4 +492,272 @@ func TestWebhook_DelayedTaskProcessing(t *testing.T) {
 	}
 }
 
+// stubSubscriptions - хранилище подписок в памяти
+type stubSubscriptions struct {
+	subs []domain.WebhookSubscription
+}
+
+func (s *stubSubscriptions) List(ctx context.Context, enabledOnly bool) ([]domain.WebhookSubscription, error) {
+	return s.subs, nil
+}
+
+func TestWebhookWorker_FanOutToSubscriptions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if err := setup(); err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+	defer cleanupTestRD(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newReceiver := func(status int) (*httptest.Server, chan *domain.EventEnvelope) {
+		received := make(chan *domain.EventEnvelope, 4)
+		server := createTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			var event domain.EventEnvelope
+			if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+				received <- &event
+			}
+			w.WriteHeader(status)
+		})
+		return server, received
+	}
+
+	entered, enteredCh := newReceiver(http.StatusOK)
+	otherZone, otherZoneCh := newReceiver(http.StatusOK)
+	failing, failingCh := newReceiver(http.StatusBadRequest)
+
+	subscriptions := &stubSubscriptions{subs: []domain.WebhookSubscription{
+		{ID: 1, URL: entered.URL, Enabled: true, EventTypes: []domain.EventType{domain.EventZoneEntered}},
+		{ID: 2, URL: otherZone.URL, Enabled: true, IncidentIDs: []int{99}},
+		{ID: 3, URL: failing.URL, Enabled: true},
+	}}
+
+	logger := createTestLogger()
+	worker := NewWebhookWorker(queueTestRepo, subscriptions, "", "", logger)
+
+	err := queueTestRepo.Enqueue(ctx, createTestEvent(1, "colorvax"))
+	require.NoError(t, err)
+
+	done := worker.Start(ctx)
+
+	select {
+	case received := <-enteredCh:
+		require.Equal(t, "colorvax", received.Data.LocationCheck.UserID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for webhook to be received")
+	}
+
+	select {
+	case <-failingCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for failing webhook to be called")
+	}
+
+	// ошибка одной подписки не влияет на доставку остальным и попадает в DLQ со своим subscription_id
+	require.Eventually(t, func() bool {
+		n, err := rdTestClient.LLen(ctx, "webhook:dlq").Result()
+		return err == nil && n == 1
+	}, 3*time.Second, 50*time.Millisecond)
+
+	dlqData, err := rdTestClient.LPop(ctx, "webhook:dlq").Result()
+	require.NoError(t, err)
+
+	var task repository.WebhookTask
+	err = json.Unmarshal([]byte(dlqData), &task)
+	require.NoError(t, err)
+	require.NotNil(t, task.SubscriptionID)
+	require.Equal(t, 3, *task.SubscriptionID)
+	require.Equal(t, 1, task.Attempt)
+
+	require.Empty(t, otherZoneCh)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout waiting for worker to stop")
+	}
+}
+
+func TestWebhookWorker_SignsRequests(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if err := setup(); err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+	defer cleanupTestRD(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	verified := make(chan error, 2)
+	server := createTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		// получатель еще не сменил секрет после ротации
+		err = signature.Verify("previous-secret-0123",
+			r.Header.Get(signature.TimestampHeader),
+			r.Header.Get(signature.SignatureHeader),
+			body, time.Minute, time.Now(),
+		)
+		verified <- err
+		w.WriteHeader(http.StatusOK)
+	})
+
+	subscriptions := &stubSubscriptions{subs: []domain.WebhookSubscription{{
+		ID:             1,
+		URL:            server.URL,
+		Secret:         "current-secret-0123",
+		PreviousSecret: "previous-secret-0123",
+		Enabled:        true,
+	}}}
+
+	logger := createTestLogger()
+	worker := NewWebhookWorker(queueTestRepo, subscriptions, "", "", logger)
+
+	err := queueTestRepo.Enqueue(ctx, createTestEvent(1, "colorvax"))
+	require.NoError(t, err)
+
+	done := worker.Start(ctx)
+
+	select {
+	case err := <-verified:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for webhook to be received")
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout waiting for worker to stop")
+	}
+}
+
+func TestWebhookWorker_RedeliversUnackedTask(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if err := setup(); err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+	defer cleanupTestRD(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	webhookReceived := make(chan *domain.EventEnvelope, 1)
+	server := createTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var event domain.EventEnvelope
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		w.WriteHeader(http.StatusOK)
+		webhookReceived <- &event
+	})
+
+	event := createTestEvent(1, "colorvax")
+	require.NoError(t, queueTestRepo.Enqueue(ctx, event))
+
+	// предыдущий воркер забрал задачу и упал, не подтвердив ее
+	task, err := queueTestRepo.Dequeue(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	processingLen, err := rdTestClient.LLen(ctx, "webhook:processing").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), processingLen)
+
+	worker := NewWebhookWorker(queueTestRepo, nil, server.URL, "", createTestLogger())
+	done := worker.Start(ctx)
+
+	select {
+	case received := <-webhookReceived:
+		require.Equal(t, event.ID, received.ID)
+	case <-time.After(testVisibilityTimeout + 5*time.Second):
+		t.Fatal("timeout waiting for unacknowledged task to be redelivered")
+	}
+
+	require.Eventually(t, func() bool {
+		n, err := rdTestClient.LLen(ctx, "webhook:processing").Result()
+		return err == nil && n == 0
+	}, 3*time.Second, 50*time.Millisecond)
+
+	leases, err := rdTestClient.ZCard(ctx, "webhook:processing:leases").Result()
+	require.NoError(t, err)
+	require.Zero(t, leases)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout waiting for worker to stop")
+	}
+}
+
+func TestWebhookWorker_DeliversLegacyTask(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	if err := setup(); err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+	defer cleanupTestRD(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	webhookReceived := make(chan *domain.EventEnvelope, 1)
+	server := createTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var event domain.EventEnvelope
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		w.WriteHeader(http.StatusOK)
+		webhookReceived <- &event
+	})
+
+	// задача, поставленная в очередь до появления событий
+	payload := `{"location_check":{"id":7,"user_id":"colorvax","checked_at":"2026-10-17T09:30:00Z","lat":55.7558,"long":37.6173,"in_danger_zone":true,"nearest_id":1},"attempt":0,"first_attempt":"2026-10-17T09:30:01Z"}`
+	require.NoError(t, rdTestClient.LPush(ctx, "webhook:queue", payload).Err())
+
+	worker := NewWebhookWorker(queueTestRepo, nil, server.URL, "", createTestLogger())
+	done := worker.Start(ctx)
+
+	select {
+	case received := <-webhookReceived:
+		require.Equal(t, "legacy-check-7", received.ID)
+		require.Equal(t, domain.EventZoneEntered, received.Type)
+		require.Equal(t, 7, received.Data.LocationCheck.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for legacy task to be delivered")
+	}
+
+	require.Eventually(t, func() bool {
+		n, err := rdTestClient.LLen(ctx, "webhook:processing").Result()
+		return err == nil && n == 0
+	}, 3*time.Second, 50*time.Millisecond)
+
+	dlqLen, err := rdTestClient.LLen(ctx, "webhook:dlq").Result()
+	require.NoError(t, err)
+	require.Zero(t, dlqLen)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout waiting for worker to stop")
+	}
+}
+
 func TestWebhook_NetworkError(t *testing.T) {}
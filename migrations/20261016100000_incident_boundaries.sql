@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE incidents
+    ADD COLUMN boundary GEOGRAPHY(MultiPolygon, 4326),
+    ADD CONSTRAINT incidents_boundary_valid_chk
+        CHECK (boundary IS NULL OR ST_IsValid(boundary::geometry));
+
+CREATE INDEX incidents_boundary_gist_idx
+ON incidents
+USING GIST (boundary);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS incidents_boundary_gist_idx;
+
+ALTER TABLE incidents
+    DROP CONSTRAINT IF EXISTS incidents_boundary_valid_chk,
+    DROP COLUMN IF EXISTS boundary;
+-- +goose StatementEnd
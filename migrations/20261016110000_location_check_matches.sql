@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE location_check_matches (
+    check_id     INTEGER NOT NULL REFERENCES location_checks(id) ON DELETE CASCADE,
+    incident_id  INTEGER NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    distance_m   DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (check_id, incident_id)
+);
+
+CREATE INDEX location_check_matches_incident_idx
+ON location_check_matches (incident_id);
+
+INSERT INTO location_check_matches (check_id, incident_id, distance_m)
+SELECT
+    c.id,
+    c.nearest_id,
+    ST_Distance(i.geom, ST_SetSRID(ST_MakePoint(c.long, c.lat), 4326)::geography)
+FROM location_checks c
+JOIN incidents i ON i.id = c.nearest_id
+WHERE c.in_danger_zone = true;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS location_check_matches;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE incidents
+    ADD COLUMN warning_buffer_m INTEGER CHECK (warning_buffer_m >= 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE incidents
+    DROP COLUMN IF EXISTS warning_buffer_m;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE user_last_checks (
+    user_id     TEXT PRIMARY KEY,
+    check_id    INTEGER NOT NULL REFERENCES location_checks(id) ON DELETE CASCADE,
+    lat         DOUBLE PRECISION NOT NULL,
+    long        DOUBLE PRECISION NOT NULL,
+    checked_at  TIMESTAMP NOT NULL
+);
+
+INSERT INTO user_last_checks (user_id, check_id, lat, long, checked_at)
+SELECT DISTINCT ON (user_id) user_id, id, lat, long, checked_at
+FROM location_checks
+ORDER BY user_id, checked_at DESC, id DESC;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_last_checks;
+-- +goose StatementEnd
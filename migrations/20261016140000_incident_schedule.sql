@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE incidents
+    ADD COLUMN starts_at TIMESTAMP,
+    ADD COLUMN ends_at TIMESTAMP,
+    ADD COLUMN activation_notified BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN expiry_notified BOOLEAN NOT NULL DEFAULT true,
+    ADD CONSTRAINT incidents_schedule_chk CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at);
+
+CREATE INDEX incidents_pending_activation_idx
+ON incidents (starts_at)
+WHERE NOT activation_notified;
+
+CREATE INDEX incidents_pending_expiry_idx
+ON incidents (ends_at)
+WHERE NOT expiry_notified;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS incidents_pending_expiry_idx;
+DROP INDEX IF EXISTS incidents_pending_activation_idx;
+
+ALTER TABLE incidents
+    DROP CONSTRAINT IF EXISTS incidents_schedule_chk,
+    DROP COLUMN IF EXISTS expiry_notified,
+    DROP COLUMN IF EXISTS activation_notified,
+    DROP COLUMN IF EXISTS ends_at,
+    DROP COLUMN IF EXISTS starts_at;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE incidents
+    ADD COLUMN severity TEXT NOT NULL DEFAULT 'warning',
+    ADD COLUMN category TEXT NOT NULL DEFAULT 'other',
+    ADD CONSTRAINT incidents_severity_chk CHECK (severity IN ('info', 'warning', 'danger', 'critical'));
+
+CREATE INDEX incidents_severity_category_idx
+ON incidents (severity, category);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS incidents_severity_category_idx;
+
+ALTER TABLE incidents
+    DROP CONSTRAINT IF EXISTS incidents_severity_chk,
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS severity;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX incidents_search_idx
+ON incidents
+USING GIN (to_tsvector('simple', title || ' ' || COALESCE(description, '')));
+
+CREATE INDEX incidents_created_at_idx
+ON incidents (created_at);
+
+CREATE INDEX incidents_updated_at_idx
+ON incidents (updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS incidents_updated_at_idx;
+DROP INDEX IF EXISTS incidents_created_at_idx;
+DROP INDEX IF EXISTS incidents_search_idx;
+-- +goose StatementEnd
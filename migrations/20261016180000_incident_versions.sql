@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE incident_versions (
+    id                BIGSERIAL PRIMARY KEY,
+    incident_id       INTEGER NOT NULL,
+    version           INTEGER NOT NULL,
+    operation         TEXT NOT NULL CHECK (operation IN ('create', 'update', 'delete')),
+    changed_by        TEXT NOT NULL,
+    changed_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    title             TEXT NOT NULL,
+    description       TEXT,
+    lat               DOUBLE PRECISION NOT NULL,
+    long              DOUBLE PRECISION NOT NULL,
+    radius_m          INTEGER NOT NULL,
+    active            BOOLEAN NOT NULL,
+    severity          TEXT NOT NULL,
+    category          TEXT NOT NULL,
+    geom              GEOGRAPHY(Point, 4326) NOT NULL,
+    boundary          GEOGRAPHY(MultiPolygon, 4326),
+    warning_buffer_m  INTEGER,
+    starts_at         TIMESTAMP,
+    ends_at           TIMESTAMP,
+    created_at        TIMESTAMP NOT NULL,
+    updated_at        TIMESTAMP NOT NULL,
+    UNIQUE (incident_id, version)
+);
+
+CREATE INDEX incident_versions_incident_changed_idx
+ON incident_versions (incident_id, changed_at);
+
+INSERT INTO incident_versions (
+    incident_id, version, operation, changed_by, changed_at,
+    title, description, lat, long, radius_m, active, severity, category,
+    geom, boundary, warning_buffer_m, starts_at, ends_at, created_at, updated_at
+)
+SELECT
+    id, version, 'create', 'system', updated_at,
+    title, description, lat, long, radius_m, active, severity, category,
+    geom, boundary, warning_buffer_m, starts_at, ends_at, created_at, updated_at
+FROM incidents;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS incident_versions;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE incidents
+    ADD COLUMN deleted_at TIMESTAMP,
+    DROP CONSTRAINT incidents_title_key;
+
+-- название уникально только среди неархивных инцидентов
+CREATE UNIQUE INDEX incidents_title_uniq_idx
+ON incidents (title)
+WHERE deleted_at IS NULL;
+
+CREATE INDEX incidents_deleted_at_idx
+ON incidents (deleted_at)
+WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE incident_versions
+    DROP CONSTRAINT incident_versions_operation_check,
+    ADD CONSTRAINT incident_versions_operation_check
+        CHECK (operation IN ('create', 'update', 'delete', 'restore'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM incidents WHERE deleted_at IS NOT NULL;
+
+DELETE FROM incident_versions WHERE operation = 'restore';
+
+ALTER TABLE incident_versions
+    DROP CONSTRAINT incident_versions_operation_check,
+    ADD CONSTRAINT incident_versions_operation_check
+        CHECK (operation IN ('create', 'update', 'delete'));
+
+DROP INDEX IF EXISTS incidents_deleted_at_idx;
+DROP INDEX IF EXISTS incidents_title_uniq_idx;
+
+ALTER TABLE incidents
+    DROP COLUMN IF EXISTS deleted_at,
+    ADD CONSTRAINT incidents_title_key UNIQUE (title);
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX location_checks_user_checked_at_idx
+ON location_checks (user_id, checked_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS location_checks_user_checked_at_idx;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX user_last_checks_geog_gist_idx
+ON user_last_checks
+USING GIST ((ST_SetSRID(ST_MakePoint(long, lat), 4326)::geography));
+
+CREATE INDEX user_last_checks_checked_at_idx
+ON user_last_checks (checked_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS user_last_checks_checked_at_idx;
+DROP INDEX IF EXISTS user_last_checks_geog_gist_idx;
+-- +goose StatementEnd
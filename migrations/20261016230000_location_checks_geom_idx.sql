@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX location_checks_geom_gist_idx
+ON location_checks
+USING GIST ((ST_SetSRID(ST_MakePoint(long, lat), 4326)));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS location_checks_geom_gist_idx;
+-- +goose StatementEnd
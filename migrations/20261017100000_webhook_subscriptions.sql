@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE webhook_subscriptions (
+    id            SERIAL PRIMARY KEY,
+    url           TEXT NOT NULL,
+    secret        TEXT NOT NULL,
+    enabled       BOOLEAN NOT NULL DEFAULT true,
+    event_types   TEXT[] NOT NULL DEFAULT '{}',
+    incident_ids  INTEGER[] NOT NULL DEFAULT '{}',
+    severities    TEXT[] NOT NULL DEFAULT '{}',
+    bbox_min_long DOUBLE PRECISION,
+    bbox_min_lat  DOUBLE PRECISION,
+    bbox_max_long DOUBLE PRECISION,
+    bbox_max_lat  DOUBLE PRECISION,
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT webhook_subscriptions_bbox_check CHECK (
+        (bbox_min_long IS NULL) = (bbox_min_lat IS NULL)
+        AND (bbox_min_long IS NULL) = (bbox_max_long IS NULL)
+        AND (bbox_min_long IS NULL) = (bbox_max_lat IS NULL)
+    )
+);
+
+CREATE INDEX webhook_subscriptions_enabled_idx ON webhook_subscriptions (id) WHERE enabled;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webhook_subscriptions;
+-- +goose StatementEnd